@@ -5,23 +5,22 @@ go 1.18
 require (
 	github.com/PereRohit/util v0.0.4
 	github.com/SebastiaanKlippert/go-wkhtmltopdf v1.7.2
+	github.com/boombuler/barcode v1.0.1
+	github.com/cbroglie/mustache v1.4.0
 	github.com/golang/mock v1.6.0
 	github.com/google/uuid v1.3.0
 	github.com/gorilla/mux v1.8.0
 	github.com/vatsal278/go-redis-cache v1.1.0
-	golang.org/x/net v0.0.0-20211112202133-69e39bad7dc2
+	github.com/yuin/goldmark v1.4.12
+	golang.org/x/image v0.18.0
+	golang.org/x/net v0.17.0
+	golang.org/x/sync v0.7.0
 )
 
 require (
 	github.com/cespare/xxhash/v2 v2.1.2 // indirect
 	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
-	github.com/go-playground/locales v0.14.0 // indirect
-	github.com/go-playground/universal-translator v0.18.0 // indirect
-	github.com/go-playground/validator/v10 v10.11.0 // indirect
 	github.com/go-redis/redis/v8 v8.11.5 // indirect
 	github.com/google/go-cmp v0.5.9 // indirect
-	github.com/leodido/go-urn v1.2.1 // indirect
-	golang.org/x/crypto v0.0.0-20211215153901-e495a2d5b3d3 // indirect
-	golang.org/x/sys v0.0.0-20211216021012-1d35b9e2eb4e // indirect
-	golang.org/x/text v0.3.7 // indirect
+	golang.org/x/text v0.16.0 // indirect
 )
@@ -19,21 +19,123 @@ const (
 	ErrConvertingToPdf
 	ErrDecodingData
 	ErrIdNeeded
+	ErrInvalidMissingKey
+	ErrMissingKey
+	ErrInvalidEngine
+	ErrInvalidFormat
+	ErrInvalidTheme
+	ErrMarkdownConversion
+	ErrInvalidLocale
+	ErrInvalidCatalog
+	ErrInvalidBundle
+	ErrInvalidAssetName
+	ErrAssetNotFound
+	ErrInvalidFuncArgs
+	ErrInvalidOutput
+	ErrRendererUnavailable
+	ErrRenderingImage
+	ErrInvalidPageSettings
+	ErrInvalidPageOrder
+	ErrPageNotFound
+	ErrLastPage
+	ErrInvalidRecords
+	ErrPayloadTooLarge
+	ErrValuesTooDeep
+	ErrPageTooLarge
+	ErrExecutionTimeout
+	ErrTooManyPages
+	ErrInvalidPageSetup
+	ErrPageSetupOverride
+	ErrInvalidHeaderFooter
+	ErrInvalidToc
+	ErrInvalidOutline
+	ErrInvalidRenderer
+	ErrQueueFull
+	ErrRendererBusy
+	ErrRenderTimeout
+	ErrRequestCanceled
+	ErrInvalidRenderTimeout
+	ErrInvalidDisposition
+	ErrInvalidFilename
+	ErrInvalidOutputProfile
+	ErrPdfAConversion
+	ErrPdfAValidation
+	ErrInvalidCompose
+	ErrComposeFileNotFound
+	ErrInvalidComposePdf
+	ErrMergingPdf
+	ErrInvalidStamp
+	ErrStampImage
+	ErrStampingPdf
+	ErrImagePageOutOfRange
+	ErrWebpUnsupported
+	ErrTooManyIterations
 )
 
 var errCodes = map[errCode]string{
-	ErrFileSizeExceeded:   "file size exceeded",
-	ErrFileParseFail:      "failed to parse file",
-	ErrReadFileFail:       "failed to read file",
-	ErrFileConversionFail: "failed to convert file",
-	ErrFileStoreFail:      "unable to store file",
-	ErrFetchingFile:       "failed to fetch file",
-	ErrIdNotfound:         "id not found",
-	ErrKeyNotFound:        "unable to find this Uuid",
-	ErrEncodingFile:       "unable to json encode the data",
-	ErrConvertingToPdf:    "unable to convert to pdf format",
-	ErrIdNeeded:           "id needed",
-	ErrDecodingData:       "unable to decode the data",
+	ErrFileSizeExceeded:     "file size exceeded",
+	ErrFileParseFail:        "failed to parse file",
+	ErrReadFileFail:         "failed to read file",
+	ErrFileConversionFail:   "failed to convert file",
+	ErrFileStoreFail:        "unable to store file",
+	ErrFetchingFile:         "failed to fetch file",
+	ErrIdNotfound:           "id not found",
+	ErrKeyNotFound:          "unable to find this Uuid",
+	ErrEncodingFile:         "unable to json encode the data",
+	ErrConvertingToPdf:      "unable to convert to pdf format",
+	ErrIdNeeded:             "id needed",
+	ErrDecodingData:         "unable to decode the data",
+	ErrInvalidMissingKey:    "invalid missing key option",
+	ErrMissingKey:           "value missing for template key",
+	ErrInvalidEngine:        "unsupported template engine",
+	ErrInvalidFormat:        "unsupported template format",
+	ErrInvalidTheme:         "unknown markdown theme",
+	ErrMarkdownConversion:   "unable to convert markdown to html",
+	ErrInvalidLocale:        "invalid locale",
+	ErrInvalidCatalog:       "invalid message catalog",
+	ErrInvalidBundle:        "invalid asset bundle",
+	ErrInvalidAssetName:     "invalid asset name",
+	ErrAssetNotFound:        "asset not found",
+	ErrInvalidFuncArgs:      "invalid template function arguments",
+	ErrInvalidOutput:        "unsupported output format or options",
+	ErrRendererUnavailable:  "renderer not available",
+	ErrRenderingImage:       "unable to render image",
+	ErrInvalidPageSettings:  "invalid page settings",
+	ErrInvalidPageOrder:     "invalid page order",
+	ErrPageNotFound:         "page not found",
+	ErrLastPage:             "a template needs at least one page",
+	ErrInvalidRecords:       "invalid mail merge records",
+	ErrPayloadTooLarge:      "request payload too large",
+	ErrValuesTooDeep:        "values nested too deep",
+	ErrPageTooLarge:         "executed page too large",
+	ErrExecutionTimeout:     "template execution timed out",
+	ErrTooManyPages:         "too many pages",
+	ErrInvalidPageSetup:     "invalid page setup",
+	ErrPageSetupOverride:    "page setup option can't be overridden",
+	ErrInvalidHeaderFooter:  "invalid header or footer",
+	ErrInvalidToc:           "invalid table of contents options",
+	ErrInvalidOutline:       "invalid outline options",
+	ErrInvalidRenderer:      "unsupported renderer",
+	ErrQueueFull:            "too many render requests, retry later",
+	ErrRendererBusy:         "all renderers busy, retry later",
+	ErrRenderTimeout:        "render timed out",
+	ErrRequestCanceled:      "request canceled",
+	ErrInvalidRenderTimeout: "invalid render timeout",
+	ErrInvalidDisposition:   "unsupported content disposition",
+	ErrInvalidFilename:      "invalid filename",
+	ErrInvalidOutputProfile: "unsupported output profile",
+	ErrPdfAConversion:       "unable to convert to pdf/a",
+	ErrPdfAValidation:       "document does not conform to pdf/a",
+	ErrInvalidCompose:       "invalid compose parts",
+	ErrComposeFileNotFound:  "uploaded pdf not found",
+	ErrInvalidComposePdf:    "unable to read uploaded pdf",
+	ErrMergingPdf:           "unable to merge pdf documents",
+	ErrInvalidStamp:         "invalid stamp",
+	ErrStampImage:           "unable to read stamp image",
+	ErrStampingPdf:          "unable to stamp pdf",
+	ErrImagePageOutOfRange:  "image page out of range",
+	ErrWebpUnsupported:      "webp output is not supported, use png or jpeg",
+	ErrTooManyIterations:    "template loops exceed the iteration limit",
 }
 
 func GetErr(code errCode) string {
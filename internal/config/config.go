@@ -3,14 +3,20 @@ package config
 import (
 	"github.com/PereRohit/util/config"
 	"github.com/vatsal278/go-redis-cache"
+
+	"github.com/vatsal278/html-pdf-service/internal/model"
 )
 
 type Config struct {
 	ServiceRouteVersion string              `json:"service_route_version"`
 	ServerConfig        config.ServerConfig `json:"server_config"`
 	// add custom config structs below for any internal services
-	Cache     CacheCfg `json:"cache"`
-	MaxMemory int64    `json:"max_memory"`
+	Cache       CacheCfg                `json:"cache"`
+	MaxMemory   int64                   `json:"max_memory"`
+	Limits      model.Limits            `json:"limits"`
+	Renderer    model.RendererConfig    `json:"renderer"`
+	Scheduler   model.SchedulerConfig   `json:"scheduler"`
+	RenderCache model.RenderCacheConfig `json:"render_cache"`
 }
 
 type CacheCfg struct {
@@ -23,8 +29,12 @@ type SvcConfig struct {
 	ServiceRouteVersion string
 	SvrCfg              config.ServerConfig
 	// add internal services after init
-	CacherSvc  CacherSvc
-	MaxMemmory int64
+	CacherSvc   CacherSvc
+	MaxMemmory  int64
+	Limits      model.Limits
+	Renderer    model.RendererConfig
+	Scheduler   model.SchedulerConfig
+	RenderCache model.RenderCacheConfig
 }
 
 type CacherSvc struct {
@@ -40,5 +50,9 @@ func InitSvcConfig(cfg Config) *SvcConfig {
 		SvrCfg:              cfg.ServerConfig,
 		CacherSvc:           CacherSvc{Cacher: cacher},
 		MaxMemmory:          cfg.MaxMemory,
+		Limits:              cfg.Limits,
+		Renderer:            cfg.Renderer,
+		Scheduler:           cfg.Scheduler,
+		RenderCache:         cfg.RenderCache,
 	}
 }
@@ -7,6 +7,8 @@ import (
 
 	"github.com/PereRohit/util/config"
 	"github.com/PereRohit/util/testutil"
+
+	"github.com/vatsal278/html-pdf-service/internal/model"
 )
 
 func TestInitSvcConfig(t *testing.T) {
@@ -28,7 +30,11 @@ func TestInitSvcConfig(t *testing.T) {
 						Port: "",
 						Host: "",
 					},
-					MaxMemory: 1000,
+					MaxMemory:   1000,
+					Limits:      model.Limits{MaxPages: 10},
+					Renderer:    model.RendererConfig{Default: model.RendererChromium},
+					Scheduler:   model.SchedulerConfig{Concurrency: 2},
+					RenderCache: model.RenderCacheConfig{Store: model.CacheStoreMemory},
 				},
 			},
 			want: func() string {
@@ -40,7 +46,11 @@ func TestInitSvcConfig(t *testing.T) {
 							Port: "",
 							Host: "",
 						},
-						MaxMemory: 1000,
+						MaxMemory:   1000,
+						Limits:      model.Limits{MaxPages: 10},
+						Renderer:    model.RendererConfig{Default: model.RendererChromium},
+						Scheduler:   model.SchedulerConfig{Concurrency: 2},
+						RenderCache: model.RenderCacheConfig{Store: model.CacheStoreMemory},
 					},
 					ServiceRouteVersion: "v2",
 					SvrCfg:              config.ServerConfig{},
@@ -48,7 +58,11 @@ func TestInitSvcConfig(t *testing.T) {
 						return CacherSvc{
 							Cacher: redis.NewCacher(redis.Config{Addr: ":"})}
 					}(),
-					MaxMemmory: 1000,
+					MaxMemmory:  1000,
+					Limits:      model.Limits{MaxPages: 10},
+					Renderer:    model.RendererConfig{Default: model.RendererChromium},
+					Scheduler:   model.SchedulerConfig{Concurrency: 2},
+					RenderCache: model.RenderCacheConfig{Store: model.CacheStoreMemory},
 				})
 				if err != nil {
 					t.Error(err)
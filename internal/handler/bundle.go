@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/vatsal278/html-pdf-service/internal/model"
+)
+
+// maxBundleSize caps the uncompressed size of an uploaded bundle
+const maxBundleSize = 64 << 20
+
+var (
+	errInvalidBundle = errors.New("invalid bundle")
+	errInvalidPages  = errors.New("invalid page settings")
+)
+
+// readBundle unpacks a zip bundle into the entry html and its assets. The entry is the file named by entry,
+// index.html or the only html file of the bundle. Asset names are relative to the directory of the entry.
+func readBundle(f multipart.File, size int64, entry string) ([]byte, map[string][]byte, error) {
+	zr, err := zip.NewReader(f, size)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", errInvalidBundle, err)
+	}
+	files := map[string][]byte{}
+	var total int64
+	for _, zf := range zr.File {
+		if zf.FileInfo().IsDir() {
+			continue
+		}
+		name, ok := model.CleanAssetName(zf.Name)
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: invalid file name %q", errInvalidBundle, zf.Name)
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %s", errInvalidBundle, err)
+		}
+		b, err := ioutil.ReadAll(io.LimitReader(rc, maxBundleSize-total+1))
+		rc.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %s", errInvalidBundle, err)
+		}
+		total += int64(len(b))
+		if total > maxBundleSize {
+			return nil, nil, fmt.Errorf("%w: uncompressed size exceeds %d bytes", errInvalidBundle, maxBundleSize)
+		}
+		files[name] = b
+	}
+	entry, err = bundleEntry(files, entry)
+	if err != nil {
+		return nil, nil, err
+	}
+	html := files[entry]
+	delete(files, entry)
+	dir := path.Dir(entry)
+	assets := make(map[string][]byte, len(files))
+	for name, b := range files {
+		if dir != "." {
+			if !strings.HasPrefix(name, dir+"/") {
+				continue
+			}
+			name = strings.TrimPrefix(name, dir+"/")
+		}
+		assets[name] = b
+	}
+	return html, assets, nil
+}
+
+func bundleEntry(files map[string][]byte, entry string) (string, error) {
+	if entry != "" {
+		name, ok := model.CleanAssetName(entry)
+		if _, found := files[name]; !ok || !found {
+			return "", fmt.Errorf("%w: entry html not found", errInvalidBundle)
+		}
+		return name, nil
+	}
+	if _, ok := files["index.html"]; ok {
+		return "index.html", nil
+	}
+	found := ""
+	for name := range files {
+		if strings.HasSuffix(strings.ToLower(name), ".html") || strings.HasSuffix(strings.ToLower(name), ".htm") {
+			if found != "" {
+				return "", fmt.Errorf("%w: entry html not found", errInvalidBundle)
+			}
+			found = name
+		}
+	}
+	if found == "" {
+		return "", fmt.Errorf("%w: entry html not found", errInvalidBundle)
+	}
+	return found, nil
+}
+
+// formAssets reads the files sent in the assets form field, they are stored under their file names
+func formAssets(files []*multipart.FileHeader) (map[string][]byte, error) {
+	assets := make(map[string][]byte, len(files))
+	for _, fh := range files {
+		name, ok := model.CleanAssetName(fh.Filename)
+		if !ok {
+			return nil, fmt.Errorf("%w: invalid asset name %q", errInvalidBundle, fh.Filename)
+		}
+		b, err := readFormFile(fh)
+		if err != nil {
+			return nil, err
+		}
+		assets[name] = b
+	}
+	return assets, nil
+}
+
+// templatePages returns the pages of a register request, either one page per file form field in the order they were
+// sent or the entry of the bundle form field, together with the assets of the bundle and the assets form field.
+// The settings of the pages are read from the pages form field, a json array in the order of the files.
+func templatePages(r *http.Request) ([]model.TemplatePage, map[string][]byte, error) {
+	var pages []model.TemplatePage
+	assets := map[string][]byte{}
+	bundle, fh, err := r.FormFile("bundle")
+	switch err {
+	case nil:
+		defer bundle.Close()
+		b, a, err := readBundle(bundle, fh.Size, r.FormValue("entry"))
+		if err != nil {
+			return nil, nil, err
+		}
+		pages, assets = []model.TemplatePage{{File: bytes.NewReader(b)}}, a
+	case http.ErrMissingFile:
+		if r.MultipartForm == nil || len(r.MultipartForm.File["file"]) == 0 {
+			return nil, nil, http.ErrMissingFile
+		}
+		for _, fh := range r.MultipartForm.File["file"] {
+			b, err := readFormFile(fh)
+			if err != nil {
+				return nil, nil, err
+			}
+			pages = append(pages, model.TemplatePage{File: bytes.NewReader(b)})
+		}
+	default:
+		return nil, nil, err
+	}
+	if v := r.FormValue("pages"); v != "" {
+		var settings []model.PageSettings
+		err = json.Unmarshal([]byte(v), &settings)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %s", errInvalidPages, err)
+		}
+		if len(settings) > len(pages) {
+			return nil, nil, fmt.Errorf("%w: settings for %d pages but %d files", errInvalidPages, len(settings), len(pages))
+		}
+		for i, s := range settings {
+			pages[i].Settings = s
+		}
+	}
+	if r.MultipartForm != nil {
+		extra, err := formAssets(r.MultipartForm.File["assets"])
+		if err != nil {
+			return nil, nil, err
+		}
+		for name, b := range extra {
+			assets[name] = b
+		}
+	}
+	return pages, assets, nil
+}
+
+func readFormFile(fh *multipart.FileHeader) ([]byte, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
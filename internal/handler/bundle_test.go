@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	respModel "github.com/PereRohit/util/model"
+	"github.com/PereRohit/util/testutil"
+	"github.com/golang/mock/gomock"
+
+	"github.com/vatsal278/html-pdf-service/internal/model"
+	"github.com/vatsal278/html-pdf-service/pkg/mock"
+)
+
+func zipBundle(t *testing.T, files map[string]string) []byte {
+	b := new(bytes.Buffer)
+	zw := zip.NewWriter(b)
+	for name, content := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = f.Write([]byte(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := zw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func Test_templateFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		files      map[string]string
+		fields     map[string]string
+		assets     map[string]string
+		wantHtml   string
+		wantAssets map[string][]byte
+		wantErr    error
+	}{
+		{
+			name:       "Success:: bundle with index.html",
+			files:      map[string]string{"index.html": "<img src='logo.png'>", "logo.png": "png", "css/a.css": "css"},
+			wantHtml:   "<img src='logo.png'>",
+			wantAssets: map[string][]byte{"logo.png": []byte("png"), "css/a.css": []byte("css")},
+		},
+		{
+			name:       "Success:: bundle with entry in a directory and extra assets",
+			files:      map[string]string{"site/page.html": "page", "site/logo.png": "png", "other/x.png": "x", "site/main.html": "main"},
+			fields:     map[string]string{"entry": "site/page.html"},
+			assets:     map[string]string{"logo.png": "new"},
+			wantHtml:   "page",
+			wantAssets: map[string][]byte{"logo.png": []byte("new"), "main.html": []byte("main")},
+		},
+		{
+			name:    "Failure:: ambiguous entry",
+			files:   map[string]string{"a.html": "a", "b.html": "b"},
+			wantErr: errInvalidBundle,
+		},
+		{
+			name:    "Failure:: file escaping the bundle",
+			files:   map[string]string{"index.html": "a", "../b.png": "b"},
+			wantErr: errInvalidBundle,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := new(bytes.Buffer)
+			y := multipart.NewWriter(b)
+			part, err := y.CreateFormFile("bundle", "bundle.zip")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = part.Write(zipBundle(t, tt.files))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tt.fields {
+				err = y.WriteField(k, v)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			for name, content := range tt.assets {
+				part, err = y.CreateFormFile("assets", name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, err = part.Write([]byte(content))
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			y.Close()
+			r := httptest.NewRequest(http.MethodPost, "/v1/register", b)
+			r.Header.Set("Content-Type", y.FormDataContentType())
+			err = r.ParseMultipartForm(1 << 20)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pages, assets, err := templatePages(r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("want %v got %v", tt.wantErr, err)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if len(pages) != 1 {
+				t.Fatalf("want a single page got %v", len(pages))
+			}
+			got, err := ioutil.ReadAll(pages[0].File)
+			if err != nil {
+				t.Fatal(err)
+			}
+			diff := testutil.Diff(string(got), tt.wantHtml)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+			diff = testutil.Diff(assets, tt.wantAssets)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestUpload_Bundle(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	b := new(bytes.Buffer)
+	y := multipart.NewWriter(b)
+	part, err := y.CreateFormFile("bundle", "bundle.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = part.Write(zipBundle(t, map[string]string{"index.html": "abc", "logo.png": "png"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	y.Close()
+	r := httptest.NewRequest(http.MethodPost, "/v1/register", b)
+	r.Header.Set("Content-Type", y.FormDataContentType())
+	mockLogicier := mock.NewMockHtmlPdfServiceLogicIer(mockCtrl)
+	mockLogicier.EXPECT().Upload(gomock.Any(), gomock.Any(), model.TemplateSettings{Assets: map[string][]byte{"logo.png": []byte("png")}}).Times(1).
+		DoAndReturn(func(_ context.Context, pages []model.TemplatePage, _ model.TemplateSettings) *respModel.Response {
+			got, err := ioutil.ReadAll(pages[0].File)
+			if err != nil {
+				t.Error(err)
+			}
+			diff := testutil.Diff(got, []byte("abc"))
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+			return &respModel.Response{Status: http.StatusCreated, Message: "SUCCESS"}
+		})
+	rec := &htmlPdfService{
+		logic:     mockLogicier,
+		maxMemory: 1 << 20,
+	}
+	w := httptest.NewRecorder()
+	rec.Upload(w, r)
+	if w.Code != http.StatusCreated {
+		t.Errorf("want %v got %v", http.StatusCreated, w.Code)
+	}
+}
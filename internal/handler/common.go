@@ -36,15 +36,15 @@ func NewCommonSvc() Commoner {
 	return &c
 }
 
-func (common) MethodNotAllowed(w http.ResponseWriter, _ *http.Request) {
+func (*common) MethodNotAllowed(w http.ResponseWriter, _ *http.Request) {
 	response.ToJson(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed), nil)
 }
 
-func (common) RouteNotFound(w http.ResponseWriter, _ *http.Request) {
+func (*common) RouteNotFound(w http.ResponseWriter, _ *http.Request) {
 	response.ToJson(w, http.StatusNotFound, http.StatusText(http.StatusNotFound), nil)
 }
 
-func (common) HealthCheck(w http.ResponseWriter, _ *http.Request) {
+func (*common) HealthCheck(w http.ResponseWriter, _ *http.Request) {
 	type svcHealthStat struct {
 		Status  string `json:"status"`
 		Message string `json:"message,omitempty"`
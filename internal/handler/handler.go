@@ -1,13 +1,22 @@
 package handler
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"github.com/PereRohit/util/log"
+	respModel "github.com/PereRohit/util/model"
 	"github.com/PereRohit/util/response"
 	"github.com/gorilla/mux"
 	"github.com/vatsal278/html-pdf-service/internal/codes"
+	"github.com/vatsal278/html-pdf-service/internal/locale"
 	"github.com/vatsal278/html-pdf-service/internal/repo/htmlToPdf"
+	"io"
+	"mime"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/vatsal278/html-pdf-service/internal/logic"
 	"github.com/vatsal278/html-pdf-service/internal/model"
@@ -22,18 +31,27 @@ type HtmlPdfServiceHandler interface {
 	HealthChecker
 	Upload(w http.ResponseWriter, r *http.Request)
 	ConvertToPdf(w http.ResponseWriter, r *http.Request)
+	Compose(w http.ResponseWriter, r *http.Request)
+	Preview(w http.ResponseWriter, r *http.Request)
 	ReplaceHtml(w http.ResponseWriter, r *http.Request)
+	ReplaceAsset(w http.ResponseWriter, r *http.Request)
+	DeleteAsset(w http.ResponseWriter, r *http.Request)
+	ReorderPages(w http.ResponseWriter, r *http.Request)
+	InsertPage(w http.ResponseWriter, r *http.Request)
+	RemovePage(w http.ResponseWriter, r *http.Request)
 }
 
 type htmlPdfService struct {
 	logic     logic.HtmlPdfServiceLogicIer
 	maxMemory int64
+	limits    model.Limits
 }
 
-func NewHtmlPdfService(ds datasource.DataSource, ht htmlToPdf.HtmlToPdf, mx int64) HtmlPdfServiceHandler {
+func NewHtmlPdfService(ds datasource.DataSource, ht htmlToPdf.HtmlToPdf, mx int64, limits model.Limits, opts ...logic.Option) HtmlPdfServiceHandler {
 	svc := &htmlPdfService{
-		logic:     logic.NewHtmlPdfServiceLogic(ds, ht),
+		logic:     logic.NewHtmlPdfServiceLogic(ds, ht, append([]logic.Option{logic.WithLimits(limits)}, opts...)...),
 		maxMemory: mx,
+		limits:    limits,
 	}
 	AddHealthChecker(svc)
 	return svc
@@ -48,7 +66,7 @@ func (svc htmlPdfService) HealthCheck() (svcName string, msg string, stat bool)
 			stat = true
 		}
 	}()
-	stat = svc.logic.HealthCheck()
+	msg, stat = svc.logic.HealthCheck()
 	set = true
 	return
 }
@@ -60,14 +78,32 @@ func (svc htmlPdfService) Upload(w http.ResponseWriter, r *http.Request) {
 		log.Error(err.Error())
 		return
 	}
-	file, _, err := r.FormFile("file")
+	pages, assets, err := templatePages(r)
+	if errors.Is(err, errInvalidBundle) {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrInvalidBundle), err.Error())
+		log.Error(err.Error())
+		return
+	}
+	if errors.Is(err, errInvalidPages) {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrInvalidPageSettings), err.Error())
+		log.Error(err.Error())
+		return
+	}
 	if err != nil {
 		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrFileParseFail), nil)
 		log.Error(err.Error())
 		return
 	}
-	defer file.Close()
-	resp := svc.logic.Upload(file)
+	settings, err := templateSettings(r)
+	if err != nil {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrDecodingData), nil)
+		log.Error(err.Error())
+		return
+	}
+	if len(assets) > 0 {
+		settings.Assets = assets
+	}
+	resp := svc.logic.Upload(r.Context(), pages, settings)
 	response.ToJson(w, resp.Status, resp.Message, resp.Data)
 }
 func (svc htmlPdfService) ConvertToPdf(w http.ResponseWriter, r *http.Request) {
@@ -78,24 +114,131 @@ func (svc htmlPdfService) ConvertToPdf(w http.ResponseWriter, r *http.Request) {
 		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrIdNeeded), nil)
 		return
 	}
-	var data model.GenerateReq
-	err := json.NewDecoder(r.Body).Decode(&data)
+	data, err := generateReq(r, id, svc.limits.WithDefaults().MaxValuesSize)
+	if errors.Is(err, errPayloadTooLarge) {
+		response.ToJson(w, http.StatusRequestEntityTooLarge, codes.GetErr(codes.ErrPayloadTooLarge), nil)
+		log.Error(err.Error())
+		return
+	}
 	if err != nil {
 		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrDecodingData), nil)
 		log.Error(err.Error())
 		return
 	}
-	data.Id = id
-	resp := svc.logic.HtmlToPdf(w, &data)
+	doc := new(bytes.Buffer)
+	resp := svc.logic.HtmlToPdf(r.Context(), doc, data)
+	contentType, ext := outputType(data.OutputFormat())
+	filename := data.Filename
+	if filename == "" {
+		filename = data.Id + ext
+	}
+	writeDocument(w, resp, doc, contentType, data.DispositionType(), filename)
+}
+
+// Compose merges rendered templates and uploaded pdfs into one pdf. The request is either the json body or the
+// form value request of a multipart form, whose files are the uploaded pdfs named by their form field.
+func (svc htmlPdfService) Compose(w http.ResponseWriter, r *http.Request) {
+	multipartForm := false
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		err := r.ParseMultipartForm(svc.maxMemory)
+		if err != nil {
+			response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrFileSizeExceeded), nil)
+			log.Error(err.Error())
+			return
+		}
+		multipartForm = true
+	}
+	data, err := composeReq(r, multipartForm, svc.limits.WithDefaults().MaxValuesSize)
+	if errors.Is(err, errPayloadTooLarge) {
+		response.ToJson(w, http.StatusRequestEntityTooLarge, codes.GetErr(codes.ErrPayloadTooLarge), nil)
+		log.Error(err.Error())
+		return
+	}
+	if err != nil {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrDecodingData), nil)
+		log.Error(err.Error())
+		return
+	}
+	doc := new(bytes.Buffer)
+	resp := svc.logic.Compose(r.Context(), doc, data)
+	filename := data.Filename
+	if filename == "" {
+		filename = "document.pdf"
+	}
+	writeDocument(w, resp, doc, "application/pdf", data.Document().DispositionType(), filename)
+}
+
+// writeDocument answers with the document rendered into doc. The document is rendered completely before the
+// headers are sent, a failed render is answered with the json error of resp only.
+func writeDocument(w http.ResponseWriter, resp *respModel.Response, doc *bytes.Buffer, contentType, disposition, filename string) {
 	if resp.Status != http.StatusOK {
+		if b, ok := resp.Data.(model.Backpressure); ok {
+			w.Header().Set("Retry-After", strconv.Itoa(b.RetryAfter))
+		}
 		response.ToJson(w, resp.Status, resp.Message, resp.Data)
 		log.Error(resp.Message)
 		return
 	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": filename}))
+	w.Header().Set("Content-Length", strconv.Itoa(doc.Len()))
+	w.WriteHeader(http.StatusOK)
+	_, err := doc.WriteTo(w)
+	if err != nil {
+		log.Error(err.Error())
+	}
+}
 
-	w.Header().Set("Content-Disposition", "attachment; filename="+data.Id+".pdf")
-	w.Header().Set("Content-Type", "application/pdf")
+// outputType returns the content type and file extension of an output format
+func outputType(format string) (string, string) {
+	switch format {
+	case model.OutputPng:
+		return "image/png", ".png"
+	case model.OutputJpeg:
+		return "image/jpeg", ".jpg"
+	}
+	return "application/pdf", ".pdf"
 }
+
+// Preview writes the executed html of a single page template as text/html, templates with several pages
+// are answered with the list of pages in the json response data
+func (svc htmlPdfService) Preview(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrIdNeeded), nil)
+		return
+	}
+	data, err := generateReq(r, id, svc.limits.WithDefaults().MaxValuesSize)
+	if errors.Is(err, errPayloadTooLarge) {
+		response.ToJson(w, http.StatusRequestEntityTooLarge, codes.GetErr(codes.ErrPayloadTooLarge), nil)
+		log.Error(err.Error())
+		return
+	}
+	if err != nil {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrDecodingData), nil)
+		log.Error(err.Error())
+		return
+	}
+	resp := svc.logic.Preview(r.Context(), data)
+	if resp.Status != http.StatusOK {
+		response.ToJson(w, resp.Status, resp.Message, resp.Data)
+		log.Error(resp.Message)
+		return
+	}
+	pages, ok := resp.Data.([]string)
+	if !ok || len(pages) != 1 {
+		response.ToJson(w, resp.Status, resp.Message, resp.Data)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(pages[0]))
+	if err != nil {
+		log.Error(err.Error())
+	}
+}
+
 func (svc htmlPdfService) ReplaceHtml(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	//we take id as a parameter from url path
@@ -106,6 +249,48 @@ func (svc htmlPdfService) ReplaceHtml(w http.ResponseWriter, r *http.Request) {
 	}
 	err := r.ParseMultipartForm(svc.maxMemory) //File size to come from config
 
+	if err != nil {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrFileSizeExceeded), nil)
+		log.Error(err.Error())
+		return
+	}
+	pages, assets, err := templatePages(r)
+	if errors.Is(err, errInvalidBundle) {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrInvalidBundle), err.Error())
+		log.Error(err.Error())
+		return
+	}
+	if errors.Is(err, errInvalidPages) {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrInvalidPageSettings), err.Error())
+		log.Error(err.Error())
+		return
+	}
+	if err != nil {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrFileParseFail), nil)
+		log.Error(err.Error())
+		return
+	}
+	settings, err := templateSettings(r)
+	if err != nil {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrDecodingData), nil)
+		log.Error(err.Error())
+		return
+	}
+	if len(assets) > 0 {
+		settings.Assets = assets
+	}
+	resp := svc.logic.Replace(r.Context(), id, pages, settings)
+	response.ToJson(w, resp.Status, resp.Message, resp.Data)
+}
+
+func (svc htmlPdfService) ReplaceAsset(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrIdNeeded), nil)
+		return
+	}
+	err := r.ParseMultipartForm(svc.maxMemory)
 	if err != nil {
 		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrFileSizeExceeded), nil)
 		log.Error(err.Error())
@@ -118,6 +303,319 @@ func (svc htmlPdfService) ReplaceHtml(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	defer file.Close()
-	resp := svc.logic.Replace(id, file)
+	resp := svc.logic.ReplaceAsset(r.Context(), id, vars["name"], file)
 	response.ToJson(w, resp.Status, resp.Message, resp.Data)
 }
+
+func (svc htmlPdfService) DeleteAsset(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrIdNeeded), nil)
+		return
+	}
+	resp := svc.logic.DeleteAsset(r.Context(), id, vars["name"])
+	response.ToJson(w, resp.Status, resp.Message, resp.Data)
+}
+
+func (svc htmlPdfService) ReorderPages(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrIdNeeded), nil)
+		return
+	}
+	var data model.ReorderReq
+	err := json.NewDecoder(r.Body).Decode(&data)
+	if err != nil {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrDecodingData), nil)
+		log.Error(err.Error())
+		return
+	}
+	resp := svc.logic.ReorderPages(r.Context(), id, data.Order)
+	response.ToJson(w, resp.Status, resp.Message, resp.Data)
+}
+
+// InsertPage adds the page sent as file with the settings of the page form field, before the page at the
+// position form field or at the end
+func (svc htmlPdfService) InsertPage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrIdNeeded), nil)
+		return
+	}
+	err := r.ParseMultipartForm(svc.maxMemory)
+	if err != nil {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrFileSizeExceeded), nil)
+		log.Error(err.Error())
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrFileParseFail), nil)
+		log.Error(err.Error())
+		return
+	}
+	defer file.Close()
+	page := model.TemplatePage{File: file}
+	position := -1
+	if v := r.FormValue("position"); v != "" {
+		position, err = strconv.Atoi(v)
+		if err != nil || position < 0 {
+			response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrDecodingData), nil)
+			return
+		}
+	}
+	if v := r.FormValue("page"); v != "" {
+		err = json.Unmarshal([]byte(v), &page.Settings)
+		if err != nil {
+			response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrInvalidPageSettings), err.Error())
+			log.Error(err.Error())
+			return
+		}
+	}
+	resp := svc.logic.InsertPage(r.Context(), id, position, page)
+	response.ToJson(w, resp.Status, resp.Message, resp.Data)
+}
+
+func (svc htmlPdfService) RemovePage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		response.ToJson(w, http.StatusBadRequest, codes.GetErr(codes.ErrIdNeeded), nil)
+		return
+	}
+	resp := svc.logic.RemovePage(r.Context(), id, vars["page"])
+	response.ToJson(w, resp.Status, resp.Message, resp.Data)
+}
+
+var errPayloadTooLarge = errors.New("request payload too large")
+
+// limitedBody reads at most n bytes and fails with errPayloadTooLarge when the body is longer
+type limitedBody struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, errPayloadTooLarge
+	}
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return 0, errPayloadTooLarge
+	}
+	return n, err
+}
+
+// generateReq decodes the body of a generate or preview request for the template id, bodies larger than maxSize
+// are rejected with errPayloadTooLarge
+func generateReq(r *http.Request, id string, maxSize int64) (*model.GenerateReq, error) {
+	var data model.GenerateReq
+	// numbers are kept as json.Number so large ids are not turned into float64
+	dec := json.NewDecoder(&limitedBody{r: r.Body, n: maxSize})
+	dec.UseNumber()
+	err := dec.Decode(&data)
+	if err != nil {
+		return nil, err
+	}
+	data.Id = id
+	return &data, nil
+}
+
+// composeReq decodes a compose request, the json body or the request value of a multipart form along with the
+// uploaded files. Requests larger than maxSize are rejected with errPayloadTooLarge.
+func composeReq(r *http.Request, multipartForm bool, maxSize int64) (*model.ComposeReq, error) {
+	var body io.Reader = r.Body
+	if multipartForm {
+		body = strings.NewReader(r.FormValue("request"))
+	}
+	var data model.ComposeReq
+	dec := json.NewDecoder(&limitedBody{r: body, n: maxSize})
+	dec.UseNumber()
+	err := dec.Decode(&data)
+	if err != nil {
+		return nil, err
+	}
+	if !multipartForm {
+		return &data, nil
+	}
+	data.Files = make(map[string][]byte, len(r.MultipartForm.File))
+	for name, fhs := range r.MultipartForm.File {
+		data.Files[name], err = readFormFile(fhs[0])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &data, nil
+}
+
+// optionalFile reads the form file name, nil if the request has no such file
+func optionalFile(r *http.Request, name string) ([]byte, error) {
+	if r.MultipartForm == nil || len(r.MultipartForm.File[name]) == 0 {
+		return nil, nil
+	}
+	return readFormFile(r.MultipartForm.File[name][0])
+}
+
+// headerFooter reads a running header or footer template sent as the form file name, its spacing and first page
+// exception are read from the name_spacing and name_skip_first_page form values
+func headerFooter(r *http.Request, name string) (*model.HeaderFooter, error) {
+	b, err := optionalFile(r, name)
+	if b == nil || err != nil {
+		return nil, err
+	}
+	hf := &model.HeaderFooter{Html: b}
+	if v := r.FormValue(name + "_spacing"); v != "" {
+		hf.Spacing, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if v := r.FormValue(name + "_skip_first_page"); v != "" {
+		hf.SkipFirstPage, err = strconv.ParseBool(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return hf, nil
+}
+
+// clearedSettings returns the settings sent with the value null, they are removed from the form so that the
+// settings read from it leave them unset
+func clearedSettings(r *http.Request) []string {
+	var clear []string
+	for name, v := range r.Form {
+		if len(v) == 1 && v[0] == "null" && model.ClearableSetting(name) {
+			clear = append(clear, name)
+		}
+	}
+	sort.Strings(clear)
+	for _, name := range clear {
+		r.Form.Del(name)
+		r.PostForm.Del(name)
+		if r.MultipartForm != nil {
+			delete(r.MultipartForm.Value, name)
+		}
+	}
+	return clear
+}
+
+// templateSettings reads the template defaults sent as form values along with the template file
+func templateSettings(r *http.Request) (model.TemplateSettings, error) {
+	settings := model.TemplateSettings{
+		Clear:      clearedSettings(r),
+		MissingKey: r.FormValue("missing_key"),
+		Engine:     r.FormValue("engine"),
+		Format:     r.FormValue("format"),
+		Theme:      r.FormValue("theme"),
+		Renderer:   r.FormValue("renderer"),
+	}
+	if v := r.FormValue("parse_time"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return settings, err
+		}
+		settings.ParseTime = &b
+	}
+	if v := r.FormValue("render_timeout_ms"); v != "" {
+		ms, err := strconv.Atoi(v)
+		if err != nil {
+			return settings, err
+		}
+		settings.RenderTimeout = ms
+	}
+	settings.Locale = r.FormValue("locale")
+	if v := r.FormValue("rtl_locales"); v != "" {
+		settings.RtlLocales = strings.Split(v, ",")
+	}
+	if v := r.FormValue("page_setup"); v != "" {
+		settings.PageSetup = &model.PageSetup{}
+		err := json.Unmarshal([]byte(v), settings.PageSetup)
+		if err != nil {
+			return settings, err
+		}
+	}
+	if v, ok := r.Form["page_overrides"]; ok {
+		// an empty value forbids all overrides
+		overrides := []string{}
+		if v[0] != "" {
+			overrides = strings.Split(v[0], ",")
+		}
+		settings.PageOverrides = &overrides
+	}
+	var err error
+	settings.Header, err = headerFooter(r, "header")
+	if err != nil {
+		return settings, err
+	}
+	settings.Footer, err = headerFooter(r, "footer")
+	if err != nil {
+		return settings, err
+	}
+	settings.Cover, err = optionalFile(r, "cover")
+	if err != nil {
+		return settings, err
+	}
+	if v := r.FormValue("toc"); v != "" {
+		settings.Toc = &model.Toc{}
+		err = json.Unmarshal([]byte(v), settings.Toc)
+		if err != nil {
+			return settings, err
+		}
+	}
+	xsl, err := optionalFile(r, "toc_xsl")
+	if err != nil {
+		return settings, err
+	}
+	if xsl != nil {
+		if settings.Toc == nil {
+			settings.Toc = &model.Toc{}
+		}
+		settings.Toc.Xsl = xsl
+	}
+	if v := r.FormValue("outline"); v != "" {
+		settings.Outline = &model.Outline{}
+		err = json.Unmarshal([]byte(v), settings.Outline)
+		if err != nil {
+			return settings, err
+		}
+	}
+	if v := r.FormValue("output"); v != "" {
+		settings.Output = &model.Output{}
+		err = json.Unmarshal([]byte(v), settings.Output)
+		if err != nil {
+			return settings, err
+		}
+	}
+	if v := r.FormValue("stamps"); v != "" {
+		err = json.Unmarshal([]byte(v), &settings.Stamps)
+		if err != nil {
+			return settings, err
+		}
+	}
+	catalog, _, err := r.FormFile("catalog")
+	if err == http.ErrMissingFile {
+		return settings, nil
+	}
+	if err != nil {
+		return settings, err
+	}
+	defer catalog.Close()
+	var catalogs map[string]model.Catalog
+	err = json.NewDecoder(catalog).Decode(&catalogs)
+	if err != nil {
+		return settings, err
+	}
+	// catalogs are looked up by the normalized locale, e.g. "en-in" for "en_IN"
+	settings.Catalogs = make(map[string]model.Catalog, len(catalogs))
+	for l, c := range catalogs {
+		settings.Catalogs[locale.Normalize(l)] = c
+	}
+	return settings, nil
+}
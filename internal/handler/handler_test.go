@@ -2,6 +2,7 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	respModel "github.com/PereRohit/util/model"
 	"github.com/PereRohit/util/testutil"
@@ -13,6 +14,7 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/vatsal278/html-pdf-service/internal/model"
@@ -37,7 +39,7 @@ func Test_htmlPdfService_HealthCheck(t *testing.T) {
 				mockLogic := mock.NewMockHtmlPdfServiceLogicIer(mockCtrl)
 
 				mockLogic.EXPECT().HealthCheck().
-					Return(true).Times(1)
+					Return("wkhtmltopdf: ok", true).Times(1)
 
 				rec := &htmlPdfService{
 					logic: mockLogic,
@@ -46,7 +48,7 @@ func Test_htmlPdfService_HealthCheck(t *testing.T) {
 				return rec
 			},
 			wantSvcName: HtmlPdfServiceName,
-			wantMsg:     "",
+			wantMsg:     "wkhtmltopdf: ok",
 			wantStat:    true,
 		},
 	}
@@ -91,6 +93,8 @@ func TestNewHtmlPdfService(t *testing.T) {
 				mockDs.EXPECT().HealthCheck().Times(1).
 					Return(false)
 				mockHt := mock.NewMockHtmlToPdf(mockCtrl)
+				mockHt.EXPECT().HealthCheck().Times(1).
+					Return("", true)
 
 				return mockDs, mockHt
 			},
@@ -100,7 +104,7 @@ func TestNewHtmlPdfService(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			ds, ht := tt.setup()
-			rec := NewHtmlPdfService(ds, ht, 10204)
+			rec := NewHtmlPdfService(ds, ht, 10204, model.Limits{})
 
 			_, _, stat := rec.HealthCheck()
 
@@ -134,13 +138,17 @@ func TestUpload(t *testing.T) {
 				if err != nil {
 					return nil, nil
 				}
+				err = y.WriteField("missing_key", "error")
+				if err != nil {
+					return nil, nil
+				}
 				y.Close()
 				r := httptest.NewRequest(http.MethodPost, "/v1/register", b)
 				r.Header.Set("Content-Type", y.FormDataContentType())
 				mockLogicier := mock.NewMockHtmlPdfServiceLogicIer(mockCtrl)
-				mockLogicier.EXPECT().Upload(gomock.Any()).Times(1).
-					DoAndReturn(func(f io.Reader) *respModel.Response {
-						gotData, err := ioutil.ReadAll(f)
+				mockLogicier.EXPECT().Upload(gomock.Any(), gomock.Any(), model.TemplateSettings{MissingKey: model.MissingKeyError}).Times(1).
+					DoAndReturn(func(_ context.Context, pages []model.TemplatePage, _ model.TemplateSettings) *respModel.Response {
+						gotData, err := ioutil.ReadAll(pages[0].File)
 						if err != nil {
 							t.Error(err)
 							t.FailNow()
@@ -191,6 +199,314 @@ func TestUpload(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "Success:: Upload:: with message catalog",
+			setupFunc: func() (*http.Request, *htmlPdfService) {
+				b := new(bytes.Buffer)
+				y := multipart.NewWriter(b)
+				part, err := y.CreateFormFile("file", "some-file")
+				if err != nil {
+					return nil, nil
+				}
+				_, err = part.Write([]byte("abc"))
+				if err != nil {
+					return nil, nil
+				}
+				part, err = y.CreateFormFile("catalog", "catalog.json")
+				if err != nil {
+					return nil, nil
+				}
+				_, err = part.Write([]byte(`{"en_IN": {"greeting": "Hello"}}`))
+				if err != nil {
+					return nil, nil
+				}
+				err = y.WriteField("locale", "en")
+				if err != nil {
+					return nil, nil
+				}
+				err = y.WriteField("rtl_locales", "xx,yy")
+				if err != nil {
+					return nil, nil
+				}
+				y.Close()
+				r := httptest.NewRequest(http.MethodPost, "/v1/register", b)
+				r.Header.Set("Content-Type", y.FormDataContentType())
+				mockLogicier := mock.NewMockHtmlPdfServiceLogicIer(mockCtrl)
+				mockLogicier.EXPECT().Upload(gomock.Any(), gomock.Any(), model.TemplateSettings{
+					Locale:     "en",
+					RtlLocales: []string{"xx", "yy"},
+					Catalogs:   map[string]model.Catalog{"en-in": {"greeting": "Hello"}},
+				}).Times(1).Return(&respModel.Response{
+					Status:  http.StatusCreated,
+					Message: "SUCCESS",
+				})
+				rec := &htmlPdfService{
+					logic: mockLogicier,
+				}
+				return r, rec
+			},
+			validateFunc: func(x *httptest.ResponseRecorder) {
+				if x.Code != http.StatusCreated {
+					t.Errorf("want %v got %v", http.StatusCreated, x)
+				}
+			},
+		},
+		{
+			name: "Success:: Upload:: with render timeout",
+			setupFunc: func() (*http.Request, *htmlPdfService) {
+				b := new(bytes.Buffer)
+				y := multipart.NewWriter(b)
+				part, err := y.CreateFormFile("file", "some-file")
+				if err != nil {
+					return nil, nil
+				}
+				_, err = part.Write([]byte("abc"))
+				if err != nil {
+					return nil, nil
+				}
+				err = y.WriteField("render_timeout_ms", "5000")
+				if err != nil {
+					return nil, nil
+				}
+				y.Close()
+				r := httptest.NewRequest(http.MethodPost, "/v1/register", b)
+				r.Header.Set("Content-Type", y.FormDataContentType())
+				mockLogicier := mock.NewMockHtmlPdfServiceLogicIer(mockCtrl)
+				mockLogicier.EXPECT().Upload(gomock.Any(), gomock.Any(), model.TemplateSettings{RenderTimeout: 5000}).Times(1).Return(&respModel.Response{
+					Status:  http.StatusCreated,
+					Message: "SUCCESS",
+				})
+				rec := &htmlPdfService{
+					logic: mockLogicier,
+				}
+				return r, rec
+			},
+			validateFunc: func(x *httptest.ResponseRecorder) {
+				if x.Code != http.StatusCreated {
+					t.Errorf("want %v got %v", http.StatusCreated, x)
+				}
+			},
+		},
+		{
+			name: "Success:: Upload:: with page setup",
+			setupFunc: func() (*http.Request, *htmlPdfService) {
+				b := new(bytes.Buffer)
+				y := multipart.NewWriter(b)
+				part, err := y.CreateFormFile("file", "some-file")
+				if err != nil {
+					return nil, nil
+				}
+				_, err = part.Write([]byte("abc"))
+				if err != nil {
+					return nil, nil
+				}
+				err = y.WriteField("page_setup", `{"page_size":"A5","margin_top":0,"grayscale":true}`)
+				if err != nil {
+					return nil, nil
+				}
+				err = y.WriteField("page_overrides", "orientation,zoom")
+				if err != nil {
+					return nil, nil
+				}
+				y.Close()
+				r := httptest.NewRequest(http.MethodPost, "/v1/register", b)
+				r.Header.Set("Content-Type", y.FormDataContentType())
+				zero := uint(0)
+				mockLogicier := mock.NewMockHtmlPdfServiceLogicIer(mockCtrl)
+				mockLogicier.EXPECT().Upload(gomock.Any(), gomock.Any(), model.TemplateSettings{
+					PageSetup:     &model.PageSetup{PageSize: "A5", MarginTop: &zero, Grayscale: true},
+					PageOverrides: &[]string{"orientation", "zoom"},
+				}).Times(1).Return(&respModel.Response{
+					Status:  http.StatusCreated,
+					Message: "SUCCESS",
+				})
+				rec := &htmlPdfService{
+					logic: mockLogicier,
+				}
+				return r, rec
+			},
+			validateFunc: func(x *httptest.ResponseRecorder) {
+				if x.Code != http.StatusCreated {
+					t.Errorf("want %v got %v", http.StatusCreated, x)
+				}
+			},
+		},
+		{
+			name: "Success:: Upload:: with header and footer",
+			setupFunc: func() (*http.Request, *htmlPdfService) {
+				b := new(bytes.Buffer)
+				y := multipart.NewWriter(b)
+				for name, content := range map[string]string{"file": "abc", "header": "<p>[page]</p>", "footer": "<p>confidential</p>"} {
+					part, err := y.CreateFormFile(name, name+".html")
+					if err != nil {
+						return nil, nil
+					}
+					_, err = part.Write([]byte(content))
+					if err != nil {
+						return nil, nil
+					}
+				}
+				err := y.WriteField("header_spacing", "4.5")
+				if err != nil {
+					return nil, nil
+				}
+				err = y.WriteField("header_skip_first_page", "true")
+				if err != nil {
+					return nil, nil
+				}
+				y.Close()
+				r := httptest.NewRequest(http.MethodPost, "/v1/register", b)
+				r.Header.Set("Content-Type", y.FormDataContentType())
+				mockLogicier := mock.NewMockHtmlPdfServiceLogicIer(mockCtrl)
+				mockLogicier.EXPECT().Upload(gomock.Any(), gomock.Any(), model.TemplateSettings{
+					Header: &model.HeaderFooter{Html: []byte("<p>[page]</p>"), Spacing: 4.5, SkipFirstPage: true},
+					Footer: &model.HeaderFooter{Html: []byte("<p>confidential</p>")},
+				}).Times(1).Return(&respModel.Response{
+					Status:  http.StatusCreated,
+					Message: "SUCCESS",
+				})
+				rec := &htmlPdfService{
+					logic: mockLogicier,
+				}
+				return r, rec
+			},
+			validateFunc: func(x *httptest.ResponseRecorder) {
+				if x.Code != http.StatusCreated {
+					t.Errorf("want %v got %v", http.StatusCreated, x)
+				}
+			},
+		},
+		{
+			name: "Success:: Upload:: with cover, table of contents, outline, output and stamps",
+			setupFunc: func() (*http.Request, *htmlPdfService) {
+				b := new(bytes.Buffer)
+				y := multipart.NewWriter(b)
+				for name, content := range map[string]string{"file": "abc", "cover": "<h1>{{.title}}</h1>", "toc_xsl": "<xsl/>"} {
+					part, err := y.CreateFormFile(name, name)
+					if err != nil {
+						return nil, nil
+					}
+					_, err = part.Write([]byte(content))
+					if err != nil {
+						return nil, nil
+					}
+				}
+				err := y.WriteField("toc", `{"enabled":true,"header_text":"Contents"}`)
+				if err != nil {
+					return nil, nil
+				}
+				err = y.WriteField("outline", `{"depth":2}`)
+				if err != nil {
+					return nil, nil
+				}
+				err = y.WriteField("output", `{"profile":"pdfa-2b"}`)
+				if err != nil {
+					return nil, nil
+				}
+				err = y.WriteField("stamps", `[{"text":"DRAFT","rotation":45,"opacity":0.3}]`)
+				if err != nil {
+					return nil, nil
+				}
+				y.Close()
+				r := httptest.NewRequest(http.MethodPost, "/v1/register", b)
+				r.Header.Set("Content-Type", y.FormDataContentType())
+				mockLogicier := mock.NewMockHtmlPdfServiceLogicIer(mockCtrl)
+				mockLogicier.EXPECT().Upload(gomock.Any(), gomock.Any(), model.TemplateSettings{
+					Cover:   []byte("<h1>{{.title}}</h1>"),
+					Toc:     &model.Toc{Enabled: true, HeaderText: "Contents", Xsl: []byte("<xsl/>")},
+					Outline: &model.Outline{Depth: 2},
+					Output:  &model.Output{Profile: model.ProfilePdfA2b},
+					Stamps:  []model.Stamp{{Text: "DRAFT", Rotation: 45, Opacity: 0.3}},
+				}).Times(1).Return(&respModel.Response{
+					Status:  http.StatusCreated,
+					Message: "SUCCESS",
+				})
+				rec := &htmlPdfService{
+					logic: mockLogicier,
+				}
+				return r, rec
+			},
+			validateFunc: func(x *httptest.ResponseRecorder) {
+				if x.Code != http.StatusCreated {
+					t.Errorf("want %v got %v", http.StatusCreated, x)
+				}
+			},
+		},
+		{
+			name: "Failure:: Upload:: invalid page setup",
+			setupFunc: func() (*http.Request, *htmlPdfService) {
+				b := new(bytes.Buffer)
+				y := multipart.NewWriter(b)
+				part, err := y.CreateFormFile("file", "some-file")
+				if err != nil {
+					return nil, nil
+				}
+				_, err = part.Write([]byte("abc"))
+				if err != nil {
+					return nil, nil
+				}
+				err = y.WriteField("page_setup", `{"page_size":`)
+				if err != nil {
+					return nil, nil
+				}
+				y.Close()
+				r := httptest.NewRequest(http.MethodPost, "/v1/register", b)
+				r.Header.Set("Content-Type", y.FormDataContentType())
+				rec := &htmlPdfService{
+					logic: mock.NewMockHtmlPdfServiceLogicIer(mockCtrl),
+				}
+				return r, rec
+			},
+			validateFunc: func(x *httptest.ResponseRecorder) {
+				if x.Code != http.StatusBadRequest {
+					t.Errorf("want %v got %v", http.StatusBadRequest, x)
+				}
+			},
+		},
+		{
+			name: "Failure:: Upload:: invalid message catalog",
+			setupFunc: func() (*http.Request, *htmlPdfService) {
+				b := new(bytes.Buffer)
+				y := multipart.NewWriter(b)
+				part, err := y.CreateFormFile("file", "some-file")
+				if err != nil {
+					return nil, nil
+				}
+				_, err = part.Write([]byte("abc"))
+				if err != nil {
+					return nil, nil
+				}
+				part, err = y.CreateFormFile("catalog", "catalog.json")
+				if err != nil {
+					return nil, nil
+				}
+				_, err = part.Write([]byte(`["greeting"]`))
+				if err != nil {
+					return nil, nil
+				}
+				y.Close()
+				r := httptest.NewRequest(http.MethodPost, "/v1/register", b)
+				r.Header.Set("Content-Type", y.FormDataContentType())
+				rec := &htmlPdfService{
+					logic: mock.NewMockHtmlPdfServiceLogicIer(mockCtrl),
+				}
+				return r, rec
+			},
+			validateFunc: func(x *httptest.ResponseRecorder) {
+				if x.Code != http.StatusBadRequest {
+					t.Errorf("want %v got %v", http.StatusBadRequest, x.Code)
+				}
+				var r respModel.Response
+				err := json.NewDecoder(x.Body).Decode(&r)
+				if err != nil {
+					t.Error(err)
+				}
+				diff := testutil.Diff(r.Message, codes.GetErr(codes.ErrDecodingData))
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+		},
 		{
 			name: "Failure:: Upload:: ParseMultiForm failure",
 			setupFunc: func() (*http.Request, *htmlPdfService) {
@@ -316,8 +632,8 @@ func TestConvertToPdf(t *testing.T) {
 				r := httptest.NewRequest(http.MethodPost, "/v1/generate/1", bytes.NewBuffer(b))
 				r = mux.SetURLVars(r, map[string]string{"id": "1"})
 				mockLogicier := mock.NewMockHtmlPdfServiceLogicIer(mockCtrl)
-				mockLogicier.EXPECT().HtmlToPdf(gomock.Any(), &temp).Times(1).
-					DoAndReturn(func(w io.Writer, req *model.GenerateReq) *respModel.Response {
+				mockLogicier.EXPECT().HtmlToPdf(gomock.Any(), gomock.Any(), &temp).Times(1).
+					DoAndReturn(func(_ context.Context, w io.Writer, req *model.GenerateReq) *respModel.Response {
 						_, err = w.Write([]byte("hello-world"))
 						if err != nil {
 							t.Errorf(err.Error())
@@ -445,8 +761,8 @@ func TestConvertToPdf(t *testing.T) {
 				r := httptest.NewRequest(http.MethodPost, "/v1/generate/1", bytes.NewBuffer(b))
 				r = mux.SetURLVars(r, map[string]string{"id": "1"})
 				mockLogicier := mock.NewMockHtmlPdfServiceLogicIer(mockCtrl)
-				mockLogicier.EXPECT().HtmlToPdf(gomock.Any(), gomock.Any()).Times(1).
-					DoAndReturn(func(w io.Writer, req *model.GenerateReq) *respModel.Response {
+				mockLogicier.EXPECT().HtmlToPdf(gomock.Any(), gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, w io.Writer, req *model.GenerateReq) *respModel.Response {
 						return &respModel.Response{
 							Status:  http.StatusInternalServerError,
 							Message: codes.GetErr(codes.ErrConvertingToPdf),
@@ -517,9 +833,9 @@ func TestReplace(t *testing.T) {
 				}
 				y.Close()
 				mockLogicier := mock.NewMockHtmlPdfServiceLogicIer(mockCtrl)
-				mockLogicier.EXPECT().Replace(gomock.Any(), gomock.Any()).Times(1).
-					DoAndReturn(func(id string, f io.Reader) *respModel.Response {
-						gotData, err := ioutil.ReadAll(f)
+				mockLogicier.EXPECT().Replace(gomock.Any(), gomock.Any(), gomock.Any(), model.TemplateSettings{}).Times(1).
+					DoAndReturn(func(_ context.Context, id string, pages []model.TemplatePage, _ model.TemplateSettings) *respModel.Response {
+						gotData, err := ioutil.ReadAll(pages[0].File)
 						if err != nil {
 							t.Errorf(err.Error())
 						}
@@ -573,18 +889,62 @@ func TestReplace(t *testing.T) {
 			},
 		},
 		{
-			name:        "Failure:: Replace:: id not found",
+			name:        "Success:: Replace:: clear settings sent as null",
 			requestBody: "1",
 			setupFunc: func() (*http.Request, *htmlPdfService) {
 				b := new(bytes.Buffer)
-				mockLogicier := mock.NewMockHtmlPdfServiceLogicIer(mockCtrl)
-				rec := &htmlPdfService{
-					logic: mockLogicier,
+				y := multipart.NewWriter(b)
+				part, err := y.CreateFormFile("file", "some-file")
+				if err != nil {
+					t.Error(err)
 				}
-				return httptest.NewRequest(http.MethodPut, "/v1/register/", b), rec
-			},
-			validateFunc: func(x *httptest.ResponseRecorder) {
-				if x.Code != http.StatusBadRequest {
+				_, err = part.Write([]byte("abc"))
+				if err != nil {
+					t.Errorf(err.Error())
+				}
+				for k, v := range map[string]string{"toc": "null", "locale": "null", "theme": "plain", "file_name": "null"} {
+					err = y.WriteField(k, v)
+					if err != nil {
+						t.Error(err)
+					}
+				}
+				y.Close()
+				mockLogicier := mock.NewMockHtmlPdfServiceLogicIer(mockCtrl)
+				mockLogicier.EXPECT().Replace(gomock.Any(), "1", gomock.Any(), model.TemplateSettings{
+					Theme: "plain",
+					Clear: []string{"locale", "toc"},
+				}).Times(1).Return(&respModel.Response{
+					Status:  http.StatusOK,
+					Message: "SUCCESS",
+					Data:    map[string]interface{}{"id": "1"},
+				})
+				rec := &htmlPdfService{
+					logic: mockLogicier,
+				}
+				r := httptest.NewRequest(http.MethodPut, "/v1/register/1", b)
+				r = mux.SetURLVars(r, map[string]string{"id": "1"})
+				r.Header.Set("Content-Type", y.FormDataContentType())
+				return r, rec
+			},
+			validateFunc: func(x *httptest.ResponseRecorder) {
+				if x.Code != http.StatusOK {
+					t.Errorf("want %v got %v", http.StatusOK, x.Code)
+				}
+			},
+		},
+		{
+			name:        "Failure:: Replace:: id not found",
+			requestBody: "1",
+			setupFunc: func() (*http.Request, *htmlPdfService) {
+				b := new(bytes.Buffer)
+				mockLogicier := mock.NewMockHtmlPdfServiceLogicIer(mockCtrl)
+				rec := &htmlPdfService{
+					logic: mockLogicier,
+				}
+				return httptest.NewRequest(http.MethodPut, "/v1/register/", b), rec
+			},
+			validateFunc: func(x *httptest.ResponseRecorder) {
+				if x.Code != http.StatusBadRequest {
 					t.Errorf("want %v got %v", http.StatusBadRequest, x.Code)
 				}
 				var r respModel.Response
@@ -715,3 +1075,539 @@ func TestReplace(t *testing.T) {
 		})
 	}
 }
+
+func TestReplaceAsset(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	tests := []struct {
+		name         string
+		setupFunc    func() (*http.Request, *htmlPdfService)
+		validateFunc func(*httptest.ResponseRecorder)
+	}{
+		{
+			name: "Success:: ReplaceAsset",
+			setupFunc: func() (*http.Request, *htmlPdfService) {
+				b := new(bytes.Buffer)
+				y := multipart.NewWriter(b)
+				part, err := y.CreateFormFile("file", "logo.png")
+				if err != nil {
+					t.Error(err)
+				}
+				_, err = part.Write([]byte("png"))
+				if err != nil {
+					t.Error(err)
+				}
+				y.Close()
+				mockLogicier := mock.NewMockHtmlPdfServiceLogicIer(mockCtrl)
+				mockLogicier.EXPECT().ReplaceAsset(gomock.Any(), "1", "img/logo.png", gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, id string, _ string, f io.Reader) *respModel.Response {
+						gotData, err := ioutil.ReadAll(f)
+						if err != nil {
+							t.Error(err)
+						}
+						diff := testutil.Diff(gotData, []byte("png"))
+						if diff != "" {
+							t.Error(testutil.Callers(), diff)
+						}
+						return &respModel.Response{Status: http.StatusOK, Message: "SUCCESS", Data: map[string]interface{}{"id": id}}
+					})
+				r := httptest.NewRequest(http.MethodPut, "/v1/register/1/assets/img/logo.png", b)
+				r = mux.SetURLVars(r, map[string]string{"id": "1", "name": "img/logo.png"})
+				r.Header.Set("Content-Type", y.FormDataContentType())
+				return r, &htmlPdfService{logic: mockLogicier}
+			},
+			validateFunc: func(x *httptest.ResponseRecorder) {
+				if x.Code != http.StatusOK {
+					t.Errorf("want %v got %v", http.StatusOK, x.Code)
+				}
+			},
+		},
+		{
+			name: "Failure:: ReplaceAsset:: no file found",
+			setupFunc: func() (*http.Request, *htmlPdfService) {
+				b := new(bytes.Buffer)
+				y := multipart.NewWriter(b)
+				y.Close()
+				r := httptest.NewRequest(http.MethodPut, "/v1/register/1/assets/logo.png", b)
+				r = mux.SetURLVars(r, map[string]string{"id": "1", "name": "logo.png"})
+				r.Header.Set("Content-Type", y.FormDataContentType())
+				return r, &htmlPdfService{logic: mock.NewMockHtmlPdfServiceLogicIer(mockCtrl)}
+			},
+			validateFunc: func(x *httptest.ResponseRecorder) {
+				if x.Code != http.StatusBadRequest {
+					t.Errorf("want %v got %v", http.StatusBadRequest, x.Code)
+				}
+			},
+		},
+		{
+			name: "Failure:: ReplaceAsset:: id not found",
+			setupFunc: func() (*http.Request, *htmlPdfService) {
+				return httptest.NewRequest(http.MethodPut, "/v1/register", nil), &htmlPdfService{}
+			},
+			validateFunc: func(x *httptest.ResponseRecorder) {
+				if x.Code != http.StatusBadRequest {
+					t.Errorf("want %v got %v", http.StatusBadRequest, x.Code)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, rec := tt.setupFunc()
+			w := httptest.NewRecorder()
+			rec.ReplaceAsset(w, r)
+			tt.validateFunc(w)
+		})
+	}
+}
+
+func TestDeleteAsset(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockLogicier := mock.NewMockHtmlPdfServiceLogicIer(mockCtrl)
+	mockLogicier.EXPECT().DeleteAsset(gomock.Any(), "1", "logo.png").Times(1).
+		Return(&respModel.Response{Status: http.StatusNotFound, Message: codes.GetErr(codes.ErrAssetNotFound)})
+	r := httptest.NewRequest(http.MethodDelete, "/v1/register/1/assets/logo.png", nil)
+	r = mux.SetURLVars(r, map[string]string{"id": "1", "name": "logo.png"})
+	w := httptest.NewRecorder()
+	rec := &htmlPdfService{logic: mockLogicier}
+	rec.DeleteAsset(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("want %v got %v", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	tests := []struct {
+		name         string
+		body         string
+		pages        interface{}
+		validateFunc func(*httptest.ResponseRecorder)
+	}{
+		{
+			name:  "Success:: Preview:: single page as html",
+			body:  `{"values":{"Name":"vatsal"}}`,
+			pages: []string{"<p>vatsal</p>"},
+			validateFunc: func(x *httptest.ResponseRecorder) {
+				if x.Code != http.StatusOK {
+					t.Errorf("want %v got %v", http.StatusOK, x.Code)
+				}
+				diff := testutil.Diff(x.Header().Get("Content-Type"), "text/html; charset=utf-8")
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+				diff = testutil.Diff(x.Body.String(), "<p>vatsal</p>")
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+		},
+		{
+			name:  "Success:: Preview:: several pages as json",
+			body:  `{"values":{}}`,
+			pages: []string{"<p>1</p>", "<p>2</p>"},
+			validateFunc: func(x *httptest.ResponseRecorder) {
+				var r respModel.Response
+				err := json.Unmarshal(x.Body.Bytes(), &r)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				diff := testutil.Diff(r, respModel.Response{
+					Status:  http.StatusOK,
+					Message: "SUCCESS",
+					Data:    []interface{}{"<p>1</p>", "<p>2</p>"},
+				})
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+		},
+		{
+			name: "Failure:: Preview:: json failure",
+			body: `{`,
+			validateFunc: func(x *httptest.ResponseRecorder) {
+				if x.Code != http.StatusBadRequest {
+					t.Errorf("want %v got %v", http.StatusBadRequest, x.Code)
+				}
+			},
+		},
+		{
+			name: "Failure:: Preview:: payload too large",
+			body: `{"values":{"Name":"` + strings.Repeat("a", 64) + `"}}`,
+			validateFunc: func(x *httptest.ResponseRecorder) {
+				if x.Code != http.StatusRequestEntityTooLarge {
+					t.Errorf("want %v got %v", http.StatusRequestEntityTooLarge, x.Code)
+				}
+				if !strings.Contains(x.Body.String(), codes.GetErr(codes.ErrPayloadTooLarge)) {
+					t.Errorf("want %v got %v", codes.GetErr(codes.ErrPayloadTooLarge), x.Body.String())
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLogicier := mock.NewMockHtmlPdfServiceLogicIer(mockCtrl)
+			if tt.pages != nil {
+				mockLogicier.EXPECT().Preview(gomock.Any(), gomock.Any()).Times(1).Return(&respModel.Response{
+					Status:  http.StatusOK,
+					Message: "SUCCESS",
+					Data:    tt.pages,
+				})
+			}
+			rec := &htmlPdfService{logic: mockLogicier, limits: model.Limits{MaxValuesSize: 64}}
+			r := httptest.NewRequest(http.MethodPost, "/v1/preview/1", bytes.NewBufferString(tt.body))
+			r = mux.SetURLVars(r, map[string]string{"id": "1"})
+			w := httptest.NewRecorder()
+			rec.Preview(w, r)
+			tt.validateFunc(w)
+		})
+	}
+}
+
+func TestConvertToPdf_Output(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	tests := []struct {
+		name string
+		body string
+		// doc is written by the logic before it returns the status
+		doc             string
+		status          int
+		data            interface{}
+		wantType        string
+		wantDisposition string
+		wantLength      string
+		wantRetryAfter  string
+	}{
+		{name: "Success:: ConvertToPdf:: pdf", body: `{"values":{}}`, doc: "%PDF-", status: http.StatusOK, wantType: "application/pdf",
+			wantDisposition: "attachment; filename=1.pdf", wantLength: "5"},
+		{name: "Success:: ConvertToPdf:: png", body: `{"values":{},"format":"png","width":800}`, doc: "png", status: http.StatusOK, wantType: "image/png",
+			wantDisposition: "attachment; filename=1.png", wantLength: "3"},
+		{name: "Success:: ConvertToPdf:: jpeg", body: `{"values":{},"format":"jpeg"}`, doc: "jpeg", status: http.StatusOK, wantType: "image/jpeg",
+			wantDisposition: "attachment; filename=1.jpg", wantLength: "4"},
+		{name: "Success:: ConvertToPdf:: inline", body: `{"values":{},"disposition":"inline"}`, doc: "%PDF-", status: http.StatusOK, wantType: "application/pdf",
+			wantDisposition: "inline; filename=1.pdf", wantLength: "5"},
+		{name: "Success:: ConvertToPdf:: filename", body: `{"values":{},"filename":"statement march.pdf"}`, doc: "%PDF-", status: http.StatusOK,
+			wantType: "application/pdf", wantDisposition: `attachment; filename="statement march.pdf"`, wantLength: "5"},
+		{name: "Success:: ConvertToPdf:: non ascii filename", body: `{"values":{},"disposition":"inline","filename":"résumé.pdf"}`, doc: "%PDF-",
+			status: http.StatusOK, wantType: "application/pdf", wantDisposition: "inline; filename*=utf-8''r%C3%A9sum%C3%A9.pdf", wantLength: "5"},
+		{name: "Failure:: ConvertToPdf:: error is json", body: `{"values":{},"format":"webp"}`, status: http.StatusBadRequest, wantType: "application/json"},
+		{name: "Failure:: ConvertToPdf:: partial document dropped", body: `{"values":{}}`, doc: "%PDF-1.4 partial", status: http.StatusInternalServerError,
+			wantType: "application/json"},
+		{name: "Failure:: ConvertToPdf:: queue full", body: `{"values":{}}`, status: http.StatusTooManyRequests, data: model.Backpressure{RetryAfter: 3, QueueDepth: 32},
+			wantType: "application/json", wantRetryAfter: "3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLogicier := mock.NewMockHtmlPdfServiceLogicIer(mockCtrl)
+			mockLogicier.EXPECT().HtmlToPdf(gomock.Any(), gomock.Any(), gomock.Any()).Times(1).
+				DoAndReturn(func(_ context.Context, w io.Writer, _ *model.GenerateReq) *respModel.Response {
+					_, err := w.Write([]byte(tt.doc))
+					if err != nil {
+						t.Error(err)
+					}
+					return &respModel.Response{Status: tt.status, Data: tt.data}
+				})
+			rec := &htmlPdfService{logic: mockLogicier}
+			r := httptest.NewRequest(http.MethodPost, "/v1/generate/1", bytes.NewBufferString(tt.body))
+			r = mux.SetURLVars(r, map[string]string{"id": "1"})
+			w := httptest.NewRecorder()
+			rec.ConvertToPdf(w, r)
+			if w.Code != tt.status {
+				t.Errorf("want %v got %v", tt.status, w.Code)
+			}
+			diff := testutil.Diff(w.Header().Get("Content-Type"), tt.wantType)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+			diff = testutil.Diff(w.Header().Get("Content-Disposition"), tt.wantDisposition)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+			diff = testutil.Diff(w.Header().Get("Content-Length"), tt.wantLength)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+			diff = testutil.Diff(w.Header().Get("Retry-After"), tt.wantRetryAfter)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+			if tt.status == http.StatusOK {
+				diff = testutil.Diff(w.Body.String(), tt.doc)
+			} else if !json.Valid(w.Body.Bytes()) {
+				diff = "want a json error got " + w.Body.String()
+			}
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestUpload_Pages(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	tests := []struct {
+		name       string
+		pages      string
+		wantStatus int
+	}{
+		{name: "Success:: Upload:: several files with page settings", pages: `[{"name":"cover","zoom":1.2}]`, wantStatus: http.StatusCreated},
+		{name: "Failure:: Upload:: more settings than files", pages: `[{},{},{}]`, wantStatus: http.StatusBadRequest},
+		{name: "Failure:: Upload:: invalid settings json", pages: `{`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := new(bytes.Buffer)
+			y := multipart.NewWriter(b)
+			for _, content := range []string{"cover", "body"} {
+				part, err := y.CreateFormFile("file", content+".html")
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, err = part.Write([]byte(content))
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := y.WriteField("pages", tt.pages)
+			if err != nil {
+				t.Fatal(err)
+			}
+			y.Close()
+			r := httptest.NewRequest(http.MethodPost, "/v1/register", b)
+			r.Header.Set("Content-Type", y.FormDataContentType())
+			mockLogicier := mock.NewMockHtmlPdfServiceLogicIer(mockCtrl)
+			if tt.wantStatus == http.StatusCreated {
+				mockLogicier.EXPECT().Upload(gomock.Any(), gomock.Any(), model.TemplateSettings{}).Times(1).
+					DoAndReturn(func(_ context.Context, pages []model.TemplatePage, _ model.TemplateSettings) *respModel.Response {
+						if len(pages) != 2 {
+							t.Fatalf("want 2 pages got %v", len(pages))
+						}
+						diff := testutil.Diff(pages[0].Settings, model.PageSettings{Name: "cover", Zoom: 1.2})
+						if diff != "" {
+							t.Error(testutil.Callers(), diff)
+						}
+						got, err := ioutil.ReadAll(pages[1].File)
+						if err != nil {
+							t.Error(err)
+						}
+						diff = testutil.Diff(string(got), "body")
+						if diff != "" {
+							t.Error(testutil.Callers(), diff)
+						}
+						return &respModel.Response{Status: http.StatusCreated, Message: "SUCCESS"}
+					})
+			}
+			rec := &htmlPdfService{logic: mockLogicier, maxMemory: 1 << 20}
+			w := httptest.NewRecorder()
+			rec.Upload(w, r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("want %v got %v", tt.wantStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestPages(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	ok := &respModel.Response{Status: http.StatusOK, Message: "SUCCESS"}
+	tests := []struct {
+		name       string
+		setupFunc  func(*mock.MockHtmlPdfServiceLogicIer) (*http.Request, func(*htmlPdfService) http.HandlerFunc)
+		wantStatus int
+	}{
+		{
+			name: "Success:: ReorderPages",
+			setupFunc: func(m *mock.MockHtmlPdfServiceLogicIer) (*http.Request, func(*htmlPdfService) http.HandlerFunc) {
+				m.EXPECT().ReorderPages(gomock.Any(), "1", []int{1, 0}).Return(ok)
+				r := httptest.NewRequest(http.MethodPut, "/v1/register/1/pages/order", bytes.NewBufferString(`{"order":[1,0]}`))
+				return mux.SetURLVars(r, map[string]string{"id": "1"}), func(s *htmlPdfService) http.HandlerFunc { return s.ReorderPages }
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "Failure:: ReorderPages:: json failure",
+			setupFunc: func(m *mock.MockHtmlPdfServiceLogicIer) (*http.Request, func(*htmlPdfService) http.HandlerFunc) {
+				r := httptest.NewRequest(http.MethodPut, "/v1/register/1/pages/order", bytes.NewBufferString(`{`))
+				return mux.SetURLVars(r, map[string]string{"id": "1"}), func(s *htmlPdfService) http.HandlerFunc { return s.ReorderPages }
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Success:: InsertPage",
+			setupFunc: func(m *mock.MockHtmlPdfServiceLogicIer) (*http.Request, func(*htmlPdfService) http.HandlerFunc) {
+				m.EXPECT().InsertPage(gomock.Any(), "1", 2, gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ string, _ int, page model.TemplatePage) *respModel.Response {
+						diff := testutil.Diff(page.Settings, model.PageSettings{Name: "appendix"})
+						if diff != "" {
+							t.Error(testutil.Callers(), diff)
+						}
+						return ok
+					})
+				b := new(bytes.Buffer)
+				y := multipart.NewWriter(b)
+				part, _ := y.CreateFormFile("file", "appendix.html")
+				part.Write([]byte("appendix"))
+				y.WriteField("position", "2")
+				y.WriteField("page", `{"name":"appendix"}`)
+				y.Close()
+				r := httptest.NewRequest(http.MethodPost, "/v1/register/1/pages", b)
+				r.Header.Set("Content-Type", y.FormDataContentType())
+				return mux.SetURLVars(r, map[string]string{"id": "1"}), func(s *htmlPdfService) http.HandlerFunc { return s.InsertPage }
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "Failure:: InsertPage:: invalid position",
+			setupFunc: func(m *mock.MockHtmlPdfServiceLogicIer) (*http.Request, func(*htmlPdfService) http.HandlerFunc) {
+				b := new(bytes.Buffer)
+				y := multipart.NewWriter(b)
+				part, _ := y.CreateFormFile("file", "appendix.html")
+				part.Write([]byte("appendix"))
+				y.WriteField("position", "first")
+				y.Close()
+				r := httptest.NewRequest(http.MethodPost, "/v1/register/1/pages", b)
+				r.Header.Set("Content-Type", y.FormDataContentType())
+				return mux.SetURLVars(r, map[string]string{"id": "1"}), func(s *htmlPdfService) http.HandlerFunc { return s.InsertPage }
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Success:: RemovePage",
+			setupFunc: func(m *mock.MockHtmlPdfServiceLogicIer) (*http.Request, func(*htmlPdfService) http.HandlerFunc) {
+				m.EXPECT().RemovePage(gomock.Any(), "1", "cover").Return(ok)
+				r := httptest.NewRequest(http.MethodDelete, "/v1/register/1/pages/cover", nil)
+				return mux.SetURLVars(r, map[string]string{"id": "1", "page": "cover"}), func(s *htmlPdfService) http.HandlerFunc { return s.RemovePage }
+			},
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLogicier := mock.NewMockHtmlPdfServiceLogicIer(mockCtrl)
+			r, handler := tt.setupFunc(mockLogicier)
+			rec := &htmlPdfService{logic: mockLogicier, maxMemory: 1 << 20}
+			w := httptest.NewRecorder()
+			handler(rec)(w, r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("want %v got %v", tt.wantStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestCompose(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	form := func(request string, files map[string]string) (*bytes.Buffer, string) {
+		body := new(bytes.Buffer)
+		mw := multipart.NewWriter(body)
+		if err := mw.WriteField("request", request); err != nil {
+			t.Fatal(err)
+		}
+		for name, content := range files {
+			fw, err := mw.CreateFormFile(name, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _ = fw.Write([]byte(content))
+		}
+		_ = mw.Close()
+		return body, mw.FormDataContentType()
+	}
+	tests := []struct {
+		name            string
+		setupFunc       func() *http.Request
+		want            *model.ComposeReq
+		status          int
+		doc             string
+		wantStatus      int
+		wantDisposition string
+	}{
+		{
+			name: "Success:: Compose:: json",
+			setupFunc: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/v1/compose", strings.NewReader(`{"parts":[{"template":"1","title":"Cover","values":{"n":1}}]}`))
+			},
+			want: &model.ComposeReq{Parts: []model.ComposePart{
+				{Template: "1", Title: "Cover", GenerateReq: model.GenerateReq{Values: map[string]interface{}{"n": json.Number("1")}}},
+			}},
+			status:          http.StatusOK,
+			doc:             "%PDF-",
+			wantStatus:      http.StatusOK,
+			wantDisposition: "attachment; filename=document.pdf",
+		},
+		{
+			name: "Success:: Compose:: multipart",
+			setupFunc: func() *http.Request {
+				body, contentType := form(`{"parts":[{"file":"terms"}],"disposition":"inline","filename":"all.pdf"}`, map[string]string{"terms": "%PDF-1.4"})
+				r := httptest.NewRequest(http.MethodPost, "/v1/compose", body)
+				r.Header.Set("Content-Type", contentType)
+				return r
+			},
+			want: &model.ComposeReq{
+				Parts:       []model.ComposePart{{File: "terms"}},
+				Disposition: "inline",
+				Filename:    "all.pdf",
+				Files:       map[string][]byte{"terms": []byte("%PDF-1.4")},
+			},
+			status:          http.StatusOK,
+			doc:             "%PDF-",
+			wantStatus:      http.StatusOK,
+			wantDisposition: "inline; filename=all.pdf",
+		},
+		{
+			name: "Failure:: Compose:: logic error",
+			setupFunc: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/v1/compose", strings.NewReader(`{"parts":[]}`))
+			},
+			want:       &model.ComposeReq{Parts: []model.ComposePart{}},
+			status:     http.StatusBadRequest,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Failure:: Compose:: invalid json",
+			setupFunc: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/v1/compose", strings.NewReader(`{"parts":`))
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Failure:: Compose:: request too large",
+			setupFunc: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/v1/compose", strings.NewReader(`{"parts":[{"title":"`+strings.Repeat("a", 100)+`"}]}`))
+			},
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLogicier := mock.NewMockHtmlPdfServiceLogicIer(mockCtrl)
+			if tt.want != nil {
+				mockLogicier.EXPECT().Compose(gomock.Any(), gomock.Any(), tt.want).Times(1).
+					DoAndReturn(func(_ context.Context, w io.Writer, _ *model.ComposeReq) *respModel.Response {
+						_, _ = w.Write([]byte(tt.doc))
+						return &respModel.Response{Status: tt.status}
+					})
+			}
+			rec := &htmlPdfService{logic: mockLogicier, maxMemory: 1 << 20, limits: model.Limits{MaxValuesSize: 100}}
+			w := httptest.NewRecorder()
+			rec.Compose(w, tt.setupFunc())
+			if w.Code != tt.wantStatus {
+				t.Errorf("want %v got %v %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			diff := testutil.Diff(w.Header().Get("Content-Disposition"), tt.wantDisposition)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+			if tt.wantStatus == http.StatusOK && w.Body.String() != tt.doc {
+				t.Errorf("want %q got %q", tt.doc, w.Body.String())
+			}
+		})
+	}
+}
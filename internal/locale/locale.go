@@ -0,0 +1,316 @@
+package locale
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const Default = "en"
+
+var tagRegex = regexp.MustCompile(`^[A-Za-z]{2,3}([-_][A-Za-z0-9]{2,8})*$`)
+
+// Valid reports whether s looks like a BCP 47 language tag such as "de" or "en-IN"
+func Valid(s string) bool {
+	return tagRegex.MatchString(s)
+}
+
+// Normalize converts a language tag to its canonical lower case form with "-" separators, e.g. "en_IN" to "en-in"
+func Normalize(s string) string {
+	return strings.ToLower(strings.ReplaceAll(s, "_", "-"))
+}
+
+// Base returns the language part of a tag, e.g. "de" for "de-AT"
+func Base(s string) string {
+	s = Normalize(s)
+	if i := strings.Index(s, "-"); i > 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// Fallbacks returns the lookup chain for a locale, the full tag first followed by its base language
+func Fallbacks(s string) []string {
+	s = Normalize(s)
+	if s == "" {
+		return nil
+	}
+	if b := Base(s); b != s {
+		return []string{s, b}
+	}
+	return []string{s}
+}
+
+var rtlLanguages = map[string]bool{"ar": true, "he": true, "fa": true, "ur": true, "ps": true, "yi": true, "dv": true, "ku": true}
+
+// IsRTL reports whether the language of the locale is written right to left
+func IsRTL(s string) bool {
+	return rtlLanguages[Base(s)]
+}
+
+const (
+	Zero  = "zero"
+	One   = "one"
+	Two   = "two"
+	Few   = "few"
+	Many  = "many"
+	Other = "other"
+)
+
+// PluralCategory returns the CLDR cardinal plural category of n in the language of the locale
+func PluralCategory(s string, n float64) string {
+	n = math.Abs(n)
+	isInt := n == math.Trunc(n)
+	i := int64(n)
+	switch Base(s) {
+	case "ja", "zh", "ko", "th", "vi", "id", "ms", "tr":
+		return Other
+	case "fr", "pt":
+		if i == 0 || i == 1 {
+			return One
+		}
+	case "hi", "bn", "gu", "kn", "mr", "fa":
+		if i == 0 || n == 1 {
+			return One
+		}
+	case "ru", "uk", "be":
+		if !isInt {
+			return Other
+		}
+		switch {
+		case i%10 == 1 && i%100 != 11:
+			return One
+		case i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14):
+			return Few
+		default:
+			return Many
+		}
+	case "pl":
+		if !isInt {
+			return Other
+		}
+		switch {
+		case i == 1:
+			return One
+		case i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14):
+			return Few
+		default:
+			return Many
+		}
+	case "ar":
+		if !isInt {
+			return Other
+		}
+		switch {
+		case i == 0:
+			return Zero
+		case i == 1:
+			return One
+		case i == 2:
+			return Two
+		case i%100 >= 3 && i%100 <= 10:
+			return Few
+		case i%100 >= 11 && i%100 <= 99:
+			return Many
+		}
+	default:
+		if isInt && i == 1 {
+			return One
+		}
+	}
+	return Other
+}
+
+type numberFormat struct {
+	decimal string
+	group   string
+	// indian grouping keeps the last three digits together and groups the rest by two, e.g. 12,34,567
+	indian bool
+}
+
+var numberFormats = map[string]numberFormat{
+	"en": {decimal: ".", group: ","},
+	"hi": {decimal: ".", group: ",", indian: true},
+	"de": {decimal: ",", group: "."},
+	"es": {decimal: ",", group: "."},
+	"it": {decimal: ",", group: "."},
+	"nl": {decimal: ",", group: "."},
+	"pt": {decimal: ",", group: "."},
+	"fr": {decimal: ",", group: "\u202f"},
+	"ru": {decimal: ",", group: "\u00a0"},
+	"pl": {decimal: ",", group: "\u00a0"},
+	"ar": {decimal: ".", group: ","},
+	"he": {decimal: ".", group: ","},
+	"ja": {decimal: ".", group: ","},
+	"zh": {decimal: ".", group: ","},
+}
+
+func formatFor(s string) numberFormat {
+	if f, ok := numberFormats[Normalize(s)]; ok {
+		return f
+	}
+	if Normalize(s) == "en-in" {
+		return numberFormats["hi"]
+	}
+	if f, ok := numberFormats[Base(s)]; ok {
+		return f
+	}
+	return numberFormats[Default]
+}
+
+// FormatNumber formats n with the given number of decimals using the separators of the locale
+func FormatNumber(s string, n float64, decimals int) string {
+	f := formatFor(s)
+	str := strconv.FormatFloat(math.Abs(n), 'f', decimals, 64)
+	intPart, fracPart := str, ""
+	if i := strings.Index(str, "."); i >= 0 {
+		intPart, fracPart = str[:i], str[i+1:]
+	}
+	out := group(intPart, f)
+	if fracPart != "" {
+		out += f.decimal + fracPart
+	}
+	if n < 0 && strings.Trim(str, "0.") != "" {
+		out = "-" + out
+	}
+	return out
+}
+
+func group(digits string, f numberFormat) string {
+	if len(digits) <= 3 {
+		return digits
+	}
+	head, tail := digits[:len(digits)-3], digits[len(digits)-3:]
+	size := 3
+	if f.indian {
+		size = 2
+	}
+	var parts []string
+	for len(head) > size {
+		parts = append([]string{head[len(head)-size:]}, parts...)
+		head = head[:len(head)-size]
+	}
+	parts = append([]string{head}, parts...)
+	return strings.Join(append(parts, tail), f.group)
+}
+
+var currencySymbols = map[string]string{
+	"USD": "$", "EUR": "€", "GBP": "£", "INR": "₹", "JPY": "¥", "CNY": "¥", "CHF": "CHF", "AUD": "A$", "CAD": "CA$",
+	"RUB": "₽", "PLN": "zł", "BRL": "R$", "AED": "د.إ", "ILS": "₪", "SEK": "kr", "NOK": "kr", "DKK": "kr",
+}
+
+var currencyDecimals = map[string]int{"JPY": 0, "KRW": 0}
+
+// symbolAfter lists the languages that write the currency symbol after the amount
+var symbolAfter = map[string]bool{"de": true, "fr": true, "es": true, "it": true, "ru": true, "pl": true, "pt": true}
+
+// FormatCurrency formats amount in the currency identified by its ISO 4217 code using the conventions of the locale
+func FormatCurrency(s string, amount float64, code string) string {
+	code = strings.ToUpper(code)
+	decimals, ok := currencyDecimals[code]
+	if !ok {
+		decimals = 2
+	}
+	symbol, ok := currencySymbols[code]
+	if !ok {
+		symbol = code
+	}
+	num := FormatNumber(s, math.Abs(amount), decimals)
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+	}
+	if symbolAfter[Base(s)] {
+		return sign + num + "\u00a0" + symbol
+	}
+	return sign + symbol + num
+}
+
+const (
+	DateShort  = "short"
+	DateMedium = "medium"
+	DateLong   = "long"
+)
+
+type dateFormat struct {
+	months []string
+	short  string
+	medium string
+	long   string
+}
+
+var dateFormats = map[string]dateFormat{
+	"en": {
+		months: []string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+		short:  "01/02/2006", medium: "Jan 2, 2006", long: "{month} 2, 2006",
+	},
+	"de": {
+		months: []string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		short:  "02.01.06", medium: "02.01.2006", long: "2. {month} 2006",
+	},
+	"fr": {
+		months: []string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		short:  "02/01/2006", medium: "02/01/2006", long: "2 {month} 2006",
+	},
+	"es": {
+		months: []string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		short:  "2/1/06", medium: "02/01/2006", long: "2 de {month} de 2006",
+	},
+	"hi": {
+		months: []string{"जनवरी", "फ़रवरी", "मार्च", "अप्रैल", "मई", "जून", "जुलाई", "अगस्त", "सितंबर", "अक्तूबर", "नवंबर", "दिसंबर"},
+		short:  "2/1/06", medium: "02/01/2006", long: "2 {month} 2006",
+	},
+}
+
+// FormatDate formats t in the short, medium or long style of the locale, unknown styles are used as a Go time layout
+func FormatDate(s string, t time.Time, style string) string {
+	f, ok := dateFormats[Base(s)]
+	if !ok {
+		f = dateFormats[Default]
+	}
+	if Normalize(s) == "en-gb" || Normalize(s) == "en-in" {
+		f.short, f.medium, f.long = "02/01/2006", "2 Jan 2006", "2 {month} 2006"
+	}
+	layout := style
+	switch style {
+	case "", DateMedium:
+		layout = f.medium
+	case DateShort:
+		layout = f.short
+	case DateLong:
+		layout = f.long
+	}
+	// month names are substituted after formatting so that their letters are not read as layout elements
+	out := t.Format(strings.ReplaceAll(layout, "{month}", "\x00"))
+	return strings.ReplaceAll(out, "\x00", f.months[t.Month()-1])
+}
+
+// verbRegex matches an escaped percent sign, a fmt verb or a percent sign of the text. A percent sign followed by a
+// space is text, e.g. "100% paid".
+var verbRegex = regexp.MustCompile(`%%|%[-+#0]*(?:\[\d+\])?(?:\d+|\*)?(?:\.(?:\d+|\*)?)?(?:\[\d+\])?[A-Za-z]|%`)
+
+// Sprintf formats a translated message, arguments are only applied when the message contains verbs. Percent signs
+// that don't start a verb are kept as they are.
+func Sprintf(msg string, args ...interface{}) string {
+	if len(args) == 0 || !strings.Contains(msg, "%") {
+		return msg
+	}
+	verbs := 0
+	format := verbRegex.ReplaceAllStringFunc(msg, func(m string) string {
+		switch m {
+		case "%%":
+		case "%":
+			return "%%"
+		default:
+			verbs++
+		}
+		return m
+	})
+	if verbs == 0 {
+		return msg
+	}
+	return fmt.Sprintf(format, args...)
+}
@@ -0,0 +1,140 @@
+package locale
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PereRohit/util/testutil"
+)
+
+func TestPluralCategory(t *testing.T) {
+	tests := []struct {
+		name   string
+		locale string
+		n      float64
+		want   string
+	}{
+		{name: "en one", locale: "en", n: 1, want: One},
+		{name: "en other", locale: "en-US", n: 0, want: Other},
+		{name: "en fraction", locale: "en", n: 1.5, want: Other},
+		{name: "de other", locale: "de", n: 2, want: Other},
+		{name: "hi zero is one", locale: "hi", n: 0, want: One},
+		{name: "fr one", locale: "fr", n: 1.5, want: One},
+		{name: "ru few", locale: "ru", n: 22, want: Few},
+		{name: "ru many", locale: "ru", n: 11, want: Many},
+		{name: "ar two", locale: "ar", n: 2, want: Two},
+		{name: "ja other", locale: "ja", n: 1, want: Other},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := testutil.Diff(PluralCategory(tt.locale, tt.n), tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		name     string
+		locale   string
+		n        float64
+		decimals int
+		want     string
+	}{
+		{name: "en", locale: "en", n: 1234567.891, decimals: 2, want: "1,234,567.89"},
+		{name: "de", locale: "de-DE", n: 1234567.891, decimals: 2, want: "1.234.567,89"},
+		{name: "fr", locale: "fr", n: -1234.5, decimals: 1, want: "-1\u202f234,5"},
+		{name: "hi", locale: "hi", n: 1234567, decimals: 0, want: "12,34,567"},
+		{name: "en-IN", locale: "en_IN", n: 123456.5, decimals: 2, want: "1,23,456.50"},
+		{name: "small", locale: "en", n: 999, decimals: 0, want: "999"},
+		{name: "unknown locale", locale: "xx", n: 1000, decimals: 0, want: "1,000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := testutil.Diff(FormatNumber(tt.locale, tt.n, tt.decimals), tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestFormatCurrency(t *testing.T) {
+	tests := []struct {
+		name   string
+		locale string
+		amount float64
+		code   string
+		want   string
+	}{
+		{name: "en usd", locale: "en", amount: 1234.5, code: "USD", want: "$1,234.50"},
+		{name: "de eur", locale: "de", amount: 1234.5, code: "eur", want: "1.234,50\u00a0€"},
+		{name: "hi inr", locale: "hi", amount: 1234567, code: "INR", want: "₹12,34,567.00"},
+		{name: "jpy has no decimals", locale: "en", amount: -1500, code: "JPY", want: "-¥1,500"},
+		{name: "unknown code", locale: "en", amount: 1, code: "XYZ", want: "XYZ1.00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := testutil.Diff(FormatCurrency(tt.locale, tt.amount, tt.code), tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	d := time.Date(2022, time.March, 5, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		locale string
+		style  string
+		want   string
+	}{
+		{name: "en long", locale: "en", style: DateLong, want: "March 5, 2022"},
+		{name: "en short", locale: "en", style: DateShort, want: "03/05/2022"},
+		{name: "en-GB medium", locale: "en-GB", style: "", want: "5 Mar 2022"},
+		{name: "de long", locale: "de", style: DateLong, want: "5. März 2022"},
+		{name: "hi long", locale: "hi", style: DateLong, want: "5 मार्च 2022"},
+		{name: "custom layout", locale: "de", style: "2006", want: "2022"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := testutil.Diff(FormatDate(tt.locale, d, tt.style), tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestIsRTL(t *testing.T) {
+	if !IsRTL("ar-EG") || !IsRTL("he") || IsRTL("de") {
+		t.Error("unexpected right to left detection")
+	}
+}
+
+func TestSprintf(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+		args []interface{}
+		want string
+	}{
+		{name: "verbs", msg: "%d items", args: []interface{}{3}, want: "3 items"},
+		{name: "no arguments", msg: "100% paid", want: "100% paid"},
+		{name: "percent sign without verbs", msg: "100% paid", args: []interface{}{3}, want: "100% paid"},
+		{name: "percent sign with verbs", msg: "%d items, 100% paid", args: []interface{}{3}, want: "3 items, 100% paid"},
+		{name: "percent sign at the end", msg: "%.1f%", args: []interface{}{12.5}, want: "12.5%"},
+		{name: "escaped percent sign", msg: "%d%% off", args: []interface{}{20}, want: "20% off"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sprintf(tt.msg, tt.args...); got != tt.want {
+				t.Errorf("want %q got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package logic
+
+import (
+	"encoding/base64"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/vatsal278/html-pdf-service/internal/model"
+)
+
+var (
+	attrRefRegex = regexp.MustCompile(`(?i)(\s(?:src|href|poster|xlink:href)\s*=\s*)("([^"]*)"|'([^']*)')`)
+	cssUrlRegex  = regexp.MustCompile(`(?i)url\(\s*("([^"]*)"|'([^']*)'|([^)'"\s]*))\s*\)`)
+)
+
+// inlineAssets replaces relative references to stored assets in an executed page with data URIs so that the
+// renderer resolves them locally without network or file system access. Stylesheets are inlined together with
+// the fonts and images they reference.
+func inlineAssets(page []byte, assets map[string][]byte) []byte {
+	if len(assets) == 0 {
+		return page
+	}
+	out := attrRefRegex.ReplaceAllFunc(page, func(m []byte) []byte {
+		sub := attrRefRegex.FindSubmatch(m)
+		ref := string(sub[3])
+		if len(sub[4]) > 0 {
+			ref = string(sub[4])
+		}
+		uri, ok := assetUri(ref, ".", assets, true)
+		if !ok {
+			return m
+		}
+		return append(append(append([]byte{}, sub[1]...), '"'), append([]byte(uri), '"')...)
+	})
+	return inlineCssUrls(out, ".", assets)
+}
+
+func inlineCssUrls(css []byte, dir string, assets map[string][]byte) []byte {
+	return cssUrlRegex.ReplaceAllFunc(css, func(m []byte) []byte {
+		sub := cssUrlRegex.FindSubmatch(m)
+		ref := string(sub[2]) + string(sub[3]) + string(sub[4])
+		// stylesheets referenced from a stylesheet are not inlined to avoid import cycles
+		uri, ok := assetUri(ref, dir, assets, false)
+		if !ok {
+			return m
+		}
+		// base64 data URIs need no quotes which keeps them valid inside quoted style attributes
+		return []byte("url(" + uri + ")")
+	})
+}
+
+// assetUri returns the data URI of the asset referenced by ref relative to dir, ok is false for references to
+// external resources or unknown assets
+func assetUri(ref string, dir string, assets map[string][]byte, nested bool) (string, bool) {
+	if ref == "" || strings.HasPrefix(ref, "#") || strings.HasPrefix(ref, "//") || strings.Contains(ref, ":") {
+		return "", false
+	}
+	// query strings and fragments are commonly used for cache busting and do not name a different asset
+	if i := strings.IndexAny(ref, "?#"); i >= 0 {
+		ref = ref[:i]
+	}
+	name, ok := model.CleanAssetName(path.Join(dir, ref))
+	if !ok {
+		return "", false
+	}
+	b, ok := assets[name]
+	if !ok {
+		return "", false
+	}
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = http.DetectContentType(b)
+	}
+	// parameters such as the charset are not allowed before the base64 marker of a data URI
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	if strings.HasPrefix(contentType, "text/css") {
+		if !nested {
+			return "", false
+		}
+		b = inlineCssUrls(b, path.Dir(name), assets)
+	}
+	return "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(b), true
+}
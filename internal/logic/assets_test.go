@@ -0,0 +1,55 @@
+package logic
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/PereRohit/util/testutil"
+)
+
+func Test_inlineAssets(t *testing.T) {
+	assets := map[string][]byte{
+		"logo.png":          {0x89, 'P', 'N', 'G'},
+		"css/style.css":     []byte(`body { background: url('../img/bg.gif'); } @font-face { src: url(fonts/a.woff2); }`),
+		"img/bg.gif":        []byte("GIF89a"),
+		"css/fonts/a.woff2": []byte("wOF2"),
+	}
+	dataUri := func(contentType string, b string) string {
+		return "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString([]byte(b))
+	}
+	tests := []struct {
+		name string
+		page string
+		want string
+	}{
+		{
+			name: "Success:: image reference",
+			page: `<img src="logo.png?v=2" alt="x"><img src='./logo.png'>`,
+			want: `<img src="` + dataUri("image/png", "\x89PNG") + `" alt="x"><img src="` + dataUri("image/png", "\x89PNG") + `">`,
+		},
+		{
+			name: "Success:: stylesheet with nested references",
+			page: `<link rel="stylesheet" href="css/style.css">`,
+			want: `<link rel="stylesheet" href="` + dataUri("text/css", `body { background: url(`+dataUri("image/gif", "GIF89a")+`); } @font-face { src: url(`+dataUri("font/woff2", "wOF2")+`); }`) + `">`,
+		},
+		{
+			name: "Success:: inline style url",
+			page: `<div style="background: url(img/bg.gif)"></div>`,
+			want: `<div style="background: url(` + dataUri("image/gif", "GIF89a") + `)"></div>`,
+		},
+		{
+			name: "Success:: external, unknown and escaping references are kept",
+			page: `<img src="https://example.com/logo.png"><a href="#top"></a><img src="missing.png"><img src="../logo.png">`,
+			want: `<img src="https://example.com/logo.png"><a href="#top"></a><img src="missing.png"><img src="../logo.png">`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := inlineAssets([]byte(tt.page), assets)
+			diff := testutil.Diff(string(got), tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
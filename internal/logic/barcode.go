@@ -0,0 +1,147 @@
+package logic
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"image/png"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/code39"
+	"github.com/boombuler/barcode/ean"
+	"github.com/boombuler/barcode/qr"
+)
+
+const (
+	minCodeSize      = 16
+	maxCodeSize      = 2000
+	defaultQrSize    = 200
+	defaultBarHeight = 80
+	// maxQrContent is the capacity of a version 40 QR code in byte mode with the lowest error correction
+	maxQrContent     = 2953
+	maxBarcodeLength = 80
+)
+
+// funcArgError reports invalid arguments passed to a template function, it is returned to the client as a bad request
+type funcArgError struct {
+	msg string
+}
+
+func (e funcArgError) Error() string {
+	return e.msg
+}
+
+func funcArgErrorf(format string, args ...interface{}) error {
+	return funcArgError{msg: fmt.Sprintf(format, args...)}
+}
+
+var qrLevels = map[string]qr.ErrorCorrectionLevel{"L": qr.L, "M": qr.M, "Q": qr.Q, "H": qr.H}
+
+// qrCode renders content as a QR code PNG data URI, opts are the size in pixels (default 200)
+// and the error correction level L, M (default), Q or H in any order
+func qrCode(content string, opts ...interface{}) (template.URL, error) {
+	size, level := defaultQrSize, qr.M
+	for _, o := range opts {
+		switch v := o.(type) {
+		case string:
+			l, ok := qrLevels[strings.ToUpper(v)]
+			if !ok {
+				return "", funcArgErrorf("qrcode: unknown error correction level %q", v)
+			}
+			level = l
+		default:
+			n, err := toFloat(v)
+			if err != nil {
+				return "", funcArgErrorf("qrcode: invalid option %v", v)
+			}
+			size = int(n)
+		}
+	}
+	if content == "" {
+		return "", funcArgErrorf("qrcode: content is empty")
+	}
+	if len(content) > maxQrContent {
+		return "", funcArgErrorf("qrcode: content longer than %d bytes", maxQrContent)
+	}
+	if size < minCodeSize || size > maxCodeSize {
+		return "", funcArgErrorf("qrcode: size must be between %d and %d", minCodeSize, maxCodeSize)
+	}
+	bc, err := qr.Encode(content, level, qr.Auto)
+	if err != nil {
+		return "", funcArgErrorf("qrcode: %s", err)
+	}
+	if size < bc.Bounds().Dx() {
+		return "", funcArgErrorf("qrcode: size must be at least %d for this content", bc.Bounds().Dx())
+	}
+	return pngDataUri(bc, size, size)
+}
+
+// barCode renders content as a 1D barcode PNG data URI, kind is code128, code39, ean13 or ean8
+// and opts are the width and height in pixels
+func barCode(kind string, content string, opts ...interface{}) (template.URL, error) {
+	if content == "" {
+		return "", funcArgErrorf("barcode: content is empty")
+	}
+	if utf8.RuneCountInString(content) > maxBarcodeLength {
+		return "", funcArgErrorf("barcode: content longer than %d characters", maxBarcodeLength)
+	}
+	var bc barcode.Barcode
+	var err error
+	switch strings.ToLower(kind) {
+	case "code128":
+		bc, err = code128.Encode(content)
+	case "code39":
+		bc, err = code39.Encode(content, true, true)
+	case "ean13", "ean8":
+		want := 13
+		if strings.ToLower(kind) == "ean8" {
+			want = 8
+		}
+		// the check digit is calculated when it is left out
+		if strings.Trim(content, "0123456789") != "" || (len(content) != want && len(content) != want-1) {
+			return "", funcArgErrorf("barcode: %s needs %d or %d digits", kind, want-1, want)
+		}
+		bc, err = ean.Encode(content)
+	default:
+		return "", funcArgErrorf("barcode: unsupported kind %q", kind)
+	}
+	if err != nil {
+		return "", funcArgErrorf("barcode: %s", err)
+	}
+	width, height := bc.Bounds().Dx()*2, defaultBarHeight
+	for i, o := range opts {
+		n, err := toFloat(o)
+		if err != nil || i > 1 {
+			return "", funcArgErrorf("barcode: invalid option %v", o)
+		}
+		if i == 0 {
+			width = int(n)
+		} else {
+			height = int(n)
+		}
+	}
+	if width < bc.Bounds().Dx() || width > maxCodeSize {
+		return "", funcArgErrorf("barcode: width must be between %d and %d for this content", bc.Bounds().Dx(), maxCodeSize)
+	}
+	if height < minCodeSize || height > maxCodeSize {
+		return "", funcArgErrorf("barcode: height must be between %d and %d", minCodeSize, maxCodeSize)
+	}
+	return pngDataUri(bc, width, height)
+}
+
+func pngDataUri(bc barcode.Barcode, width, height int) (template.URL, error) {
+	scaled, err := barcode.Scale(bc, width, height)
+	if err != nil {
+		return "", funcArgErrorf("%s", err)
+	}
+	buf := bytes.NewBuffer(nil)
+	err = png.Encode(buf, scaled)
+	if err != nil {
+		return "", err
+	}
+	return template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())), nil
+}
@@ -0,0 +1,112 @@
+package logic
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"html/template"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/PereRohit/util/testutil"
+)
+
+func decodeDataUri(t *testing.T, uri template.URL) (int, int) {
+	b64 := strings.TrimPrefix(string(uri), "data:image/png;base64,")
+	if b64 == string(uri) {
+		t.Fatalf("unexpected data uri %v", uri)
+	}
+	b, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img.Bounds().Dx(), img.Bounds().Dy()
+}
+
+func Test_qrCode(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		opts       []interface{}
+		wantSize   int
+		wantErrMsg string
+	}{
+		{name: "Success:: default size", content: "https://pay.example.com/?id=1", wantSize: defaultQrSize},
+		{name: "Success:: size and level", content: "upi://pay?pa=x@y", opts: []interface{}{int64(120), "h"}, wantSize: 120},
+		{name: "Failure:: empty content", content: "", wantErrMsg: "qrcode: content is empty"},
+		{name: "Failure:: unknown level", content: "a", opts: []interface{}{"X"}, wantErrMsg: `qrcode: unknown error correction level "X"`},
+		{name: "Failure:: size too large", content: "a", opts: []interface{}{5000}, wantErrMsg: "qrcode: size must be between 16 and 2000"},
+		{name: "Failure:: content too long", content: strings.Repeat("a", maxQrContent+1), wantErrMsg: "qrcode: content longer than 2953 bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uri, err := qrCode(tt.content, tt.opts...)
+			if tt.wantErrMsg != "" {
+				var argErr funcArgError
+				if !errors.As(err, &argErr) {
+					t.Fatalf("want funcArgError got %v", err)
+				}
+				diff := testutil.Diff(err.Error(), tt.wantErrMsg)
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			w, h := decodeDataUri(t, uri)
+			if w != tt.wantSize || h != tt.wantSize {
+				t.Errorf("want %vx%v got %vx%v", tt.wantSize, tt.wantSize, w, h)
+			}
+		})
+	}
+}
+
+func Test_barCode(t *testing.T) {
+	tests := []struct {
+		name       string
+		kind       string
+		content    string
+		opts       []interface{}
+		wantWidth  int
+		wantHeight int
+		wantErrMsg string
+	}{
+		{name: "Success:: code128", kind: "code128", content: "TRACK123456", opts: []interface{}{400, 60}, wantWidth: 400, wantHeight: 60},
+		{name: "Success:: ean13 with check digit computed", kind: "EAN13", content: "400638133393", opts: []interface{}{300}, wantWidth: 300, wantHeight: defaultBarHeight},
+		{name: "Success:: code39", kind: "code39", content: "ABC-123", opts: []interface{}{500}, wantWidth: 500, wantHeight: defaultBarHeight},
+		{name: "Failure:: ean13 with letters", kind: "ean13", content: "40063813339A", wantErrMsg: "barcode: ean13 needs 12 or 13 digits"},
+		{name: "Failure:: unsupported kind", kind: "pdf417", content: "a", wantErrMsg: `barcode: unsupported kind "pdf417"`},
+		{name: "Failure:: width too small", kind: "code128", content: "TRACK123456", opts: []interface{}{10}, wantErrMsg: "barcode: width must be between 134 and 2000 for this content"},
+		{name: "Failure:: too many options", kind: "code128", content: "a", opts: []interface{}{400, 60, 1}, wantErrMsg: "barcode: invalid option 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uri, err := barCode(tt.kind, tt.content, tt.opts...)
+			if tt.wantErrMsg != "" {
+				var argErr funcArgError
+				if !errors.As(err, &argErr) {
+					t.Fatalf("want funcArgError got %v", err)
+				}
+				diff := testutil.Diff(err.Error(), tt.wantErrMsg)
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			w, h := decodeDataUri(t, uri)
+			if w != tt.wantWidth || h != tt.wantHeight {
+				t.Errorf("want %vx%v got %vx%v", tt.wantWidth, tt.wantHeight, w, h)
+			}
+		})
+	}
+}
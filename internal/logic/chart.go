@@ -0,0 +1,550 @@
+package logic
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/vatsal278/html-pdf-service/internal/locale"
+)
+
+const (
+	defaultChartWidth  = 600
+	defaultChartHeight = 320
+	minChartSize       = 100
+	maxChartSize       = 4000
+	maxChartPoints     = 500
+	maxChartSeries     = 20
+	chartTicks         = 5
+	chartFontSize      = 11
+	// chartCharWidth approximates the advance of a character at chartFontSize, svg text cannot be measured
+	// without a layout engine
+	chartCharWidth = 6.5
+)
+
+var (
+	defaultPalette = []string{"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f", "#edc948", "#b07aa1", "#ff9da7", "#9c755f", "#bab0ac"}
+	colorRegex     = regexp.MustCompile(`^(#[0-9a-fA-F]{3}|#[0-9a-fA-F]{6}|[a-zA-Z]+)$`)
+)
+
+type chartSeries struct {
+	name   string
+	values []float64
+}
+
+type chartData struct {
+	labels []string
+	series []chartSeries
+}
+
+type chartOptions struct {
+	title  string
+	xLabel string
+	yLabel string
+	width  float64
+	height float64
+	colors []string
+	legend *bool
+}
+
+// chartFuncs returns the chart template functions, numbers on the axes and in legends are formatted for loc
+func chartFuncs(loc string) map[string]interface{} {
+	return map[string]interface{}{
+		"barchart": func(data interface{}, opts ...interface{}) (template.HTML, error) {
+			return renderChart("barchart", loc, data, opts)
+		},
+		"linechart": func(data interface{}, opts ...interface{}) (template.HTML, error) {
+			return renderChart("linechart", loc, data, opts)
+		},
+		"piechart": func(data interface{}, opts ...interface{}) (template.HTML, error) {
+			return renderChart("piechart", loc, data, opts)
+		},
+	}
+}
+
+func renderChart(kind string, loc string, data interface{}, opts []interface{}) (template.HTML, error) {
+	d, err := parseChartData(kind, data)
+	if err != nil {
+		return "", err
+	}
+	o, err := parseChartOptions(kind, opts)
+	if err != nil {
+		return "", err
+	}
+	c := svgChart{loc: loc, data: d, opts: o}
+	switch kind {
+	case "barchart":
+		c.axes(true)
+	case "linechart":
+		c.axes(false)
+	default:
+		if len(d.series) != 1 {
+			return "", funcArgErrorf("piechart: needs a single series")
+		}
+		for _, v := range d.series[0].values {
+			if v < 0 {
+				return "", funcArgErrorf("piechart: values must not be negative")
+			}
+		}
+		c.pie()
+	}
+	return template.HTML(c.String()), nil
+}
+
+// parseChartData accepts a list of numbers, a list of {"label", "value"} objects, an object of labels to values
+// or an object with "labels" and a list of {"name", "values"} "series" for charts with several series
+func parseChartData(kind string, data interface{}) (chartData, error) {
+	var d chartData
+	switch v := data.(type) {
+	case map[string]interface{}:
+		if s, ok := v["series"]; ok {
+			labels, err := chartList(kind, v["labels"])
+			if err != nil {
+				return d, err
+			}
+			for _, l := range labels {
+				d.labels = append(d.labels, fmt.Sprint(l))
+			}
+			series, err := chartList(kind, s)
+			if err != nil {
+				return d, err
+			}
+			if len(series) == 0 || len(series) > maxChartSeries {
+				return d, funcArgErrorf("%s: needs between 1 and %d series", kind, maxChartSeries)
+			}
+			for i, s := range series {
+				m, ok := s.(map[string]interface{})
+				if !ok {
+					return d, funcArgErrorf("%s: series %d is not an object", kind, i)
+				}
+				values, err := chartList(kind, m["values"])
+				if err != nil {
+					return d, err
+				}
+				if len(values) != len(d.labels) {
+					return d, funcArgErrorf("%s: series %d has %d values for %d labels", kind, i, len(values), len(d.labels))
+				}
+				cs := chartSeries{name: fmt.Sprint(m["name"])}
+				if m["name"] == nil {
+					cs.name = ""
+				}
+				for _, x := range values {
+					f, err := chartValue(kind, x)
+					if err != nil {
+						return d, err
+					}
+					cs.values = append(cs.values, f)
+				}
+				d.series = append(d.series, cs)
+			}
+			break
+		}
+		// objects carry no order, the labels are sorted to keep the output deterministic
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		cs := chartSeries{}
+		for _, k := range keys {
+			f, err := chartValue(kind, v[k])
+			if err != nil {
+				return d, err
+			}
+			d.labels = append(d.labels, k)
+			cs.values = append(cs.values, f)
+		}
+		d.series = []chartSeries{cs}
+	default:
+		list, err := chartList(kind, data)
+		if err != nil {
+			return d, err
+		}
+		cs := chartSeries{}
+		for i, x := range list {
+			label := strconv.Itoa(i + 1)
+			if m, ok := x.(map[string]interface{}); ok {
+				label, x = fmt.Sprint(m["label"]), m["value"]
+			}
+			f, err := chartValue(kind, x)
+			if err != nil {
+				return d, err
+			}
+			d.labels = append(d.labels, label)
+			cs.values = append(cs.values, f)
+		}
+		d.series = []chartSeries{cs}
+	}
+	if len(d.labels) == 0 || len(d.labels) > maxChartPoints {
+		return d, funcArgErrorf("%s: needs between 1 and %d data points", kind, maxChartPoints)
+	}
+	return d, nil
+}
+
+func chartList(kind string, v interface{}) ([]interface{}, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, funcArgErrorf("%s: %v is not a list", kind, v)
+	}
+	list := make([]interface{}, rv.Len())
+	for i := range list {
+		list[i] = rv.Index(i).Interface()
+	}
+	return list, nil
+}
+
+func chartValue(kind string, v interface{}) (float64, error) {
+	f, err := toFloat(v)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, funcArgErrorf("%s: %v is not a number", kind, v)
+	}
+	return f, nil
+}
+
+// parseChartOptions reads name value pairs: title, x_label, y_label, width, height, colors and legend
+func parseChartOptions(kind string, opts []interface{}) (chartOptions, error) {
+	o := chartOptions{width: defaultChartWidth, height: defaultChartHeight, colors: defaultPalette}
+	if len(opts)%2 != 0 {
+		return o, funcArgErrorf("%s: options must be name value pairs", kind)
+	}
+	for i := 0; i < len(opts); i += 2 {
+		name, _ := opts[i].(string)
+		v := opts[i+1]
+		switch name {
+		case "title":
+			o.title = fmt.Sprint(v)
+		case "x_label":
+			o.xLabel = fmt.Sprint(v)
+		case "y_label":
+			o.yLabel = fmt.Sprint(v)
+		case "width", "height":
+			n, err := toFloat(v)
+			if err != nil || n < minChartSize || n > maxChartSize {
+				return o, funcArgErrorf("%s: %s must be between %d and %d", kind, name, minChartSize, maxChartSize)
+			}
+			if name == "width" {
+				o.width = math.Round(n)
+			} else {
+				o.height = math.Round(n)
+			}
+		case "colors":
+			colors, err := chartColors(kind, v)
+			if err != nil {
+				return o, err
+			}
+			o.colors = colors
+		case "legend":
+			b, ok := v.(bool)
+			if !ok {
+				return o, funcArgErrorf("%s: legend must be true or false", kind)
+			}
+			o.legend = &b
+		default:
+			return o, funcArgErrorf("%s: unknown option %v", kind, opts[i])
+		}
+	}
+	return o, nil
+}
+
+// chartColors accepts a comma separated string or a list of hex or named colours
+func chartColors(kind string, v interface{}) ([]string, error) {
+	var colors []string
+	if s, ok := v.(string); ok {
+		colors = strings.Split(s, ",")
+	} else {
+		list, err := chartList(kind, v)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range list {
+			colors = append(colors, fmt.Sprint(c))
+		}
+	}
+	for i, c := range colors {
+		colors[i] = strings.TrimSpace(c)
+		if !colorRegex.MatchString(colors[i]) {
+			return nil, funcArgErrorf("%s: invalid colour %q", kind, c)
+		}
+	}
+	if len(colors) == 0 {
+		return nil, funcArgErrorf("%s: colors is empty", kind)
+	}
+	return colors, nil
+}
+
+type legendItem struct {
+	name  string
+	color string
+}
+
+type svgChart struct {
+	loc  string
+	data chartData
+	opts chartOptions
+	b    strings.Builder
+}
+
+func (c *svgChart) color(i int) string {
+	return c.opts.colors[i%len(c.opts.colors)]
+}
+
+func (c *svgChart) String() string {
+	w, h := num(c.opts.width), num(c.opts.height)
+	head := `<svg xmlns="http://www.w3.org/2000/svg" role="img" width="` + w + `" height="` + h + `" viewBox="0 0 ` + w + ` ` + h +
+		`" font-family="sans-serif" font-size="` + strconv.Itoa(chartFontSize) + `">`
+	if c.opts.title != "" {
+		head += `<title>` + template.HTMLEscapeString(c.opts.title) + `</title>`
+	}
+	return head + c.b.String() + `</svg>`
+}
+
+func (c *svgChart) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&c.b, format, args...)
+}
+
+func (c *svgChart) text(x, y float64, anchor string, s string, extra string) {
+	c.printf(`<text x="%s" y="%s" text-anchor="%s"%s>%s</text>`, num(x), num(y), anchor, extra, template.HTMLEscapeString(s))
+}
+
+// legendRows lays out the legend items in rows that fit the chart width and returns the rows
+func (c *svgChart) legendRows(items []legendItem) [][]legendItem {
+	var rows [][]legendItem
+	x := 0.0
+	for _, it := range items {
+		w := legendItemWidth(it.name)
+		if len(rows) == 0 || x+w > c.opts.width-20 {
+			rows = append(rows, nil)
+			x = 0
+		}
+		rows[len(rows)-1] = append(rows[len(rows)-1], it)
+		x += w
+	}
+	return rows
+}
+
+func (c *svgChart) legend(rows [][]legendItem, top float64) {
+	for r, row := range rows {
+		w := 0.0
+		for _, it := range row {
+			w += legendItemWidth(it.name)
+		}
+		x := (c.opts.width - w) / 2
+		y := top + float64(r)*18
+		for _, it := range row {
+			c.printf(`<rect x="%s" y="%s" width="10" height="10" fill="%s"/>`, num(x), num(y), it.color)
+			c.text(x+14, y+9, "start", it.name, "")
+			x += legendItemWidth(it.name)
+		}
+	}
+}
+
+func legendItemWidth(name string) float64 {
+	return 24 + float64(utf8.RuneCountInString(name))*chartCharWidth
+}
+
+func (c *svgChart) showLegend(def bool) bool {
+	if c.opts.legend != nil {
+		return *c.opts.legend
+	}
+	return def
+}
+
+// axes draws a bar or line chart with a value axis on the left and the labels below the plot
+func (c *svgChart) axes(bars bool) {
+	var items []legendItem
+	for i, s := range c.data.series {
+		name := s.name
+		if name == "" {
+			name = fmt.Sprintf("%d", i+1)
+		}
+		items = append(items, legendItem{name: name, color: c.color(i)})
+	}
+	var rows [][]legendItem
+	if c.showLegend(len(c.data.series) > 1) {
+		rows = c.legendRows(items)
+	}
+
+	lo, hi := 0.0, 0.0
+	for _, s := range c.data.series {
+		for _, v := range s.values {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+	}
+	lo, hi, step := niceScale(lo, hi, chartTicks)
+	decimals := 0
+	if step < 1 {
+		decimals = int(math.Ceil(-math.Log10(step)))
+	}
+	ticks := make([]float64, int(math.Round((hi-lo)/step))+1)
+	tickWidth := 0.0
+	for i := range ticks {
+		// ticks are computed from their index, accumulating the step adds float noise to the labels
+		ticks[i] = lo + float64(i)*step
+		v := ticks[i]
+		tickWidth = math.Max(tickWidth, float64(utf8.RuneCountInString(locale.FormatNumber(c.loc, v, decimals)))*chartCharWidth)
+	}
+
+	top, left, right, bottom := 10.0, tickWidth+16, 16.0, 28.0
+	if c.opts.title != "" {
+		top += 24
+	}
+	if c.opts.yLabel != "" {
+		left += 18
+	}
+	if c.opts.xLabel != "" {
+		bottom += 18
+	}
+	bottom += float64(len(rows)) * 18
+	pw, ph := c.opts.width-left-right, c.opts.height-top-bottom
+	pw, ph = math.Max(pw, 1), math.Max(ph, 1)
+	y := func(v float64) float64 { return top + ph - (v-lo)/(hi-lo)*ph }
+
+	if c.opts.title != "" {
+		c.text(c.opts.width/2, 22, "middle", c.opts.title, ` font-size="14" font-weight="bold"`)
+	}
+	for _, v := range ticks {
+		c.printf(`<line x1="%s" y1="%s" x2="%s" y2="%s" stroke="#e0e0e0"/>`, num(left), num(y(v)), num(left+pw), num(y(v)))
+		c.text(left-6, y(v)+4, "end", locale.FormatNumber(c.loc, v, decimals), "")
+	}
+	if c.opts.yLabel != "" {
+		cy := top + ph/2
+		c.text(14, cy, "middle", c.opts.yLabel, fmt.Sprintf(` transform="rotate(-90 14 %s)"`, num(cy)))
+	}
+
+	n := float64(len(c.data.labels))
+	band := pw / n
+	// labels are thinned out when they would overlap
+	every := 1
+	for _, l := range c.data.labels {
+		if w := float64(utf8.RuneCountInString(l))*chartCharWidth + 6; w > band*float64(every) {
+			every = int(math.Ceil(w / band))
+		}
+	}
+	for i, l := range c.data.labels {
+		if i%every == 0 {
+			c.text(left+band*(float64(i)+0.5), top+ph+16, "middle", l, "")
+		}
+	}
+	if c.opts.xLabel != "" {
+		c.text(left+pw/2, top+ph+34, "middle", c.opts.xLabel, "")
+	}
+
+	if bars {
+		group := band * 0.8
+		bw := group / float64(len(c.data.series))
+		for si, s := range c.data.series {
+			for i, v := range s.values {
+				x := left + band*float64(i) + (band-group)/2 + bw*float64(si)
+				y0, y1 := y(math.Max(v, 0)), y(math.Min(v, 0))
+				c.printf(`<rect x="%s" y="%s" width="%s" height="%s" fill="%s"/>`, num(x), num(y0), num(bw), num(y1-y0), c.color(si))
+			}
+		}
+	} else {
+		for si, s := range c.data.series {
+			points := make([]string, len(s.values))
+			for i, v := range s.values {
+				points[i] = num(left+band*(float64(i)+0.5)) + "," + num(y(v))
+			}
+			c.printf(`<polyline points="%s" fill="none" stroke="%s" stroke-width="2"/>`, strings.Join(points, " "), c.color(si))
+			for _, p := range points {
+				xy := strings.Split(p, ",")
+				c.printf(`<circle cx="%s" cy="%s" r="3" fill="%s"/>`, xy[0], xy[1], c.color(si))
+			}
+		}
+	}
+	c.printf(`<line x1="%s" y1="%s" x2="%s" y2="%s" stroke="#333"/>`, num(left), num(y(0)), num(left+pw), num(y(0)))
+	c.printf(`<line x1="%s" y1="%s" x2="%s" y2="%s" stroke="#333"/>`, num(left), num(top), num(left), num(top+ph))
+	c.legend(rows, c.opts.height-float64(len(rows))*18)
+}
+
+// pie draws the single series as slices clockwise from the top, the legend lists the share of every label
+func (c *svgChart) pie() {
+	values := c.data.series[0].values
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	var items []legendItem
+	for i, l := range c.data.labels {
+		share := 0.0
+		if total > 0 {
+			share = values[i] / total * 100
+		}
+		items = append(items, legendItem{name: l + " (" + locale.FormatNumber(c.loc, share, 1) + "%)", color: c.color(i)})
+	}
+	var rows [][]legendItem
+	if c.showLegend(true) {
+		rows = c.legendRows(items)
+	}
+	top, bottom := 10.0, 10.0+float64(len(rows))*18
+	if c.opts.title != "" {
+		top += 24
+		c.text(c.opts.width/2, 22, "middle", c.opts.title, ` font-size="14" font-weight="bold"`)
+	}
+	r := math.Max(math.Min(c.opts.width-20, c.opts.height-top-bottom)/2, 1)
+	cx, cy := c.opts.width/2, top+r
+	if total > 0 {
+		angle := -math.Pi / 2
+		for i, v := range values {
+			if v == 0 {
+				continue
+			}
+			if v == total {
+				c.printf(`<circle cx="%s" cy="%s" r="%s" fill="%s"/>`, num(cx), num(cy), num(r), c.color(i))
+				break
+			}
+			end := angle + v/total*2*math.Pi
+			large := 0
+			if end-angle > math.Pi {
+				large = 1
+			}
+			c.printf(`<path d="M%s %sL%s %sA%s %s 0 %d 1 %s %sZ" fill="%s" stroke="#fff"/>`,
+				num(cx), num(cy), num(cx+r*math.Cos(angle)), num(cy+r*math.Sin(angle)), num(r), num(r), large,
+				num(cx+r*math.Cos(end)), num(cy+r*math.Sin(end)), c.color(i))
+			angle = end
+		}
+	}
+	c.legend(rows, c.opts.height-float64(len(rows))*18)
+}
+
+// niceScale widens the range to include zero and rounds it to steps of 1, 2 or 5 times a power of ten
+func niceScale(lo, hi float64, ticks int) (float64, float64, float64) {
+	if lo == hi {
+		hi = lo + 1
+	}
+	step := niceNum(niceNum(hi-lo, false)/float64(ticks-1), true)
+	return math.Floor(lo/step) * step, math.Ceil(hi/step) * step, step
+}
+
+func niceNum(x float64, round bool) float64 {
+	exp := math.Floor(math.Log10(x))
+	f := x / math.Pow(10, exp)
+	var nf float64
+	switch {
+	case round && f < 1.5, !round && f <= 1:
+		nf = 1
+	case round && f < 3, !round && f <= 2:
+		nf = 2
+	case round && f < 7, !round && f <= 5:
+		nf = 5
+	default:
+		nf = 10
+	}
+	return nf * math.Pow(10, exp)
+}
+
+// num formats svg coordinates with at most two decimals so the output does not depend on float noise
+func num(f float64) string {
+	s := strconv.FormatFloat(f, 'f', 2, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
@@ -0,0 +1,172 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/PereRohit/util/testutil"
+)
+
+func Test_renderChart(t *testing.T) {
+	multi := map[string]interface{}{
+		"labels": []interface{}{"Jan", "Feb", "Mar"},
+		"series": []interface{}{
+			map[string]interface{}{"name": "2021", "values": []interface{}{int64(10), int64(20), int64(15)}},
+			map[string]interface{}{"name": "2022", "values": []interface{}{12.5, json.Number("22"), int64(-5)}},
+		},
+	}
+	tests := []struct {
+		name          string
+		kind          string
+		loc           string
+		data          interface{}
+		opts          []interface{}
+		wantContains  []string
+		wantExcluding []string
+		wantErrMsg    string
+	}{
+		{
+			name: "Success:: bar chart with several series",
+			kind: "barchart",
+			loc:  "en",
+			data: multi,
+			opts: []interface{}{"title", "Sales <2022>", "y_label", "EUR", "x_label", "Month", "width", int64(500)},
+			wantContains: []string{
+				`<svg xmlns="http://www.w3.org/2000/svg" role="img" width="500" height="320" viewBox="0 0 500 320"`,
+				`<title>Sales &lt;2022&gt;</title>`,
+				`>Jan</text>`, `>Month</text>`, `>EUR</text>`, `>-10</text>`, `>30</text>`,
+				`fill="#4e79a7"`, `fill="#f28e2b"`, `>2021</text>`, `>2022</text>`,
+			},
+		},
+		{
+			name:          "Success:: line chart from label value objects without legend",
+			kind:          "linechart",
+			loc:           "de",
+			data:          []interface{}{map[string]interface{}{"label": "Q1", "value": 1500.5}, map[string]interface{}{"label": "Q2", "value": int64(3000)}},
+			opts:          []interface{}{"colors", "red, #00ff00"},
+			wantContains:  []string{`<polyline points=`, `stroke="red"`, `>Q2</text>`, `>3.000</text>`},
+			wantExcluding: []string{`<rect x=`, `<title>`},
+		},
+		{
+			name:         "Success:: pie chart sorted by label",
+			kind:         "piechart",
+			loc:          "en",
+			data:         map[string]interface{}{"b": int64(1), "a": int64(3)},
+			wantContains: []string{`<path d="M300 151L300 10A141 141 0 1 1 159 151Z"`, `>a (75.0%)</text>`, `>b (25.0%)</text>`},
+		},
+		{
+			name:         "Success:: pie chart with a single slice",
+			kind:         "piechart",
+			loc:          "en",
+			data:         []interface{}{0, 5},
+			wantContains: []string{`<circle cx="300"`, `>2 (100.0%)</text>`},
+		},
+		{
+			name:       "Failure:: pie chart with negative values",
+			kind:       "piechart",
+			data:       []interface{}{1, -1},
+			wantErrMsg: "piechart: values must not be negative",
+		},
+		{
+			name:       "Failure:: pie chart with several series",
+			kind:       "piechart",
+			data:       multi,
+			wantErrMsg: "piechart: needs a single series",
+		},
+		{
+			name:       "Failure:: not a number",
+			kind:       "barchart",
+			data:       []interface{}{"x"},
+			wantErrMsg: "barchart: x is not a number",
+		},
+		{
+			name: "Failure:: series length mismatch",
+			kind: "linechart",
+			data: map[string]interface{}{
+				"labels": []interface{}{"a"},
+				"series": []interface{}{map[string]interface{}{"values": []interface{}{1, 2}}},
+			},
+			wantErrMsg: "linechart: series 0 has 2 values for 1 labels",
+		},
+		{
+			name:       "Failure:: empty data",
+			kind:       "barchart",
+			data:       []interface{}{},
+			wantErrMsg: "barchart: needs between 1 and 500 data points",
+		},
+		{
+			name:       "Failure:: invalid colour",
+			kind:       "barchart",
+			data:       []interface{}{1},
+			opts:       []interface{}{"colors", []interface{}{`red" onload="x`}},
+			wantErrMsg: `barchart: invalid colour "red\" onload=\"x"`,
+		},
+		{
+			name:       "Failure:: unknown option",
+			kind:       "barchart",
+			data:       []interface{}{1},
+			opts:       []interface{}{"depth", 3},
+			wantErrMsg: "barchart: unknown option depth",
+		},
+		{
+			name:       "Failure:: odd options",
+			kind:       "barchart",
+			data:       []interface{}{1},
+			opts:       []interface{}{"title"},
+			wantErrMsg: "barchart: options must be name value pairs",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderChart(tt.kind, tt.loc, tt.data, tt.opts)
+			if tt.wantErrMsg != "" {
+				var argErr funcArgError
+				if !errors.As(err, &argErr) {
+					t.Fatalf("want funcArgError got %v", err)
+				}
+				diff := testutil.Diff(err.Error(), tt.wantErrMsg)
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.wantContains {
+				if !strings.Contains(string(got), want) {
+					t.Errorf("want %v in %v", want, got)
+				}
+			}
+			for _, want := range tt.wantExcluding {
+				if strings.Contains(string(got), want) {
+					t.Errorf("did not want %v in %v", want, got)
+				}
+			}
+			again, _ := renderChart(tt.kind, tt.loc, tt.data, tt.opts)
+			if again != got {
+				t.Error("chart output is not deterministic")
+			}
+		})
+	}
+}
+
+func Test_niceScale(t *testing.T) {
+	tests := []struct {
+		lo, hi               float64
+		wantLo, wantHi, step float64
+	}{
+		{lo: 0, hi: 22, wantLo: 0, wantHi: 30, step: 10},
+		{lo: -5, hi: 22, wantLo: -10, wantHi: 30, step: 10},
+		{lo: 0, hi: 0.37, wantLo: 0, wantHi: 0.4, step: 0.1},
+		{lo: 0, hi: 0, wantLo: 0, wantHi: 1, step: 0.2},
+	}
+	for _, tt := range tests {
+		lo, hi, step := niceScale(tt.lo, tt.hi, chartTicks)
+		if num(lo) != num(tt.wantLo) || num(hi) != num(tt.wantHi) || num(step) != num(tt.step) {
+			t.Errorf("niceScale(%v, %v) = %v, %v, %v", tt.lo, tt.hi, lo, hi, step)
+		}
+	}
+}
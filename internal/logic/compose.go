@@ -0,0 +1,165 @@
+package logic
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/PereRohit/util/log"
+	respModel "github.com/PereRohit/util/model"
+
+	"github.com/vatsal278/html-pdf-service/internal/codes"
+	"github.com/vatsal278/html-pdf-service/internal/model"
+	"github.com/vatsal278/html-pdf-service/internal/pdf"
+)
+
+// Compose renders the template parts of req and merges them with the uploaded pdfs in the order of the parts into
+// one pdf with a combined outline. The output profile of the request applies to the merged pdf.
+func (l htmlPdfServiceLogic) Compose(ctx context.Context, w io.Writer, req *model.ComposeReq) *respModel.Response {
+	if len(req.Parts) == 0 || len(req.Parts) > model.MaxComposeParts {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidCompose),
+			Data:    fmt.Sprintf("a document is composed of 1 to %d parts", model.MaxComposeParts),
+		}
+	}
+	doc := req.Document()
+	if !doc.ValidDisposition() {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidDisposition),
+			Data:    nil,
+		}
+	}
+	if !doc.ValidFilename() {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidFilename),
+			Data:    nil,
+		}
+	}
+	if req.Output != nil && !req.Output.Valid() {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidOutputProfile),
+			Data:    nil,
+		}
+	}
+	parts := make([]pdf.MergePart, len(req.Parts))
+	for i := range req.Parts {
+		d, resp := l.composePart(ctx, i, &req.Parts[i], req.Files)
+		if resp != nil {
+			return resp
+		}
+		parts[i] = pdf.MergePart{Doc: d, Title: req.Parts[i].Title}
+	}
+	merged, err := pdf.Merge(parts)
+	if errors.Is(err, pdf.ErrNoPages) {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidCompose),
+			Data:    err.Error(),
+		}
+	}
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrMergingPdf),
+			Data:    nil,
+		}
+	}
+	if resp := pageLimit(merged, l.limits.WithDefaults()); resp != nil {
+		return resp
+	}
+	if req.Output != nil && req.Output.Profile == model.ProfilePdfA2b {
+		return convertPdfA(w, merged)
+	}
+	buf := new(bytes.Buffer)
+	err = merged.Write(buf)
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrMergingPdf),
+			Data:    nil,
+		}
+	}
+	return writeRendered(w, model.OutputPdf, buf.Bytes())
+}
+
+// composePart returns the document of the part i, the uploaded pdf named by the part or the pdf of its template
+// rendered like HtmlToPdf renders it
+func (l htmlPdfServiceLogic) composePart(ctx context.Context, i int, part *model.ComposePart, files map[string][]byte) (*pdf.Document, *respModel.Response) {
+	if (part.Template == "") == (part.File == "") {
+		return nil, &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidCompose),
+			Data:    fmt.Sprintf("part %d needs either a template or a file", i),
+		}
+	}
+	if part.File != "" {
+		b, ok := files[part.File]
+		if !ok {
+			return nil, &respModel.Response{
+				Status:  http.StatusBadRequest,
+				Message: codes.GetErr(codes.ErrComposeFileNotFound),
+				Data:    part.File,
+			}
+		}
+		d, err := pdf.Parse(b)
+		if err != nil {
+			log.Error(err)
+			return nil, &respModel.Response{
+				Status:  http.StatusBadRequest,
+				Message: codes.GetErr(codes.ErrInvalidComposePdf),
+				Data:    part.File,
+			}
+		}
+		return d, nil
+	}
+	req := part.GenerateReq
+	if req.OutputFormat() != model.OutputPdf || !req.ValidOutput() {
+		return nil, &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidOutput),
+			Data:    fmt.Sprintf("part %d is no pdf", i),
+		}
+	}
+	if req.Stamps != nil && !model.ValidStamps(*req.Stamps) {
+		return nil, &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidStamp),
+			Data:    fmt.Sprintf("part %d", i),
+		}
+	}
+	req.Id = part.Template
+	// the profile of the compose request is applied once the parts are merged
+	req.Output = &model.Output{}
+	tmpl, resp := l.template(ctx, &req)
+	if resp != nil {
+		return nil, resp
+	}
+	buf := new(bytes.Buffer)
+	if l.cache == nil || timestamped(&req, tmpl) {
+		resp = l.render(ctx, buf, &req, tmpl)
+	} else {
+		resp = l.renderCached(ctx, buf, &req, tmpl)
+	}
+	if resp.Status != http.StatusOK {
+		return nil, resp
+	}
+	d, err := pdf.Parse(buf.Bytes())
+	if err != nil {
+		log.Error(err)
+		return nil, &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrMergingPdf),
+			Data:    nil,
+		}
+	}
+	return d, nil
+}
@@ -0,0 +1,195 @@
+package logic
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	respModel "github.com/PereRohit/util/model"
+	"github.com/PereRohit/util/testutil"
+	"github.com/golang/mock/gomock"
+
+	"github.com/vatsal278/html-pdf-service/internal/codes"
+	"github.com/vatsal278/html-pdf-service/internal/model"
+	"github.com/vatsal278/html-pdf-service/internal/pdf"
+	"github.com/vatsal278/html-pdf-service/pkg/mock"
+)
+
+func Test_Compose(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	page := base64.StdEncoding.EncodeToString([]byte("<p>{{.Name}}</p>"))
+	stored := []byte(`{"Pages":[{"Base64PageData":"` + page + `"}],"Settings":{"output":{"profile":"pdfa-2b"}}}`)
+	// renders expects the templates of ids to be rendered, in order, into the pdf doc
+	renders := func(doc []byte, ids ...string) *htmlPdfServiceLogic {
+		mockDatasource := mock.NewMockDataSource(mockCtrl)
+		mockHtmlToPdf := mock.NewMockHtmlToPdf(mockCtrl)
+		for _, id := range ids {
+			gomock.InOrder(
+				mockDatasource.EXPECT().GetFile(gomock.Any(), id).Return(stored, nil),
+				mockHtmlToPdf.EXPECT().GeneratePdf(gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, w io.Writer, _ []byte) error {
+						_, err := w.Write(doc)
+						return err
+					}),
+			)
+		}
+		return &htmlPdfServiceLogic{dsSvc: mockDatasource, htSvc: mockHtmlToPdf}
+	}
+	upload := rendered(t, "Courier")
+	failure := func(status int, code string, data interface{}) func(*respModel.Response, *bytes.Buffer) {
+		return func(x *respModel.Response, w *bytes.Buffer) {
+			diff := testutil.Diff(x, &respModel.Response{Status: status, Message: code, Data: data})
+			if diff != "" || w.Len() != 0 {
+				t.Error(testutil.Callers(), diff, w.String())
+			}
+		}
+	}
+	tests := []struct {
+		name         string
+		req          *model.ComposeReq
+		setupFunc    func() *htmlPdfServiceLogic
+		validateFunc func(*respModel.Response, *bytes.Buffer)
+	}{
+		{
+			name: "Success:: Compose",
+			req: &model.ComposeReq{
+				Parts: []model.ComposePart{
+					{Template: "1", Title: "Cover"},
+					{File: "terms.pdf", Title: "Terms"},
+					{Template: "2"},
+				},
+				Files: map[string][]byte{"terms.pdf": upload},
+			},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return renders(rendered(t, "Helvetica"), "1", "2")
+			},
+			validateFunc: func(x *respModel.Response, w *bytes.Buffer) {
+				if x.Status != http.StatusOK {
+					t.Fatalf("want %v got %v", http.StatusOK, x)
+				}
+				d, err := pdf.Parse(w.Bytes())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if n := len(d.Pages()); n != 3 {
+					t.Errorf("want 3 pages got %d", n)
+				}
+				// the template parts are not converted to the profile of their template
+				if d.Catalog()["Metadata"] != nil {
+					t.Error("a part was converted to pdf/a")
+				}
+				outline := d.Dict(d.Catalog()["Outlines"])
+				if outline["Count"] != int64(2) {
+					t.Errorf("want the outline items of the titled parts got %v", outline)
+				}
+			},
+		},
+		{
+			name: "Success:: Compose:: pdf/a",
+			req: &model.ComposeReq{
+				Parts:  []model.ComposePart{{Template: "1"}, {File: "terms.pdf"}},
+				Output: &model.Output{Profile: model.ProfilePdfA2b},
+				Files:  map[string][]byte{"terms.pdf": upload},
+			},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return renders(rendered(t, "Helvetica"), "1")
+			},
+			validateFunc: func(x *respModel.Response, w *bytes.Buffer) {
+				if x.Status != http.StatusOK {
+					t.Fatalf("want %v got %v", http.StatusOK, x)
+				}
+				if err := pdf.ValidatePdfA2b(w.Bytes()); err != nil {
+					t.Error(err)
+				}
+			},
+		},
+		{
+			name: "Failure:: Compose:: no parts",
+			req:  &model.ComposeReq{},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return &htmlPdfServiceLogic{}
+			},
+			validateFunc: failure(http.StatusBadRequest, codes.GetErr(codes.ErrInvalidCompose), "a document is composed of 1 to 100 parts"),
+		},
+		{
+			name: "Failure:: Compose:: invalid filename",
+			req:  &model.ComposeReq{Parts: []model.ComposePart{{File: "a.pdf"}}, Filename: "../a.pdf"},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return &htmlPdfServiceLogic{}
+			},
+			validateFunc: failure(http.StatusBadRequest, codes.GetErr(codes.ErrInvalidFilename), nil),
+		},
+		{
+			name: "Failure:: Compose:: unsupported profile",
+			req:  &model.ComposeReq{Parts: []model.ComposePart{{File: "a.pdf"}}, Output: &model.Output{Profile: "pdfa-1a"}},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return &htmlPdfServiceLogic{}
+			},
+			validateFunc: failure(http.StatusBadRequest, codes.GetErr(codes.ErrInvalidOutputProfile), nil),
+		},
+		{
+			name: "Failure:: Compose:: part with a template and a file",
+			req:  &model.ComposeReq{Parts: []model.ComposePart{{File: "a.pdf"}, {Template: "1", File: "a.pdf"}}, Files: map[string][]byte{"a.pdf": upload}},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return &htmlPdfServiceLogic{}
+			},
+			validateFunc: failure(http.StatusBadRequest, codes.GetErr(codes.ErrInvalidCompose), "part 1 needs either a template or a file"),
+		},
+		{
+			name: "Failure:: Compose:: file not uploaded",
+			req:  &model.ComposeReq{Parts: []model.ComposePart{{File: "a.pdf"}}},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return &htmlPdfServiceLogic{}
+			},
+			validateFunc: failure(http.StatusBadRequest, codes.GetErr(codes.ErrComposeFileNotFound), "a.pdf"),
+		},
+		{
+			name: "Failure:: Compose:: uploaded file is no pdf",
+			req:  &model.ComposeReq{Parts: []model.ComposePart{{File: "a.pdf"}}, Files: map[string][]byte{"a.pdf": []byte("hello")}},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return &htmlPdfServiceLogic{}
+			},
+			validateFunc: failure(http.StatusBadRequest, codes.GetErr(codes.ErrInvalidComposePdf), "a.pdf"),
+		},
+		{
+			name: "Failure:: Compose:: image part",
+			req:  &model.ComposeReq{Parts: []model.ComposePart{{Template: "1", GenerateReq: model.GenerateReq{Format: model.OutputPng}}}},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return &htmlPdfServiceLogic{}
+			},
+			validateFunc: failure(http.StatusBadRequest, codes.GetErr(codes.ErrInvalidOutput), "part 0 is no pdf"),
+		},
+		{
+			name: "Failure:: Compose:: render fails",
+			req:  &model.ComposeReq{Parts: []model.ComposePart{{Template: "1"}}},
+			setupFunc: func() *htmlPdfServiceLogic {
+				mockDatasource := mock.NewMockDataSource(mockCtrl)
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(stored, nil)
+				mockHtmlToPdf := mock.NewMockHtmlToPdf(mockCtrl)
+				mockHtmlToPdf.EXPECT().GeneratePdf(gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("exit status 1"))
+				return &htmlPdfServiceLogic{dsSvc: mockDatasource, htSvc: mockHtmlToPdf}
+			},
+			validateFunc: failure(http.StatusInternalServerError, codes.GetErr(codes.ErrConvertingToPdf), nil),
+		},
+		{
+			name: "Failure:: Compose:: renderer output is no pdf",
+			req:  &model.ComposeReq{Parts: []model.ComposePart{{Template: "1"}}},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return renders([]byte("not a pdf"), "1")
+			},
+			validateFunc: failure(http.StatusInternalServerError, codes.GetErr(codes.ErrConvertingToPdf), nil),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := tt.setupFunc()
+			w := bytes.NewBuffer(nil)
+			tt.validateFunc(rec.Compose(context.Background(), w, tt.req), w)
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package logic
+
+import (
+	"encoding/json"
+	"net/http"
+
+	respModel "github.com/PereRohit/util/model"
+
+	"github.com/vatsal278/html-pdf-service/internal/codes"
+	"github.com/vatsal278/html-pdf-service/internal/model"
+)
+
+// documentOptions returns the table of contents and outline options of the template with the overrides of the
+// request applied, the style sheet of the table of contents can't be overridden
+func documentOptions(settings model.TemplateSettings, req *model.GenerateReq) (*model.Toc, *model.Outline, *respModel.Response) {
+	toc, outline := settings.Toc, settings.Outline
+	if len(req.Toc) > 0 {
+		var t model.Toc
+		if toc != nil {
+			t = *toc
+		}
+		// json.Unmarshal would decode an xsl of the request into the array of the template style sheet
+		xsl := t.Xsl
+		t.Xsl = nil
+		err := json.Unmarshal(req.Toc, &t)
+		if err != nil || !t.Valid() {
+			return nil, nil, &respModel.Response{
+				Status:  http.StatusBadRequest,
+				Message: codes.GetErr(codes.ErrInvalidToc),
+				Data:    nil,
+			}
+		}
+		t.Xsl = xsl
+		toc = &t
+	}
+	if len(req.Outline) > 0 {
+		var o model.Outline
+		if outline != nil {
+			o = *outline
+		}
+		err := json.Unmarshal(req.Outline, &o)
+		if err != nil || !o.Valid() {
+			return nil, nil, &respModel.Response{
+				Status:  http.StatusBadRequest,
+				Message: codes.GetErr(codes.ErrInvalidOutline),
+				Data:    nil,
+			}
+		}
+		outline = &o
+	}
+	return toc, outline, nil
+}
@@ -0,0 +1,130 @@
+package logic
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	respModel "github.com/PereRohit/util/model"
+	"github.com/PereRohit/util/testutil"
+	"github.com/golang/mock/gomock"
+
+	"github.com/vatsal278/html-pdf-service/internal/codes"
+	"github.com/vatsal278/html-pdf-service/internal/model"
+	"github.com/vatsal278/html-pdf-service/pkg/mock"
+)
+
+func Test_documentOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		settings    model.TemplateSettings
+		req         *model.GenerateReq
+		wantToc     *model.Toc
+		wantOutline *model.Outline
+		wantResp    *respModel.Response
+	}{
+		{
+			name:        "Success:: documentOptions:: template options without overrides",
+			settings:    model.TemplateSettings{Toc: &model.Toc{Enabled: true}, Outline: &model.Outline{Depth: 3}},
+			req:         &model.GenerateReq{},
+			wantToc:     &model.Toc{Enabled: true},
+			wantOutline: &model.Outline{Depth: 3},
+		},
+		{
+			name:        "Success:: documentOptions:: request overrides template",
+			settings:    model.TemplateSettings{Toc: &model.Toc{Enabled: true, HeaderText: "Contents", Xsl: []byte("<xsl/>")}},
+			req:         &model.GenerateReq{Toc: json.RawMessage(`{"header_text":"Inhalt","xsl":"PHg+"}`), Outline: json.RawMessage(`{"disabled":true}`)},
+			wantToc:     &model.Toc{Enabled: true, HeaderText: "Inhalt", Xsl: []byte("<xsl/>")},
+			wantOutline: &model.Outline{Disabled: true},
+		},
+		{
+			name:     "Failure:: documentOptions:: invalid toc",
+			req:      &model.GenerateReq{Toc: json.RawMessage(`{"text_size_shrink":2}`)},
+			wantResp: &respModel.Response{Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrInvalidToc), Data: nil},
+		},
+		{
+			name:     "Failure:: documentOptions:: invalid outline",
+			req:      &model.GenerateReq{Outline: json.RawMessage(`{"depth":12}`)},
+			wantResp: &respModel.Response{Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrInvalidOutline), Data: nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toc, outline, resp := documentOptions(tt.settings, tt.req)
+			diff := testutil.Diff(resp, tt.wantResp)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+			diff = testutil.Diff(toc, tt.wantToc)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+			diff = testutil.Diff(outline, tt.wantOutline)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func Test_HtmlToPdf_Cover(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	encode := func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	}
+	stored := []byte(`{"Pages":[{"Base64PageData":"` + encode("<p>1</p>") + `"}],"Settings":{"cover":"` + encode("<h1>{{.Title}}</h1>") + `","toc":{"enabled":true}}}`)
+	noCover := false
+	tests := []struct {
+		name      string
+		req       *model.GenerateReq
+		wantCover []byte
+		wantToc   *model.Toc
+	}{
+		{
+			name:      "Success:: HtmlToPdf:: cover executed with the values",
+			req:       &model.GenerateReq{Id: "1", Values: map[string]interface{}{"Title": "Report"}},
+			wantCover: []byte("<h1>Report</h1>"),
+			wantToc:   &model.Toc{Enabled: true},
+		},
+		{
+			name:    "Success:: HtmlToPdf:: cover and toc left out by the request",
+			req:     &model.GenerateReq{Id: "1", Cover: &noCover, Toc: json.RawMessage(`{"enabled":false}`)},
+			wantToc: &model.Toc{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDatasource := mock.NewMockDataSource(mockCtrl)
+			mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(stored, nil)
+			mockHtmlToPdf := mock.NewMockHtmlToPdf(mockCtrl)
+			mockHtmlToPdf.EXPECT().GeneratePdf(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, w io.Writer, b []byte) error {
+				var doc struct {
+					Settings model.TemplateSettings
+				}
+				err := json.Unmarshal(b, &doc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				diff := testutil.Diff(doc.Settings.Cover, tt.wantCover)
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+				diff = testutil.Diff(doc.Settings.Toc, tt.wantToc)
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+				return renderPdf(t, w)
+			})
+			rec := &htmlPdfServiceLogic{dsSvc: mockDatasource, htSvc: mockHtmlToPdf}
+			resp := rec.HtmlToPdf(context.Background(), new(bytes.Buffer), tt.req)
+			if resp.Status != http.StatusOK {
+				t.Errorf("want %v got %v", http.StatusOK, resp)
+			}
+		})
+	}
+}
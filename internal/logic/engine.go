@@ -0,0 +1,256 @@
+package logic
+
+import (
+	htmlTemplate "html/template"
+	"io"
+	"strings"
+	textTemplate "text/template"
+	"text/template/parse"
+
+	"github.com/cbroglie/mustache"
+
+	"github.com/vatsal278/html-pdf-service/internal/model"
+)
+
+// templateEngine parses a stored page into something that can be executed with the request values
+type templateEngine interface {
+	Parse(name string, src string, opts engineOptions) (executable, error)
+}
+
+// executable is a parsed page, its execution stays within the iterations of budget
+type executable interface {
+	Execute(w io.Writer, data interface{}, budget *execBudget) error
+}
+
+type engineOptions struct {
+	missingKey string
+	funcs      map[string]interface{}
+	// raw leaves the values unescaped, the markdown converter escapes them
+	raw bool
+}
+
+var engines = map[string]templateEngine{
+	model.EngineHtml:     htmlEngine{},
+	model.EngineText:     textEngine{},
+	model.EngineMustache: mustacheEngine{},
+}
+
+// engineFor returns the engine registered for name, html/template is used when name is empty
+func engineFor(name string) (templateEngine, bool) {
+	if name == "" {
+		name = model.EngineHtml
+	}
+	e, ok := engines[name]
+	return e, ok
+}
+
+type htmlEngine struct{}
+
+func (htmlEngine) Parse(name string, src string, opts engineOptions) (executable, error) {
+	if opts.raw {
+		return textEngine{}.Parse(name, src, opts)
+	}
+	g := &goTemplate{}
+	t, err := htmlTemplate.New(name).Option("missingkey=" + opts.missingKey).Funcs(g.funcs(opts.funcs)).Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range t.Templates() {
+		err = countIterations(d.Tree)
+		if err != nil {
+			return nil, err
+		}
+	}
+	g.t = t
+	return g, nil
+}
+
+type textEngine struct{}
+
+func (textEngine) Parse(name string, src string, opts engineOptions) (executable, error) {
+	g := &goTemplate{}
+	t, err := textTemplate.New(name).Option("missingkey=" + opts.missingKey).Funcs(g.funcs(opts.funcs)).Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range t.Templates() {
+		err = countIterations(d.Tree)
+		if err != nil {
+			return nil, err
+		}
+	}
+	g.t = t
+	return g, nil
+}
+
+// tickFunc is the template function counting the iterations of a go template
+const tickFunc = "_tick"
+
+// goTemplate is a html/template or text/template page counting every range iteration and template call against
+// the budget of the execution, a loop given up on stops at its next iteration even when it writes nothing
+type goTemplate struct {
+	t interface {
+		Execute(w io.Writer, data interface{}) error
+	}
+	budget *execBudget
+}
+
+// funcs returns the template functions with the iteration counter
+func (g *goTemplate) funcs(funcs map[string]interface{}) map[string]interface{} {
+	all := make(map[string]interface{}, len(funcs)+1)
+	for k, v := range funcs {
+		all[k] = v
+	}
+	all[tickFunc] = func() (string, error) {
+		return "", g.budget.tick()
+	}
+	return all
+}
+
+// Execute runs the template with budget, a parsed page is executed by one execution at a time
+func (g *goTemplate) Execute(w io.Writer, data interface{}, budget *execBudget) error {
+	g.budget = budget
+	return g.t.Execute(w, data)
+}
+
+// countIterations adds a call of the iteration counter to the start of the template and of every range body
+func countIterations(tree *parse.Tree) error {
+	if tree == nil || tree.Root == nil {
+		return nil
+	}
+	err := countRanges(tree.Root)
+	if err != nil {
+		return err
+	}
+	return prependTick(tree.Root)
+}
+
+func countRanges(n parse.Node) error {
+	var lists []*parse.ListNode
+	switch n := n.(type) {
+	case *parse.ListNode:
+		for _, c := range n.Nodes {
+			err := countRanges(c)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case *parse.IfNode:
+		lists = []*parse.ListNode{n.List, n.ElseList}
+	case *parse.WithNode:
+		lists = []*parse.ListNode{n.List, n.ElseList}
+	case *parse.RangeNode:
+		if n.List != nil {
+			err := prependTick(n.List)
+			if err != nil {
+				return err
+			}
+		}
+		lists = []*parse.ListNode{n.List, n.ElseList}
+	}
+	for _, l := range lists {
+		if l == nil {
+			continue
+		}
+		err := countRanges(l)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func prependTick(l *parse.ListNode) error {
+	tick, err := tickNode()
+	if err != nil {
+		return err
+	}
+	l.Nodes = append([]parse.Node{tick}, l.Nodes...)
+	return nil
+}
+
+// tickNode returns the action calling the iteration counter, a declaration so it writes nothing in any context
+func tickNode() (parse.Node, error) {
+	trees, err := parse.Parse(tickFunc, "{{$_ := "+tickFunc+"}}", "{{", "}}", map[string]interface{}{tickFunc: func() {}})
+	if err != nil {
+		return nil, err
+	}
+	return trees[tickFunc].Root.Nodes[0], nil
+}
+
+// mustacheEngine renders Mustache/Handlebars style templates, missing variables always render empty
+type mustacheEngine struct{}
+
+func (mustacheEngine) Parse(_ string, src string, opts engineOptions) (executable, error) {
+	t, err := mustache.ParseStringRaw(src, opts.raw)
+	if err != nil {
+		return nil, err
+	}
+	return mustacheTemplate{t}, nil
+}
+
+type mustacheTemplate struct {
+	t *mustache.Template
+}
+
+// Execute renders the template when the sections can't iterate more often than budget allows. Sections only
+// iterate over the lists of data, their iterations are estimated from the longest list of every key.
+func (m mustacheTemplate) Execute(w io.Writer, data interface{}, budget *execBudget) error {
+	lens := map[string]int64{}
+	listLengths(data, "", lens)
+	if sectionIterations(m.t.Tags(), lens, budget.max) > budget.max {
+		return errTooManyIterations
+	}
+	return m.t.FRender(w, data)
+}
+
+// listLengths records the length of the longest list of every key of v, the longest list of all under "."
+func listLengths(v interface{}, key string, lens map[string]int64) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			listLengths(e, k, lens)
+		}
+	case []interface{}:
+		n := int64(len(t))
+		if n > lens[key] {
+			lens[key] = n
+		}
+		if n > lens["."] {
+			lens["."] = n
+		}
+		for _, e := range t {
+			listLengths(e, ".", lens)
+		}
+	}
+}
+
+// sectionIterations estimates the iterations of the sections of tags, counting stops once it exceeds max
+func sectionIterations(tags []mustache.Tag, lens map[string]int64, max int64) int64 {
+	var total int64
+	for _, tag := range tags {
+		if tag.Type() != mustache.Section && tag.Type() != mustache.InvertedSection {
+			continue
+		}
+		n := int64(1)
+		if tag.Type() == mustache.Section {
+			name := tag.Name()
+			if i := strings.LastIndex(name, "."); i >= 0 && name != "." {
+				name = name[i+1:]
+			}
+			if l, ok := lens[name]; ok {
+				n = l
+			}
+		}
+		inner := sectionIterations(tag.Tags(), lens, max)
+		if n > 0 && inner+1 > max/n {
+			return max + 1
+		}
+		total += n * (inner + 1)
+		if total > max {
+			return max + 1
+		}
+	}
+	return total
+}
@@ -0,0 +1,136 @@
+package logic
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// flightDeadlines holds the context of every shared render by its render cache key
+type flightDeadlines struct {
+	mu    sync.Mutex
+	calls map[string]*flightContext
+}
+
+// join adds the request ctx to the waiters of the shared render of key and returns the context of that render.
+// Every join is paired with a leave of the same request once it stops waiting.
+func (f *flightDeadlines) join(key string, ctx context.Context) *flightContext {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.calls == nil {
+		f.calls = map[string]*flightContext{}
+	}
+	c, ok := f.calls[key]
+	if !ok || c.Err() != nil {
+		c = &flightContext{Context: ctx, done: make(chan struct{}), waiters: map[context.Context]int{}}
+		f.calls[key] = c
+	}
+	c.add(ctx)
+	return c
+}
+
+// leave removes the request ctx from the waiters of the shared render c of key, the render is canceled and
+// forgotten once nobody waits for it
+func (f *flightDeadlines) leave(key string, c *flightContext, ctx context.Context) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if c.remove(ctx) == 0 && f.calls[key] == c {
+		delete(f.calls, key)
+	}
+}
+
+// flightContext is the context of a render shared by identical requests. It keeps the values of the request
+// starting the render but is not canceled with it, it is done at the latest deadline of the waiting requests,
+// never while one of them has no deadline, and canceled once no request waits anymore.
+type flightContext struct {
+	context.Context
+
+	mu       sync.Mutex
+	done     chan struct{}
+	err      error
+	waiters  map[context.Context]int
+	deadline time.Time
+	timer    *time.Timer
+}
+
+func (c *flightContext) add(ctx context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.waiters[ctx]++
+	c.update()
+}
+
+// remove returns the number of waiters left
+func (c *flightContext) remove(ctx context.Context) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.waiters[ctx]--; c.waiters[ctx] <= 0 {
+		delete(c.waiters, ctx)
+	}
+	if len(c.waiters) == 0 {
+		c.finish(context.Canceled)
+		return 0
+	}
+	c.update()
+	return len(c.waiters)
+}
+
+// update moves the deadline to the latest deadline of the waiters, c.mu is held
+func (c *flightContext) update() {
+	if c.err != nil {
+		return
+	}
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.deadline = time.Time{}
+	for w := range c.waiters {
+		d, ok := w.Deadline()
+		if !ok {
+			c.deadline = time.Time{}
+			return
+		}
+		if d.After(c.deadline) {
+			c.deadline = d
+		}
+	}
+	c.timer = time.AfterFunc(time.Until(c.deadline), c.expire)
+}
+
+func (c *flightContext) expire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.deadline.IsZero() || time.Now().Before(c.deadline) {
+		return
+	}
+	c.finish(context.DeadlineExceeded)
+}
+
+// finish ends the render with err, c.mu is held
+func (c *flightContext) finish(err error) {
+	if c.err != nil {
+		return
+	}
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	c.err = err
+	close(c.done)
+}
+
+func (c *flightContext) Deadline() (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.deadline, !c.deadline.IsZero()
+}
+
+func (c *flightContext) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *flightContext) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
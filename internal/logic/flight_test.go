@@ -0,0 +1,104 @@
+package logic
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFlightDeadlines(t *testing.T) {
+	running := func(t *testing.T, c *flightContext) {
+		t.Helper()
+		select {
+		case <-c.Done():
+			t.Errorf("want the shared render running got %v", c.Err())
+		case <-time.After(30 * time.Millisecond):
+		}
+	}
+	t.Run("not canceled with the first request", func(t *testing.T) {
+		var f flightDeadlines
+		first, cancel := context.WithCancel(context.Background())
+		second := context.Background()
+		c := f.join("a", first)
+		f.join("a", second)
+		defer f.leave("a", c, second)
+		cancel()
+		f.leave("a", c, first)
+		running(t, c)
+	})
+	t.Run("canceled once no request waits", func(t *testing.T) {
+		var f flightDeadlines
+		first, second := context.Background(), context.Background()
+		c := f.join("a", first)
+		f.join("a", second)
+		f.leave("a", c, first)
+		f.leave("a", c, second)
+		select {
+		case <-c.Done():
+			if c.Err() != context.Canceled {
+				t.Errorf("want %v got %v", context.Canceled, c.Err())
+			}
+		case <-time.After(time.Second):
+			t.Error("want the render canceled")
+		}
+		if f.join("a", first) == c {
+			t.Error("want a new render context")
+		}
+	})
+	t.Run("latest deadline of the waiters", func(t *testing.T) {
+		var f flightDeadlines
+		short, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		long, cancel2 := context.WithTimeout(context.Background(), 60*time.Millisecond)
+		defer cancel2()
+		c := f.join("a", short)
+		defer f.leave("a", c, short)
+		if f.join("a", long) != c {
+			t.Fatal("want the waiters to share the render context")
+		}
+		defer f.leave("a", c, long)
+		want, _ := long.Deadline()
+		if d, ok := c.Deadline(); !ok || !d.Equal(want) {
+			t.Errorf("want deadline %v got %v %v", want, d, ok)
+		}
+		running(t, c)
+		select {
+		case <-c.Done():
+			if c.Err() != context.DeadlineExceeded {
+				t.Errorf("want %v got %v", context.DeadlineExceeded, c.Err())
+			}
+		case <-time.After(time.Second):
+			t.Error("want the render done at the latest deadline")
+		}
+	})
+	t.Run("deadline of the remaining waiters", func(t *testing.T) {
+		var f flightDeadlines
+		short, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		c := f.join("a", short)
+		defer f.leave("a", c, short)
+		f.join("a", context.Background())
+		if _, ok := c.Deadline(); ok {
+			t.Error("want no deadline while a waiter has none")
+		}
+		f.leave("a", c, context.Background())
+		want, _ := short.Deadline()
+		if d, ok := c.Deadline(); !ok || !d.Equal(want) {
+			t.Errorf("want deadline %v got %v %v", want, d, ok)
+		}
+	})
+	t.Run("new render after the deadline", func(t *testing.T) {
+		var f flightDeadlines
+		short, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		c := f.join("a", short)
+		defer f.leave("a", c, short)
+		<-c.Done()
+		next := f.join("a", context.Background())
+		defer f.leave("a", next, context.Background())
+		if next == c {
+			t.Fatal("want a new render context")
+		}
+		running(t, next)
+	})
+}
@@ -0,0 +1,208 @@
+package logic
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vatsal278/html-pdf-service/internal/locale"
+	"github.com/vatsal278/html-pdf-service/internal/model"
+)
+
+// templateFuncs returns the helper functions available to html and text templates while rendering in loc
+func templateFuncs(loc string, settings model.TemplateSettings) map[string]interface{} {
+	tr := translator{locale: loc, settings: settings}
+	funcs := map[string]interface{}{
+		"t":       tr.translate,
+		"qrcode":  qrCode,
+		"barcode": barCode,
+		"locale":  func() string { return loc },
+		"dir": func() string {
+			if isRTL(loc, settings) {
+				return "rtl"
+			}
+			return "ltr"
+		},
+		"number": func(v interface{}, decimals ...int) (string, error) {
+			f, err := toFloat(v)
+			if err != nil {
+				return "", err
+			}
+			d := 0
+			if f != float64(int64(f)) {
+				d = 2
+			}
+			if len(decimals) > 0 {
+				d = decimals[0]
+			}
+			return locale.FormatNumber(loc, f, d), nil
+		},
+		"currency": func(v interface{}, code string) (string, error) {
+			f, err := toFloat(v)
+			if err != nil {
+				return "", err
+			}
+			return locale.FormatCurrency(loc, f, code), nil
+		},
+		"date": func(v interface{}, style ...string) (string, error) {
+			t, err := toTime(v)
+			if err != nil {
+				return "", err
+			}
+			s := ""
+			if len(style) > 0 {
+				s = style[0]
+			}
+			return locale.FormatDate(loc, t, s), nil
+		},
+	}
+	for name, f := range chartFuncs(loc) {
+		funcs[name] = f
+	}
+	return funcs
+}
+
+// resolveLocale returns the locale of the request, falling back to the template default and then to english
+func resolveLocale(req *model.GenerateReq, settings model.TemplateSettings) string {
+	if req.Locale != "" {
+		return locale.Normalize(req.Locale)
+	}
+	if settings.Locale != "" {
+		return locale.Normalize(settings.Locale)
+	}
+	return locale.Default
+}
+
+func isRTL(loc string, settings model.TemplateSettings) bool {
+	for _, l := range settings.RtlLocales {
+		if locale.Normalize(l) == loc || locale.Normalize(l) == locale.Base(loc) {
+			return true
+		}
+	}
+	return locale.IsRTL(loc)
+}
+
+var htmlTagRegex = regexp.MustCompile(`(?i)<html(\s[^>]*)?>`)
+
+// withDirection marks the document as right to left, the dir attribute is added to the html element when present
+func withDirection(page []byte) []byte {
+	loc := htmlTagRegex.FindIndex(page)
+	if loc == nil {
+		return []byte(`<div dir="rtl">` + string(page) + `</div>`)
+	}
+	tag := page[loc[0]:loc[1]]
+	if strings.Contains(strings.ToLower(string(tag)), "dir=") {
+		return page
+	}
+	out := make([]byte, 0, len(page)+len(` dir="rtl"`))
+	out = append(out, page[:loc[0]+len("<html")]...)
+	out = append(out, ` dir="rtl"`...)
+	return append(out, page[loc[0]+len("<html"):]...)
+}
+
+type translator struct {
+	locale   string
+	settings model.TemplateSettings
+}
+
+// translate looks up key in the message catalog of the locale, the base language and the template default locale.
+// Plural messages pick their form with the first argument, all arguments are applied with fmt verbs.
+// The key itself is returned when no translation exists.
+func (tr translator) translate(key string, args ...interface{}) (string, error) {
+	chain := locale.Fallbacks(tr.locale)
+	chain = append(chain, locale.Fallbacks(tr.settings.Locale)...)
+	for _, l := range chain {
+		msg, ok := tr.settings.Catalogs[l][key]
+		if !ok {
+			continue
+		}
+		switch m := msg.(type) {
+		case string:
+			return locale.Sprintf(m, args...), nil
+		case map[string]interface{}:
+			if len(args) == 0 {
+				return "", fmt.Errorf("plural message %q needs a count", key)
+			}
+			n, err := toFloat(args[0])
+			if err != nil {
+				return "", err
+			}
+			form, ok := m[locale.PluralCategory(l, n)].(string)
+			if !ok {
+				form, _ = m[locale.Other].(string)
+			}
+			return locale.Sprintf(form, args...), nil
+		}
+	}
+	return key, nil
+}
+
+// validCatalogs checks that every message is either a string or a map of plural categories to strings
+func validCatalogs(catalogs map[string]model.Catalog) bool {
+	for l, c := range catalogs {
+		if !locale.Valid(l) {
+			return false
+		}
+		for _, msg := range c {
+			switch m := msg.(type) {
+			case string:
+			case map[string]interface{}:
+				for cat, form := range m {
+					if _, ok := form.(string); !ok {
+						return false
+					}
+					switch cat {
+					case locale.Zero, locale.One, locale.Two, locale.Few, locale.Many, locale.Other:
+					default:
+						return false
+					}
+				}
+			default:
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case float32:
+		return float64(n), nil
+	case int:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case int32:
+		return float64(n), nil
+	case uint:
+		return float64(n), nil
+	case uint64:
+		return float64(n), nil
+	case json.Number:
+		return n.Float64()
+	case string:
+		return strconv.ParseFloat(n, 64)
+	}
+	return 0, fmt.Errorf("%v is not a number", v)
+}
+
+func toTime(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case string:
+		for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+			tm, err := time.Parse(layout, t)
+			if err == nil {
+				return tm, nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("%v is not a date", v)
+}
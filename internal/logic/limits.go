@@ -0,0 +1,143 @@
+package logic
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	respModel "github.com/PereRohit/util/model"
+
+	"github.com/vatsal278/html-pdf-service/internal/codes"
+	"github.com/vatsal278/html-pdf-service/internal/model"
+	"github.com/vatsal278/html-pdf-service/internal/pdf"
+)
+
+var errPageTooLarge = errors.New("executed page exceeds the size limit")
+var errExecTimeout = errors.New("template execution timed out")
+var errTooManyIterations = errors.New("template loops exceed the iteration limit")
+
+// statusClientClosedRequest answers requests whose client went away before the response was written
+const statusClientClosedRequest = 499
+
+// WithLimits sets the execution limits, limits left at zero use the defaults
+func WithLimits(limits model.Limits) Option {
+	return func(l *htmlPdfServiceLogic) {
+		l.limits = limits
+	}
+}
+
+// cappedWriter buffers the executed page and fails once the page grows beyond max bytes or the execution was aborted
+type cappedWriter struct {
+	buf     bytes.Buffer
+	max     int64
+	aborted int32
+}
+
+func (c *cappedWriter) Write(p []byte) (int, error) {
+	if atomic.LoadInt32(&c.aborted) != 0 {
+		return 0, errExecTimeout
+	}
+	if int64(c.buf.Len()+len(p)) > c.max {
+		return 0, errPageTooLarge
+	}
+	return c.buf.Write(p)
+}
+
+// abort makes the next write fail, which stops a template still executing after its timeout
+func (c *cappedWriter) abort() {
+	atomic.StoreInt32(&c.aborted, 1)
+}
+
+// execBudget bounds the loop iterations and template calls of an execution and stops it once it was given up on
+type execBudget struct {
+	max     int64
+	n       int64
+	aborted int32
+}
+
+// tick counts an iteration, it fails beyond the iteration limit and after abort
+func (b *execBudget) tick() error {
+	if atomic.LoadInt32(&b.aborted) != 0 {
+		return errExecTimeout
+	}
+	if atomic.AddInt64(&b.n, 1) > b.max {
+		return errTooManyIterations
+	}
+	return nil
+}
+
+func (b *execBudget) abort() {
+	atomic.StoreInt32(&b.aborted, 1)
+}
+
+// executeLimited executes the template into a size capped buffer within the iteration limit and gives up after the
+// execution timeout or when ctx is done. The engines can't be interrupted, an execution given up on stops at its
+// next write or loop iteration.
+func executeLimited(ctx context.Context, t executable, data interface{}, limits model.Limits) ([]byte, error) {
+	w := &cappedWriter{max: limits.MaxPageSize}
+	budget := &execBudget{max: limits.MaxIterations}
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("template execution panicked: %v", r)
+			}
+		}()
+		done <- t.Execute(w, data, budget)
+	}()
+	timer := time.NewTimer(limits.ExecDuration())
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		if err != nil {
+			return nil, err
+		}
+		return w.buf.Bytes(), nil
+	case <-timer.C:
+		w.abort()
+		budget.abort()
+		return nil, errExecTimeout
+	case <-ctx.Done():
+		w.abort()
+		budget.abort()
+		return nil, ctx.Err()
+	}
+}
+
+// pageLimit checks the number of pages of the generated pdf against the page limit
+func pageLimit(d *pdf.Document, limits model.Limits) *respModel.Response {
+	if n := len(d.Pages()); n > limits.MaxPages {
+		return &respModel.Response{
+			Status:  http.StatusUnprocessableEntity,
+			Message: codes.GetErr(codes.ErrTooManyPages),
+			Data:    fmt.Sprintf("the document has %d pages, at most %d are allowed", n, limits.MaxPages),
+		}
+	}
+	return nil
+}
+
+// valuesDepth returns the nesting depth of decoded json values, scalars have a depth of 0
+func valuesDepth(v interface{}) int {
+	depth := 0
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for _, e := range t {
+			if d := valuesDepth(e); d > depth {
+				depth = d
+			}
+		}
+		return depth + 1
+	case []interface{}:
+		for _, e := range t {
+			if d := valuesDepth(e); d > depth {
+				depth = d
+			}
+		}
+		return depth + 1
+	}
+	return depth
+}
@@ -0,0 +1,310 @@
+package logic
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	respModel "github.com/PereRohit/util/model"
+	"github.com/PereRohit/util/testutil"
+	"github.com/golang/mock/gomock"
+
+	"github.com/vatsal278/html-pdf-service/internal/codes"
+	"github.com/vatsal278/html-pdf-service/internal/model"
+	"github.com/vatsal278/html-pdf-service/internal/pdf"
+	"github.com/vatsal278/html-pdf-service/pkg/mock"
+)
+
+type slowTemplate struct {
+	delay time.Duration
+}
+
+func (s slowTemplate) Execute(w io.Writer, _ interface{}, _ *execBudget) error {
+	for {
+		time.Sleep(s.delay)
+		_, err := w.Write([]byte("x"))
+		if err != nil {
+			return err
+		}
+	}
+}
+
+type panicTemplate struct{}
+
+func (panicTemplate) Execute(io.Writer, interface{}, *execBudget) error {
+	panic("boom")
+}
+
+func Test_executeLimited(t *testing.T) {
+	parse := func(text string) executable {
+		tmpl, err := htmlEngine{}.Parse("1", text, engineOptions{missingKey: model.MissingKeyDefault})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tmpl
+	}
+	limits := model.Limits{MaxPageSize: 10, ExecTimeout: 50}.WithDefaults()
+	tests := []struct {
+		name    string
+		tmpl    executable
+		want    string
+		wantErr error
+	}{
+		{
+			name: "Success:: executeLimited",
+			tmpl: parse("<p>{{.}}</p>"),
+			want: "<p>ab</p>",
+		},
+		{
+			name:    "Failure:: executeLimited:: page too large",
+			tmpl:    parse("<p>{{.}}{{.}}{{.}}{{.}}</p>"),
+			wantErr: errPageTooLarge,
+		},
+		{
+			name:    "Failure:: executeLimited:: timeout",
+			tmpl:    slowTemplate{delay: 20 * time.Millisecond},
+			wantErr: errExecTimeout,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := executeLimited(context.Background(), tt.tmpl, "ab", limits)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("want error %v got %v", tt.wantErr, err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("want %q got %q", tt.want, got)
+			}
+		})
+	}
+	items := make([]interface{}, 200)
+	for i := range items {
+		items[i] = i
+	}
+	small := model.Limits{MaxIterations: 1000, ExecTimeout: 5000}.WithDefaults()
+	t.Run("Failure:: executeLimited:: loops beyond the iteration limit", func(t *testing.T) {
+		_, err := executeLimited(context.Background(), parse("{{range $}}{{range $}}{{range $}}{{end}}{{end}}{{end}}"), items, small)
+		if !errors.Is(err, errTooManyIterations) {
+			t.Fatalf("want error %v got %v", errTooManyIterations, err)
+		}
+	})
+	t.Run("Failure:: executeLimited:: recursive template beyond the iteration limit", func(t *testing.T) {
+		_, err := executeLimited(context.Background(), parse(`{{define "r"}}{{template "r" .}}{{template "r" .}}{{end}}{{template "r" .}}`), nil, small)
+		if !errors.Is(err, errTooManyIterations) {
+			t.Fatalf("want error %v got %v", errTooManyIterations, err)
+		}
+	})
+	t.Run("Failure:: executeLimited:: timeout of a loop without output", func(t *testing.T) {
+		var calls int64
+		tmpl, err := textEngine{}.Parse("1", "{{range $}}{{range $}}{{range $}}{{$x := spin}}{{end}}{{end}}{{end}}", engineOptions{
+			missingKey: model.MissingKeyDefault,
+			funcs: map[string]interface{}{"spin": func() int {
+				time.Sleep(time.Millisecond)
+				return int(atomic.AddInt64(&calls, 1))
+			}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		limits := limits
+		limits.MaxIterations = 1 << 40
+		_, err = executeLimited(context.Background(), tmpl, items, limits)
+		if !errors.Is(err, errExecTimeout) {
+			t.Fatalf("want error %v got %v", errExecTimeout, err)
+		}
+		// the execution given up on stops at its next iteration
+		time.Sleep(20 * time.Millisecond)
+		stopped := atomic.LoadInt64(&calls)
+		time.Sleep(50 * time.Millisecond)
+		if n := atomic.LoadInt64(&calls); n != stopped {
+			t.Errorf("want the execution stopped got %d more calls", n-stopped)
+		}
+	})
+	t.Run("Success:: executeLimited:: iteration counter writes nothing", func(t *testing.T) {
+		got, err := executeLimited(context.Background(), parse(`<script>{{range $}}f({{.}});{{end}}</script>`), []interface{}{1, 2}, small)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != "<script>f( 1 );f( 2 );</script>" {
+			t.Errorf("unexpected output %q", got)
+		}
+	})
+	t.Run("Failure:: executeLimited:: mustache sections beyond the iteration limit", func(t *testing.T) {
+		tmpl, err := mustacheEngine{}.Parse("1", "{{#a}}{{#a}}{{#a}}{{/a}}{{/a}}{{/a}}", engineOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = executeLimited(context.Background(), tmpl, map[string]interface{}{"a": items}, small)
+		if !errors.Is(err, errTooManyIterations) {
+			t.Fatalf("want error %v got %v", errTooManyIterations, err)
+		}
+	})
+	t.Run("Failure:: executeLimited:: canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := executeLimited(ctx, slowTemplate{delay: 20 * time.Millisecond}, nil, limits)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("want error %v got %v", context.Canceled, err)
+		}
+	})
+	t.Run("Failure:: executeLimited:: panic", func(t *testing.T) {
+		_, err := executeLimited(context.Background(), panicTemplate{}, nil, limits)
+		if err == nil || !strings.Contains(err.Error(), "boom") {
+			t.Errorf("want panic error got %v", err)
+		}
+	})
+}
+
+func Test_valuesDepth(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  int
+	}{
+		{name: "scalar", value: "a", want: 0},
+		{name: "empty map", value: map[string]interface{}{}, want: 1},
+		{name: "nested", value: map[string]interface{}{"a": []interface{}{1, map[string]interface{}{"b": 2}}, "c": 1}, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := valuesDepth(tt.value); got != tt.want {
+				t.Errorf("want %d got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_Preview_Limits(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	stored := []byte(`{"Pages":[{"Base64PageData":"` + base64.StdEncoding.EncodeToString([]byte("<p>{{.Name}}</p>")) + `"}]}`)
+	limits := model.Limits{MaxValuesDepth: 2, MaxPageSize: 16, MaxPages: 2}
+	tests := []struct {
+		name     string
+		req      *model.GenerateReq
+		fetch    bool
+		expected respModel.Response
+	}{
+		{
+			name:  "Success:: Preview:: within limits",
+			req:   &model.GenerateReq{Values: map[string]interface{}{"Name": "A"}},
+			fetch: true,
+			expected: respModel.Response{
+				Status:  http.StatusOK,
+				Message: "SUCCESS",
+				Data:    []string{"<p>A</p>"},
+			},
+		},
+		{
+			name: "Failure:: Preview:: values too deep",
+			req:  &model.GenerateReq{Values: map[string]interface{}{"Name": map[string]interface{}{"a": []interface{}{1}}}},
+			expected: respModel.Response{
+				Status:  http.StatusBadRequest,
+				Message: codes.GetErr(codes.ErrValuesTooDeep),
+				Data:    nil,
+			},
+		},
+		{
+			name:  "Failure:: Preview:: page too large",
+			req:   &model.GenerateReq{Values: map[string]interface{}{"Name": strings.Repeat("A", 10)}},
+			fetch: true,
+			expected: respModel.Response{
+				Status:  http.StatusUnprocessableEntity,
+				Message: codes.GetErr(codes.ErrPageTooLarge),
+				Data:    nil,
+			},
+		},
+		{
+			name: "Failure:: Preview:: too many pages",
+			req: &model.GenerateReq{Records: []interface{}{
+				map[string]interface{}{"Name": "A"}, map[string]interface{}{"Name": "B"}, map[string]interface{}{"Name": "C"},
+			}},
+			fetch: true,
+			expected: respModel.Response{
+				Status:  http.StatusUnprocessableEntity,
+				Message: codes.GetErr(codes.ErrTooManyPages),
+				Data:    nil,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDatasource := mock.NewMockDataSource(mockCtrl)
+			if tt.fetch {
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(stored, nil)
+			}
+			rec := &htmlPdfServiceLogic{dsSvc: mockDatasource, htSvc: mock.NewMockHtmlToPdf(mockCtrl), limits: limits}
+			tt.req.Id = "1"
+			diff := testutil.Diff(rec.Preview(context.Background(), tt.req), &tt.expected)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func Test_HtmlToPdf_PageLimit(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	stored := []byte(`{"Pages":[{"Base64PageData":"` + base64.StdEncoding.EncodeToString([]byte("<p>long</p>")) + `"}]}`)
+	// the single page object of the template is rendered into a pdf of three pages
+	w := pdf.NewWriter()
+	pages := w.Reserve()
+	var kids []string
+	for i := 0; i < 3; i++ {
+		kids = append(kids, w.Add("<< /Type /Page /Parent "+pages.String()+" /MediaBox [0 0 100 100] >>").String())
+	}
+	w.Set(pages, "<< /Type /Pages /Kids ["+strings.Join(kids, " ")+"] /Count 3 >>")
+	doc := new(bytes.Buffer)
+	if err := w.WriteTo(doc, w.Add("<< /Type /Catalog /Pages "+pages.String()+" >>"), 0); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		maxPages int
+		expected respModel.Response
+	}{
+		{
+			name:     "Success:: HtmlToPdf:: pages within the limit",
+			maxPages: 3,
+			expected: respModel.Response{Status: http.StatusOK},
+		},
+		{
+			name:     "Failure:: HtmlToPdf:: rendered pdf has too many pages",
+			maxPages: 2,
+			expected: respModel.Response{
+				Status:  http.StatusUnprocessableEntity,
+				Message: codes.GetErr(codes.ErrTooManyPages),
+				Data:    "the document has 3 pages, at most 2 are allowed",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDatasource := mock.NewMockDataSource(mockCtrl)
+			mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(stored, nil)
+			mockHtmlToPdf := mock.NewMockHtmlToPdf(mockCtrl)
+			mockHtmlToPdf.EXPECT().GeneratePdf(gomock.Any(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, w io.Writer, _ []byte) error {
+					_, err := w.Write(doc.Bytes())
+					return err
+				})
+			rec := &htmlPdfServiceLogic{dsSvc: mockDatasource, htSvc: mockHtmlToPdf, limits: model.Limits{MaxPages: tt.maxPages}}
+			out := new(bytes.Buffer)
+			diff := testutil.Diff(rec.HtmlToPdf(context.Background(), out, &model.GenerateReq{Id: "1"}), &tt.expected)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+			if (tt.expected.Status == http.StatusOK) != (out.Len() > 0) {
+				t.Errorf("unexpected output of %d bytes", out.Len())
+			}
+		})
+	}
+}
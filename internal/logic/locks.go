@@ -0,0 +1,44 @@
+package logic
+
+import "sync"
+
+// templateLocks serializes the changes of a stored template, every change reads the document, modifies it and
+// stores it again, so two concurrent changes of the same template would lose one of them. The datasource offers no
+// transactions, the lock covers the changes made through this process.
+var templateLocks keyLocks
+
+// keyLocks holds a mutex per key while the key is locked
+type keyLocks struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lock locks key and returns the function unlocking it
+func (k *keyLocks) lock(key string) func() {
+	k.mu.Lock()
+	if k.locks == nil {
+		k.locks = map[string]*keyLock{}
+	}
+	l, ok := k.locks[key]
+	if !ok {
+		l = &keyLock{}
+		k.locks[key] = l
+	}
+	l.refs++
+	k.mu.Unlock()
+	l.Lock()
+	return func() {
+		l.Unlock()
+		k.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
@@ -0,0 +1,32 @@
+package logic
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestKeyLocks(t *testing.T) {
+	var k keyLocks
+	var counts [2]int
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		i := i % 2
+		key := []string{"a", "b"}[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := k.lock(key)
+			defer unlock()
+			// the read and the write of a key are not interleaved with the ones of another goroutine
+			n := counts[i]
+			counts[i] = n + 1
+		}()
+	}
+	wg.Wait()
+	if counts != [2]int{50, 50} {
+		t.Errorf("want 50 changes per key got %v", counts)
+	}
+	if len(k.locks) != 0 {
+		t.Errorf("want the locks released got %v", k.locks)
+	}
+}
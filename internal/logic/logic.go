@@ -2,70 +2,116 @@ package logic
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/vatsal278/html-pdf-service/internal/codes"
+	"github.com/vatsal278/html-pdf-service/internal/locale"
 	"github.com/vatsal278/html-pdf-service/internal/model"
 	"github.com/vatsal278/html-pdf-service/internal/repo/htmlToPdf"
-	"html/template"
 	"io"
 	"io/ioutil"
+	"math"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/PereRohit/util/log"
 	respModel "github.com/PereRohit/util/model"
 	"github.com/vatsal278/html-pdf-service/internal/repo/datasource"
+	"github.com/vatsal278/html-pdf-service/internal/repo/renderCache"
+	"golang.org/x/sync/singleflight"
 )
 
 //go:generate mockgen --build_flags=--mod=mod --destination=./../../pkg/mock/mock_logic.go --package=mock github.com/vatsal278/html-pdf-service/internal/logic HtmlPdfServiceLogicIer
 
 type HtmlPdfServiceLogicIer interface {
-	HealthCheck() bool
-	HtmlToPdf(w io.Writer, req *model.GenerateReq) *respModel.Response
-	Upload(file io.Reader) *respModel.Response
-	Replace(id string, file io.Reader) *respModel.Response
+	HealthCheck() (string, bool)
+	HtmlToPdf(ctx context.Context, w io.Writer, req *model.GenerateReq) *respModel.Response
+	Preview(ctx context.Context, req *model.GenerateReq) *respModel.Response
+	Compose(ctx context.Context, w io.Writer, req *model.ComposeReq) *respModel.Response
+	Upload(ctx context.Context, pages []model.TemplatePage, settings model.TemplateSettings) *respModel.Response
+	Replace(ctx context.Context, id string, pages []model.TemplatePage, settings model.TemplateSettings) *respModel.Response
+	ReorderPages(ctx context.Context, id string, order []int) *respModel.Response
+	InsertPage(ctx context.Context, id string, position int, page model.TemplatePage) *respModel.Response
+	RemovePage(ctx context.Context, id string, page string) *respModel.Response
+	ReplaceAsset(ctx context.Context, id string, name string, file io.Reader) *respModel.Response
+	DeleteAsset(ctx context.Context, id string, name string) *respModel.Response
 }
 
 type htmlPdfServiceLogic struct {
-	dsSvc datasource.DataSource
-	htSvc htmlToPdf.HtmlToPdf
+	dsSvc  datasource.DataSource
+	htSvc  htmlToPdf.HtmlToPdf
+	imgSvc htmlToPdf.Renderer
+	limits model.Limits
+	cache  renderCache.RenderCache
+	flight *singleflight.Group
+	// deadlines holds the contexts of the shared renders
+	deadlines *flightDeadlines
 }
 
-func NewHtmlPdfServiceLogic(ds datasource.DataSource, ht htmlToPdf.HtmlToPdf) HtmlPdfServiceLogicIer {
-	return &htmlPdfServiceLogic{
+// Option configures the optional services of the logic layer
+type Option func(*htmlPdfServiceLogic)
+
+// WithImageRenderer enables the png and jpeg output formats
+func WithImageRenderer(r htmlToPdf.Renderer) Option {
+	return func(l *htmlPdfServiceLogic) {
+		l.imgSvc = r
+	}
+}
+
+// WithRenderCache serves identical render requests from the cache c, a nil cache leaves caching off
+func WithRenderCache(c renderCache.RenderCache) Option {
+	return func(l *htmlPdfServiceLogic) {
+		if c == nil {
+			return
+		}
+		l.cache = c
+		l.flight = new(singleflight.Group)
+		l.deadlines = new(flightDeadlines)
+	}
+}
+
+func NewHtmlPdfServiceLogic(ds datasource.DataSource, ht htmlToPdf.HtmlToPdf, opts ...Option) HtmlPdfServiceLogicIer {
+	l := &htmlPdfServiceLogic{
 		dsSvc: ds,
 		htSvc: ht,
 	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
 }
 
-func (l htmlPdfServiceLogic) HealthCheck() bool {
-	// check all internal services are working fine
-	return l.dsSvc.HealthCheck() && l.htSvc.HealthCheck()
-
+// HealthCheck checks all internal services are working fine, the message describes the renderers
+func (l htmlPdfServiceLogic) HealthCheck() (string, bool) {
+	msg, ok := l.htSvc.HealthCheck()
+	if l.imgSvc != nil {
+		imgMsg, imgOk := l.imgSvc.HealthCheck()
+		msg = strings.TrimPrefix(msg+"; "+imgMsg, "; ")
+		ok = ok && imgOk
+	}
+	if !l.dsSvc.HealthCheck() {
+		return strings.TrimSuffix("datasource: unreachable; "+msg, "; "), false
+	}
+	return msg, ok
 }
 
-func (l htmlPdfServiceLogic) Upload(file io.Reader) *respModel.Response {
-	fileBytes, err := ioutil.ReadAll(file)
-	if err != nil {
-		log.Error(err)
-		return &respModel.Response{
-			Status:  http.StatusInternalServerError,
-			Message: codes.GetErr(codes.ErrReadFileFail),
-			Data:    nil,
-		}
+func (l htmlPdfServiceLogic) Upload(ctx context.Context, pages []model.TemplatePage, settings model.TemplateSettings) *respModel.Response {
+	if resp := validateSettings(settings); resp != nil {
+		return resp
 	}
-	jb, err := l.htSvc.GetJsonFromHtml(fileBytes)
-	if err != nil {
-		return &respModel.Response{
-			Status:  http.StatusInternalServerError,
-			Message: codes.GetErr(codes.ErrFileConversionFail),
-			Data:    nil,
-		}
+	jb, resp := l.templateDoc(pages, settings)
+	if resp != nil {
+		return resp
 	}
 	u := uuid.NewString()
-	err = l.dsSvc.SaveFile(u, jb, 0)
+	err := l.dsSvc.SaveFile(ctx, u, jb, 0)
 	if err != nil {
 		log.Error(err)
 		return &respModel.Response{
@@ -83,85 +129,495 @@ func (l htmlPdfServiceLogic) Upload(file io.Reader) *respModel.Response {
 	}
 }
 
-func (l htmlPdfServiceLogic) Replace(id string, file io.Reader) *respModel.Response {
-	_, err := l.dsSvc.GetFile(id)
+// Replace replaces the pages of the stored template, the settings set in the request overwrite the stored ones and
+// the others, e.g. the assets, are kept
+func (l htmlPdfServiceLogic) Replace(ctx context.Context, id string, pages []model.TemplatePage, settings model.TemplateSettings) *respModel.Response {
+	if resp := validateSettings(settings); resp != nil {
+		return resp
+	}
+	return l.updateDoc(ctx, id, func(z map[string]interface{}) *respModel.Response {
+		stored, err := settingsFromDoc(z)
+		if err != nil {
+			log.Error("error decoding template settings:" + err.Error())
+			return &respModel.Response{
+				Status:  http.StatusInternalServerError,
+				Message: codes.GetErr(codes.ErrFileParseFail),
+				Data:    nil,
+			}
+		}
+		settings := stored.Update(settings)
+		if resp := validateSettings(settings); resp != nil {
+			return resp
+		}
+		jb, resp := l.templateDoc(pages, settings)
+		if resp != nil {
+			return resp
+		}
+		var doc map[string]interface{}
+		err = json.Unmarshal(jb, &doc)
+		if err != nil {
+			log.Error(err)
+			return &respModel.Response{
+				Status:  http.StatusInternalServerError,
+				Message: codes.GetErr(codes.ErrFileConversionFail),
+				Data:    nil,
+			}
+		}
+		for k := range z {
+			delete(z, k)
+		}
+		for k, v := range doc {
+			z[k] = v
+		}
+		return nil
+	})
+}
+
+// templateDoc converts the pages of a register request into the stored document, one page object per file
+func (l htmlPdfServiceLogic) templateDoc(pages []model.TemplatePage, settings model.TemplateSettings) ([]byte, *respModel.Response) {
+	files := make([][]byte, 0, len(pages))
+	pageSettings := make([]model.PageSettings, 0, len(pages))
+	for _, p := range pages {
+		if !p.Settings.Valid() {
+			return nil, &respModel.Response{
+				Status:  http.StatusBadRequest,
+				Message: codes.GetErr(codes.ErrInvalidPageSettings),
+				Data:    nil,
+			}
+		}
+		fileBytes, err := ioutil.ReadAll(p.File)
+		if err != nil {
+			log.Error(err)
+			return nil, &respModel.Response{
+				Status:  http.StatusInternalServerError,
+				Message: codes.GetErr(codes.ErrReadFileFail),
+				Data:    nil,
+			}
+		}
+		files = append(files, fileBytes)
+		pageSettings = append(pageSettings, p.Settings)
+	}
+	jb, err := l.htSvc.GetJsonFromHtml(files...)
 	if err != nil {
-		log.Error(err)
-		return &respModel.Response{
+		return nil, &respModel.Response{
 			Status:  http.StatusInternalServerError,
-			Message: codes.GetErr(codes.ErrKeyNotFound),
+			Message: codes.GetErr(codes.ErrFileConversionFail),
 			Data:    nil,
 		}
 	}
-	fileBytes, err := ioutil.ReadAll(file)
+	jb, err = withSettings(jb, settings)
+	if err == nil {
+		jb, err = withPageSettings(jb, pageSettings)
+	}
 	if err != nil {
 		log.Error(err)
-		return &respModel.Response{
+		return nil, &respModel.Response{
 			Status:  http.StatusInternalServerError,
-			Message: codes.GetErr(codes.ErrReadFileFail),
+			Message: codes.GetErr(codes.ErrFileConversionFail),
 			Data:    nil,
 		}
 	}
-	jb, err := l.htSvc.GetJsonFromHtml(fileBytes)
-	if err != nil {
+	return jb, nil
+}
+
+func (l htmlPdfServiceLogic) HtmlToPdf(ctx context.Context, w io.Writer, req *model.GenerateReq) *respModel.Response {
+	if req.OutputFormat() == model.OutputWebp {
 		return &respModel.Response{
-			Status:  http.StatusInternalServerError,
-			Message: codes.GetErr(codes.ErrFileConversionFail),
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrWebpUnsupported),
+			Data:    nil,
+		}
+	}
+	if !req.ValidOutput() {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidOutput),
+			Data:    nil,
+		}
+	}
+	if !req.ValidDisposition() {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidDisposition),
 			Data:    nil,
 		}
 	}
-	err = l.dsSvc.SaveFile(id, jb, 0)
+	if !req.ValidFilename() {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidFilename),
+			Data:    nil,
+		}
+	}
+	if req.Output != nil && !req.Output.Valid() {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidOutputProfile),
+			Data:    nil,
+		}
+	}
+	if req.Stamps != nil && !model.ValidStamps(*req.Stamps) {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidStamp),
+			Data:    nil,
+		}
+	}
+	format := req.OutputFormat()
+	if format != model.OutputPdf && l.imgSvc == nil {
+		return &respModel.Response{
+			Status:  http.StatusNotImplemented,
+			Message: codes.GetErr(codes.ErrRendererUnavailable),
+			Data:    nil,
+		}
+	}
+	tmpl, resp := l.template(ctx, req)
+	if resp != nil {
+		return resp
+	}
+	if l.cache == nil || timestamped(req, tmpl) {
+		return l.render(ctx, w, req, tmpl)
+	}
+	return l.renderCached(ctx, w, req, tmpl)
+}
+
+// render executes the template tmpl with the values of req and renders the document into w
+func (l htmlPdfServiceLogic) render(ctx context.Context, w io.Writer, req *model.GenerateReq, tmpl []byte) *respModel.Response {
+	z, pages, resp := l.executeTemplate(ctx, req, tmpl)
+	if resp != nil {
+		return resp
+	}
+	format := req.OutputFormat()
+	if format != model.OutputPdf && req.ImagePage() > len(pages) {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrImagePageOutOfRange),
+			Data:    nil,
+		}
+	}
+	buff := bytes.NewBuffer(nil)
+	err := json.NewEncoder(buff).Encode(z)
 	if err != nil {
 		log.Error(err)
 		return &respModel.Response{
 			Status:  http.StatusInternalServerError,
-			Message: codes.GetErr(codes.ErrFileStoreFail),
+			Message: codes.GetErr(codes.ErrEncodingFile),
 			Data:    nil,
 		}
 	}
+	ctx, cancel := context.WithTimeout(ctx, l.renderDeadline(buff.Bytes()))
+	defer cancel()
+	profile := ""
+	var stamps []model.Stamp
+	var assets map[string][]byte
+	if format == model.OutputPdf {
+		profile = outputProfile(req, z)
+		stamps, assets, err = documentStamps(req, z)
+		if err != nil {
+			log.Error(err)
+			return &respModel.Response{
+				Status:  http.StatusInternalServerError,
+				Message: codes.GetErr(codes.ErrFileParseFail),
+				Data:    nil,
+			}
+		}
+	}
+	out := w
+	rendered := new(bytes.Buffer)
+	if format == model.OutputPdf {
+		// the pages of the pdf are counted and it is stamped and converted to the profile before it is written
+		out = rendered
+	}
+	if format != model.OutputPdf {
+		err = l.imgSvc.Render(ctx, out, buff.Bytes(), htmlToPdf.RenderOptions{Format: format, Width: req.Width, Quality: req.Quality, Page: req.ImagePage()})
+	} else {
+		err = l.htSvc.GeneratePdf(ctx, out, buff.Bytes())
+	}
+	if err != nil {
+		log.Error(err)
+		if resp := renderFailure(ctx, err); resp != nil {
+			return resp
+		}
+		return renderError(format)
+	}
+	if out == rendered {
+		return postProcess(w, rendered.Bytes(), l.limits.WithDefaults(), profile, stamps, assets)
+	}
+	return &respModel.Response{Status: http.StatusOK}
+}
+
+// renderedDoc is the result of a render shared by identical requests
+type renderedDoc struct {
+	resp *respModel.Response
+	doc  []byte
+	// err is set when the shared render ended because its waiters went away or their deadlines passed
+	err error
+}
+
+// renderCached serves the document from the render cache. Identical requests arriving while the document is
+// rendered wait for that render instead of rendering it again.
+func (l htmlPdfServiceLogic) renderCached(ctx context.Context, w io.Writer, req *model.GenerateReq, tmpl []byte) *respModel.Response {
+	key, err := renderKey(req, tmpl)
+	if err != nil {
+		log.Error(err)
+		return l.render(ctx, w, req, tmpl)
+	}
+	if doc, ok := l.cache.Get(ctx, key); ok {
+		return writeRendered(w, req.OutputFormat(), doc)
+	}
+	for {
+		resp, retry := l.renderShared(ctx, w, req, tmpl, key)
+		if !retry {
+			return resp
+		}
+	}
+}
+
+// renderShared waits for the shared render of key. The render is not canceled with the request starting it, it ends
+// at the latest deadline of the requests waiting for it and is canceled once none is waiting. A request joining a
+// render that ended this way renders again.
+func (l htmlPdfServiceLogic) renderShared(ctx context.Context, w io.Writer, req *model.GenerateReq, tmpl []byte, key string) (*respModel.Response, bool) {
+	c := l.deadlines.join(key, ctx)
+	defer l.deadlines.leave(key, c, ctx)
+	ch := l.flight.DoChan(key, func() (interface{}, error) {
+		buff := bytes.NewBuffer(nil)
+		resp := l.render(c, buff, req, tmpl)
+		if resp.Status == http.StatusOK {
+			l.cache.Set(c, key, buff.Bytes())
+		}
+		return renderedDoc{resp: resp, doc: buff.Bytes(), err: c.Err()}, nil
+	})
+	select {
+	case <-ctx.Done():
+		return canceledResponse(ctx.Err()), false
+	case res := <-ch:
+		r := res.Val.(renderedDoc)
+		if r.err != nil && ctx.Err() == nil {
+			return nil, true
+		}
+		if r.resp.Status != http.StatusOK {
+			return r.resp, false
+		}
+		return writeRendered(w, req.OutputFormat(), r.doc), false
+	}
+}
+
+// renderKey returns the render cache key of req, the template id and version, a hash of the render options and
+// a canonical hash of the values. The version is a hash of the stored template so any change of the template, its
+// settings or its assets changes the key.
+func renderKey(req *model.GenerateReq, tmpl []byte) (string, error) {
+	opts := *req
+	// the disposition and filename only change the response headers, not the document
+	opts.Values, opts.Records, opts.Disposition, opts.Filename = nil, nil, "", ""
+	o, err := json.Marshal(opts)
+	if err != nil {
+		return "", err
+	}
+	// maps are encoded with sorted keys, the same values in any order give the same hash
+	v, err := json.Marshal([]interface{}{req.Values, req.Records})
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{req.Id, digest(tmpl), digest(o), digest(v)}, ":"), nil
+}
+
+func digest(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:16])
+}
+
+func writeRendered(w io.Writer, format string, doc []byte) *respModel.Response {
+	_, err := w.Write(doc)
+	if err != nil {
+		log.Error(err)
+		return renderError(format)
+	}
+	return &respModel.Response{Status: http.StatusOK}
+}
+
+// renderError is the response of a failed render of the output format
+func renderError(format string) *respModel.Response {
+	code := codes.ErrConvertingToPdf
+	if format != model.OutputPdf {
+		code = codes.ErrRenderingImage
+	}
+	return &respModel.Response{
+		Status:  http.StatusInternalServerError,
+		Message: codes.GetErr(code),
+		Data:    nil,
+	}
+}
+
+// Preview executes the template like HtmlToPdf and returns the html of every page in Data without rendering it
+func (l htmlPdfServiceLogic) Preview(ctx context.Context, req *model.GenerateReq) *respModel.Response {
+	_, pages, resp := l.executePages(ctx, req)
+	if resp != nil {
+		return resp
+	}
+	html := make([]string, len(pages))
+	for i, p := range pages {
+		html[i] = string(p)
+	}
 	return &respModel.Response{
 		Status:  http.StatusOK,
 		Message: "SUCCESS",
-		Data: map[string]interface{}{
-			"id": id,
-		},
+		Data:    html,
 	}
 }
 
-func (l htmlPdfServiceLogic) HtmlToPdf(w io.Writer, req *model.GenerateReq) *respModel.Response {
-	var z map[string]interface{}
-	b, err := l.dsSvc.GetFile(req.Id)
+// executePages loads the template of req and executes every page with the request values. It returns the
+// document with the executed pages, ready to be rendered, and the executed html of the pages.
+func (l htmlPdfServiceLogic) executePages(ctx context.Context, req *model.GenerateReq) (map[string]interface{}, [][]byte, *respModel.Response) {
+	b, resp := l.template(ctx, req)
+	if resp != nil {
+		return nil, nil, resp
+	}
+	return l.executeTemplate(ctx, req, b)
+}
+
+// template checks the nesting of the request values and loads the stored template of req
+func (l htmlPdfServiceLogic) template(ctx context.Context, req *model.GenerateReq) ([]byte, *respModel.Response) {
+	limits := l.limits.WithDefaults()
+	if valuesDepth(req.Values) > limits.MaxValuesDepth || valuesDepth(req.Records) > limits.MaxValuesDepth+1 {
+		return nil, &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrValuesTooDeep),
+			Data:    nil,
+		}
+	}
+	b, err := l.dsSvc.GetFile(ctx, req.Id)
 	if err != nil {
 		log.Error(err)
-		return &respModel.Response{
+		return nil, &respModel.Response{
 			Status:  http.StatusInternalServerError,
 			Message: codes.GetErr(codes.ErrFetchingFile),
 			Data:    nil,
 		}
 	}
-	err = json.NewDecoder(bytes.NewBuffer(b)).Decode(&z)
+	return b, nil
+}
+
+// executeTemplate executes every page of the stored template b with the values of req
+func (l htmlPdfServiceLogic) executeTemplate(ctx context.Context, req *model.GenerateReq, b []byte) (map[string]interface{}, [][]byte, *respModel.Response) {
+	limits := l.limits.WithDefaults()
+	var z map[string]interface{}
+	err := json.NewDecoder(bytes.NewBuffer(b)).Decode(&z)
 	if err != nil {
 		log.Error("error unmarshalling JSON:" + err.Error())
-		return &respModel.Response{
+		return nil, nil, &respModel.Response{
 			Status:  http.StatusInternalServerError,
 			Message: codes.GetErr(codes.ErrFileParseFail),
 			Data:    nil,
 		}
 	}
+	settings, err := settingsFromDoc(z)
+	if err != nil {
+		log.Error("error decoding template settings:" + err.Error())
+		return nil, nil, &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrFileParseFail),
+			Data:    nil,
+		}
+	}
+	setup, resp := pageSetup(settings, req.PageSetup)
+	if resp != nil {
+		return nil, nil, resp
+	}
+	toc, outline, resp := documentOptions(settings, req)
+	if resp != nil {
+		return nil, nil, resp
+	}
+	noCover := req.Cover != nil && !*req.Cover && settings.Cover != nil
+	if setup != settings.PageSetup || toc != settings.Toc || outline != settings.Outline || noCover {
+		// the renderer reads the options with the request overrides applied from the document
+		settings.PageSetup, settings.Toc, settings.Outline = setup, toc, outline
+		if noCover {
+			settings.Cover = nil
+		}
+		z["Settings"] = settings
+	}
+	missingKey := missingKeyOption(req, settings)
+	if !model.ValidMissingKey(missingKey) {
+		return nil, nil, &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidMissingKey),
+			Data:    nil,
+		}
+	}
+	theme := settings.Theme
+	if req.Theme != "" {
+		theme = req.Theme
+	}
+	if _, ok := themeCss(theme); settings.Format == model.FormatMarkdown && !ok {
+		return nil, nil, &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidTheme),
+			Data:    nil,
+		}
+	}
+	if req.Locale != "" && !locale.Valid(req.Locale) {
+		return nil, nil, &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidLocale),
+			Data:    nil,
+		}
+	}
+	loc := resolveLocale(req, settings)
+	engine, ok := engineFor(settings.Engine)
+	if !ok {
+		log.Error("unsupported template engine " + settings.Engine)
+		return nil, nil, &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrInvalidEngine),
+			Data:    nil,
+		}
+	}
+	values := normalizeValues(req.Values, parseTimeOption(req, settings))
+	records, err := mergeRecords(req, values, parseTimeOption(req, settings))
+	if err != nil {
+		return nil, nil, &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidRecords),
+			Data:    err.Error(),
+		}
+	}
+	datasets := []interface{}{values}
+	if records != nil {
+		datasets = make([]interface{}, len(records))
+		for r, rec := range records {
+			datasets[r] = recordValues(values, rec)
+		}
+	}
 	k, ok := z["Pages"].([]interface{})
 	if !ok {
 		log.Error("assertion for Pages failed")
-		return &respModel.Response{
+		return nil, nil, &respModel.Response{
 			Status:  http.StatusBadRequest,
 			Message: codes.GetErr(codes.ErrDecodingData),
 			Data:    nil,
 		}
 	}
+	// every page object starts a new page of the pdf, a document of too many page objects is rejected before it is
+	// executed and the pages of the rendered pdf are counted once it is rendered
+	pageCount := len(k)
+	if records != nil && (req.PageBreak == nil || *req.PageBreak) {
+		pageCount *= len(records)
+	}
+	if pageCount > limits.MaxPages {
+		return nil, nil, &respModel.Response{
+			Status:  http.StatusUnprocessableEntity,
+			Message: codes.GetErr(codes.ErrTooManyPages),
+			Data:    nil,
+		}
+	}
+	// outputs holds the executed html of every page per data set, nil for pages without page data
+	outputs := make([][][]byte, len(k))
+	firstDataPage := -1
 	for i, p := range k {
 		page, ok := p.(map[string]interface{})
 		if !ok {
 			log.Error("assertion for map[string]interface{} failed")
-			return &respModel.Response{
+			return nil, nil, &respModel.Response{
 				Status:  http.StatusBadRequest,
 				Message: codes.GetErr(codes.ErrDecodingData),
 				Data:    nil,
@@ -171,38 +627,218 @@ func (l htmlPdfServiceLogic) HtmlToPdf(w io.Writer, req *model.GenerateReq) *res
 		if !ok {
 			continue
 		}
+		if firstDataPage < 0 {
+			firstDataPage = i
+		}
 		buf, err := base64.StdEncoding.DecodeString(l)
 		if err != nil {
 			log.Error("error decoding base 64 input on page " + fmt.Sprint(i) + " " + err.Error())
-			return &respModel.Response{
+			return nil, nil, &respModel.Response{
 				Status:  http.StatusInternalServerError,
 				Message: codes.GetErr(codes.ErrDecodingData),
 				Data:    nil,
 			}
 		}
-		t, err := template.New(req.Id).Parse(string(buf))
+		t, err := engine.Parse(req.Id, string(buf), engineOptions{
+			missingKey: missingKey,
+			funcs:      templateFuncs(loc, settings),
+			raw:        settings.Format == model.FormatMarkdown,
+		})
 		if err != nil {
 			log.Error(err)
-			return &respModel.Response{
+			return nil, nil, &respModel.Response{
 				Status:  http.StatusInternalServerError,
 				Message: codes.GetErr(codes.ErrFileParseFail),
 				Data:    nil,
 			}
 		}
-		buffer := bytes.NewBuffer(nil)
-		err = t.Execute(buffer, req.Values)
+		for r, data := range datasets {
+			out, err := executeLimited(ctx, t, data, limits)
+			if err != nil {
+				log.Error(err)
+				return nil, nil, executeError(err, missingKey)
+			}
+			if settings.Format == model.FormatMarkdown {
+				out, err = markdownToHtml(out, theme, req.Id)
+				if err != nil {
+					log.Error(err)
+					return nil, nil, &respModel.Response{
+						Status:  http.StatusInternalServerError,
+						Message: codes.GetErr(codes.ErrMarkdownConversion),
+						Data:    nil,
+					}
+				}
+			}
+			out = inlineAssets(out, settings.Assets)
+			if isRTL(loc, settings) {
+				out = withDirection(out)
+			}
+			if records != nil && i == firstDataPage && req.Bookmark != "" {
+				out = withBookmark(out, recordTitle(records[r], req.Bookmark))
+			}
+			outputs[i] = append(outputs[i], out)
+		}
+	}
+	if settings.Header != nil || settings.Footer != nil || settings.Cover != nil {
+		// the cover, running headers and footers are executed once with the values, records do not apply to them
+		run := func(src []byte) ([]byte, *respModel.Response) {
+			t, err := engine.Parse(req.Id, string(src), engineOptions{missingKey: missingKey, funcs: templateFuncs(loc, settings)})
+			if err != nil {
+				log.Error(err)
+				return nil, &respModel.Response{
+					Status:  http.StatusInternalServerError,
+					Message: codes.GetErr(codes.ErrFileParseFail),
+					Data:    nil,
+				}
+			}
+			out, err := executeLimited(ctx, t, values, limits)
+			if err != nil {
+				log.Error(err)
+				return nil, executeError(err, missingKey)
+			}
+			out = inlineAssets(out, settings.Assets)
+			if isRTL(loc, settings) {
+				out = withDirection(out)
+			}
+			return out, nil
+		}
+		if settings.Header != nil {
+			header := *settings.Header
+			header.Html, resp = run(header.Html)
+			if resp != nil {
+				return nil, nil, resp
+			}
+			settings.Header = &header
+		}
+		if settings.Footer != nil {
+			footer := *settings.Footer
+			footer.Html, resp = run(footer.Html)
+			if resp != nil {
+				return nil, nil, resp
+			}
+			settings.Footer = &footer
+		}
+		if settings.Cover != nil {
+			settings.Cover, resp = run(settings.Cover)
+			if resp != nil {
+				return nil, nil, resp
+			}
+		}
+		z["Settings"] = settings
+	}
+	if records == nil {
+		var pages [][]byte
+		for i, p := range k {
+			if outputs[i] != nil {
+				p.(map[string]interface{})["Base64PageData"] = base64.StdEncoding.EncodeToString(outputs[i][0])
+				pages = append(pages, outputs[i][0])
+			}
+		}
+		return z, pages, nil
+	}
+	merged, pages := mergePages(k, outputs, len(records), req.PageBreak == nil || *req.PageBreak)
+	z["Pages"] = merged
+	return z, pages, nil
+}
+
+func (l htmlPdfServiceLogic) ReplaceAsset(ctx context.Context, id string, name string, file io.Reader) *respModel.Response {
+	name, ok := model.CleanAssetName(name)
+	if !ok {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidAssetName),
+			Data:    nil,
+		}
+	}
+	b, err := ioutil.ReadAll(file)
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrReadFileFail),
+			Data:    nil,
+		}
+	}
+	return l.updateSettings(ctx, id, func(settings *model.TemplateSettings) *respModel.Response {
+		if settings.Assets == nil {
+			settings.Assets = map[string][]byte{}
+		}
+		settings.Assets[name] = b
+		return nil
+	})
+}
+
+func (l htmlPdfServiceLogic) DeleteAsset(ctx context.Context, id string, name string) *respModel.Response {
+	name, ok := model.CleanAssetName(name)
+	if !ok {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidAssetName),
+			Data:    nil,
+		}
+	}
+	return l.updateSettings(ctx, id, func(settings *model.TemplateSettings) *respModel.Response {
+		if _, ok := settings.Assets[name]; !ok {
+			return &respModel.Response{
+				Status:  http.StatusNotFound,
+				Message: codes.GetErr(codes.ErrAssetNotFound),
+				Data:    nil,
+			}
+		}
+		delete(settings.Assets, name)
+		return nil
+	})
+}
+
+// updateSettings loads the stored template, applies update to its settings and stores the template again.
+// A non nil response returned by update aborts the change.
+func (l htmlPdfServiceLogic) updateSettings(ctx context.Context, id string, update func(*model.TemplateSettings) *respModel.Response) *respModel.Response {
+	return l.updateDoc(ctx, id, func(z map[string]interface{}) *respModel.Response {
+		settings, err := settingsFromDoc(z)
 		if err != nil {
-			log.Error(err)
+			log.Error("error decoding template settings:" + err.Error())
 			return &respModel.Response{
 				Status:  http.StatusInternalServerError,
-				Message: codes.GetErr(codes.ErrFileStoreFail),
+				Message: codes.GetErr(codes.ErrFileParseFail),
 				Data:    nil,
 			}
 		}
-		page["Base64PageData"] = base64.StdEncoding.EncodeToString(buffer.Bytes())
+		if resp := update(&settings); resp != nil {
+			return resp
+		}
+		z["Settings"] = settings
+		return nil
+	})
+}
+
+// updateDoc loads the stored template document, applies update to it and stores the document again.
+// A non nil response returned by update aborts the change. Changes of the same template are serialized.
+func (l htmlPdfServiceLogic) updateDoc(ctx context.Context, id string, update func(map[string]interface{}) *respModel.Response) *respModel.Response {
+	unlock := templateLocks.lock(id)
+	defer unlock()
+	b, err := l.dsSvc.GetFile(ctx, id)
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrKeyNotFound),
+			Data:    nil,
+		}
 	}
-	buff := bytes.NewBuffer(nil)
-	err = json.NewEncoder(buff).Encode(z)
+	var z map[string]interface{}
+	err = json.Unmarshal(b, &z)
+	if err != nil {
+		log.Error("error unmarshalling JSON:" + err.Error())
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrFileParseFail),
+			Data:    nil,
+		}
+	}
+	if resp := update(z); resp != nil {
+		return resp
+	}
+	jb, err := json.Marshal(z)
 	if err != nil {
 		log.Error(err)
 		return &respModel.Response{
@@ -211,15 +847,229 @@ func (l htmlPdfServiceLogic) HtmlToPdf(w io.Writer, req *model.GenerateReq) *res
 			Data:    nil,
 		}
 	}
-	err = l.htSvc.GeneratePdf(w, buff.Bytes())
+	err = l.dsSvc.SaveFile(ctx, id, jb, 0)
 	if err != nil {
 		log.Error(err)
 		return &respModel.Response{
 			Status:  http.StatusInternalServerError,
-			Message: codes.GetErr(codes.ErrConvertingToPdf),
+			Message: codes.GetErr(codes.ErrFileStoreFail),
 			Data:    nil,
 		}
 	}
+	return &respModel.Response{
+		Status:  http.StatusOK,
+		Message: "SUCCESS",
+		Data: map[string]interface{}{
+			"id": id,
+		},
+	}
+}
 
-	return &respModel.Response{Status: http.StatusOK}
+// executeError maps an error executing a template to the response of the request
+func executeError(err error, missingKey string) *respModel.Response {
+	if resp := canceledResponse(err); resp != nil {
+		return resp
+	}
+	if errors.Is(err, errPageTooLarge) {
+		return &respModel.Response{
+			Status:  http.StatusUnprocessableEntity,
+			Message: codes.GetErr(codes.ErrPageTooLarge),
+			Data:    nil,
+		}
+	}
+	if errors.Is(err, errExecTimeout) {
+		return &respModel.Response{
+			Status:  http.StatusUnprocessableEntity,
+			Message: codes.GetErr(codes.ErrExecutionTimeout),
+			Data:    nil,
+		}
+	}
+	if errors.Is(err, errTooManyIterations) {
+		return &respModel.Response{
+			Status:  http.StatusUnprocessableEntity,
+			Message: codes.GetErr(codes.ErrTooManyIterations),
+			Data:    nil,
+		}
+	}
+	var argErr funcArgError
+	if errors.As(err, &argErr) {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidFuncArgs),
+			Data:    argErr.Error(),
+		}
+	}
+	if missingKey == model.MissingKeyError && strings.Contains(err.Error(), "map has no entry for key") {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrMissingKey),
+			Data:    err.Error(),
+		}
+	}
+	return &respModel.Response{
+		Status:  http.StatusInternalServerError,
+		Message: codes.GetErr(codes.ErrFileStoreFail),
+		Data:    nil,
+	}
+}
+
+// renderDeadline returns the render deadline of the document, the deadline of its template when it is shorter than
+// the configured render timeout
+func (l htmlPdfServiceLogic) renderDeadline(doc []byte) time.Duration {
+	var d struct {
+		Settings struct {
+			RenderTimeout int `json:"render_timeout_ms"`
+		}
+	}
+	// the document was encoded by the caller, a settings object of another shape leaves the template deadline unset
+	_ = json.Unmarshal(doc, &d)
+	return l.limits.WithDefaults().RenderDuration(d.Settings.RenderTimeout)
+}
+
+// renderFailure returns the response of a render that was interrupted or rejected, or nil for any other error.
+// A render past its deadline is answered with 504 and a render of a client that went away with 499. Renders
+// rejected by the scheduler get 429 when the queue is full and 503 when they waited too long.
+func renderFailure(ctx context.Context, err error) *respModel.Response {
+	if resp := canceledResponse(ctx.Err()); resp != nil {
+		return resp
+	}
+	var busy *htmlToPdf.BusyError
+	if !errors.As(err, &busy) {
+		return nil
+	}
+	status, code := http.StatusServiceUnavailable, codes.ErrRendererBusy
+	if errors.Is(err, htmlToPdf.ErrQueueFull) {
+		status, code = http.StatusTooManyRequests, codes.ErrQueueFull
+	}
+	return &respModel.Response{
+		Status:  status,
+		Message: codes.GetErr(code),
+		Data:    model.Backpressure{RetryAfter: int(math.Ceil(busy.RetryAfter.Seconds())), QueueDepth: busy.QueueDepth},
+	}
+}
+
+// canceledResponse returns the response of a request whose context ended with err, or nil when err is nil
+func canceledResponse(err error) *respModel.Response {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return &respModel.Response{
+			Status:  http.StatusGatewayTimeout,
+			Message: codes.GetErr(codes.ErrRenderTimeout),
+			Data:    nil,
+		}
+	case errors.Is(err, context.Canceled):
+		return &respModel.Response{
+			Status:  statusClientClosedRequest,
+			Message: codes.GetErr(codes.ErrRequestCanceled),
+			Data:    nil,
+		}
+	}
+	return nil
+}
+
+// validateSettings checks the template defaults sent at register time and returns the error response if any
+func validateSettings(settings model.TemplateSettings) *respModel.Response {
+	if !model.ValidMissingKey(settings.MissingKey) {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidMissingKey),
+			Data:    nil,
+		}
+	}
+	if !model.ValidEngine(settings.Engine) {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidEngine),
+			Data:    nil,
+		}
+	}
+	if !model.ValidFormat(settings.Format) {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidFormat),
+			Data:    nil,
+		}
+	}
+	if _, ok := themeCss(settings.Theme); !ok {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidTheme),
+			Data:    nil,
+		}
+	}
+	for _, l := range append([]string{settings.Locale}, settings.RtlLocales...) {
+		if l != "" && !locale.Valid(l) {
+			return &respModel.Response{
+				Status:  http.StatusBadRequest,
+				Message: codes.GetErr(codes.ErrInvalidLocale),
+				Data:    nil,
+			}
+		}
+	}
+	if !validCatalogs(settings.Catalogs) {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidCatalog),
+			Data:    nil,
+		}
+	}
+	for _, hf := range []*model.HeaderFooter{settings.Header, settings.Footer} {
+		if hf != nil && !hf.Valid() {
+			return &respModel.Response{
+				Status:  http.StatusBadRequest,
+				Message: codes.GetErr(codes.ErrInvalidHeaderFooter),
+				Data:    nil,
+			}
+		}
+	}
+	if settings.Toc != nil && !settings.Toc.Valid() {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidToc),
+			Data:    nil,
+		}
+	}
+	if settings.Outline != nil && !settings.Outline.Valid() {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidOutline),
+			Data:    nil,
+		}
+	}
+	if !validPageSetup(settings) {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidPageSetup),
+			Data:    nil,
+		}
+	}
+	if !model.ValidRenderer(settings.Renderer) {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidRenderer),
+			Data:    nil,
+		}
+	}
+	if settings.RenderTimeout < 0 {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidRenderTimeout),
+			Data:    nil,
+		}
+	}
+	if settings.Output != nil && !settings.Output.Valid() {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidOutputProfile),
+			Data:    nil,
+		}
+	}
+	if !model.ValidStamps(settings.Stamps) {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidStamp),
+			Data:    nil,
+		}
+	}
+	return nil
 }
@@ -2,10 +2,12 @@ package logic
 
 import (
 	"bytes"
+	"context"
 	"encoding/base32"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/PereRohit/util/log"
 	"github.com/vatsal278/html-pdf-service/internal/codes"
 	"github.com/vatsal278/html-pdf-service/internal/repo/htmlToPdf"
@@ -13,6 +15,7 @@ import (
 	"net/http"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -22,6 +25,7 @@ import (
 
 	"github.com/vatsal278/html-pdf-service/internal/model"
 	"github.com/vatsal278/html-pdf-service/internal/repo/datasource"
+	"github.com/vatsal278/html-pdf-service/internal/repo/renderCache"
 	"github.com/vatsal278/html-pdf-service/pkg/mock"
 )
 
@@ -36,9 +40,11 @@ func Test_htmlPdfServiceLogic_HealthCheck(t *testing.T) {
 	defer mockCtrl.Finish()
 
 	tests := []struct {
-		name  string
-		setup func() (datasource.DataSource, htmlToPdf.HtmlToPdf)
-		want  bool
+		name    string
+		setup   func() (datasource.DataSource, htmlToPdf.HtmlToPdf)
+		img     func() htmlToPdf.Renderer
+		want    bool
+		wantMsg string
 	}{
 		{
 			name: "Success",
@@ -47,22 +53,94 @@ func Test_htmlPdfServiceLogic_HealthCheck(t *testing.T) {
 				mockDs.EXPECT().HealthCheck().Times(1).
 					Return(true)
 				mockHt := mock.NewMockHtmlToPdf(mockCtrl)
-				mockHt.EXPECT().HealthCheck().Times(1).Return(true)
+				mockHt.EXPECT().HealthCheck().Times(1).Return("wkhtmltopdf: ok", true)
 				return mockDs, mockHt
 			},
-			want: true,
+			want:    true,
+			wantMsg: "wkhtmltopdf: ok",
+		},
+		{
+			name: "Failure::renderer",
+			setup: func() (datasource.DataSource, htmlToPdf.HtmlToPdf) {
+				mockDs := mock.NewMockDataSource(mockCtrl)
+				mockDs.EXPECT().HealthCheck().Times(1).
+					Return(true)
+				mockHt := mock.NewMockHtmlToPdf(mockCtrl)
+				mockHt.EXPECT().HealthCheck().Times(1).Return("wkhtmltopdf: not found", false)
+				return mockDs, mockHt
+			},
+			want:    false,
+			wantMsg: "wkhtmltopdf: not found",
+		},
+		{
+			name: "Failure::datasource",
+			setup: func() (datasource.DataSource, htmlToPdf.HtmlToPdf) {
+				mockDs := mock.NewMockDataSource(mockCtrl)
+				mockDs.EXPECT().HealthCheck().Times(1).
+					Return(false)
+				mockHt := mock.NewMockHtmlToPdf(mockCtrl)
+				mockHt.EXPECT().HealthCheck().Times(1).Return("wkhtmltopdf: ok", true)
+				return mockDs, mockHt
+			},
+			want:    false,
+			wantMsg: "datasource: unreachable; wkhtmltopdf: ok",
+		},
+		{
+			name: "Success::image renderer",
+			setup: func() (datasource.DataSource, htmlToPdf.HtmlToPdf) {
+				mockDs := mock.NewMockDataSource(mockCtrl)
+				mockDs.EXPECT().HealthCheck().Times(1).
+					Return(true)
+				mockHt := mock.NewMockHtmlToPdf(mockCtrl)
+				mockHt.EXPECT().HealthCheck().Times(1).Return("wkhtmltopdf: ok", true)
+				return mockDs, mockHt
+			},
+			img: func() htmlToPdf.Renderer {
+				mockImg := mock.NewMockRenderer(mockCtrl)
+				mockImg.EXPECT().HealthCheck().Times(1).Return("wkhtmltoimage: /usr/bin/wkhtmltoimage", true)
+				return mockImg
+			},
+			want:    true,
+			wantMsg: "wkhtmltopdf: ok; wkhtmltoimage: /usr/bin/wkhtmltoimage",
+		},
+		{
+			name: "Failure::image renderer",
+			setup: func() (datasource.DataSource, htmlToPdf.HtmlToPdf) {
+				mockDs := mock.NewMockDataSource(mockCtrl)
+				mockDs.EXPECT().HealthCheck().Times(1).
+					Return(true)
+				mockHt := mock.NewMockHtmlToPdf(mockCtrl)
+				mockHt.EXPECT().HealthCheck().Times(1).Return("wkhtmltopdf: ok", true)
+				return mockDs, mockHt
+			},
+			img: func() htmlToPdf.Renderer {
+				mockImg := mock.NewMockRenderer(mockCtrl)
+				mockImg.EXPECT().HealthCheck().Times(1).Return("wkhtmltoimage: not found", false)
+				return mockImg
+			},
+			want:    false,
+			wantMsg: "wkhtmltopdf: ok; wkhtmltoimage: not found",
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			rec := NewHtmlPdfServiceLogic(tt.setup())
+			ds, ht := tt.setup()
+			var opts []Option
+			if tt.img != nil {
+				opts = append(opts, WithImageRenderer(tt.img()))
+			}
+			rec := NewHtmlPdfServiceLogic(ds, ht, opts...)
 
-			got := rec.HealthCheck()
+			msg, got := rec.HealthCheck()
 
 			diff := testutil.Diff(got, tt.want)
 			if diff != "" {
 				t.Error(testutil.Callers(), diff)
 			}
+			diff = testutil.Diff(msg, tt.wantMsg)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
 		})
 	}
 }
@@ -82,9 +160,9 @@ func Test_Upload(t *testing.T) {
 			requestBody: strings.NewReader("abc"),
 			setupFunc: func() *htmlPdfServiceLogic {
 				mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
-				mockHtmlsvc.EXPECT().GetJsonFromHtml([]byte("abc")).Return([]byte("abc"), nil)
+				mockHtmlsvc.EXPECT().GetJsonFromHtml([]byte("abc")).Return([]byte("{}"), nil)
 				mockDatasource := mock.NewMockDataSource(mockCtrl)
-				mockDatasource.EXPECT().SaveFile(gomock.Any(), []byte("abc"), time.Duration(0)).Return(nil)
+				mockDatasource.EXPECT().SaveFile(gomock.Any(), gomock.Any(), []byte(`{"Settings":{}}`), time.Duration(0)).Return(nil)
 				rec := &htmlPdfServiceLogic{
 					dsSvc: mockDatasource,
 					htSvc: mockHtmlsvc,
@@ -159,9 +237,9 @@ func Test_Upload(t *testing.T) {
 			requestBody: strings.NewReader("abc"),
 			setupFunc: func() *htmlPdfServiceLogic {
 				mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
-				mockHtmlsvc.EXPECT().GetJsonFromHtml([]byte("abc")).Return([]byte("abc"), nil)
+				mockHtmlsvc.EXPECT().GetJsonFromHtml([]byte("abc")).Return([]byte("{}"), nil)
 				mockDatasource := mock.NewMockDataSource(mockCtrl)
-				mockDatasource.EXPECT().SaveFile(gomock.Any(), []byte("abc"), time.Duration(0)).Return(errors.New(""))
+				mockDatasource.EXPECT().SaveFile(gomock.Any(), gomock.Any(), []byte(`{"Settings":{}}`), time.Duration(0)).Return(errors.New(""))
 				rec := &htmlPdfServiceLogic{
 					dsSvc: mockDatasource,
 					htSvc: mockHtmlsvc,
@@ -183,7 +261,7 @@ func Test_Upload(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			rec := tt.setupFunc()
-			resp := rec.Upload(tt.requestBody.(io.Reader))
+			resp := rec.Upload(context.Background(), []model.TemplatePage{{File: tt.requestBody.(io.Reader)}}, model.TemplateSettings{})
 			tt.validateFunc(resp)
 		})
 	}
@@ -204,10 +282,10 @@ func Test_Replace(t *testing.T) {
 			requestBody: strings.NewReader("abc"),
 			setupFunc: func() *htmlPdfServiceLogic {
 				mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
-				mockHtmlsvc.EXPECT().GetJsonFromHtml([]byte("abc")).Return([]byte("abc"), nil)
+				mockHtmlsvc.EXPECT().GetJsonFromHtml([]byte("abc")).Return([]byte("{}"), nil)
 				mockDatasource := mock.NewMockDataSource(mockCtrl)
-				mockDatasource.EXPECT().SaveFile("1", []byte("abc"), time.Duration(0)).Return(nil)
-				mockDatasource.EXPECT().GetFile("1").Return([]byte(""), nil)
+				mockDatasource.EXPECT().SaveFile(gomock.Any(), "1", []byte(`{"Settings":{}}`), time.Duration(0)).Return(nil)
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return([]byte("{}"), nil)
 				rec := &htmlPdfServiceLogic{
 					dsSvc: mockDatasource,
 					htSvc: mockHtmlsvc,
@@ -230,9 +308,9 @@ func Test_Replace(t *testing.T) {
 			requestBody: strings.NewReader("abc"),
 			setupFunc: func() *htmlPdfServiceLogic {
 				mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
-				//mockHtmlsvc.EXPECT().GetJsonFromHtml([]byte("abc")).Return([]byte("abc"), nil)
+				//mockHtmlsvc.EXPECT().GetJsonFromHtml([]byte("abc")).Return([]byte("{}"), nil)
 				mockDatasource := mock.NewMockDataSource(mockCtrl)
-				mockDatasource.EXPECT().GetFile("1").Return(nil, errors.New(""))
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(nil, errors.New(""))
 				rec := &htmlPdfServiceLogic{
 					dsSvc: mockDatasource,
 					htSvc: mockHtmlsvc,
@@ -256,7 +334,7 @@ func Test_Replace(t *testing.T) {
 			setupFunc: func() *htmlPdfServiceLogic {
 				mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
 				mockDatasource := mock.NewMockDataSource(mockCtrl)
-				mockDatasource.EXPECT().GetFile("1").Return([]byte(""), nil)
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return([]byte("{}"), nil)
 				rec := &htmlPdfServiceLogic{
 					dsSvc: mockDatasource,
 					htSvc: mockHtmlsvc,
@@ -279,10 +357,10 @@ func Test_Replace(t *testing.T) {
 			requestBody: strings.NewReader("abc"),
 			setupFunc: func() *htmlPdfServiceLogic {
 				mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
-				mockHtmlsvc.EXPECT().GetJsonFromHtml([]byte("abc")).Return([]byte("abc"), nil)
+				mockHtmlsvc.EXPECT().GetJsonFromHtml([]byte("abc")).Return([]byte("{}"), nil)
 				mockDatasource := mock.NewMockDataSource(mockCtrl)
-				mockDatasource.EXPECT().SaveFile("1", []byte("abc"), time.Duration(0)).Return(errors.New(""))
-				mockDatasource.EXPECT().GetFile("1").Return([]byte(""), nil)
+				mockDatasource.EXPECT().SaveFile(gomock.Any(), "1", []byte(`{"Settings":{}}`), time.Duration(0)).Return(errors.New(""))
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return([]byte("{}"), nil)
 				rec := &htmlPdfServiceLogic{
 					dsSvc: mockDatasource,
 					htSvc: mockHtmlsvc,
@@ -307,7 +385,7 @@ func Test_Replace(t *testing.T) {
 				mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
 				mockHtmlsvc.EXPECT().GetJsonFromHtml([]byte("abc")).Return(nil, errors.New(""))
 				mockDatasource := mock.NewMockDataSource(mockCtrl)
-				mockDatasource.EXPECT().GetFile("1").Return([]byte(""), nil)
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return([]byte("{}"), nil)
 				rec := &htmlPdfServiceLogic{
 					dsSvc: mockDatasource,
 					htSvc: mockHtmlsvc,
@@ -329,13 +407,115 @@ func Test_Replace(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			rec := tt.setupFunc()
-			resp := rec.Replace("1", tt.requestBody.(io.Reader))
+			resp := rec.Replace(context.Background(), "1", []model.TemplatePage{{File: tt.requestBody.(io.Reader)}}, model.TemplateSettings{})
 			tt.validateFunc(resp)
 		})
 	}
 
 }
 
+func Test_Replace_KeepsSettings(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	page := func(html string) string {
+		return `{"Pages":[{"Base64PageData":"` + base64.StdEncoding.EncodeToString([]byte(html)) + `"}]}`
+	}
+	store := map[string][]byte{"1": []byte(`{"Pages":[],"Settings":{"theme":"serif","missing_key":"error"}}`)}
+	mockDatasource := mock.NewMockDataSource(mockCtrl)
+	mockDatasource.EXPECT().GetFile(gomock.Any(), "1").AnyTimes().DoAndReturn(func(_ context.Context, id string) ([]byte, error) {
+		return store[id], nil
+	})
+	mockDatasource.EXPECT().SaveFile(gomock.Any(), "1", gomock.Any(), time.Duration(0)).AnyTimes().DoAndReturn(func(_ context.Context, id string, v interface{}, _ time.Duration) error {
+		store[id] = v.([]byte)
+		return nil
+	})
+	mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
+	mockHtmlsvc.EXPECT().GetJsonFromHtml([]byte(`<img src="logo.png">`)).Return([]byte(page(`<img src="logo.png">`)), nil)
+	mockHtmlsvc.EXPECT().GeneratePdf(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, w io.Writer, b []byte) error {
+		var data struct {
+			Pages []struct {
+				Base64PageData string
+			}
+		}
+		err := json.Unmarshal(b, &data)
+		if err != nil {
+			return err
+		}
+		got, err := base64.StdEncoding.DecodeString(data.Pages[0].Base64PageData)
+		if err != nil {
+			return err
+		}
+		diff := testutil.Diff(string(got), `<img src="data:image/png;base64,AAE=">`)
+		if diff != "" {
+			t.Error(testutil.Callers(), diff)
+		}
+		return renderPdf(t, w)
+	})
+	rec := &htmlPdfServiceLogic{dsSvc: mockDatasource, htSvc: mockHtmlsvc}
+	ctx := context.Background()
+	resp := rec.ReplaceAsset(ctx, "1", "logo.png", bytes.NewReader([]byte{0, 1}))
+	if resp.Status != http.StatusOK {
+		t.Fatalf("want %v got %v", http.StatusOK, resp)
+	}
+	pages := []model.TemplatePage{{File: strings.NewReader(`<img src="logo.png">`)}}
+	resp = rec.Replace(ctx, "1", pages, model.TemplateSettings{Theme: "plain"})
+	if resp.Status != http.StatusOK {
+		t.Fatalf("want %v got %v", http.StatusOK, resp)
+	}
+	var doc struct {
+		Settings model.TemplateSettings
+	}
+	err := json.Unmarshal(store["1"], &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := testutil.Diff(doc.Settings, model.TemplateSettings{
+		Theme:      "plain",
+		MissingKey: "error",
+		Assets:     map[string][]byte{"logo.png": {0, 1}},
+	})
+	if diff != "" {
+		t.Error(testutil.Callers(), diff)
+	}
+	resp = rec.HtmlToPdf(ctx, io.Discard, &model.GenerateReq{Id: "1", Values: map[string]interface{}{}})
+	if resp.Status != http.StatusOK {
+		t.Errorf("want %v got %v", http.StatusOK, resp)
+	}
+}
+
+func Test_Replace_ClearsSettings(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	store := map[string][]byte{"1": []byte(`{"Pages":[],"Settings":{"theme":"serif","locale":"de","toc":{"enabled":true,"header_text":"Index"}}}`)}
+	mockDatasource := mock.NewMockDataSource(mockCtrl)
+	mockDatasource.EXPECT().GetFile(gomock.Any(), "1").AnyTimes().DoAndReturn(func(_ context.Context, id string) ([]byte, error) {
+		return store[id], nil
+	})
+	mockDatasource.EXPECT().SaveFile(gomock.Any(), "1", gomock.Any(), time.Duration(0)).AnyTimes().DoAndReturn(func(_ context.Context, id string, v interface{}, _ time.Duration) error {
+		store[id] = v.([]byte)
+		return nil
+	})
+	mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
+	mockHtmlsvc.EXPECT().GetJsonFromHtml([]byte("abc")).Return([]byte(`{"Pages":[]}`), nil)
+	rec := &htmlPdfServiceLogic{dsSvc: mockDatasource, htSvc: mockHtmlsvc}
+	pages := []model.TemplatePage{{File: strings.NewReader("abc")}}
+	resp := rec.Replace(context.Background(), "1", pages, model.TemplateSettings{Clear: []string{"locale", "toc"}})
+	if resp.Status != http.StatusOK {
+		t.Fatalf("want %v got %v", http.StatusOK, resp)
+	}
+	var doc struct {
+		Settings model.TemplateSettings
+	}
+	err := json.Unmarshal(store["1"], &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := testutil.Diff(doc.Settings, model.TemplateSettings{Theme: "serif"})
+	if diff != "" {
+		t.Error(testutil.Callers(), diff)
+	}
+}
+
 func Test_HtmlToPdf(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -362,7 +542,7 @@ func Test_HtmlToPdf(t *testing.T) {
 					t.Errorf("unable to encode data")
 				}
 				mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
-				mockHtmlsvc.EXPECT().GeneratePdf(gomock.Any(), gomock.Any()).Return(nil).Times(1).DoAndReturn(func(_ io.Writer, b []byte) error {
+				mockHtmlsvc.EXPECT().GeneratePdf(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1).DoAndReturn(func(_ context.Context, w io.Writer, b []byte) error {
 					t.Log(string(b))
 					type Pages struct {
 						P string `json:"Base64PageData"`
@@ -393,10 +573,10 @@ func Test_HtmlToPdf(t *testing.T) {
 <li>{Water 100}</li>
 <li>{Gas 100}</li>`, string(decodedB))
 					}
-					return nil
+					return renderPdf(t, w)
 				})
 				mockDatasource := mock.NewMockDataSource(mockCtrl)
-				mockDatasource.EXPECT().GetFile("1").Return(buff.Bytes(), nil) //PR COMMENT STILL IN PROGRESS
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(buff.Bytes(), nil) //PR COMMENT STILL IN PROGRESS
 				rec := &htmlPdfServiceLogic{
 					dsSvc: mockDatasource,
 					htSvc: mockHtmlsvc,
@@ -418,7 +598,7 @@ func Test_HtmlToPdf(t *testing.T) {
 			setupFunc: func() *htmlPdfServiceLogic {
 				mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
 				mockDatasource := mock.NewMockDataSource(mockCtrl)
-				mockDatasource.EXPECT().GetFile("1").Return(nil, errors.New(""))
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(nil, errors.New(""))
 				rec := &htmlPdfServiceLogic{
 					dsSvc: mockDatasource,
 					htSvc: mockHtmlsvc,
@@ -442,7 +622,7 @@ func Test_HtmlToPdf(t *testing.T) {
 			setupFunc: func() *htmlPdfServiceLogic {
 				mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
 				mockDatasource := mock.NewMockDataSource(mockCtrl)
-				mockDatasource.EXPECT().GetFile("1").Return([]byte(""), nil)
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return([]byte(""), nil)
 				rec := &htmlPdfServiceLogic{
 					dsSvc: mockDatasource,
 					htSvc: mockHtmlsvc,
@@ -473,7 +653,7 @@ func Test_HtmlToPdf(t *testing.T) {
 				})
 				mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
 				mockDatasource := mock.NewMockDataSource(mockCtrl)
-				mockDatasource.EXPECT().GetFile("1").Return(js, nil)
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(js, nil)
 				rec := &htmlPdfServiceLogic{
 					dsSvc: mockDatasource,
 					htSvc: mockHtmlsvc,
@@ -505,9 +685,9 @@ func Test_HtmlToPdf(t *testing.T) {
 					},
 				})
 				mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
-				mockHtmlsvc.EXPECT().GeneratePdf(gomock.Any(), buff.Bytes()).Return(errors.New("Base64PageData is empty")).Times(1)
+				mockHtmlsvc.EXPECT().GeneratePdf(gomock.Any(), gomock.Any(), buff.Bytes()).Return(errors.New("Base64PageData is empty")).Times(1)
 				mockDatasource := mock.NewMockDataSource(mockCtrl)
-				mockDatasource.EXPECT().GetFile("1").Return(buff.Bytes(), nil)
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(buff.Bytes(), nil)
 				rec := &htmlPdfServiceLogic{
 					dsSvc: mockDatasource,
 					htSvc: mockHtmlsvc,
@@ -525,6 +705,70 @@ func Test_HtmlToPdf(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:        "Failure:: HtmlToPdf:: queue full",
+			requestBody: "1",
+			setupFunc: func() *htmlPdfServiceLogic {
+				js, _ := json.Marshal(map[string]interface{}{
+					"Pages": []interface{}{
+						map[string]interface{}{
+							"Base64PageData": base64.StdEncoding.EncodeToString([]byte("<p>{{ .Title }}</p>")),
+						},
+					},
+				})
+				mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
+				mockHtmlsvc.EXPECT().GeneratePdf(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(&htmlToPdf.BusyError{Err: htmlToPdf.ErrQueueFull, RetryAfter: 2500 * time.Millisecond, QueueDepth: 32}).Times(1)
+				mockDatasource := mock.NewMockDataSource(mockCtrl)
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(js, nil)
+				return &htmlPdfServiceLogic{
+					dsSvc: mockDatasource,
+					htSvc: mockHtmlsvc,
+				}
+			},
+			validateFunc: func(x *respModel.Response) {
+				expected := respModel.Response{
+					Status:  http.StatusTooManyRequests,
+					Message: codes.GetErr(codes.ErrQueueFull),
+					Data:    model.Backpressure{RetryAfter: 3, QueueDepth: 32},
+				}
+				if !reflect.DeepEqual(x, &expected) {
+					t.Errorf("want %v got %v", expected, x)
+				}
+			},
+		},
+		{
+			name:        "Failure:: HtmlToPdf:: queue timeout",
+			requestBody: "1",
+			setupFunc: func() *htmlPdfServiceLogic {
+				js, _ := json.Marshal(map[string]interface{}{
+					"Pages": []interface{}{
+						map[string]interface{}{
+							"Base64PageData": base64.StdEncoding.EncodeToString([]byte("<p>{{ .Title }}</p>")),
+						},
+					},
+				})
+				mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
+				mockHtmlsvc.EXPECT().GeneratePdf(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(&htmlToPdf.BusyError{Err: htmlToPdf.ErrQueueTimeout, RetryAfter: 2500 * time.Millisecond, QueueDepth: 5}).Times(1)
+				mockDatasource := mock.NewMockDataSource(mockCtrl)
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(js, nil)
+				return &htmlPdfServiceLogic{
+					dsSvc: mockDatasource,
+					htSvc: mockHtmlsvc,
+				}
+			},
+			validateFunc: func(x *respModel.Response) {
+				expected := respModel.Response{
+					Status:  http.StatusServiceUnavailable,
+					Message: codes.GetErr(codes.ErrRendererBusy),
+					Data:    model.Backpressure{RetryAfter: 3, QueueDepth: 5},
+				}
+				if !reflect.DeepEqual(x, &expected) {
+					t.Errorf("want %v got %v", expected, x)
+				}
+			},
+		},
 		{
 			name:        "Failure:: HtmlToPdf:: assertion for map[string]interface{} failed",
 			requestBody: "1",
@@ -540,7 +784,7 @@ func Test_HtmlToPdf(t *testing.T) {
 				})
 				mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
 				mockDatasource := mock.NewMockDataSource(mockCtrl)
-				mockDatasource.EXPECT().GetFile("1").Return(js, nil)
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(js, nil)
 				rec := &htmlPdfServiceLogic{
 					dsSvc: mockDatasource,
 					htSvc: mockHtmlsvc,
@@ -568,7 +812,7 @@ func Test_HtmlToPdf(t *testing.T) {
 				)
 				mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
 				mockDatasource := mock.NewMockDataSource(mockCtrl)
-				mockDatasource.EXPECT().GetFile("1").Return(js, nil)
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(js, nil)
 				rec := &htmlPdfServiceLogic{
 					dsSvc: mockDatasource,
 					htSvc: mockHtmlsvc,
@@ -602,7 +846,7 @@ func Test_HtmlToPdf(t *testing.T) {
 				}
 				mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
 				mockDatasource := mock.NewMockDataSource(mockCtrl)
-				mockDatasource.EXPECT().GetFile(gomock.Any()).Return(js, nil)
+				mockDatasource.EXPECT().GetFile(gomock.Any(), gomock.Any()).Return(js, nil)
 				rec := &htmlPdfServiceLogic{
 					dsSvc: mockDatasource,
 					htSvc: mockHtmlsvc,
@@ -636,7 +880,7 @@ func Test_HtmlToPdf(t *testing.T) {
 				}
 				mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
 				mockDatasource := mock.NewMockDataSource(mockCtrl)
-				mockDatasource.EXPECT().GetFile(gomock.Any()).Return(js, nil)
+				mockDatasource.EXPECT().GetFile(gomock.Any(), gomock.Any()).Return(js, nil)
 				rec := &htmlPdfServiceLogic{
 					dsSvc: mockDatasource,
 					htSvc: mockHtmlsvc,
@@ -666,9 +910,9 @@ func Test_HtmlToPdf(t *testing.T) {
 					},
 				})
 				mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
-				mockHtmlsvc.EXPECT().GeneratePdf(gomock.Any(), gomock.Any()).Return(errors.New(""))
+				mockHtmlsvc.EXPECT().GeneratePdf(gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New(""))
 				mockDatasource := mock.NewMockDataSource(mockCtrl)
-				mockDatasource.EXPECT().GetFile("1").Return(js, nil)
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(js, nil)
 				rec := &htmlPdfServiceLogic{
 					dsSvc: mockDatasource,
 					htSvc: mockHtmlsvc,
@@ -719,7 +963,7 @@ func Test_HtmlToPdf(t *testing.T) {
 				},
 				"Title": "Inventory list",
 			}
-			resp := rec.HtmlToPdf(nil, &model.GenerateReq{
+			resp := rec.HtmlToPdf(context.Background(), io.Discard, &model.GenerateReq{
 
 				Values: value,
 				Id:     tt.requestBody,
@@ -728,3 +972,1214 @@ func Test_HtmlToPdf(t *testing.T) {
 		})
 	}
 }
+
+func Test_HtmlToPdf_Values(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	parseTime := true
+	tests := []struct {
+		name         string
+		template     string
+		settings     map[string]interface{}
+		req          *model.GenerateReq
+		wantHtml     string
+		wantContains []string
+		validateFunc func(*respModel.Response)
+	}{
+		{
+			name:     "Success:: HtmlToPdf:: large ids kept as json.Number",
+			template: "{{.Id}} {{.Small}} {{.Amount}}",
+			req: &model.GenerateReq{Values: map[string]interface{}{
+				"Id":     json.Number("123456789012345678901234567890"),
+				"Small":  json.Number("42"),
+				"Amount": json.Number("56.7"),
+			}},
+			wantHtml: "123456789012345678901234567890 42 56.7",
+		},
+		{
+			name:     "Success:: HtmlToPdf:: rfc 3339 strings parsed as time",
+			template: `{{.Date.Format "02 Jan 2006"}}`,
+			req: &model.GenerateReq{
+				Values:    map[string]interface{}{"Date": "2022-10-02T15:04:05Z"},
+				ParseTime: &parseTime,
+			},
+			wantHtml: "02 Oct 2022",
+		},
+		{
+			name:     "Success:: HtmlToPdf:: template default parse time",
+			template: `{{.Date.Year}}`,
+			settings: map[string]interface{}{"parse_time": true},
+			req:      &model.GenerateReq{Values: map[string]interface{}{"Date": "2022-10-02T15:04:05Z"}},
+			wantHtml: "2022",
+		},
+		{
+			name:     "Success:: HtmlToPdf:: missing key zero",
+			template: `[{{.Missing}}]`,
+			req:      &model.GenerateReq{Values: map[string]interface{}{}, MissingKey: model.MissingKeyZero},
+			wantHtml: "[]",
+		},
+		{
+			name:         "Success:: HtmlToPdf:: qr code rendered as data uri",
+			template:     `<img src="{{qrcode .Link 100 "H"}}">`,
+			req:          &model.GenerateReq{Values: map[string]interface{}{"Link": "https://example.com/pay?id=1"}},
+			wantContains: []string{`<img src="data:image/png;base64,`},
+		},
+		{
+			name:         "Success:: HtmlToPdf:: bar chart rendered as inline svg",
+			template:     `<div>{{barchart .Sales "title" "Sales"}}</div>`,
+			req:          &model.GenerateReq{Values: map[string]interface{}{"Sales": map[string]interface{}{"Jan": json.Number("10"), "Feb": json.Number("20")}}},
+			wantContains: []string{`<div><svg xmlns="http://www.w3.org/2000/svg"`, `<title>Sales</title>`, `>Feb</text>`},
+		},
+		{
+			name:     "Failure:: HtmlToPdf:: invalid barcode arguments",
+			template: `<img src="{{barcode "ean13" .Code}}">`,
+			req:      &model.GenerateReq{Values: map[string]interface{}{"Code": "12AB"}},
+			validateFunc: func(x *respModel.Response) {
+				if x.Status != http.StatusBadRequest || x.Message != codes.GetErr(codes.ErrInvalidFuncArgs) {
+					t.Errorf("want %v got %v", codes.GetErr(codes.ErrInvalidFuncArgs), x)
+				}
+				if x.Data != "barcode: ean13 needs 12 or 13 digits" {
+					t.Errorf("unexpected data %v", x.Data)
+				}
+			},
+		},
+		{
+			name:     "Failure:: HtmlToPdf:: missing key error from template default",
+			template: `{{.Missing}}`,
+			settings: map[string]interface{}{"missing_key": "error"},
+			req:      &model.GenerateReq{Values: map[string]interface{}{}},
+			validateFunc: func(x *respModel.Response) {
+				if x.Status != http.StatusBadRequest || x.Message != codes.GetErr(codes.ErrMissingKey) {
+					t.Errorf("want %v got %v", codes.GetErr(codes.ErrMissingKey), x)
+				}
+			},
+		},
+		{
+			name:     "Success:: HtmlToPdf:: html engine escapes css",
+			template: `<style>{{.Css}}</style><p>{{.Text}}</p>`,
+			req:      &model.GenerateReq{Values: map[string]interface{}{"Css": "p{color:red}", "Text": "<b>"}},
+			wantHtml: `<style>ZgotmplZ</style><p>&lt;b&gt;</p>`,
+		},
+		{
+			name:     "Success:: HtmlToPdf:: text engine",
+			template: `<style>{{.Css}}</style><p>{{.Text}}</p>`,
+			settings: map[string]interface{}{"engine": "text"},
+			req:      &model.GenerateReq{Values: map[string]interface{}{"Css": "p{color:red}", "Text": "<b>"}},
+			wantHtml: `<style>p{color:red}</style><p><b></p>`,
+		},
+		{
+			name:     "Success:: HtmlToPdf:: mustache engine",
+			template: `{{#Items}}<li>{{Name}}</li>{{/Items}}{{{Raw}}}{{Text}}`,
+			settings: map[string]interface{}{"engine": "mustache"},
+			req: &model.GenerateReq{Values: map[string]interface{}{
+				"Items": []interface{}{map[string]interface{}{"Name": "a"}, map[string]interface{}{"Name": "b"}},
+				"Raw":   "<br>",
+				"Text":  "<b>",
+			}},
+			wantHtml: `<li>a</li><li>b</li><br>&lt;b&gt;`,
+		},
+		{
+			name:     "Success:: HtmlToPdf:: markdown with tables and footnotes",
+			template: "# {{.Title}}\n\n| Item | Qty |\n|------|-----|\n{{range .Items}}| {{.}} | 1 |\n{{end}}\nSee \"terms\"[^1].\n\n[^1]: Applies to all items.\n",
+			settings: map[string]interface{}{"format": "markdown", "theme": "serif"},
+			req: &model.GenerateReq{Values: map[string]interface{}{
+				"Title": "Policy",
+				"Items": []interface{}{"Bread", "Rice"},
+			}},
+			wantContains: []string{
+				`<h1 id="policy">Policy</h1>`,
+				"<td>Bread</td>",
+				"<td>Rice</td>",
+				`See &quot;terms&quot;<sup id="fnref:1">`,
+				`<div class="footnotes" role="doc-endnotes">`,
+				"font-family: Georgia",
+			},
+		},
+		{
+			name: "Success:: HtmlToPdf:: markdown escapes the values",
+			template: "# {{.Title}}\n\n{{.Body}}\n\n[site]({{.Link}}) `{{.Code}}` {{.Text}}\n\n[up](JavaScript:alert(3)) <JavaScript:alert(4)> " +
+				"![svg](data:image/svg+xml;base64,PHN2Zz4=) ![png](data:image/png;base64,AAE=) [mail](mailto:a@b.c) [doc](img/a.png)\n",
+			settings: map[string]interface{}{"format": "markdown"},
+			req: &model.GenerateReq{Values: map[string]interface{}{
+				"Title": "<script>alert(1)</script>",
+				"Body":  `<iframe src="file:///etc/passwd"></iframe>`,
+				"Link":  "javascript:alert(1)",
+				"Code":  "a < b && c",
+				"Text":  `"R&D" [x](javascript:alert(2))`,
+			}},
+			wantContains: []string{
+				"<h1 id=\"scriptalert1script\"><!-- raw HTML omitted -->alert(1)<!-- raw HTML omitted --></h1>",
+				"<!-- raw HTML omitted -->\n<p>",
+				`<a href="">site</a>`,
+				"<code>a &lt; b &amp;&amp; c</code>",
+				`&quot;R&amp;D&quot; <a href="">x</a>`,
+				`<a href="">up</a> JavaScript:alert(4) <img src="" alt="svg"> <img src="data:image/png;base64,AAE=" alt="png">`,
+				`<a href="mailto:a@b.c">mail</a> <a href="img/a.png">doc</a>`,
+			},
+		},
+		{
+			name:     "Success:: HtmlToPdf:: translated messages with plurals and locale formatting",
+			template: `{{t "greeting" .Name}} {{t "items" .Count}} {{currency .Total "EUR"}} {{date .Date "long"}} {{t "unknown"}}`,
+			settings: map[string]interface{}{
+				"locale": "en",
+				"catalogs": map[string]interface{}{
+					"en": map[string]interface{}{"greeting": "Hello %s", "unknown": "fallback"},
+					"de": map[string]interface{}{
+						"greeting": "Hallo %s",
+						"items":    map[string]interface{}{"one": "%d Artikel", "other": "%d Artikel gesamt"},
+					},
+				},
+			},
+			req: &model.GenerateReq{
+				Values: map[string]interface{}{
+					"Name":  "Ada",
+					"Count": json.Number("3"),
+					"Total": json.Number("1234.5"),
+					"Date":  "2022-03-05",
+				},
+				Locale: "de-AT",
+			},
+			wantHtml: "Hallo Ada 3 Artikel gesamt 1.234,50\u00a0€ 5. März 2022 fallback",
+		},
+		{
+			name:     "Success:: HtmlToPdf:: hindi plural rule",
+			template: `{{t "items" .Count}} {{number .Amount}}`,
+			settings: map[string]interface{}{
+				"catalogs": map[string]interface{}{
+					"hi": map[string]interface{}{"items": map[string]interface{}{"one": "%d वस्तु", "other": "%d वस्तुएँ"}},
+				},
+			},
+			req: &model.GenerateReq{
+				Values: map[string]interface{}{"Count": json.Number("0"), "Amount": json.Number("1234567")},
+				Locale: "hi",
+			},
+			wantHtml: "0 वस्तु 12,34,567",
+		},
+		{
+			name:     "Success:: HtmlToPdf:: right to left locale",
+			template: `<html><body dir="{{dir}}">{{locale}}</body></html>`,
+			req:      &model.GenerateReq{Values: map[string]interface{}{}, Locale: "ar"},
+			wantHtml: `<html dir="rtl"><body dir="rtl">ar</body></html>`,
+		},
+		{
+			name:     "Success:: HtmlToPdf:: template marked right to left locale",
+			template: `<p>{{locale}}</p>`,
+			settings: map[string]interface{}{"rtl_locales": []string{"xx"}},
+			req:      &model.GenerateReq{Values: map[string]interface{}{}, Locale: "xx-YY"},
+			wantHtml: `<div dir="rtl"><p>xx-yy</p></div>`,
+		},
+		{
+			name:     "Success:: HtmlToPdf:: relative assets resolved locally",
+			template: `<img src="{{.Logo}}">`,
+			settings: map[string]interface{}{"assets": map[string]interface{}{"logo.png": "AAE="}},
+			req:      &model.GenerateReq{Values: map[string]interface{}{"Logo": "logo.png"}},
+			wantHtml: `<img src="data:image/png;base64,AAE=">`,
+		},
+		{
+			name:     "Failure:: HtmlToPdf:: invalid locale",
+			template: "abc",
+			req:      &model.GenerateReq{Values: map[string]interface{}{}, Locale: "<script>"},
+			validateFunc: func(x *respModel.Response) {
+				expected := respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrInvalidLocale),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(x, &expected) {
+					t.Errorf("want %v got %v", expected, x)
+				}
+			},
+		},
+		{
+			name:     "Failure:: HtmlToPdf:: unknown theme",
+			template: "# Title",
+			settings: map[string]interface{}{"format": "markdown"},
+			req:      &model.GenerateReq{Values: map[string]interface{}{}, Theme: "neon"},
+			validateFunc: func(x *respModel.Response) {
+				expected := respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrInvalidTheme),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(x, &expected) {
+					t.Errorf("want %v got %v", expected, x)
+				}
+			},
+		},
+		{
+			name:     "Failure:: HtmlToPdf:: unsupported engine",
+			template: `{{.Missing}}`,
+			settings: map[string]interface{}{"engine": "jinja"},
+			req:      &model.GenerateReq{Values: map[string]interface{}{}},
+			validateFunc: func(x *respModel.Response) {
+				expected := respModel.Response{
+					Status:  http.StatusInternalServerError,
+					Message: codes.GetErr(codes.ErrInvalidEngine),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(x, &expected) {
+					t.Errorf("want %v got %v", expected, x)
+				}
+			},
+		},
+		{
+			name:     "Failure:: HtmlToPdf:: invalid missing key option",
+			template: `{{.Missing}}`,
+			req:      &model.GenerateReq{Values: map[string]interface{}{}, MissingKey: "panic"},
+			validateFunc: func(x *respModel.Response) {
+				expected := respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrInvalidMissingKey),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(x, &expected) {
+					t.Errorf("want %v got %v", expected, x)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			js, err := json.Marshal(map[string]interface{}{
+				"Settings": tt.settings,
+				"Pages": []interface{}{
+					map[string]interface{}{
+						"Base64PageData": base64.StdEncoding.EncodeToString([]byte(tt.template)),
+					},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			mockDatasource := mock.NewMockDataSource(mockCtrl)
+			mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(js, nil)
+			mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
+			if tt.validateFunc == nil {
+				mockHtmlsvc.EXPECT().GeneratePdf(gomock.Any(), gomock.Any(), gomock.Any()).Times(1).DoAndReturn(func(_ context.Context, w io.Writer, b []byte) error {
+					var data struct {
+						Pages []struct {
+							Base64PageData string
+						}
+					}
+					err := json.Unmarshal(b, &data)
+					if err != nil {
+						return err
+					}
+					got, err := base64.StdEncoding.DecodeString(data.Pages[0].Base64PageData)
+					if err != nil {
+						return err
+					}
+					if tt.wantContains != nil {
+						for _, c := range tt.wantContains {
+							if !strings.Contains(string(got), c) {
+								t.Errorf("want %v in %v", c, string(got))
+							}
+						}
+						return renderPdf(t, w)
+					}
+					diff := testutil.Diff(string(got), tt.wantHtml)
+					if diff != "" {
+						t.Error(testutil.Callers(), diff)
+					}
+					return renderPdf(t, w)
+				})
+			}
+			rec := &htmlPdfServiceLogic{
+				dsSvc: mockDatasource,
+				htSvc: mockHtmlsvc,
+			}
+			tt.req.Id = "1"
+			resp := rec.HtmlToPdf(context.Background(), io.Discard, tt.req)
+			if tt.validateFunc != nil {
+				tt.validateFunc(resp)
+				return
+			}
+			if resp.Status != http.StatusOK {
+				t.Errorf("want %v got %v", http.StatusOK, resp)
+			}
+		})
+	}
+}
+
+func Test_Upload_InvalidSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings model.TemplateSettings
+		wantMsg  string
+	}{
+		{
+			name:     "Failure:: Upload:: invalid missing key",
+			settings: model.TemplateSettings{MissingKey: "panic"},
+			wantMsg:  codes.GetErr(codes.ErrInvalidMissingKey),
+		},
+		{
+			name:     "Failure:: Upload:: invalid format",
+			settings: model.TemplateSettings{Format: "docx"},
+			wantMsg:  codes.GetErr(codes.ErrInvalidFormat),
+		},
+		{
+			name:     "Failure:: Upload:: invalid theme",
+			settings: model.TemplateSettings{Format: model.FormatMarkdown, Theme: "neon"},
+			wantMsg:  codes.GetErr(codes.ErrInvalidTheme),
+		},
+		{
+			name:     "Failure:: Upload:: invalid locale",
+			settings: model.TemplateSettings{Locale: "not a locale"},
+			wantMsg:  codes.GetErr(codes.ErrInvalidLocale),
+		},
+		{
+			name: "Failure:: Upload:: invalid catalog",
+			settings: model.TemplateSettings{Catalogs: map[string]model.Catalog{
+				"en": {"items": map[string]interface{}{"several": "%d items"}},
+			}},
+			wantMsg: codes.GetErr(codes.ErrInvalidCatalog),
+		},
+		{
+			name:     "Failure:: Upload:: invalid engine",
+			settings: model.TemplateSettings{Engine: "jinja"},
+			wantMsg:  codes.GetErr(codes.ErrInvalidEngine),
+		},
+		{
+			name:     "Failure:: Upload:: invalid renderer",
+			settings: model.TemplateSettings{Renderer: "prince"},
+			wantMsg:  codes.GetErr(codes.ErrInvalidRenderer),
+		},
+		{
+			name:     "Failure:: Upload:: invalid render timeout",
+			settings: model.TemplateSettings{RenderTimeout: -1},
+			wantMsg:  codes.GetErr(codes.ErrInvalidRenderTimeout),
+		},
+		{
+			name:     "Failure:: Upload:: invalid output profile",
+			settings: model.TemplateSettings{Output: &model.Output{Profile: "pdfx"}},
+			wantMsg:  codes.GetErr(codes.ErrInvalidOutputProfile),
+		},
+		{
+			name:     "Failure:: Upload:: stamp with a text and an image",
+			settings: model.TemplateSettings{Stamps: []model.Stamp{{Text: "DRAFT", Image: "logo.png"}}},
+			wantMsg:  codes.GetErr(codes.ErrInvalidStamp),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &htmlPdfServiceLogic{}
+			resp := rec.Upload(context.Background(), []model.TemplatePage{{File: strings.NewReader("abc")}}, tt.settings)
+			expected := respModel.Response{
+				Status:  http.StatusBadRequest,
+				Message: tt.wantMsg,
+				Data:    nil,
+			}
+			if !reflect.DeepEqual(resp, &expected) {
+				t.Errorf("want %v got %v", expected, resp)
+			}
+		})
+	}
+}
+
+func Test_ReplaceAsset(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	stored := []byte(`{"Pages":[],"Settings":{"assets":{"old.png":"AAE="}}}`)
+	tests := []struct {
+		name         string
+		assetName    string
+		body         io.Reader
+		setupFunc    func() *htmlPdfServiceLogic
+		validateFunc func(*respModel.Response)
+	}{
+		{
+			name:      "Success:: ReplaceAsset",
+			assetName: "img/../logo.png",
+			body:      strings.NewReader("png"),
+			setupFunc: func() *htmlPdfServiceLogic {
+				mockDatasource := mock.NewMockDataSource(mockCtrl)
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(stored, nil)
+				mockDatasource.EXPECT().SaveFile(gomock.Any(), "1", gomock.Any(), time.Duration(0)).DoAndReturn(func(_ context.Context, _ string, v interface{}, _ time.Duration) error {
+					var doc struct {
+						Settings model.TemplateSettings
+					}
+					err := json.Unmarshal(v.([]byte), &doc)
+					if err != nil {
+						return err
+					}
+					diff := testutil.Diff(doc.Settings.Assets, map[string][]byte{"old.png": {0, 1}, "logo.png": []byte("png")})
+					if diff != "" {
+						t.Error(testutil.Callers(), diff)
+					}
+					return nil
+				})
+				return &htmlPdfServiceLogic{dsSvc: mockDatasource}
+			},
+			validateFunc: func(x *respModel.Response) {
+				expected := respModel.Response{
+					Status:  http.StatusOK,
+					Message: "SUCCESS",
+					Data:    map[string]interface{}{"id": "1"},
+				}
+				if !reflect.DeepEqual(x, &expected) {
+					t.Errorf("want %v got %v", expected, x)
+				}
+			},
+		},
+		{
+			name:      "Failure:: ReplaceAsset:: invalid name",
+			assetName: "../secret",
+			body:      strings.NewReader("png"),
+			setupFunc: func() *htmlPdfServiceLogic {
+				return &htmlPdfServiceLogic{}
+			},
+			validateFunc: func(x *respModel.Response) {
+				expected := respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrInvalidAssetName),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(x, &expected) {
+					t.Errorf("want %v got %v", expected, x)
+				}
+			},
+		},
+		{
+			name:      "Failure:: ReplaceAsset:: template not found",
+			assetName: "logo.png",
+			body:      strings.NewReader("png"),
+			setupFunc: func() *htmlPdfServiceLogic {
+				mockDatasource := mock.NewMockDataSource(mockCtrl)
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(nil, errors.New(""))
+				return &htmlPdfServiceLogic{dsSvc: mockDatasource}
+			},
+			validateFunc: func(x *respModel.Response) {
+				expected := respModel.Response{
+					Status:  http.StatusInternalServerError,
+					Message: codes.GetErr(codes.ErrKeyNotFound),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(x, &expected) {
+					t.Errorf("want %v got %v", expected, x)
+				}
+			},
+		},
+		{
+			name:      "Failure:: ReplaceAsset:: SaveFile fail",
+			assetName: "logo.png",
+			body:      strings.NewReader("png"),
+			setupFunc: func() *htmlPdfServiceLogic {
+				mockDatasource := mock.NewMockDataSource(mockCtrl)
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(stored, nil)
+				mockDatasource.EXPECT().SaveFile(gomock.Any(), "1", gomock.Any(), time.Duration(0)).Return(errors.New(""))
+				return &htmlPdfServiceLogic{dsSvc: mockDatasource}
+			},
+			validateFunc: func(x *respModel.Response) {
+				expected := respModel.Response{
+					Status:  http.StatusInternalServerError,
+					Message: codes.GetErr(codes.ErrFileStoreFail),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(x, &expected) {
+					t.Errorf("want %v got %v", expected, x)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := tt.setupFunc()
+			tt.validateFunc(rec.ReplaceAsset(context.Background(), "1", tt.assetName, tt.body))
+		})
+	}
+}
+
+func Test_DeleteAsset(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	stored := []byte(`{"Pages":[],"Settings":{"assets":{"old.png":"AAE="}}}`)
+	tests := []struct {
+		name         string
+		assetName    string
+		setupFunc    func() *htmlPdfServiceLogic
+		validateFunc func(*respModel.Response)
+	}{
+		{
+			name:      "Success:: DeleteAsset",
+			assetName: "old.png",
+			setupFunc: func() *htmlPdfServiceLogic {
+				mockDatasource := mock.NewMockDataSource(mockCtrl)
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(stored, nil)
+				mockDatasource.EXPECT().SaveFile(gomock.Any(), "1", []byte(`{"Pages":[],"Settings":{}}`), time.Duration(0)).Return(nil)
+				return &htmlPdfServiceLogic{dsSvc: mockDatasource}
+			},
+			validateFunc: func(x *respModel.Response) {
+				if x.Status != http.StatusOK {
+					t.Errorf("want %v got %v", http.StatusOK, x)
+				}
+			},
+		},
+		{
+			name:      "Failure:: DeleteAsset:: asset not found",
+			assetName: "new.png",
+			setupFunc: func() *htmlPdfServiceLogic {
+				mockDatasource := mock.NewMockDataSource(mockCtrl)
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(stored, nil)
+				return &htmlPdfServiceLogic{dsSvc: mockDatasource}
+			},
+			validateFunc: func(x *respModel.Response) {
+				expected := respModel.Response{
+					Status:  http.StatusNotFound,
+					Message: codes.GetErr(codes.ErrAssetNotFound),
+					Data:    nil,
+				}
+				if !reflect.DeepEqual(x, &expected) {
+					t.Errorf("want %v got %v", expected, x)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := tt.setupFunc()
+			tt.validateFunc(rec.DeleteAsset(context.Background(), "1", tt.assetName))
+		})
+	}
+}
+
+func Test_Preview(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	page := func(html string) string {
+		return `{"Base64PageData":"` + base64.StdEncoding.EncodeToString([]byte(html)) + `"}`
+	}
+	tests := []struct {
+		name         string
+		setupFunc    func() *htmlPdfServiceLogic
+		validateFunc func(*respModel.Response)
+	}{
+		{
+			name: "Success:: Preview:: pages executed without rendering",
+			setupFunc: func() *htmlPdfServiceLogic {
+				mockDatasource := mock.NewMockDataSource(mockCtrl)
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return([]byte(`{"Pages":[`+page("<p>{{.Name}}</p>")+`,`+page("<p>2</p>")+`]}`), nil)
+				// the renderer must not be called
+				mockHtmlToPdf := mock.NewMockHtmlToPdf(mockCtrl)
+				return &htmlPdfServiceLogic{dsSvc: mockDatasource, htSvc: mockHtmlToPdf}
+			},
+			validateFunc: func(x *respModel.Response) {
+				expected := respModel.Response{
+					Status:  http.StatusOK,
+					Message: "SUCCESS",
+					Data:    []string{"<p>vatsal</p>", "<p>2</p>"},
+				}
+				diff := testutil.Diff(x, &expected)
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+		},
+		{
+			name: "Failure:: Preview:: template not found",
+			setupFunc: func() *htmlPdfServiceLogic {
+				mockDatasource := mock.NewMockDataSource(mockCtrl)
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(nil, errors.New("not found"))
+				return &htmlPdfServiceLogic{dsSvc: mockDatasource}
+			},
+			validateFunc: func(x *respModel.Response) {
+				expected := respModel.Response{
+					Status:  http.StatusInternalServerError,
+					Message: codes.GetErr(codes.ErrFetchingFile),
+					Data:    nil,
+				}
+				diff := testutil.Diff(x, &expected)
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := tt.setupFunc()
+			tt.validateFunc(rec.Preview(context.Background(), &model.GenerateReq{Id: "1", Values: map[string]interface{}{"Name": "vatsal"}}))
+		})
+	}
+}
+
+func Test_HtmlToPdf_Output(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	stored := []byte(`{"Pages":[{"Base64PageData":"` + base64.StdEncoding.EncodeToString([]byte("<p>{{.Name}}</p>")) + `"}]}`)
+	twoPages := []byte(`{"Pages":[{"Base64PageData":"` + base64.StdEncoding.EncodeToString([]byte("<p>1</p>")) + `"},{"Base64PageData":"` +
+		base64.StdEncoding.EncodeToString([]byte("<p>2</p>")) + `"}]}`)
+	tests := []struct {
+		name         string
+		req          *model.GenerateReq
+		setupFunc    func() *htmlPdfServiceLogic
+		validateFunc func(*respModel.Response, *bytes.Buffer)
+	}{
+		{
+			name: "Success:: HtmlToPdf:: png rendered by the image renderer",
+			req:  &model.GenerateReq{Values: map[string]interface{}{"Name": "A"}, Format: model.OutputPng, Width: 600, Quality: 80},
+			setupFunc: func() *htmlPdfServiceLogic {
+				mockDatasource := mock.NewMockDataSource(mockCtrl)
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(stored, nil)
+				mockRenderer := mock.NewMockRenderer(mockCtrl)
+				mockRenderer.EXPECT().Render(gomock.Any(), gomock.Any(), gomock.Any(), htmlToPdf.RenderOptions{Format: "png", Width: 600, Quality: 80, Page: 1}).
+					DoAndReturn(func(_ context.Context, w io.Writer, b []byte, _ htmlToPdf.RenderOptions) error {
+						_, err := w.Write([]byte("png"))
+						return err
+					})
+				return &htmlPdfServiceLogic{dsSvc: mockDatasource, htSvc: mock.NewMockHtmlToPdf(mockCtrl), imgSvc: mockRenderer}
+			},
+			validateFunc: func(x *respModel.Response, w *bytes.Buffer) {
+				if x.Status != http.StatusOK || w.String() != "png" {
+					t.Errorf("want %v got %v %v", http.StatusOK, x, w.String())
+				}
+			},
+		},
+		{
+			name: "Failure:: HtmlToPdf:: image rendering fails",
+			req:  &model.GenerateReq{Format: model.OutputJpeg},
+			setupFunc: func() *htmlPdfServiceLogic {
+				mockDatasource := mock.NewMockDataSource(mockCtrl)
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(stored, nil)
+				mockRenderer := mock.NewMockRenderer(mockCtrl)
+				mockRenderer.EXPECT().Render(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("exit status 1"))
+				return &htmlPdfServiceLogic{dsSvc: mockDatasource, imgSvc: mockRenderer}
+			},
+			validateFunc: func(x *respModel.Response, _ *bytes.Buffer) {
+				diff := testutil.Diff(x, &respModel.Response{
+					Status:  http.StatusInternalServerError,
+					Message: codes.GetErr(codes.ErrRenderingImage),
+				})
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+		},
+		{
+			name: "Success:: HtmlToPdf:: later page rendered as image",
+			req:  &model.GenerateReq{Values: map[string]interface{}{"Name": "A"}, Format: model.OutputPng, Page: 2},
+			setupFunc: func() *htmlPdfServiceLogic {
+				mockDatasource := mock.NewMockDataSource(mockCtrl)
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(twoPages, nil)
+				mockRenderer := mock.NewMockRenderer(mockCtrl)
+				mockRenderer.EXPECT().Render(gomock.Any(), gomock.Any(), gomock.Any(), htmlToPdf.RenderOptions{Format: "png", Page: 2}).Return(nil)
+				return &htmlPdfServiceLogic{dsSvc: mockDatasource, imgSvc: mockRenderer}
+			},
+			validateFunc: func(x *respModel.Response, _ *bytes.Buffer) {
+				if x.Status != http.StatusOK {
+					t.Errorf("want %v got %v", http.StatusOK, x)
+				}
+			},
+		},
+		{
+			name: "Failure:: HtmlToPdf:: image page out of range",
+			req:  &model.GenerateReq{Format: model.OutputPng, Page: 3},
+			setupFunc: func() *htmlPdfServiceLogic {
+				mockDatasource := mock.NewMockDataSource(mockCtrl)
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(twoPages, nil)
+				return &htmlPdfServiceLogic{dsSvc: mockDatasource, imgSvc: mock.NewMockRenderer(mockCtrl)}
+			},
+			validateFunc: func(x *respModel.Response, _ *bytes.Buffer) {
+				diff := testutil.Diff(x, &respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrImagePageOutOfRange),
+				})
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+		},
+		{
+			name: "Failure:: HtmlToPdf:: page for pdf",
+			req:  &model.GenerateReq{Page: 2},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return &htmlPdfServiceLogic{}
+			},
+			validateFunc: func(x *respModel.Response, _ *bytes.Buffer) {
+				if x.Status != http.StatusBadRequest || x.Message != codes.GetErr(codes.ErrInvalidOutput) {
+					t.Errorf("want %v got %v", codes.GetErr(codes.ErrInvalidOutput), x)
+				}
+			},
+		},
+		{
+			name: "Failure:: HtmlToPdf:: unsupported format",
+			req:  &model.GenerateReq{Format: "gif"},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return &htmlPdfServiceLogic{}
+			},
+			validateFunc: func(x *respModel.Response, _ *bytes.Buffer) {
+				diff := testutil.Diff(x, &respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrInvalidOutput),
+				})
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+		},
+		{
+			name: "Failure:: HtmlToPdf:: webp",
+			req:  &model.GenerateReq{Format: model.OutputWebp},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return &htmlPdfServiceLogic{}
+			},
+			validateFunc: func(x *respModel.Response, _ *bytes.Buffer) {
+				diff := testutil.Diff(x, &respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrWebpUnsupported),
+				})
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+		},
+		{
+			name: "Failure:: HtmlToPdf:: image options for pdf",
+			req:  &model.GenerateReq{Width: 100},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return &htmlPdfServiceLogic{}
+			},
+			validateFunc: func(x *respModel.Response, _ *bytes.Buffer) {
+				if x.Status != http.StatusBadRequest || x.Message != codes.GetErr(codes.ErrInvalidOutput) {
+					t.Errorf("want %v got %v", codes.GetErr(codes.ErrInvalidOutput), x)
+				}
+			},
+		},
+		{
+			name: "Failure:: HtmlToPdf:: no image renderer configured",
+			req:  &model.GenerateReq{Format: model.OutputPng},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return &htmlPdfServiceLogic{}
+			},
+			validateFunc: func(x *respModel.Response, _ *bytes.Buffer) {
+				if x.Status != http.StatusNotImplemented || x.Message != codes.GetErr(codes.ErrRendererUnavailable) {
+					t.Errorf("want %v got %v", codes.GetErr(codes.ErrRendererUnavailable), x)
+				}
+			},
+		},
+		{
+			name: "Failure:: HtmlToPdf:: unsupported disposition",
+			req:  &model.GenerateReq{Disposition: "download"},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return &htmlPdfServiceLogic{}
+			},
+			validateFunc: func(x *respModel.Response, _ *bytes.Buffer) {
+				if x.Status != http.StatusBadRequest || x.Message != codes.GetErr(codes.ErrInvalidDisposition) {
+					t.Errorf("want %v got %v", codes.GetErr(codes.ErrInvalidDisposition), x)
+				}
+			},
+		},
+		{
+			name: "Failure:: HtmlToPdf:: filename with a path",
+			req:  &model.GenerateReq{Filename: "../statement.pdf"},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return &htmlPdfServiceLogic{}
+			},
+			validateFunc: func(x *respModel.Response, _ *bytes.Buffer) {
+				if x.Status != http.StatusBadRequest || x.Message != codes.GetErr(codes.ErrInvalidFilename) {
+					t.Errorf("want %v got %v", codes.GetErr(codes.ErrInvalidFilename), x)
+				}
+			},
+		},
+		{
+			name: "Failure:: HtmlToPdf:: filename with a line break",
+			req:  &model.GenerateReq{Filename: "a.pdf\r\nSet-Cookie: a=b"},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return &htmlPdfServiceLogic{}
+			},
+			validateFunc: func(x *respModel.Response, _ *bytes.Buffer) {
+				if x.Status != http.StatusBadRequest || x.Message != codes.GetErr(codes.ErrInvalidFilename) {
+					t.Errorf("want %v got %v", codes.GetErr(codes.ErrInvalidFilename), x)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := tt.setupFunc()
+			w := bytes.NewBuffer(nil)
+			tt.req.Id = "1"
+			tt.validateFunc(rec.HtmlToPdf(context.Background(), w, tt.req), w)
+		})
+	}
+}
+
+func Test_HtmlToPdf_HeaderFooter(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	encode := func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	}
+	stored := []byte(`{"Pages":[{"Base64PageData":"` + encode("<p>{{.Name}}</p>") + `"}],"Settings":{` +
+		`"header":{"html":"` + encode("<p>{{.Company}} [page]/[topage]</p>") + `","spacing":5,"skip_first_page":true},` +
+		`"footer":{"html":"` + encode("<p>{{.Missing}}</p>") + `"}}}`)
+	tests := []struct {
+		name         string
+		req          *model.GenerateReq
+		setupFunc    func() *htmlPdfServiceLogic
+		validateFunc func(*respModel.Response)
+	}{
+		{
+			name: "Success:: HtmlToPdf:: header and footer executed with the values",
+			req:  &model.GenerateReq{Id: "1", Values: map[string]interface{}{"Name": "A", "Company": "ACME"}},
+			setupFunc: func() *htmlPdfServiceLogic {
+				mockDatasource := mock.NewMockDataSource(mockCtrl)
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(stored, nil)
+				mockHtmlToPdf := mock.NewMockHtmlToPdf(mockCtrl)
+				mockHtmlToPdf.EXPECT().GeneratePdf(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, w io.Writer, b []byte) error {
+					var doc struct {
+						Settings model.TemplateSettings
+					}
+					err := json.Unmarshal(b, &doc)
+					if err != nil {
+						t.Fatal(err)
+					}
+					diff := testutil.Diff(doc.Settings.Header, &model.HeaderFooter{Html: []byte("<p>ACME [page]/[topage]</p>"), Spacing: 5, SkipFirstPage: true})
+					if diff != "" {
+						t.Error(testutil.Callers(), diff)
+					}
+					diff = testutil.Diff(doc.Settings.Footer, &model.HeaderFooter{Html: []byte("<p></p>")})
+					if diff != "" {
+						t.Error(testutil.Callers(), diff)
+					}
+					return renderPdf(t, w)
+				})
+				return &htmlPdfServiceLogic{dsSvc: mockDatasource, htSvc: mockHtmlToPdf}
+			},
+			validateFunc: func(x *respModel.Response) {
+				if x.Status != http.StatusOK {
+					t.Errorf("want %v got %v", http.StatusOK, x)
+				}
+			},
+		},
+		{
+			name: "Failure:: HtmlToPdf:: missing key in footer",
+			req:  &model.GenerateReq{Id: "1", Values: map[string]interface{}{"Name": "A", "Company": "ACME"}, MissingKey: model.MissingKeyError},
+			setupFunc: func() *htmlPdfServiceLogic {
+				mockDatasource := mock.NewMockDataSource(mockCtrl)
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(stored, nil)
+				return &htmlPdfServiceLogic{dsSvc: mockDatasource, htSvc: mock.NewMockHtmlToPdf(mockCtrl)}
+			},
+			validateFunc: func(x *respModel.Response) {
+				if x.Status != http.StatusBadRequest || x.Message != codes.GetErr(codes.ErrMissingKey) {
+					t.Errorf("want %v got %v", codes.GetErr(codes.ErrMissingKey), x)
+				}
+			},
+		},
+		{
+			name: "Failure:: Upload:: invalid header spacing",
+			setupFunc: func() *htmlPdfServiceLogic {
+				return &htmlPdfServiceLogic{}
+			},
+			validateFunc: func(x *respModel.Response) {
+				expected := respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrInvalidHeaderFooter),
+					Data:    nil,
+				}
+				diff := testutil.Diff(x, &expected)
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := tt.setupFunc()
+			if tt.req == nil {
+				tt.validateFunc(rec.Upload(context.Background(), []model.TemplatePage{{File: strings.NewReader("abc")}}, model.TemplateSettings{Header: &model.HeaderFooter{Spacing: 500}}))
+				return
+			}
+			tt.validateFunc(rec.HtmlToPdf(context.Background(), new(bytes.Buffer), tt.req))
+		})
+	}
+}
+
+func Test_HtmlToPdf_RenderDeadline(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	doc := func(settings map[string]interface{}) []byte {
+		js, _ := json.Marshal(map[string]interface{}{
+			"Settings": settings,
+			"Pages": []interface{}{
+				map[string]interface{}{
+					"Base64PageData": base64.StdEncoding.EncodeToString([]byte("<p>{{ .Title }}</p>")),
+				},
+			},
+		})
+		return js
+	}
+	// waitDone renders until the context of the request is done
+	waitDone := func(ctx context.Context, _ io.Writer, _ []byte) error {
+		<-ctx.Done()
+		return fmt.Errorf("renderer killed: %w", ctx.Err())
+	}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		limits   model.Limits
+		settings map[string]interface{}
+		// renders is the number of renders started, a canceled request stops before rendering
+		renders int
+		want    respModel.Response
+	}{
+		{
+			name:    "Failure:: HtmlToPdf:: render timeout",
+			ctx:     context.Background(),
+			limits:  model.Limits{RenderTimeout: 20},
+			renders: 1,
+			want: respModel.Response{
+				Status:  http.StatusGatewayTimeout,
+				Message: codes.GetErr(codes.ErrRenderTimeout),
+			},
+		},
+		{
+			name:     "Failure:: HtmlToPdf:: template render timeout shorter than the configured one",
+			ctx:      context.Background(),
+			settings: map[string]interface{}{"render_timeout_ms": 20},
+			renders:  1,
+			want: respModel.Response{
+				Status:  http.StatusGatewayTimeout,
+				Message: codes.GetErr(codes.ErrRenderTimeout),
+			},
+		},
+		{
+			name: "Failure:: HtmlToPdf:: request canceled",
+			ctx:  canceled,
+			want: respModel.Response{
+				Status:  statusClientClosedRequest,
+				Message: codes.GetErr(codes.ErrRequestCanceled),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDatasource := mock.NewMockDataSource(mockCtrl)
+			mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(doc(tt.settings), nil)
+			mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
+			mockHtmlsvc.EXPECT().GeneratePdf(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(waitDone).Times(tt.renders)
+			rec := &htmlPdfServiceLogic{
+				dsSvc:  mockDatasource,
+				htSvc:  mockHtmlsvc,
+				limits: tt.limits,
+			}
+			start := time.Now()
+			resp := rec.HtmlToPdf(tt.ctx, new(bytes.Buffer), &model.GenerateReq{Id: "1", Values: map[string]interface{}{"Title": "a"}})
+			if !reflect.DeepEqual(resp, &tt.want) {
+				t.Errorf("want %v got %v", tt.want, resp)
+			}
+			if time.Since(start) > 5*time.Second {
+				t.Errorf("render not interrupted after %s", time.Since(start))
+			}
+		})
+	}
+}
+
+func Test_HtmlToPdf_RenderCache(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	js, _ := json.Marshal(map[string]interface{}{
+		"Pages": []interface{}{
+			map[string]interface{}{
+				"Base64PageData": base64.StdEncoding.EncodeToString([]byte("<p>{{ .Title }}</p>")),
+			},
+		},
+	})
+	req := func() *model.GenerateReq {
+		return &model.GenerateReq{Id: "1", Values: map[string]interface{}{"Title": "a"}}
+	}
+	key, err := renderKey(req(), js)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := rendered(t, "Helvetica")
+	t.Run("Success:: HtmlToPdf:: cache miss", func(t *testing.T) {
+		mockDatasource := mock.NewMockDataSource(mockCtrl)
+		mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(js, nil)
+		mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
+		mockHtmlsvc.EXPECT().GeneratePdf(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, w io.Writer, _ []byte) error {
+			_, err := w.Write(doc)
+			return err
+		})
+		mockCache := mock.NewMockRenderCache(mockCtrl)
+		mockCache.EXPECT().Get(gomock.Any(), key).Return(nil, false)
+		mockCache.EXPECT().Set(gomock.Any(), key, doc)
+		rec := NewHtmlPdfServiceLogic(mockDatasource, mockHtmlsvc, WithRenderCache(mockCache))
+		out := new(bytes.Buffer)
+		resp := rec.HtmlToPdf(context.Background(), out, req())
+		if resp.Status != http.StatusOK || !bytes.Equal(out.Bytes(), doc) {
+			t.Errorf("want %d got %v %q", http.StatusOK, resp, out)
+		}
+	})
+	t.Run("Success:: HtmlToPdf:: cache hit", func(t *testing.T) {
+		mockDatasource := mock.NewMockDataSource(mockCtrl)
+		mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(js, nil)
+		mockCache := mock.NewMockRenderCache(mockCtrl)
+		mockCache.EXPECT().Get(gomock.Any(), key).Return([]byte("%PDF-cached"), true)
+		rec := NewHtmlPdfServiceLogic(mockDatasource, mock.NewMockHtmlToPdf(mockCtrl), WithRenderCache(mockCache))
+		out := new(bytes.Buffer)
+		resp := rec.HtmlToPdf(context.Background(), out, req())
+		if resp.Status != http.StatusOK || out.String() != "%PDF-cached" {
+			t.Errorf("want %d %q got %v %q", http.StatusOK, "%PDF-cached", resp, out)
+		}
+	})
+	t.Run("Failure:: HtmlToPdf:: failed render not cached", func(t *testing.T) {
+		mockDatasource := mock.NewMockDataSource(mockCtrl)
+		mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(js, nil)
+		mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
+		mockHtmlsvc.EXPECT().GeneratePdf(gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("boom"))
+		mockCache := mock.NewMockRenderCache(mockCtrl)
+		mockCache.EXPECT().Get(gomock.Any(), key).Return(nil, false)
+		rec := NewHtmlPdfServiceLogic(mockDatasource, mockHtmlsvc, WithRenderCache(mockCache))
+		resp := rec.HtmlToPdf(context.Background(), new(bytes.Buffer), req())
+		if resp.Status != http.StatusInternalServerError || resp.Message != codes.GetErr(codes.ErrConvertingToPdf) {
+			t.Errorf("want %d got %v", http.StatusInternalServerError, resp)
+		}
+	})
+	t.Run("Success:: HtmlToPdf:: identical requests rendered once", func(t *testing.T) {
+		mockDatasource := mock.NewMockDataSource(mockCtrl)
+		mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(js, nil).Times(3)
+		started, release := make(chan struct{}), make(chan struct{})
+		mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
+		mockHtmlsvc.EXPECT().GeneratePdf(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, w io.Writer, _ []byte) error {
+			close(started)
+			<-release
+			_, err := w.Write(doc)
+			return err
+		}).Times(1)
+		rec := NewHtmlPdfServiceLogic(mockDatasource, mockHtmlsvc, WithRenderCache(renderCache.NewMemoryCache(model.RenderCacheConfig{})))
+		outs := make([]*bytes.Buffer, 3)
+		var wg sync.WaitGroup
+		generate := func(i int) {
+			defer wg.Done()
+			outs[i] = new(bytes.Buffer)
+			if resp := rec.HtmlToPdf(context.Background(), outs[i], req()); resp.Status != http.StatusOK {
+				t.Errorf("want %d got %v", http.StatusOK, resp)
+			}
+		}
+		wg.Add(3)
+		go generate(0)
+		<-started
+		go generate(1)
+		go generate(2)
+		// requests arriving after the render are served from the cache, either way the document is rendered once
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		for i, out := range outs {
+			if !bytes.Equal(out.Bytes(), doc) {
+				t.Errorf("request %d: want the rendered pdf got %q", i, out)
+			}
+		}
+	})
+	t.Run("Success:: HtmlToPdf:: shared render outlives the canceled first request", func(t *testing.T) {
+		mockDatasource := mock.NewMockDataSource(mockCtrl)
+		mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(js, nil).Times(2)
+		started, release := make(chan struct{}), make(chan struct{})
+		mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
+		mockHtmlsvc.EXPECT().GeneratePdf(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, w io.Writer, _ []byte) error {
+			close(started)
+			<-release
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			_, err := w.Write(doc)
+			return err
+		}).Times(1)
+		rec := NewHtmlPdfServiceLogic(mockDatasource, mockHtmlsvc, WithRenderCache(renderCache.NewMemoryCache(model.RenderCacheConfig{})))
+		ctx, cancel := context.WithCancel(context.Background())
+		first := make(chan *respModel.Response)
+		go func() {
+			first <- rec.HtmlToPdf(ctx, io.Discard, req())
+		}()
+		<-started
+		second := make(chan *respModel.Response)
+		out := new(bytes.Buffer)
+		go func() {
+			second <- rec.HtmlToPdf(context.Background(), out, req())
+		}()
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+		if resp := <-first; resp.Status != statusClientClosedRequest {
+			t.Errorf("want %d got %v", statusClientClosedRequest, resp)
+		}
+		close(release)
+		if resp := <-second; resp.Status != http.StatusOK || !bytes.Equal(out.Bytes(), doc) {
+			t.Errorf("want %d and the rendered pdf got %v %q", http.StatusOK, resp, out)
+		}
+	})
+	t.Run("Failure:: HtmlToPdf:: shared render canceled once every request went away", func(t *testing.T) {
+		mockDatasource := mock.NewMockDataSource(mockCtrl)
+		mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(js, nil).Times(2)
+		started, killed := make(chan struct{}), make(chan struct{})
+		mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
+		mockHtmlsvc.EXPECT().GeneratePdf(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, _ io.Writer, _ []byte) error {
+			close(started)
+			<-ctx.Done()
+			close(killed)
+			return ctx.Err()
+		}).Times(1)
+		rec := NewHtmlPdfServiceLogic(mockDatasource, mockHtmlsvc, WithRenderCache(renderCache.NewMemoryCache(model.RenderCacheConfig{})))
+		ctx1, cancel1 := context.WithCancel(context.Background())
+		ctx2, cancel2 := context.WithCancel(context.Background())
+		resps := make(chan *respModel.Response, 2)
+		go func() {
+			resps <- rec.HtmlToPdf(ctx1, io.Discard, req())
+		}()
+		<-started
+		go func() {
+			resps <- rec.HtmlToPdf(ctx2, io.Discard, req())
+		}()
+		time.Sleep(20 * time.Millisecond)
+		cancel1()
+		cancel2()
+		for i := 0; i < 2; i++ {
+			if resp := <-resps; resp.Status != statusClientClosedRequest {
+				t.Errorf("want %d got %v", statusClientClosedRequest, resp)
+			}
+		}
+		select {
+		case <-killed:
+		case <-time.After(time.Second):
+			t.Error("want the shared render canceled")
+		}
+	})
+}
+
+func Test_renderKey(t *testing.T) {
+	key := func(req model.GenerateReq, tmpl string) string {
+		k, err := renderKey(&req, []byte(tmpl))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+	base := key(model.GenerateReq{Id: "1", Values: map[string]interface{}{"a": 1, "b": "x"}}, "v1")
+	if !strings.HasPrefix(base, "1:") {
+		t.Errorf("want the template id first got %s", base)
+	}
+	tests := []struct {
+		name string
+		req  model.GenerateReq
+		tmpl string
+		same bool
+	}{
+		{name: "same values", req: model.GenerateReq{Id: "1", Values: map[string]interface{}{"b": "x", "a": 1}}, tmpl: "v1", same: true},
+		{name: "other values", req: model.GenerateReq{Id: "1", Values: map[string]interface{}{"a": 2, "b": "x"}}, tmpl: "v1"},
+		{name: "other template version", req: model.GenerateReq{Id: "1", Values: map[string]interface{}{"a": 1, "b": "x"}}, tmpl: "v2"},
+		{name: "other template", req: model.GenerateReq{Id: "2", Values: map[string]interface{}{"a": 1, "b": "x"}}, tmpl: "v1"},
+		{name: "other options", req: model.GenerateReq{Id: "1", Values: map[string]interface{}{"a": 1, "b": "x"}, Format: model.OutputPng}, tmpl: "v1"},
+		{name: "other records", req: model.GenerateReq{Id: "1", Values: map[string]interface{}{"a": 1, "b": "x"}, Records: []interface{}{1}}, tmpl: "v1"},
+		{name: "other filename", req: model.GenerateReq{Id: "1", Values: map[string]interface{}{"a": 1, "b": "x"}, Filename: "a.pdf", Disposition: model.DispositionInline},
+			tmpl: "v1", same: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := key(tt.req, tt.tmpl); (got == base) != tt.same {
+				t.Errorf("want same key %v got %s and %s", tt.same, base, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package logic
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"html"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+
+	"github.com/vatsal278/html-pdf-service/internal/model"
+)
+
+var errUnknownTheme = errors.New("unknown markdown theme")
+
+//go:embed themes/*.css
+var themes embed.FS
+
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM, extension.Footnote),
+	// raw html and links to dangerous urls such as javascript: are not rendered, the values of the placeholders are
+	// executed unescaped and escaped once here like the rest of the document
+	goldmark.WithParserOptions(parser.WithAutoHeadingID(), parser.WithASTTransformers(util.Prioritized(linkFilter{}, 0))),
+)
+
+// linkFilter removes the destinations of links and images that are not relative or http, https or mailto urls,
+// images may also be png, gif, jpeg or webp data urls
+type linkFilter struct{}
+
+func (linkFilter) Transform(doc *ast.Document, reader text.Reader, _ parser.Context) {
+	source := reader.Source()
+	var autoLinks []*ast.AutoLink
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Link:
+			if !safeURL(n.Destination, false) {
+				n.Destination = nil
+			}
+		case *ast.Image:
+			if !safeURL(n.Destination, true) {
+				n.Destination = nil
+			}
+		case *ast.AutoLink:
+			if !safeURL(n.URL(source), false) {
+				autoLinks = append(autoLinks, n)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	for _, n := range autoLinks {
+		n.Parent().ReplaceChild(n.Parent(), n, ast.NewString(n.Label(source)))
+	}
+}
+
+// safeURL reports whether url is relative or uses an allowed scheme. Browsers ignore the case of the scheme and
+// the whitespace and control characters in it, so does the check.
+func safeURL(url []byte, image bool) bool {
+	u := strings.ToLower(strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, string(url)))
+	i := strings.IndexAny(u, ":/?#")
+	if i < 0 || u[i] != ':' {
+		return true
+	}
+	switch u[:i] {
+	case "http", "https", "mailto":
+		return true
+	case "data":
+		if !image {
+			return false
+		}
+		for _, t := range []string{"png", "gif", "jpeg", "webp"} {
+			if strings.HasPrefix(u, "data:image/"+t+";") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// themeCss returns the stylesheet of a built-in markdown theme, the default theme is used when name is empty
+func themeCss(name string) ([]byte, bool) {
+	if name == "" {
+		name = model.ThemeDefault
+	}
+	b, err := themes.ReadFile("themes/" + name + ".css")
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+// markdownToHtml converts the executed markdown document into a standalone html page styled with the theme
+func markdownToHtml(src []byte, theme string, title string) ([]byte, error) {
+	css, ok := themeCss(theme)
+	if !ok {
+		return nil, errUnknownTheme
+	}
+	body := bytes.NewBuffer(nil)
+	err := markdown.Convert(src, body)
+	if err != nil {
+		return nil, err
+	}
+	out := bytes.NewBuffer(nil)
+	out.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>")
+	out.WriteString(html.EscapeString(title))
+	out.WriteString("</title>\n<style>\n")
+	out.Write(css)
+	out.WriteString("</style>\n</head>\n<body>\n")
+	out.Write(body.Bytes())
+	out.WriteString("</body>\n</html>\n")
+	return out.Bytes(), nil
+}
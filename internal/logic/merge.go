@@ -0,0 +1,168 @@
+package logic
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/vatsal278/html-pdf-service/internal/model"
+)
+
+var bodyRegex = regexp.MustCompile(`(?is)(<body[^>]*>)(.*)</body>`)
+var bodyTagRegex = regexp.MustCompile(`(?i)<body[^>]*>`)
+
+// bookmarkStyle hides the bookmark heading while keeping it in the outline of the document
+const bookmarkStyle = "height:0;margin:0;padding:0;overflow:hidden;font-size:1px;line-height:0;color:transparent"
+
+// mergeRecords returns the records of a mail merge request, nil if the request does not use mail merge.
+// The records are either given inline or found at a dotted path inside the values.
+func mergeRecords(req *model.GenerateReq, values interface{}, parseTime bool) ([]map[string]interface{}, error) {
+	var list []interface{}
+	switch {
+	case req.Records != nil && req.RecordsPath != "":
+		return nil, errors.New("records and records_path are mutually exclusive")
+	case req.Records != nil:
+		list, _ = normalizeValues(req.Records, parseTime).([]interface{})
+	case req.RecordsPath != "":
+		v, ok := lookupPath(values, req.RecordsPath)
+		if !ok {
+			return nil, fmt.Errorf("records_path %q not found in values", req.RecordsPath)
+		}
+		list, ok = v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("records_path %q is not an array", req.RecordsPath)
+		}
+	default:
+		return nil, nil
+	}
+	if len(list) == 0 {
+		return nil, errors.New("no records to merge")
+	}
+	records := make([]map[string]interface{}, len(list))
+	for i, e := range list {
+		rec, ok := e.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("record %d is not an object", i)
+		}
+		records[i] = rec
+	}
+	return records, nil
+}
+
+// lookupPath resolves a dotted path like "order.items" inside nested objects
+func lookupPath(v interface{}, path string) (interface{}, bool) {
+	for _, key := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		v, ok = m[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// recordValues returns the data a page is executed with for one record, the values overlaid with the record
+func recordValues(values interface{}, rec map[string]interface{}) map[string]interface{} {
+	base, _ := values.(map[string]interface{})
+	data := make(map[string]interface{}, len(base)+len(rec))
+	for k, v := range base {
+		data[k] = v
+	}
+	for k, v := range rec {
+		data[k] = v
+	}
+	return data
+}
+
+// recordTitle returns the bookmark title of a record, the value found at the bookmark path
+func recordTitle(rec map[string]interface{}, path string) string {
+	v, ok := lookupPath(rec, path)
+	if !ok || v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// withBookmark adds a hidden heading with the title at the start of the page body, so that the record shows up in the outline
+func withBookmark(page []byte, title string) []byte {
+	if title == "" {
+		return page
+	}
+	heading := `<h1 style="` + bookmarkStyle + `">` + html.EscapeString(title) + `</h1>`
+	loc := bodyTagRegex.FindIndex(page)
+	if loc == nil {
+		return append([]byte(heading), page...)
+	}
+	out := make([]byte, 0, len(page)+len(heading))
+	out = append(out, page[:loc[1]]...)
+	out = append(out, heading...)
+	return append(out, page[loc[1]:]...)
+}
+
+// mergeBodies joins the executed pages of all records into one document, the body content of every page is appended
+// to the body of the first page
+func mergeBodies(outs [][]byte) []byte {
+	first := bodyRegex.FindSubmatchIndex(outs[0])
+	if first == nil {
+		return bytes.Join(outs, nil)
+	}
+	content := make([][]byte, len(outs))
+	for i, o := range outs {
+		content[i] = o
+		if m := bodyRegex.FindSubmatch(o); m != nil {
+			content[i] = m[2]
+		}
+	}
+	out := make([]byte, 0, len(outs[0])*len(outs))
+	out = append(out, outs[0][:first[4]]...)
+	out = append(out, bytes.Join(content, nil)...)
+	return append(out, outs[0][first[5]:]...)
+}
+
+// mergePages builds the page objects of a mail merge. With page breaks every record gets its own copy of all pages,
+// without page breaks the records of a page are joined into a single page object.
+func mergePages(k []interface{}, outputs [][][]byte, n int, pageBreak bool) ([]interface{}, [][]byte) {
+	var merged []interface{}
+	var pages [][]byte
+	add := func(p interface{}, out []byte) {
+		page, _ := p.(map[string]interface{})
+		if out == nil {
+			merged = append(merged, page)
+			return
+		}
+		c := make(map[string]interface{}, len(page))
+		for key, v := range page {
+			c[key] = v
+		}
+		c["Base64PageData"] = base64.StdEncoding.EncodeToString(out)
+		merged = append(merged, c)
+		pages = append(pages, out)
+	}
+	if !pageBreak {
+		for i, p := range k {
+			if outputs[i] == nil {
+				add(p, nil)
+				continue
+			}
+			add(p, mergeBodies(outputs[i]))
+		}
+		return merged, pages
+	}
+	for r := 0; r < n; r++ {
+		for i, p := range k {
+			if outputs[i] == nil {
+				add(p, nil)
+				continue
+			}
+			add(p, outputs[i][r])
+		}
+	}
+	return merged, pages
+}
@@ -0,0 +1,182 @@
+package logic
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	respModel "github.com/PereRohit/util/model"
+	"github.com/PereRohit/util/testutil"
+	"github.com/golang/mock/gomock"
+
+	"github.com/vatsal278/html-pdf-service/internal/codes"
+	"github.com/vatsal278/html-pdf-service/internal/model"
+	"github.com/vatsal278/html-pdf-service/pkg/mock"
+)
+
+func Test_Preview_MailMerge(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	page := func(html string) string {
+		return `{"Base64PageData":"` + base64.StdEncoding.EncodeToString([]byte(html)) + `"}`
+	}
+	stored := []byte(`{"Pages":[` + page("<body><p>{{.Greeting}} {{.Name}}</p></body>") + `,` + page("<p>end</p>") + `]}`)
+	noBreak := false
+	tests := []struct {
+		name     string
+		req      *model.GenerateReq
+		expected respModel.Response
+	}{
+		{
+			name: "Success:: Preview:: records repeat all pages",
+			req: &model.GenerateReq{
+				Values:  map[string]interface{}{"Greeting": "Hi"},
+				Records: []interface{}{map[string]interface{}{"Name": "A"}, map[string]interface{}{"Name": "B"}},
+			},
+			expected: respModel.Response{
+				Status:  http.StatusOK,
+				Message: "SUCCESS",
+				Data:    []string{"<body><p>Hi A</p></body>", "<p>end</p>", "<body><p>Hi B</p></body>", "<p>end</p>"},
+			},
+		},
+		{
+			name: "Success:: Preview:: records from path override values",
+			req: &model.GenerateReq{
+				Values: map[string]interface{}{"Greeting": "Hi", "list": map[string]interface{}{
+					"people": []interface{}{map[string]interface{}{"Name": "A", "Greeting": "Hello"}},
+				}},
+				RecordsPath: "list.people",
+			},
+			expected: respModel.Response{
+				Status:  http.StatusOK,
+				Message: "SUCCESS",
+				Data:    []string{"<body><p>Hello A</p></body>", "<p>end</p>"},
+			},
+		},
+		{
+			name: "Success:: Preview:: records joined without page break",
+			req: &model.GenerateReq{
+				Values:    map[string]interface{}{"Greeting": "Hi"},
+				Records:   []interface{}{map[string]interface{}{"Name": "A"}, map[string]interface{}{"Name": "B"}},
+				PageBreak: &noBreak,
+			},
+			expected: respModel.Response{
+				Status:  http.StatusOK,
+				Message: "SUCCESS",
+				Data:    []string{"<body><p>Hi A</p><p>Hi B</p></body>", "<p>end</p><p>end</p>"},
+			},
+		},
+		{
+			name: "Success:: Preview:: bookmark added per record",
+			req: &model.GenerateReq{
+				Values:   map[string]interface{}{"Greeting": "Hi"},
+				Records:  []interface{}{map[string]interface{}{"Name": "A&B"}},
+				Bookmark: "Name",
+			},
+			expected: respModel.Response{
+				Status:  http.StatusOK,
+				Message: "SUCCESS",
+				Data:    []string{`<body><h1 style="` + bookmarkStyle + `">A&amp;B</h1><p>Hi A&amp;B</p></body>`, "<p>end</p>"},
+			},
+		},
+		{
+			name: "Failure:: Preview:: records and records_path",
+			req:  &model.GenerateReq{Records: []interface{}{map[string]interface{}{}}, RecordsPath: "list"},
+			expected: respModel.Response{
+				Status:  http.StatusBadRequest,
+				Message: codes.GetErr(codes.ErrInvalidRecords),
+				Data:    "records and records_path are mutually exclusive",
+			},
+		},
+		{
+			name: "Failure:: Preview:: records_path not an array",
+			req:  &model.GenerateReq{Values: map[string]interface{}{"list": "x"}, RecordsPath: "list"},
+			expected: respModel.Response{
+				Status:  http.StatusBadRequest,
+				Message: codes.GetErr(codes.ErrInvalidRecords),
+				Data:    `records_path "list" is not an array`,
+			},
+		},
+		{
+			name: "Failure:: Preview:: records_path not found",
+			req:  &model.GenerateReq{RecordsPath: "list.people"},
+			expected: respModel.Response{
+				Status:  http.StatusBadRequest,
+				Message: codes.GetErr(codes.ErrInvalidRecords),
+				Data:    `records_path "list.people" not found in values`,
+			},
+		},
+		{
+			name: "Failure:: Preview:: empty records",
+			req:  &model.GenerateReq{Records: []interface{}{}},
+			expected: respModel.Response{
+				Status:  http.StatusBadRequest,
+				Message: codes.GetErr(codes.ErrInvalidRecords),
+				Data:    "no records to merge",
+			},
+		},
+		{
+			name: "Failure:: Preview:: record not an object",
+			req:  &model.GenerateReq{Records: []interface{}{"A"}},
+			expected: respModel.Response{
+				Status:  http.StatusBadRequest,
+				Message: codes.GetErr(codes.ErrInvalidRecords),
+				Data:    "record 0 is not an object",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDatasource := mock.NewMockDataSource(mockCtrl)
+			mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(stored, nil)
+			rec := &htmlPdfServiceLogic{dsSvc: mockDatasource, htSvc: mock.NewMockHtmlToPdf(mockCtrl)}
+			tt.req.Id = "1"
+			diff := testutil.Diff(rec.Preview(context.Background(), tt.req), &tt.expected)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func Test_HtmlToPdf_MailMerge(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	stored := []byte(`{"Pages":[{"Base64PageData":"` + base64.StdEncoding.EncodeToString([]byte("<p>{{.Name}}</p>")) + `","Settings":{"zoom":2}}]}`)
+	mockDatasource := mock.NewMockDataSource(mockCtrl)
+	mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(stored, nil)
+	mockHtmlToPdf := mock.NewMockHtmlToPdf(mockCtrl)
+	mockHtmlToPdf.EXPECT().GeneratePdf(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, w io.Writer, b []byte) error {
+		var doc struct {
+			Pages []struct {
+				Base64PageData string
+				Settings       model.PageSettings
+			}
+		}
+		err := json.Unmarshal(b, &doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(doc.Pages) != 3 {
+			t.Fatalf("want 3 pages got %d", len(doc.Pages))
+		}
+		for i, name := range []string{"A", "B", "C"} {
+			html, _ := base64.StdEncoding.DecodeString(doc.Pages[i].Base64PageData)
+			if string(html) != "<p>"+name+"</p>" || doc.Pages[i].Settings.Zoom != 2 {
+				t.Errorf("page %d: got %s %v", i, html, doc.Pages[i].Settings)
+			}
+		}
+		return renderPdf(t, w)
+	})
+	rec := &htmlPdfServiceLogic{dsSvc: mockDatasource, htSvc: mockHtmlToPdf}
+	resp := rec.HtmlToPdf(context.Background(), new(bytes.Buffer), &model.GenerateReq{Id: "1", Records: []interface{}{
+		map[string]interface{}{"Name": "A"}, map[string]interface{}{"Name": "B"}, map[string]interface{}{"Name": "C"},
+	}})
+	if resp.Status != http.StatusOK {
+		t.Errorf("want %v got %v", http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,168 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/PereRohit/util/log"
+	respModel "github.com/PereRohit/util/model"
+
+	"github.com/vatsal278/html-pdf-service/internal/codes"
+	"github.com/vatsal278/html-pdf-service/internal/model"
+)
+
+// ReorderPages moves the pages of a template, order lists the current index of every page in the new order
+func (l htmlPdfServiceLogic) ReorderPages(ctx context.Context, id string, order []int) *respModel.Response {
+	return l.updateDoc(ctx, id, func(z map[string]interface{}) *respModel.Response {
+		pages, _ := z["Pages"].([]interface{})
+		if len(order) != len(pages) {
+			return &respModel.Response{
+				Status:  http.StatusBadRequest,
+				Message: codes.GetErr(codes.ErrInvalidPageOrder),
+				Data:    nil,
+			}
+		}
+		seen := make([]bool, len(pages))
+		reordered := make([]interface{}, len(pages))
+		for i, o := range order {
+			if o < 0 || o >= len(pages) || seen[o] {
+				return &respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrInvalidPageOrder),
+					Data:    nil,
+				}
+			}
+			seen[o] = true
+			reordered[i] = pages[o]
+		}
+		z["Pages"] = reordered
+		return nil
+	})
+}
+
+// InsertPage adds a page before the page at position, a negative position appends the page
+func (l htmlPdfServiceLogic) InsertPage(ctx context.Context, id string, position int, page model.TemplatePage) *respModel.Response {
+	if !page.Settings.Valid() {
+		return &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidPageSettings),
+			Data:    nil,
+		}
+	}
+	fileBytes, err := ioutil.ReadAll(page.File)
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrReadFileFail),
+			Data:    nil,
+		}
+	}
+	jb, err := l.htSvc.GetJsonFromHtml(fileBytes)
+	if err == nil {
+		jb, err = withPageSettings(jb, []model.PageSettings{page.Settings})
+	}
+	var doc struct {
+		Pages []interface{}
+	}
+	if err == nil {
+		err = json.Unmarshal(jb, &doc)
+	}
+	if err == nil && len(doc.Pages) != 1 {
+		err = errors.New("converted page count " + strconv.Itoa(len(doc.Pages)))
+	}
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrFileConversionFail),
+			Data:    nil,
+		}
+	}
+	return l.updateDoc(ctx, id, func(z map[string]interface{}) *respModel.Response {
+		pages, _ := z["Pages"].([]interface{})
+		if position > len(pages) {
+			return &respModel.Response{
+				Status:  http.StatusBadRequest,
+				Message: codes.GetErr(codes.ErrPageNotFound),
+				Data:    nil,
+			}
+		}
+		if position < 0 {
+			position = len(pages)
+		}
+		inserted := make([]interface{}, 0, len(pages)+1)
+		inserted = append(inserted, pages[:position]...)
+		inserted = append(inserted, doc.Pages[0])
+		z["Pages"] = append(inserted, pages[position:]...)
+		return nil
+	})
+}
+
+// RemovePage removes the page addressed by its index or by its name
+func (l htmlPdfServiceLogic) RemovePage(ctx context.Context, id string, page string) *respModel.Response {
+	return l.updateDoc(ctx, id, func(z map[string]interface{}) *respModel.Response {
+		pages, _ := z["Pages"].([]interface{})
+		i, ok := pageIndex(pages, page)
+		if !ok {
+			return &respModel.Response{
+				Status:  http.StatusNotFound,
+				Message: codes.GetErr(codes.ErrPageNotFound),
+				Data:    nil,
+			}
+		}
+		if len(pages) == 1 {
+			return &respModel.Response{
+				Status:  http.StatusBadRequest,
+				Message: codes.GetErr(codes.ErrLastPage),
+				Data:    nil,
+			}
+		}
+		z["Pages"] = append(pages[:i:i], pages[i+1:]...)
+		return nil
+	})
+}
+
+// pageIndex resolves a page reference, either the index of the page or the name in its settings
+func pageIndex(pages []interface{}, ref string) (int, bool) {
+	if i, err := strconv.Atoi(ref); err == nil {
+		return i, i >= 0 && i < len(pages)
+	}
+	for i, p := range pages {
+		page, _ := p.(map[string]interface{})
+		settings, _ := page["Settings"].(map[string]interface{})
+		if name, _ := settings["name"].(string); name != "" && name == ref {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// withPageSettings stores the settings of every page on its page object, pages without settings are left unchanged
+func withPageSettings(jb []byte, settings []model.PageSettings) ([]byte, error) {
+	set := false
+	for _, s := range settings {
+		set = set || s != model.PageSettings{}
+	}
+	if !set {
+		return jb, nil
+	}
+	var z map[string]interface{}
+	err := json.Unmarshal(jb, &z)
+	if err != nil {
+		return nil, err
+	}
+	pages, _ := z["Pages"].([]interface{})
+	for i, p := range pages {
+		page, ok := p.(map[string]interface{})
+		if !ok || i >= len(settings) || settings[i] == (model.PageSettings{}) {
+			continue
+		}
+		page["Settings"] = settings[i]
+	}
+	return json.Marshal(z)
+}
@@ -0,0 +1,200 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	respModel "github.com/PereRohit/util/model"
+	"github.com/PereRohit/util/testutil"
+	"github.com/golang/mock/gomock"
+
+	"github.com/vatsal278/html-pdf-service/internal/codes"
+	"github.com/vatsal278/html-pdf-service/internal/model"
+	"github.com/vatsal278/html-pdf-service/pkg/mock"
+)
+
+const storedPages = `{"Pages":[{"Base64PageData":"YQ=="},{"Base64PageData":"Yg==","Settings":{"name":"body"}},{"Base64PageData":"Yw=="}]}`
+
+func Test_Upload_Pages(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
+	mockHtmlsvc.EXPECT().GetJsonFromHtml([]byte("cover"), []byte("body")).Return([]byte(`{"Pages":[{"Base64PageData":"Y292ZXI="},{"Base64PageData":"Ym9keQ=="}]}`), nil)
+	mockDatasource := mock.NewMockDataSource(mockCtrl)
+	mockDatasource.EXPECT().SaveFile(gomock.Any(), gomock.Any(), []byte(`{"Pages":[{"Base64PageData":"Y292ZXI=","Settings":{"name":"cover","zoom":1.5}},{"Base64PageData":"Ym9keQ=="}],"Settings":{}}`), time.Duration(0)).Return(nil)
+	rec := &htmlPdfServiceLogic{dsSvc: mockDatasource, htSvc: mockHtmlsvc}
+	resp := rec.Upload(context.Background(), []model.TemplatePage{
+		{File: strings.NewReader("cover"), Settings: model.PageSettings{Name: "cover", Zoom: 1.5}},
+		{File: strings.NewReader("body")},
+	}, model.TemplateSettings{})
+	if resp.Status != http.StatusCreated {
+		t.Errorf("want %v got %v", http.StatusCreated, resp)
+	}
+
+	resp = rec.Upload(context.Background(), []model.TemplatePage{{File: strings.NewReader("x"), Settings: model.PageSettings{Zoom: 20}}}, model.TemplateSettings{})
+	diff := testutil.Diff(resp, &respModel.Response{Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrInvalidPageSettings)})
+	if diff != "" {
+		t.Error(testutil.Callers(), diff)
+	}
+
+	resp = rec.Upload(context.Background(), []model.TemplatePage{{File: strings.NewReader("x"), Settings: model.PageSettings{Name: "2"}}}, model.TemplateSettings{})
+	diff = testutil.Diff(resp, &respModel.Response{Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrInvalidPageSettings)})
+	if diff != "" {
+		t.Error(testutil.Callers(), diff)
+	}
+}
+
+func Test_ReorderPages(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	tests := []struct {
+		name      string
+		order     []int
+		wantSaved string
+		wantResp  *respModel.Response
+	}{
+		{
+			name:      "Success:: ReorderPages",
+			order:     []int{2, 0, 1},
+			wantSaved: `{"Pages":[{"Base64PageData":"Yw=="},{"Base64PageData":"YQ=="},{"Base64PageData":"Yg==","Settings":{"name":"body"}}]}`,
+			wantResp:  &respModel.Response{Status: http.StatusOK, Message: "SUCCESS", Data: map[string]interface{}{"id": "1"}},
+		},
+		{
+			name:     "Failure:: ReorderPages:: duplicate index",
+			order:    []int{0, 0, 1},
+			wantResp: &respModel.Response{Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrInvalidPageOrder)},
+		},
+		{
+			name:     "Failure:: ReorderPages:: missing page",
+			order:    []int{1, 0},
+			wantResp: &respModel.Response{Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrInvalidPageOrder)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDatasource := mock.NewMockDataSource(mockCtrl)
+			mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return([]byte(storedPages), nil)
+			if tt.wantSaved != "" {
+				mockDatasource.EXPECT().SaveFile(gomock.Any(), "1", []byte(tt.wantSaved), time.Duration(0)).Return(nil)
+			}
+			rec := &htmlPdfServiceLogic{dsSvc: mockDatasource}
+			diff := testutil.Diff(rec.ReorderPages(context.Background(), "1", tt.order), tt.wantResp)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func Test_InsertPage(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	tests := []struct {
+		name      string
+		position  int
+		settings  model.PageSettings
+		convert   error
+		wantSaved string
+		wantResp  *respModel.Response
+	}{
+		{
+			name:      "Success:: InsertPage:: at position",
+			position:  1,
+			settings:  model.PageSettings{Name: "appendix"},
+			wantSaved: `{"Pages":[{"Base64PageData":"YQ=="},{"Base64PageData":"ZA==","Settings":{"name":"appendix"}},{"Base64PageData":"Yg==","Settings":{"name":"body"}},{"Base64PageData":"Yw=="}]}`,
+			wantResp:  &respModel.Response{Status: http.StatusOK, Message: "SUCCESS", Data: map[string]interface{}{"id": "1"}},
+		},
+		{
+			name:      "Success:: InsertPage:: appended",
+			position:  -1,
+			wantSaved: `{"Pages":[{"Base64PageData":"YQ=="},{"Base64PageData":"Yg==","Settings":{"name":"body"}},{"Base64PageData":"Yw=="},{"Base64PageData":"ZA=="}]}`,
+			wantResp:  &respModel.Response{Status: http.StatusOK, Message: "SUCCESS", Data: map[string]interface{}{"id": "1"}},
+		},
+		{
+			name:     "Failure:: InsertPage:: position after the last page",
+			position: 4,
+			wantResp: &respModel.Response{Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrPageNotFound)},
+		},
+		{
+			name:     "Failure:: InsertPage:: conversion failure",
+			convert:  errors.New("conversion"),
+			wantResp: &respModel.Response{Status: http.StatusInternalServerError, Message: codes.GetErr(codes.ErrFileConversionFail)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockHtmlsvc := mock.NewMockHtmlToPdf(mockCtrl)
+			mockHtmlsvc.EXPECT().GetJsonFromHtml([]byte("d")).Return([]byte(`{"Pages":[{"Base64PageData":"ZA=="}]}`), tt.convert)
+			mockDatasource := mock.NewMockDataSource(mockCtrl)
+			if tt.convert == nil {
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return([]byte(storedPages), nil)
+			}
+			if tt.wantSaved != "" {
+				mockDatasource.EXPECT().SaveFile(gomock.Any(), "1", []byte(tt.wantSaved), time.Duration(0)).Return(nil)
+			}
+			rec := &htmlPdfServiceLogic{dsSvc: mockDatasource, htSvc: mockHtmlsvc}
+			resp := rec.InsertPage(context.Background(), "1", tt.position, model.TemplatePage{File: strings.NewReader("d"), Settings: tt.settings})
+			diff := testutil.Diff(resp, tt.wantResp)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func Test_RemovePage(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	tests := []struct {
+		name      string
+		stored    string
+		page      string
+		wantSaved string
+		wantResp  *respModel.Response
+	}{
+		{
+			name:      "Success:: RemovePage:: by index",
+			stored:    storedPages,
+			page:      "0",
+			wantSaved: `{"Pages":[{"Base64PageData":"Yg==","Settings":{"name":"body"}},{"Base64PageData":"Yw=="}]}`,
+			wantResp:  &respModel.Response{Status: http.StatusOK, Message: "SUCCESS", Data: map[string]interface{}{"id": "1"}},
+		},
+		{
+			name:      "Success:: RemovePage:: by name",
+			stored:    storedPages,
+			page:      "body",
+			wantSaved: `{"Pages":[{"Base64PageData":"YQ=="},{"Base64PageData":"Yw=="}]}`,
+			wantResp:  &respModel.Response{Status: http.StatusOK, Message: "SUCCESS", Data: map[string]interface{}{"id": "1"}},
+		},
+		{
+			name:     "Failure:: RemovePage:: unknown page",
+			stored:   storedPages,
+			page:     "3",
+			wantResp: &respModel.Response{Status: http.StatusNotFound, Message: codes.GetErr(codes.ErrPageNotFound)},
+		},
+		{
+			name:     "Failure:: RemovePage:: last page",
+			stored:   `{"Pages":[{"Base64PageData":"YQ=="}]}`,
+			page:     "0",
+			wantResp: &respModel.Response{Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrLastPage)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDatasource := mock.NewMockDataSource(mockCtrl)
+			mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return([]byte(tt.stored), nil)
+			if tt.wantSaved != "" {
+				mockDatasource.EXPECT().SaveFile(gomock.Any(), "1", []byte(tt.wantSaved), time.Duration(0)).Return(nil)
+			}
+			rec := &htmlPdfServiceLogic{dsSvc: mockDatasource}
+			diff := testutil.Diff(rec.RemovePage(context.Background(), "1", tt.page), tt.wantResp)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
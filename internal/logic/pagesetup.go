@@ -0,0 +1,108 @@
+package logic
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	respModel "github.com/PereRohit/util/model"
+
+	"github.com/vatsal278/html-pdf-service/internal/codes"
+	"github.com/vatsal278/html-pdf-service/internal/model"
+)
+
+// pageSetup returns the page setup of the template with the overrides of the request applied.
+// Only the options listed in the page overrides of the template may be overridden.
+func pageSetup(settings model.TemplateSettings, raw json.RawMessage) (*model.PageSetup, *respModel.Response) {
+	if len(raw) == 0 {
+		return settings.PageSetup, nil
+	}
+	var overrides map[string]json.RawMessage
+	err := json.Unmarshal(raw, &overrides)
+	if err != nil {
+		return nil, &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidPageSetup),
+			Data:    nil,
+		}
+	}
+	allowed := model.DefaultPageOverrides
+	if settings.PageOverrides != nil {
+		allowed = *settings.PageOverrides
+	}
+	keys := make([]string, 0, len(overrides))
+	for k := range overrides {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !contains(allowed, k) {
+			return nil, &respModel.Response{
+				Status:  http.StatusBadRequest,
+				Message: codes.GetErr(codes.ErrPageSetupOverride),
+				Data:    k,
+			}
+		}
+	}
+	var setup model.PageSetup
+	if settings.PageSetup != nil {
+		setup = *settings.PageSetup
+		// json.Unmarshal writes through the margin pointers shared with the template
+		setup.MarginTop = copyUint(setup.MarginTop)
+		setup.MarginBottom = copyUint(setup.MarginBottom)
+		setup.MarginLeft = copyUint(setup.MarginLeft)
+		setup.MarginRight = copyUint(setup.MarginRight)
+	}
+	// a named size replaces a custom size of the template and the other way round
+	_, size := overrides["page_size"]
+	_, width := overrides["page_width"]
+	_, height := overrides["page_height"]
+	if size && !width && !height {
+		setup.PageWidth, setup.PageHeight = 0, 0
+	}
+	if !size && (width || height) {
+		setup.PageSize = ""
+	}
+	err = json.Unmarshal(raw, &setup)
+	if err != nil || !setup.Valid() {
+		return nil, &respModel.Response{
+			Status:  http.StatusBadRequest,
+			Message: codes.GetErr(codes.ErrInvalidPageSetup),
+			Data:    nil,
+		}
+	}
+	return &setup, nil
+}
+
+// validPageSetup checks the page setup and the page overrides captured at register time
+func validPageSetup(settings model.TemplateSettings) bool {
+	if settings.PageSetup != nil && !settings.PageSetup.Valid() {
+		return false
+	}
+	if settings.PageOverrides == nil {
+		return true
+	}
+	for _, o := range *settings.PageOverrides {
+		if !model.ValidPageSetupOption(o) {
+			return false
+		}
+	}
+	return true
+}
+
+func copyUint(p *uint) *uint {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package logic
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	respModel "github.com/PereRohit/util/model"
+	"github.com/PereRohit/util/testutil"
+	"github.com/golang/mock/gomock"
+
+	"github.com/vatsal278/html-pdf-service/internal/codes"
+	"github.com/vatsal278/html-pdf-service/internal/model"
+	"github.com/vatsal278/html-pdf-service/pkg/mock"
+)
+
+func Test_pageSetup(t *testing.T) {
+	five, ten := uint(5), uint(10)
+	none := []string{}
+	tests := []struct {
+		name     string
+		settings model.TemplateSettings
+		raw      string
+		want     *model.PageSetup
+		wantResp *respModel.Response
+	}{
+		{
+			name:     "Success:: pageSetup:: template defaults without overrides",
+			settings: model.TemplateSettings{PageSetup: &model.PageSetup{PageSize: "A5"}},
+			want:     &model.PageSetup{PageSize: "A5"},
+		},
+		{
+			name:     "Success:: pageSetup:: request overrides template",
+			settings: model.TemplateSettings{PageSetup: &model.PageSetup{PageSize: "A5", MarginTop: &ten, Grayscale: true}},
+			raw:      `{"orientation":"landscape","margin_top":5,"grayscale":false}`,
+			want:     &model.PageSetup{PageSize: "A5", Orientation: "landscape", MarginTop: &five},
+		},
+		{
+			name:     "Success:: pageSetup:: custom size replaces named size",
+			settings: model.TemplateSettings{PageSetup: &model.PageSetup{PageSize: "A5"}},
+			raw:      `{"page_width":100,"page_height":200}`,
+			want:     &model.PageSetup{PageWidth: 100, PageHeight: 200},
+		},
+		{
+			name:     "Success:: pageSetup:: named size replaces custom size",
+			settings: model.TemplateSettings{PageSetup: &model.PageSetup{PageWidth: 100, PageHeight: 200}},
+			raw:      `{"page_size":"Letter"}`,
+			want:     &model.PageSetup{PageSize: "Letter"},
+		},
+		{
+			name:     "Success:: pageSetup:: template allows dpi",
+			settings: model.TemplateSettings{PageOverrides: &[]string{"dpi"}},
+			raw:      `{"dpi":300}`,
+			want:     &model.PageSetup{Dpi: 300},
+		},
+		{
+			name:     "Failure:: pageSetup:: option not in default allowlist",
+			raw:      `{"dpi":300}`,
+			wantResp: &respModel.Response{Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrPageSetupOverride), Data: "dpi"},
+		},
+		{
+			name:     "Failure:: pageSetup:: template forbids overrides",
+			settings: model.TemplateSettings{PageOverrides: &none},
+			raw:      `{"orientation":"landscape"}`,
+			wantResp: &respModel.Response{Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrPageSetupOverride), Data: "orientation"},
+		},
+		{
+			name:     "Failure:: pageSetup:: invalid value",
+			raw:      `{"page_size":"A99"}`,
+			wantResp: &respModel.Response{Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrInvalidPageSetup), Data: nil},
+		},
+		{
+			name:     "Failure:: pageSetup:: not an object",
+			raw:      `[]`,
+			wantResp: &respModel.Response{Status: http.StatusBadRequest, Message: codes.GetErr(codes.ErrInvalidPageSetup), Data: nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template, _ := json.Marshal(tt.settings)
+			got, resp := pageSetup(tt.settings, json.RawMessage(tt.raw))
+			after, _ := json.Marshal(tt.settings)
+			diff := testutil.Diff(string(after), string(template))
+			if diff != "" {
+				t.Error("template settings changed", diff)
+			}
+			diff = testutil.Diff(resp, tt.wantResp)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+			diff = testutil.Diff(got, tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func Test_validPageSetup(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings model.TemplateSettings
+		want     bool
+	}{
+		{name: "unset", settings: model.TemplateSettings{}, want: true},
+		{name: "valid", settings: model.TemplateSettings{PageSetup: &model.PageSetup{PageSize: "a4", Orientation: "Landscape"}, PageOverrides: &[]string{"zoom"}}, want: true},
+		{name: "width without height", settings: model.TemplateSettings{PageSetup: &model.PageSetup{PageWidth: 100}}, want: false},
+		{name: "size and custom size", settings: model.TemplateSettings{PageSetup: &model.PageSetup{PageSize: "A4", PageWidth: 100, PageHeight: 100}}, want: false},
+		{name: "dpi too high", settings: model.TemplateSettings{PageSetup: &model.PageSetup{Dpi: 5000}}, want: false},
+		{name: "unknown override", settings: model.TemplateSettings{PageOverrides: &[]string{"copies"}}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validPageSetup(tt.settings); got != tt.want {
+				t.Errorf("want %v got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_HtmlToPdf_PageSetup(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	stored := []byte(`{"Pages":[{"Base64PageData":"` + base64.StdEncoding.EncodeToString([]byte("<p>1</p>")) + `"}],"Settings":{"page_setup":{"page_size":"A5"}}}`)
+	mockDatasource := mock.NewMockDataSource(mockCtrl)
+	mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(stored, nil)
+	mockHtmlToPdf := mock.NewMockHtmlToPdf(mockCtrl)
+	mockHtmlToPdf.EXPECT().GeneratePdf(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, w io.Writer, b []byte) error {
+		var doc struct {
+			Settings model.TemplateSettings
+		}
+		err := json.Unmarshal(b, &doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		diff := testutil.Diff(doc.Settings.PageSetup, &model.PageSetup{PageSize: "A5", Orientation: "landscape"})
+		if diff != "" {
+			t.Error(testutil.Callers(), diff)
+		}
+		return renderPdf(t, w)
+	})
+	rec := &htmlPdfServiceLogic{dsSvc: mockDatasource, htSvc: mockHtmlToPdf}
+	resp := rec.HtmlToPdf(context.Background(), new(bytes.Buffer), &model.GenerateReq{Id: "1", PageSetup: json.RawMessage(`{"orientation":"landscape"}`)})
+	if resp.Status != http.StatusOK {
+		t.Errorf("want %v got %v", http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,64 @@
+package logic
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/PereRohit/util/log"
+	respModel "github.com/PereRohit/util/model"
+
+	"github.com/vatsal278/html-pdf-service/internal/codes"
+	"github.com/vatsal278/html-pdf-service/internal/model"
+	"github.com/vatsal278/html-pdf-service/internal/pdf"
+)
+
+// outputProfile returns the conformance profile of the generated pdf, the output of the request replaces the output
+// of the template settings stored in the document z
+func outputProfile(req *model.GenerateReq, z map[string]interface{}) string {
+	if req.Output != nil {
+		return req.Output.Profile
+	}
+	switch s := z["Settings"].(type) {
+	case model.TemplateSettings:
+		if s.Output != nil {
+			return s.Output.Profile
+		}
+	case map[string]interface{}:
+		o, _ := s["output"].(map[string]interface{})
+		p, _ := o["profile"].(string)
+		return p
+	}
+	return ""
+}
+
+// convertPdfA converts the document d to PDF/A-2b and writes it once the validator accepts the result.
+// A document the validator rejects is not written, the response lists the problems.
+func convertPdfA(w io.Writer, d *pdf.Document) *respModel.Response {
+	pdf.ConvertPdfA2b(d, time.Now())
+	buf := new(bytes.Buffer)
+	err := d.Write(buf)
+	if err == nil {
+		err = pdf.ValidatePdfA2b(buf.Bytes())
+	}
+	var verr *pdf.ValidationError
+	if errors.As(err, &verr) {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusUnprocessableEntity,
+			Message: codes.GetErr(codes.ErrPdfAValidation),
+			Data:    verr.Problems,
+		}
+	}
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrPdfAConversion),
+			Data:    nil,
+		}
+	}
+	return writeRendered(w, model.OutputPdf, buf.Bytes())
+}
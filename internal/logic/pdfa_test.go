@@ -0,0 +1,190 @@
+package logic
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	respModel "github.com/PereRohit/util/model"
+	"github.com/PereRohit/util/testutil"
+	"github.com/golang/mock/gomock"
+
+	"github.com/vatsal278/html-pdf-service/internal/codes"
+	"github.com/vatsal278/html-pdf-service/internal/model"
+	"github.com/vatsal278/html-pdf-service/internal/pdf"
+	"github.com/vatsal278/html-pdf-service/pkg/mock"
+)
+
+// rendered writes a one page pdf set in the font with the base font name, the way the native renderer does
+func rendered(t *testing.T, font string) []byte {
+	t.Helper()
+	w := pdf.NewWriter()
+	pages := w.Reserve()
+	f := w.Add("<< /Type /Font /Subtype /Type1 /BaseFont /" + font + " >>")
+	content := w.AddStream("", []byte("BT /F1 12 Tf 10 10 Td (hi) Tj ET"))
+	page := w.Add("<< /Type /Page /Parent " + pages.String() + " /MediaBox [0 0 100 100] /Resources << /Font << /F1 " + f.String() + " >> >> /Contents " + content.String() + " >>")
+	w.Set(pages, "<< /Type /Pages /Kids ["+page.String()+"] /Count 1 >>")
+	root := w.Add("<< /Type /Catalog /Pages " + pages.String() + " >>")
+	out := new(bytes.Buffer)
+	if err := w.WriteTo(out, root, w.Add("<< /Title (Invoice) >>")); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+// renderPdf writes the one page pdf of rendered into w like a renderer does
+func renderPdf(t *testing.T, w io.Writer) error {
+	_, err := w.Write(rendered(t, "Helvetica"))
+	return err
+}
+
+func Test_HtmlToPdf_PdfA(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	page := base64.StdEncoding.EncodeToString([]byte("<p>{{.Name}}</p>"))
+	plain := []byte(`{"Pages":[{"Base64PageData":"` + page + `"}]}`)
+	archived := []byte(`{"Pages":[{"Base64PageData":"` + page + `"}],"Settings":{"output":{"profile":"pdfa-2b"}}}`)
+	renders := func(stored []byte, doc []byte) *htmlPdfServiceLogic {
+		mockDatasource := mock.NewMockDataSource(mockCtrl)
+		mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(stored, nil)
+		mockHtmlToPdf := mock.NewMockHtmlToPdf(mockCtrl)
+		mockHtmlToPdf.EXPECT().GeneratePdf(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, w io.Writer, _ []byte) error {
+				_, err := w.Write(doc)
+				return err
+			})
+		return &htmlPdfServiceLogic{dsSvc: mockDatasource, htSvc: mockHtmlToPdf}
+	}
+	conforms := func(x *respModel.Response, w *bytes.Buffer) {
+		if x.Status != http.StatusOK {
+			t.Fatalf("want %v got %v", http.StatusOK, x)
+		}
+		if err := pdf.ValidatePdfA2b(w.Bytes()); err != nil {
+			t.Error(err)
+		}
+	}
+	tests := []struct {
+		name         string
+		req          *model.GenerateReq
+		setupFunc    func() *htmlPdfServiceLogic
+		validateFunc func(*respModel.Response, *bytes.Buffer)
+	}{
+		{
+			name: "Success:: HtmlToPdf:: profile of the template",
+			req:  &model.GenerateReq{},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return renders(archived, rendered(t, "Helvetica"))
+			},
+			validateFunc: conforms,
+		},
+		{
+			name: "Success:: HtmlToPdf:: profile of the request",
+			req:  &model.GenerateReq{Output: &model.Output{Profile: model.ProfilePdfA2b}},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return renders(plain, rendered(t, "Courier-Bold"))
+			},
+			validateFunc: conforms,
+		},
+		{
+			name: "Success:: HtmlToPdf:: request without a profile overrides the template",
+			req:  &model.GenerateReq{Output: &model.Output{}},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return renders(archived, rendered(t, "Courier"))
+			},
+			validateFunc: func(x *respModel.Response, w *bytes.Buffer) {
+				if x.Status != http.StatusOK || !bytes.Equal(w.Bytes(), rendered(t, "Courier")) {
+					t.Errorf("want the pdf of the renderer got %v %q", x, w.String())
+				}
+			},
+		},
+		{
+			name: "Failure:: HtmlToPdf:: unsupported profile",
+			req:  &model.GenerateReq{Output: &model.Output{Profile: "pdfa-1a"}},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return &htmlPdfServiceLogic{}
+			},
+			validateFunc: func(x *respModel.Response, _ *bytes.Buffer) {
+				diff := testutil.Diff(x, &respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrInvalidOutputProfile),
+				})
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+		},
+		{
+			name: "Failure:: HtmlToPdf:: profile of an image",
+			req:  &model.GenerateReq{Format: model.OutputPng, Output: &model.Output{Profile: model.ProfilePdfA2b}},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return &htmlPdfServiceLogic{}
+			},
+			validateFunc: func(x *respModel.Response, _ *bytes.Buffer) {
+				if x.Status != http.StatusBadRequest || x.Message != codes.GetErr(codes.ErrInvalidOutput) {
+					t.Errorf("want %v got %v", codes.GetErr(codes.ErrInvalidOutput), x)
+				}
+			},
+		},
+		{
+			name: "Failure:: HtmlToPdf:: renderer output is no pdf",
+			req:  &model.GenerateReq{},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return renders(archived, []byte("not a pdf"))
+			},
+			validateFunc: func(x *respModel.Response, w *bytes.Buffer) {
+				diff := testutil.Diff(x, &respModel.Response{
+					Status:  http.StatusInternalServerError,
+					Message: codes.GetErr(codes.ErrPdfAConversion),
+				})
+				if diff != "" || w.Len() != 0 {
+					t.Error(testutil.Callers(), diff, w.String())
+				}
+			},
+		},
+		{
+			name: "Failure:: HtmlToPdf:: font without a substitute",
+			req:  &model.GenerateReq{},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return renders(archived, rendered(t, "ZapfDingbats"))
+			},
+			validateFunc: func(x *respModel.Response, w *bytes.Buffer) {
+				diff := testutil.Diff(x, &respModel.Response{
+					Status:  http.StatusUnprocessableEntity,
+					Message: codes.GetErr(codes.ErrPdfAValidation),
+					Data:    []string{"font ZapfDingbats is not embedded"},
+				})
+				if diff != "" || w.Len() != 0 {
+					t.Error(testutil.Callers(), diff, w.String())
+				}
+			},
+		},
+		{
+			name: "Failure:: HtmlToPdf:: render fails",
+			req:  &model.GenerateReq{},
+			setupFunc: func() *htmlPdfServiceLogic {
+				mockDatasource := mock.NewMockDataSource(mockCtrl)
+				mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(archived, nil)
+				mockHtmlToPdf := mock.NewMockHtmlToPdf(mockCtrl)
+				mockHtmlToPdf.EXPECT().GeneratePdf(gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("exit status 1"))
+				return &htmlPdfServiceLogic{dsSvc: mockDatasource, htSvc: mockHtmlToPdf}
+			},
+			validateFunc: func(x *respModel.Response, _ *bytes.Buffer) {
+				if x.Status != http.StatusInternalServerError || x.Message != codes.GetErr(codes.ErrConvertingToPdf) {
+					t.Errorf("want %v got %v", codes.GetErr(codes.ErrConvertingToPdf), x)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := tt.setupFunc()
+			w := bytes.NewBuffer(nil)
+			tt.req.Id = "1"
+			tt.validateFunc(rec.HtmlToPdf(context.Background(), w, tt.req), w)
+		})
+	}
+}
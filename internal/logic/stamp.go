@@ -0,0 +1,184 @@
+package logic
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PereRohit/util/log"
+	respModel "github.com/PereRohit/util/model"
+
+	"github.com/vatsal278/html-pdf-service/internal/codes"
+	"github.com/vatsal278/html-pdf-service/internal/model"
+	"github.com/vatsal278/html-pdf-service/internal/pdf"
+)
+
+// maxStampPixels bounds the size of a decoded stamp image
+const maxStampPixels = 4096 * 4096
+
+// timestampLayout is the layout of the time appended to the text of a stamp
+const timestampLayout = "2006-01-02 15:04:05 MST"
+
+// documentStamps returns the stamps of the generated pdf and the assets of the template, the stamps of the request
+// replace the stamps of the template settings stored in the document z
+func documentStamps(req *model.GenerateReq, z map[string]interface{}) ([]model.Stamp, map[string][]byte, error) {
+	settings, err := settingsFromDoc(z)
+	if err != nil {
+		return nil, nil, err
+	}
+	if req.Stamps != nil {
+		return *req.Stamps, settings.Assets, nil
+	}
+	return settings.Stamps, settings.Assets, nil
+}
+
+// timestamped reports whether the pdf of req is stamped with the time it is generated, such a pdf is rendered for
+// every request instead of being served from the render cache
+func timestamped(req *model.GenerateReq, tmpl []byte) bool {
+	var stamps []model.Stamp
+	if req.Stamps != nil {
+		stamps = *req.Stamps
+	} else {
+		var d struct {
+			Settings struct {
+				Stamps []model.Stamp `json:"stamps"`
+			}
+		}
+		// a settings object of another shape has no stamps
+		_ = json.Unmarshal(tmpl, &d)
+		stamps = d.Settings.Stamps
+	}
+	for _, s := range stamps {
+		if s.Timestamp {
+			return true
+		}
+	}
+	return false
+}
+
+// postProcess checks the page limit, stamps the pdf of the renderer and converts it to the output profile before it
+// is written into w, the html is not rendered again
+func postProcess(w io.Writer, doc []byte, limits model.Limits, profile string, stamps []model.Stamp, assets map[string][]byte) *respModel.Response {
+	overlays, resp := stampOverlays(stamps, assets, time.Now())
+	if resp != nil {
+		return resp
+	}
+	d, err := pdf.Parse(doc)
+	if err != nil {
+		log.Error(err)
+		code := codes.ErrConvertingToPdf
+		if profile != "" {
+			code = codes.ErrPdfAConversion
+		} else if len(stamps) > 0 {
+			code = codes.ErrStampingPdf
+		}
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(code),
+			Data:    nil,
+		}
+	}
+	if resp := pageLimit(d, limits); resp != nil {
+		return resp
+	}
+	if profile == "" && len(overlays) == 0 {
+		return writeRendered(w, model.OutputPdf, doc)
+	}
+	d.Stamp(overlays)
+	if profile == model.ProfilePdfA2b {
+		return convertPdfA(w, d)
+	}
+	buf := new(bytes.Buffer)
+	err = d.Write(buf)
+	if err != nil {
+		log.Error(err)
+		return &respModel.Response{
+			Status:  http.StatusInternalServerError,
+			Message: codes.GetErr(codes.ErrStampingPdf),
+			Data:    nil,
+		}
+	}
+	return writeRendered(w, model.OutputPdf, buf.Bytes())
+}
+
+// stampOverlays returns the overlays of the stamps with the defaults applied, images are decoded from the assets
+// of the template and timestamps show now
+func stampOverlays(stamps []model.Stamp, assets map[string][]byte, now time.Time) ([]pdf.Overlay, *respModel.Response) {
+	overlays := make([]pdf.Overlay, 0, len(stamps))
+	for _, s := range stamps {
+		o := pdf.Overlay{
+			Text:     s.Text,
+			FontSize: s.FontSize,
+			Width:    s.Width,
+			Opacity:  s.Opacity,
+			Rotation: s.Rotation,
+			Margin:   model.DefaultStampMargin,
+			Pages:    s.Pages,
+		}
+		o.AnchorX, o.AnchorY = s.Anchor()
+		if s.Timestamp {
+			o.Text = strings.TrimSpace(o.Text + " " + now.Format(timestampLayout))
+		}
+		if o.FontSize == 0 {
+			o.FontSize = model.DefaultStampSize
+		}
+		if o.Width == 0 {
+			o.Width = model.DefaultStampWidth
+		}
+		if o.Opacity == 0 {
+			o.Opacity = 1
+		}
+		if s.Margin != nil {
+			o.Margin = *s.Margin
+		}
+		color := s.Color
+		if color == "" {
+			color = model.DefaultStampColor
+		}
+		for i := range o.Color {
+			c, _ := strconv.ParseUint(color[1+2*i:3+2*i], 16, 8)
+			o.Color[i] = float64(c) / 255
+		}
+		if o.Text == "" {
+			img, err := stampImage(assets, s.Image)
+			if err != nil {
+				log.Error(err)
+				return nil, &respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrStampImage),
+					Data:    s.Image,
+				}
+			}
+			o.Image = img
+		}
+		overlays = append(overlays, o)
+	}
+	return overlays, nil
+}
+
+// stampImage decodes the png, jpeg or gif asset name
+func stampImage(assets map[string][]byte, name string) (image.Image, error) {
+	name, _ = model.CleanAssetName(name)
+	b, ok := assets[name]
+	if !ok {
+		return nil, fmt.Errorf("asset %s not found", name)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width == 0 || cfg.Height == 0 || cfg.Width*cfg.Height > maxStampPixels {
+		return nil, fmt.Errorf("image %s of %dx%d pixels", name, cfg.Width, cfg.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(b))
+	return img, err
+}
@@ -0,0 +1,253 @@
+package logic
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	respModel "github.com/PereRohit/util/model"
+	"github.com/PereRohit/util/testutil"
+	"github.com/golang/mock/gomock"
+
+	"github.com/vatsal278/html-pdf-service/internal/codes"
+	"github.com/vatsal278/html-pdf-service/internal/model"
+	"github.com/vatsal278/html-pdf-service/internal/pdf"
+	"github.com/vatsal278/html-pdf-service/pkg/mock"
+)
+
+// stampContents returns the decoded content streams of the first page of the pdf b
+func stampContents(t *testing.T, b []byte) string {
+	t.Helper()
+	d, err := pdf.Parse(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := d.Dict(d.Pages()[0])
+	var streams []string
+	contents, ok := d.Resolve(page["Contents"]).(pdf.Array)
+	if !ok {
+		contents = pdf.Array{page["Contents"]}
+	}
+	for _, c := range contents {
+		data, err := pdf.Decode(d.Resolve(c).(*pdf.Stream))
+		if err != nil {
+			t.Fatal(err)
+		}
+		streams = append(streams, string(data))
+	}
+	return strings.Join(streams, "|")
+}
+
+func Test_HtmlToPdf_Stamps(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	logo := new(bytes.Buffer)
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	img.Set(0, 0, color.NRGBA{R: 255, A: 255})
+	if err := png.Encode(logo, img); err != nil {
+		t.Fatal(err)
+	}
+	page := base64.StdEncoding.EncodeToString([]byte("<p>{{.Name}}</p>"))
+	assets := `"assets":{"logo.png":"` + base64.StdEncoding.EncodeToString(logo.Bytes()) + `"}`
+	draft := []byte(`{"Pages":[{"Base64PageData":"` + page + `"}],"Settings":{"stamps":[{"text":"DRAFT","rotation":45,"opacity":0.3}],` + assets + `}}`)
+	renders := func(stored []byte, doc []byte) *htmlPdfServiceLogic {
+		mockDatasource := mock.NewMockDataSource(mockCtrl)
+		mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(stored, nil)
+		mockHtmlToPdf := mock.NewMockHtmlToPdf(mockCtrl)
+		mockHtmlToPdf.EXPECT().GeneratePdf(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, w io.Writer, _ []byte) error {
+				_, err := w.Write(doc)
+				return err
+			})
+		return &htmlPdfServiceLogic{dsSvc: mockDatasource, htSvc: mockHtmlToPdf}
+	}
+	stamped := func(want ...string) func(*respModel.Response, *bytes.Buffer) {
+		return func(x *respModel.Response, w *bytes.Buffer) {
+			if x.Status != http.StatusOK {
+				t.Fatalf("want %v got %v", http.StatusOK, x)
+			}
+			got := stampContents(t, w.Bytes())
+			for _, s := range want {
+				if !strings.Contains(got, s) {
+					t.Errorf("the content %q lacks %q", got, s)
+				}
+			}
+		}
+	}
+	tests := []struct {
+		name         string
+		req          *model.GenerateReq
+		setupFunc    func() *htmlPdfServiceLogic
+		validateFunc func(*respModel.Response, *bytes.Buffer)
+	}{
+		{
+			name: "Success:: HtmlToPdf:: stamps of the template",
+			req:  &model.GenerateReq{},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return renders(draft, rendered(t, "Helvetica"))
+			},
+			validateFunc: stamped("(hi) Tj", "/GS0 gs\n0.707 0.707 -0.707 0.707 50 50 cm", "(DRAFT) Tj"),
+		},
+		{
+			name: "Success:: HtmlToPdf:: stamps of the request",
+			req: &model.GenerateReq{Stamps: &[]model.Stamp{
+				{Text: "COPY", Timestamp: true, Position: model.PositionTopRight, Color: "#ff0000"},
+				{Image: "logo.png", Width: 40, Position: model.PositionBottomLeft, Pages: []int{1}},
+			}},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return renders(draft, rendered(t, "Helvetica"))
+			},
+			validateFunc: stamped("1 0 0 1 80 80 cm\n1 0 0 rg", "(COPY "+time.Now().Format("2006-01-02"),
+				"1 0 0 1 20 20 cm\n40 0 0 20 0 0 cm\n/Im0 Do"),
+		},
+		{
+			name: "Success:: HtmlToPdf:: request without stamps overrides the template",
+			req:  &model.GenerateReq{Stamps: &[]model.Stamp{}},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return renders(draft, rendered(t, "Courier"))
+			},
+			validateFunc: func(x *respModel.Response, w *bytes.Buffer) {
+				if x.Status != http.StatusOK || !bytes.Equal(w.Bytes(), rendered(t, "Courier")) {
+					t.Errorf("want the pdf of the renderer got %v %q", x, w.String())
+				}
+			},
+		},
+		{
+			name: "Success:: HtmlToPdf:: stamps and pdf/a",
+			req:  &model.GenerateReq{Output: &model.Output{Profile: model.ProfilePdfA2b}},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return renders(draft, rendered(t, "Helvetica"))
+			},
+			validateFunc: func(x *respModel.Response, w *bytes.Buffer) {
+				if x.Status != http.StatusOK {
+					t.Fatalf("want %v got %v", http.StatusOK, x)
+				}
+				if err := pdf.ValidatePdfA2b(w.Bytes()); err != nil {
+					t.Error(err)
+				}
+			},
+		},
+		{
+			name: "Failure:: HtmlToPdf:: invalid stamp",
+			req:  &model.GenerateReq{Stamps: &[]model.Stamp{{Text: "DRAFT", Opacity: 2}}},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return &htmlPdfServiceLogic{}
+			},
+			validateFunc: func(x *respModel.Response, _ *bytes.Buffer) {
+				diff := testutil.Diff(x, &respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrInvalidStamp),
+				})
+				if diff != "" {
+					t.Error(testutil.Callers(), diff)
+				}
+			},
+		},
+		{
+			name: "Failure:: HtmlToPdf:: stamps of an image",
+			req:  &model.GenerateReq{Format: model.OutputPng, Stamps: &[]model.Stamp{{Text: "DRAFT"}}},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return &htmlPdfServiceLogic{}
+			},
+			validateFunc: func(x *respModel.Response, _ *bytes.Buffer) {
+				if x.Status != http.StatusBadRequest || x.Message != codes.GetErr(codes.ErrInvalidOutput) {
+					t.Errorf("want %v got %v", codes.GetErr(codes.ErrInvalidOutput), x)
+				}
+			},
+		},
+		{
+			name: "Failure:: HtmlToPdf:: stamp image not found",
+			req:  &model.GenerateReq{Stamps: &[]model.Stamp{{Image: "missing.png"}}},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return renders(draft, rendered(t, "Helvetica"))
+			},
+			validateFunc: func(x *respModel.Response, w *bytes.Buffer) {
+				diff := testutil.Diff(x, &respModel.Response{
+					Status:  http.StatusBadRequest,
+					Message: codes.GetErr(codes.ErrStampImage),
+					Data:    "missing.png",
+				})
+				if diff != "" || w.Len() != 0 {
+					t.Error(testutil.Callers(), diff, w.String())
+				}
+			},
+		},
+		{
+			name: "Failure:: HtmlToPdf:: renderer output is no pdf",
+			req:  &model.GenerateReq{},
+			setupFunc: func() *htmlPdfServiceLogic {
+				return renders(draft, []byte("not a pdf"))
+			},
+			validateFunc: func(x *respModel.Response, w *bytes.Buffer) {
+				diff := testutil.Diff(x, &respModel.Response{
+					Status:  http.StatusInternalServerError,
+					Message: codes.GetErr(codes.ErrStampingPdf),
+				})
+				if diff != "" || w.Len() != 0 {
+					t.Error(testutil.Callers(), diff, w.String())
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := tt.setupFunc()
+			w := bytes.NewBuffer(nil)
+			tt.req.Id = "1"
+			tt.validateFunc(rec.HtmlToPdf(context.Background(), w, tt.req), w)
+		})
+	}
+}
+
+func Test_HtmlToPdf_TimestampNotCached(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	page := base64.StdEncoding.EncodeToString([]byte("<p>copy</p>"))
+	stored := []byte(`{"Pages":[{"Base64PageData":"` + page + `"}],"Settings":{"stamps":[{"text":"COPY","timestamp":true}]}}`)
+	mockDatasource := mock.NewMockDataSource(mockCtrl)
+	mockDatasource.EXPECT().GetFile(gomock.Any(), "1").Return(stored, nil)
+	mockHtmlToPdf := mock.NewMockHtmlToPdf(mockCtrl)
+	mockHtmlToPdf.EXPECT().GeneratePdf(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, w io.Writer, _ []byte) error {
+			_, err := w.Write(rendered(t, "Helvetica"))
+			return err
+		})
+	// the cache is neither read nor written
+	rec := NewHtmlPdfServiceLogic(mockDatasource, mockHtmlToPdf, WithRenderCache(mock.NewMockRenderCache(mockCtrl)))
+	w := new(bytes.Buffer)
+	resp := rec.HtmlToPdf(context.Background(), w, &model.GenerateReq{Id: "1"})
+	if resp.Status != http.StatusOK || !strings.Contains(stampContents(t, w.Bytes()), "(COPY ") {
+		t.Errorf("want a stamped pdf got %v", resp)
+	}
+}
+
+func Test_stampOverlays(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	margin := 0.0
+	got, resp := stampOverlays([]model.Stamp{
+		{Text: "DRAFT"},
+		{Timestamp: true, FontSize: 10, Color: "#FF8000", Opacity: 0.5, Rotation: -30, Position: model.PositionBottom, Margin: &margin, Pages: []int{-1}},
+	}, nil, now)
+	if resp != nil {
+		t.Fatal(resp)
+	}
+	diff := testutil.Diff(got, []pdf.Overlay{
+		{Text: "DRAFT", FontSize: 48, Color: [3]float64{128.0 / 255, 128.0 / 255, 128.0 / 255}, Width: 100, Opacity: 1, AnchorX: 0.5, AnchorY: 0.5, Margin: 20},
+		{Text: "2024-03-01 12:30:00 UTC", FontSize: 10, Color: [3]float64{1, 128.0 / 255, 0}, Width: 100, Opacity: 0.5, Rotation: -30, AnchorX: 0.5, Pages: []int{-1}},
+	})
+	if diff != "" {
+		t.Error(diff)
+	}
+	_, resp = stampOverlays([]model.Stamp{{Image: "logo.png"}}, map[string][]byte{"logo.png": []byte("no image")}, now)
+	if resp == nil || resp.Message != codes.GetErr(codes.ErrStampImage) {
+		t.Errorf("want %v got %v", codes.GetErr(codes.ErrStampImage), resp)
+	}
+}
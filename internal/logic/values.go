@@ -0,0 +1,95 @@
+package logic
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/vatsal278/html-pdf-service/internal/model"
+)
+
+// normalizeValues converts json.Number values decoded by the handler into the narrowest type that keeps them exact.
+// Integers that fit into int64 become int64, other integers stay json.Number so that large ids are never rendered
+// in scientific notation and the remaining numbers become float64. RFC 3339 strings become time.Time if parseTime is set.
+func normalizeValues(v interface{}, parseTime bool) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			t[k] = normalizeValues(e, parseTime)
+		}
+		return t
+	case []interface{}:
+		for i, e := range t {
+			t[i] = normalizeValues(e, parseTime)
+		}
+		return t
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		if !strings.ContainsAny(t.String(), ".eE") {
+			return t
+		}
+		f, err := t.Float64()
+		if err != nil || math.IsInf(f, 0) {
+			return t
+		}
+		return f
+	case string:
+		if !parseTime {
+			return t
+		}
+		tm, err := time.Parse(time.RFC3339, t)
+		if err != nil {
+			return t
+		}
+		return tm
+	}
+	return v
+}
+
+// settingsFromDoc extracts the template settings stored next to the wkhtmltopdf document.
+func settingsFromDoc(z map[string]interface{}) (model.TemplateSettings, error) {
+	var s model.TemplateSettings
+	raw, ok := z["Settings"]
+	if !ok || raw == nil {
+		return s, nil
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return s, err
+	}
+	err = json.Unmarshal(b, &s)
+	return s, err
+}
+
+// withSettings stores the template settings into the wkhtmltopdf json document produced by the renderer.
+func withSettings(doc []byte, s model.TemplateSettings) ([]byte, error) {
+	var z map[string]interface{}
+	err := json.Unmarshal(doc, &z)
+	if err != nil {
+		return nil, err
+	}
+	z["Settings"] = s
+	return json.Marshal(z)
+}
+
+// missingKeyOption resolves the missingkey option, the request value takes precedence over the template default.
+func missingKeyOption(req *model.GenerateReq, s model.TemplateSettings) string {
+	if req.MissingKey != "" {
+		return req.MissingKey
+	}
+	if s.MissingKey != "" {
+		return s.MissingKey
+	}
+	return model.MissingKeyDefault
+}
+
+// parseTimeOption resolves whether RFC 3339 strings are converted, the request value takes precedence over the template default.
+func parseTimeOption(req *model.GenerateReq, s model.TemplateSettings) bool {
+	if req.ParseTime != nil {
+		return *req.ParseTime
+	}
+	return s.ParseTime != nil && *s.ParseTime
+}
@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+// render cache stores
+const (
+	CacheStoreMemory     = "memory"
+	CacheStoreDataSource = "datasource"
+)
+
+// default render cache settings, used for every setting that is not configured
+const (
+	DefaultCacheTTL          = 600000
+	DefaultCacheMaxSize      = 64 << 20
+	DefaultCacheMaxEntrySize = 8 << 20
+)
+
+// RenderCacheConfig configures the cache of rendered documents, the cache is off when no store is set
+type RenderCacheConfig struct {
+	// Store is memory or datasource
+	Store string `json:"store"`
+	// TTL is the time in milliseconds a rendered document is served from the cache
+	TTL int `json:"ttl_ms"`
+	// MaxSize is the total size in bytes of the cached documents, the memory store evicts the least recently used
+	// documents beyond it and the datasource store the oldest ones
+	MaxSize int64 `json:"max_size"`
+	// MaxEntrySize is the size in bytes of the largest document cached, larger documents are rendered every time
+	MaxEntrySize int64 `json:"max_entry_size"`
+}
+
+// WithDefaults returns the config with the defaults filled in
+func (c RenderCacheConfig) WithDefaults() RenderCacheConfig {
+	if c.TTL <= 0 {
+		c.TTL = DefaultCacheTTL
+	}
+	if c.MaxSize <= 0 {
+		c.MaxSize = DefaultCacheMaxSize
+	}
+	if c.MaxEntrySize <= 0 {
+		c.MaxEntrySize = DefaultCacheMaxEntrySize
+	}
+	if c.MaxEntrySize > c.MaxSize {
+		c.MaxEntrySize = c.MaxSize
+	}
+	return c
+}
+
+// TTLDuration returns the ttl as a duration
+func (c RenderCacheConfig) TTLDuration() time.Duration {
+	return time.Duration(c.TTL) * time.Millisecond
+}
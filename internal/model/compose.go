@@ -0,0 +1,31 @@
+package model
+
+// MaxComposeParts is the largest number of parts of a compose request
+const MaxComposeParts = 100
+
+// ComposePart is a part of a composed document, either a registered template rendered with the generate options
+// or an uploaded pdf
+type ComposePart struct {
+	// Template is the id of a registered template
+	Template string `json:"template,omitempty"`
+	// File is the name of the uploaded pdf
+	File string `json:"file,omitempty"`
+	// Title is the outline item of the part, the outline of the part is nested below it
+	Title string `json:"title,omitempty"`
+	GenerateReq
+}
+
+// ComposeReq merges the documents of the parts in order into one pdf
+type ComposeReq struct {
+	Parts       []ComposePart `json:"parts"`
+	Output      *Output       `json:"output,omitempty"`
+	Disposition string        `json:"disposition,omitempty"`
+	Filename    string        `json:"filename,omitempty"`
+	// Files are the uploaded pdfs by name
+	Files map[string][]byte `json:"-"`
+}
+
+// Document returns the options of the composed document, which are those of a generated pdf
+func (r ComposeReq) Document() GenerateReq {
+	return GenerateReq{Output: r.Output, Disposition: r.Disposition, Filename: r.Filename}
+}
@@ -0,0 +1,38 @@
+package model
+
+// limits of the table of contents and outline options
+const (
+	MaxOutlineDepth    = 9
+	MaxTocIndentation  = 10
+	MaxTocTextShrink   = 1
+	MaxTocHeaderLength = 200
+)
+
+// Toc holds the options of the generated table of contents, it is placed after the cover and before the pages
+type Toc struct {
+	Enabled            bool    `json:"enabled,omitempty"`
+	HeaderText         string  `json:"header_text,omitempty"`
+	DisableDottedLines bool    `json:"disable_dotted_lines,omitempty"`
+	DisableLinks       bool    `json:"disable_links,omitempty"`
+	LevelIndentation   uint    `json:"level_indentation,omitempty"`
+	TextSizeShrink     float64 `json:"text_size_shrink,omitempty"`
+	// Xsl is a style sheet replacing the default layout of the table of contents, it can only be set at register time
+	Xsl []byte `json:"xsl,omitempty"`
+}
+
+// Valid checks the options of the table of contents
+func (t Toc) Valid() bool {
+	return len(t.HeaderText) <= MaxTocHeaderLength && t.LevelIndentation <= MaxTocIndentation &&
+		t.TextSizeShrink >= 0 && t.TextSizeShrink <= MaxTocTextShrink
+}
+
+// Outline holds the options of the bookmark outline built from the headings of the pages
+type Outline struct {
+	Disabled bool `json:"disabled,omitempty"`
+	Depth    uint `json:"depth,omitempty"`
+}
+
+// Valid checks the options of the outline
+func (o Outline) Valid() bool {
+	return o.Depth <= MaxOutlineDepth
+}
@@ -0,0 +1,24 @@
+package model
+
+// MaxHeaderFooterSpacing is the largest spacing in millimeters between a header or footer and the content
+const MaxHeaderFooterSpacing = 200
+
+// HeaderFooterVars are the variables replaced in running headers and footers when the page is rendered
+var HeaderFooterVars = []string{
+	"page", "frompage", "topage", "section", "subsection", "title", "doctitle", "date", "isodate", "time",
+}
+
+// HeaderFooter holds a running header or footer of a template. Html is the template registered with the
+// template and the executed html in the document handed to the renderer.
+type HeaderFooter struct {
+	Html []byte `json:"html"`
+	// Spacing is the distance between the header or footer and the content in millimeters
+	Spacing float64 `json:"spacing,omitempty"`
+	// SkipFirstPage leaves the first page of the document without this header or footer, e.g. for a cover
+	SkipFirstPage bool `json:"skip_first_page,omitempty"`
+}
+
+// Valid checks the spacing of the header or footer
+func (h HeaderFooter) Valid() bool {
+	return h.Spacing >= 0 && h.Spacing <= MaxHeaderFooterSpacing
+}
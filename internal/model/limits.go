@@ -0,0 +1,75 @@
+package model
+
+import "time"
+
+// default execution limits, used for every limit that is not configured
+const (
+	DefaultMaxValuesSize  = 1 << 20
+	DefaultMaxValuesDepth = 64
+	DefaultMaxPageSize    = 8 << 20
+	DefaultExecTimeout    = 10000
+	DefaultMaxPages       = 1000
+	DefaultRenderTimeout  = 120000
+	DefaultMaxIterations  = 1000000
+)
+
+// Limits bounds the resources a single generate request may use, zero values fall back to the defaults
+type Limits struct {
+	// MaxValuesSize is the maximum size of the generate request body in bytes
+	MaxValuesSize int64 `json:"max_values_size"`
+	// MaxValuesDepth is the maximum nesting depth of values and records
+	MaxValuesDepth int `json:"max_values_depth"`
+	// MaxPageSize is the maximum size of the executed html of a page in bytes
+	MaxPageSize int64 `json:"max_page_size"`
+	// ExecTimeout is the time in milliseconds a page template may take to execute
+	ExecTimeout int `json:"exec_timeout_ms"`
+	// MaxPages is the maximum number of pages of the generated pdf, templates of more page objects are rejected
+	// before they are rendered
+	MaxPages int `json:"max_pages"`
+	// RenderTimeout is the time in milliseconds a document may take to render including the wait for a free renderer,
+	// templates may set a shorter deadline
+	RenderTimeout int `json:"render_timeout_ms"`
+	// MaxIterations is the maximum number of loop iterations and template calls of a page execution
+	MaxIterations int64 `json:"max_iterations"`
+}
+
+// WithDefaults returns the limits with the defaults filled in
+func (l Limits) WithDefaults() Limits {
+	if l.MaxValuesSize <= 0 {
+		l.MaxValuesSize = DefaultMaxValuesSize
+	}
+	if l.MaxValuesDepth <= 0 {
+		l.MaxValuesDepth = DefaultMaxValuesDepth
+	}
+	if l.MaxPageSize <= 0 {
+		l.MaxPageSize = DefaultMaxPageSize
+	}
+	if l.ExecTimeout <= 0 {
+		l.ExecTimeout = DefaultExecTimeout
+	}
+	if l.MaxPages <= 0 {
+		l.MaxPages = DefaultMaxPages
+	}
+	if l.RenderTimeout <= 0 {
+		l.RenderTimeout = DefaultRenderTimeout
+	}
+	if l.MaxIterations <= 0 {
+		l.MaxIterations = DefaultMaxIterations
+	}
+	return l
+}
+
+// ExecDuration returns the template execution timeout as a duration
+func (l Limits) ExecDuration() time.Duration {
+	return time.Duration(l.ExecTimeout) * time.Millisecond
+}
+
+// RenderDuration returns the render deadline of a template, the template deadline in milliseconds applies when it
+// is set and shorter than the render timeout
+func (l Limits) RenderDuration(template int) time.Duration {
+	ms := l.RenderTimeout
+	if template > 0 && template < ms {
+		ms = template
+	}
+	return time.Duration(ms) * time.Millisecond
+}
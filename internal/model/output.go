@@ -0,0 +1,21 @@
+package model
+
+// conformance profiles of a generated pdf
+const (
+	ProfilePdfA2b = "pdfa-2b"
+)
+
+// Output holds the options of the generated pdf file
+type Output struct {
+	// Profile is the conformance profile the rendered pdf is converted to, empty for the pdf of the renderer
+	Profile string `json:"profile,omitempty"`
+}
+
+// Valid checks the output options
+func (o Output) Valid() bool {
+	switch o.Profile {
+	case "", ProfilePdfA2b:
+		return true
+	}
+	return false
+}
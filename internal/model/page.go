@@ -0,0 +1,53 @@
+package model
+
+import (
+	"io"
+	"regexp"
+	"strconv"
+)
+
+// limits of the page settings
+const (
+	MaxZoom            = 10
+	MaxJavascriptDelay = 10000
+	MaxMinimumFontSize = 100
+)
+
+var pageNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// PageSettings holds the options of a single page of a template, e.g. a cover, body or appendix.
+// They are stored on the page object of the wkhtmltopdf document under the "Settings" key and applied by the renderer.
+type PageSettings struct {
+	Name               string  `json:"name,omitempty"`
+	Zoom               float64 `json:"zoom,omitempty"`
+	PrintMediaType     bool    `json:"print_media_type,omitempty"`
+	NoBackground       bool    `json:"no_background,omitempty"`
+	ExcludeFromOutline bool    `json:"exclude_from_outline,omitempty"`
+	DisableJavascript  bool    `json:"disable_javascript,omitempty"`
+	// JavascriptDelay is the time in milliseconds to wait for javascript to finish
+	JavascriptDelay uint `json:"javascript_delay,omitempty"`
+	MinimumFontSize uint `json:"minimum_font_size,omitempty"`
+}
+
+// Valid reports whether the page settings are within their limits, zero values mean unset.
+func (p PageSettings) Valid() bool {
+	if p.Name != "" && !pageNameRegex.MatchString(p.Name) {
+		return false
+	}
+	// pages are addressed by index or name, a name that is a number would always be taken for an index
+	if _, err := strconv.Atoi(p.Name); err == nil {
+		return false
+	}
+	return p.Zoom >= 0 && p.Zoom <= MaxZoom && p.JavascriptDelay <= MaxJavascriptDelay && p.MinimumFontSize <= MaxMinimumFontSize
+}
+
+// TemplatePage is a page of a register request, the html file together with its settings
+type TemplatePage struct {
+	File     io.Reader
+	Settings PageSettings
+}
+
+// ReorderReq lists the current index of every page of a template in the new order
+type ReorderReq struct {
+	Order []int `json:"order"`
+}
@@ -0,0 +1,105 @@
+package model
+
+import "strings"
+
+// page orientations
+const (
+	OrientationPortrait  = "portrait"
+	OrientationLandscape = "landscape"
+)
+
+// limits of the page setup options, sizes and margins are in millimeters
+const (
+	MinPageLength = 10
+	MaxPageLength = 5000
+	MaxMargin     = 500
+	MaxDpi        = 1200
+)
+
+// paperSizes are the named paper sizes understood by the renderers
+var paperSizes = map[string]string{}
+
+func init() {
+	for _, s := range []string{
+		"A0", "A1", "A2", "A3", "A4", "A5", "A6", "A7", "A8", "A9",
+		"B0", "B1", "B2", "B3", "B4", "B5", "B6", "B7", "B8", "B9", "B10",
+		"C5E", "Comm10E", "DLE", "Executive", "Folio", "Ledger", "Legal", "Letter", "Tabloid",
+	} {
+		paperSizes[strings.ToLower(s)] = s
+	}
+}
+
+// PageSetup holds the document wide page options of a template, a generate request may override the options
+// listed in the page overrides of the template. Margins are pointers so that a margin of 0 can be told from unset.
+type PageSetup struct {
+	PageSize       string  `json:"page_size,omitempty"`
+	PageWidth      uint    `json:"page_width,omitempty"`
+	PageHeight     uint    `json:"page_height,omitempty"`
+	Orientation    string  `json:"orientation,omitempty"`
+	MarginTop      *uint   `json:"margin_top,omitempty"`
+	MarginBottom   *uint   `json:"margin_bottom,omitempty"`
+	MarginLeft     *uint   `json:"margin_left,omitempty"`
+	MarginRight    *uint   `json:"margin_right,omitempty"`
+	Dpi            uint    `json:"dpi,omitempty"`
+	Grayscale      bool    `json:"grayscale,omitempty"`
+	ImageQuality   uint    `json:"image_quality,omitempty"`
+	Zoom           float64 `json:"zoom,omitempty"`
+	PrintMediaType bool    `json:"print_media_type,omitempty"`
+}
+
+// PageSetupOptions lists the json names of all page setup options
+var PageSetupOptions = []string{
+	"page_size", "page_width", "page_height", "orientation",
+	"margin_top", "margin_bottom", "margin_left", "margin_right",
+	"dpi", "grayscale", "image_quality", "zoom", "print_media_type",
+}
+
+// DefaultPageOverrides lists the options a generate request may override when the template does not list its own,
+// options that mostly change the cost of rendering like dpi and image_quality are left out
+var DefaultPageOverrides = []string{
+	"page_size", "page_width", "page_height", "orientation",
+	"margin_top", "margin_bottom", "margin_left", "margin_right",
+	"grayscale", "zoom", "print_media_type",
+}
+
+// ValidPageSetupOption reports whether s is the json name of a page setup option
+func ValidPageSetupOption(s string) bool {
+	for _, o := range PageSetupOptions {
+		if o == s {
+			return true
+		}
+	}
+	return false
+}
+
+// PaperSize returns the canonical name of a paper size, the lookup ignores case
+func PaperSize(s string) (string, bool) {
+	name, ok := paperSizes[strings.ToLower(s)]
+	return name, ok
+}
+
+// Valid checks the page setup, a custom size needs both width and height and can't be combined with a page size
+func (p PageSetup) Valid() bool {
+	if p.PageSize != "" {
+		if _, ok := PaperSize(p.PageSize); !ok || p.PageWidth != 0 || p.PageHeight != 0 {
+			return false
+		}
+	}
+	if (p.PageWidth == 0) != (p.PageHeight == 0) {
+		return false
+	}
+	if p.PageWidth != 0 && (p.PageWidth < MinPageLength || p.PageWidth > MaxPageLength || p.PageHeight < MinPageLength || p.PageHeight > MaxPageLength) {
+		return false
+	}
+	switch strings.ToLower(p.Orientation) {
+	case "", OrientationPortrait, OrientationLandscape:
+	default:
+		return false
+	}
+	for _, m := range []*uint{p.MarginTop, p.MarginBottom, p.MarginLeft, p.MarginRight} {
+		if m != nil && *m > MaxMargin {
+			return false
+		}
+	}
+	return p.Dpi <= MaxDpi && p.ImageQuality <= MaxQuality && p.Zoom >= 0 && p.Zoom <= MaxZoom
+}
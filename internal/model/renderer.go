@@ -0,0 +1,44 @@
+package model
+
+// names of the html to pdf renderers
+const (
+	RendererWkhtmltopdf = "wkhtmltopdf"
+	RendererChromium    = "chromium"
+	RendererNative      = "native"
+)
+
+// RendererConfig selects the renderer of templates that don't name their own
+type RendererConfig struct {
+	Default     string            `json:"default"`
+	Wkhtmltopdf WkhtmltopdfConfig `json:"wkhtmltopdf"`
+	Chromium    ChromiumConfig    `json:"chromium"`
+	Health      HealthConfig      `json:"health"`
+}
+
+// WkhtmltopdfConfig holds the options of the wkhtmltopdf renderer, an empty path looks up the binary in PATH
+type WkhtmltopdfConfig struct {
+	Path string `json:"path"`
+}
+
+// ChromiumConfig holds the options of the headless chromium renderer, an empty path looks up the binary in PATH
+type ChromiumConfig struct {
+	Path string `json:"path"`
+	// NoSandbox disables the chromium sandbox, needed when the service runs as root in a container
+	NoSandbox bool `json:"no_sandbox"`
+}
+
+// HealthConfig sets how often the health check renders a canary document with each renderer and how long the
+// render may take, zero uses the defaults
+type HealthConfig struct {
+	CanaryIntervalMs int `json:"canary_interval_ms"`
+	CanaryTimeoutMs  int `json:"canary_timeout_ms"`
+}
+
+// ValidRenderer reports whether s names a supported renderer, empty means the configured default.
+func ValidRenderer(s string) bool {
+	switch s {
+	case "", RendererWkhtmltopdf, RendererChromium, RendererNative:
+		return true
+	}
+	return false
+}
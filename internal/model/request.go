@@ -1,6 +1,114 @@
 package model
 
+import (
+	"encoding/json"
+	"unicode/utf8"
+)
+
+// output formats of a generate request
+const (
+	OutputPdf  = "pdf"
+	OutputPng  = "png"
+	OutputJpeg = "jpeg"
+	// OutputWebp is not rendered, requests for it are rejected with a dedicated error
+	OutputWebp = "webp"
+)
+
+// content dispositions of a generated document
+const (
+	DispositionAttachment = "attachment"
+	DispositionInline     = "inline"
+)
+
+// MaxFilenameLength is the length of the longest filename of a generated document
+const MaxFilenameLength = 255
+
+// limits of the image output options
+const (
+	MaxImageWidth = 10000
+	MaxQuality    = 100
+)
+
 type GenerateReq struct {
-	Values map[string]interface{} `json:"values"`
-	Id     string                 `json:"-"`
+	Values      map[string]interface{} `json:"values"`
+	MissingKey  string                 `json:"missing_key,omitempty"`
+	ParseTime   *bool                  `json:"parse_time,omitempty"`
+	Theme       string                 `json:"theme,omitempty"`
+	Locale      string                 `json:"locale,omitempty"`
+	Format      string                 `json:"format,omitempty"`
+	Width       int                    `json:"width,omitempty"`
+	Quality     int                    `json:"quality,omitempty"`
+	Page        int                    `json:"page,omitempty"`
+	Records     []interface{}          `json:"records,omitempty"`
+	RecordsPath string                 `json:"records_path,omitempty"`
+	PageBreak   *bool                  `json:"page_break,omitempty"`
+	Bookmark    string                 `json:"bookmark,omitempty"`
+	PageSetup   json.RawMessage        `json:"page_setup,omitempty"`
+	Toc         json.RawMessage        `json:"toc,omitempty"`
+	Outline     json.RawMessage        `json:"outline,omitempty"`
+	Cover       *bool                  `json:"cover,omitempty"`
+	Disposition string                 `json:"disposition,omitempty"`
+	Filename    string                 `json:"filename,omitempty"`
+	Output      *Output                `json:"output,omitempty"`
+	Stamps      *[]Stamp               `json:"stamps,omitempty"`
+	Id          string                 `json:"-"`
+}
+
+// OutputFormat returns the requested output format, pdf when none is set
+func (r GenerateReq) OutputFormat() string {
+	if r.Format == "" {
+		return OutputPdf
+	}
+	return r.Format
+}
+
+// ImagePage returns the 1-based page of the template rendered as an image, the first when none is set
+func (r GenerateReq) ImagePage() int {
+	if r.Page == 0 {
+		return 1
+	}
+	return r.Page
+}
+
+// ValidOutput checks the output format and the image options of the request, a pdf profile and stamps only apply
+// to pdf
+func (r GenerateReq) ValidOutput() bool {
+	switch r.OutputFormat() {
+	case OutputPdf:
+		return r.Width == 0 && r.Quality == 0 && r.Page == 0
+	case OutputPng, OutputJpeg:
+		return r.Page >= 0 && r.Width >= 0 && r.Width <= MaxImageWidth && r.Quality >= 0 && r.Quality <= MaxQuality &&
+			(r.Output == nil || r.Output.Profile == "") && (r.Stamps == nil || len(*r.Stamps) == 0)
+	}
+	return false
+}
+
+// DispositionType returns the requested content disposition, attachment when none is set
+func (r GenerateReq) DispositionType() string {
+	if r.Disposition == "" {
+		return DispositionAttachment
+	}
+	return r.Disposition
+}
+
+// ValidDisposition checks the content disposition of the request
+func (r GenerateReq) ValidDisposition() bool {
+	switch r.DispositionType() {
+	case DispositionAttachment, DispositionInline:
+		return true
+	}
+	return false
+}
+
+// ValidFilename checks the filename of the request is a plain file name without a path or control characters
+func (r GenerateReq) ValidFilename() bool {
+	if len(r.Filename) > MaxFilenameLength || !utf8.ValidString(r.Filename) {
+		return false
+	}
+	for _, c := range r.Filename {
+		if c < ' ' || c == 0x7f || c == '/' || c == '\\' {
+			return false
+		}
+	}
+	return r.Filename != "." && r.Filename != ".."
 }
@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// default render scheduler settings, used for every setting that is not configured
+const (
+	DefaultConcurrency  = 4
+	DefaultQueueSize    = 32
+	DefaultQueueTimeout = 30000
+)
+
+// SchedulerConfig bounds the renders running at once, zero values fall back to the defaults
+type SchedulerConfig struct {
+	// Concurrency is the number of renders running at once
+	Concurrency int `json:"concurrency"`
+	// QueueSize is the number of renders waiting for a free slot, requests beyond it are rejected with 429
+	QueueSize int `json:"queue_size"`
+	// QueueTimeout is the time in milliseconds a render may wait for a slot before it is rejected with 503
+	QueueTimeout int `json:"queue_timeout_ms"`
+}
+
+// WithDefaults returns the config with the defaults filled in
+func (c SchedulerConfig) WithDefaults() SchedulerConfig {
+	if c.Concurrency <= 0 {
+		c.Concurrency = DefaultConcurrency
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = DefaultQueueSize
+	}
+	if c.QueueTimeout <= 0 {
+		c.QueueTimeout = DefaultQueueTimeout
+	}
+	return c
+}
+
+// QueueDuration returns the queue timeout as a duration
+func (c SchedulerConfig) QueueDuration() time.Duration {
+	return time.Duration(c.QueueTimeout) * time.Millisecond
+}
+
+// Backpressure is the data of a response rejected because all renderers are busy
+type Backpressure struct {
+	// RetryAfter is the number of seconds after which the request should be retried
+	RetryAfter int `json:"retry_after"`
+	QueueDepth int `json:"queue_depth"`
+}
@@ -0,0 +1,120 @@
+package model
+
+import (
+	"regexp"
+	"unicode/utf8"
+)
+
+// positions of a stamp on the page
+const (
+	PositionCenter      = "center"
+	PositionTop         = "top"
+	PositionBottom      = "bottom"
+	PositionLeft        = "left"
+	PositionRight       = "right"
+	PositionTopLeft     = "top-left"
+	PositionTopRight    = "top-right"
+	PositionBottomLeft  = "bottom-left"
+	PositionBottomRight = "bottom-right"
+)
+
+// limits and defaults of the stamp options
+const (
+	MaxStamps          = 10
+	MaxStampTextLength = 200
+	MaxStampFontSize   = 500
+	MaxStampWidth      = 5000
+	MaxStampMargin     = 500
+	DefaultStampSize   = 48
+	DefaultStampWidth  = 100
+	DefaultStampColor  = "#808080"
+	DefaultStampMargin = 20
+)
+
+var stampColorRegex = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// Stamp is a text or an image overlaid on the pages of the generated pdf, e.g. a diagonal DRAFT watermark
+type Stamp struct {
+	// Text is the text of the stamp, Timestamp appends the time the document is generated to it
+	Text      string `json:"text,omitempty"`
+	Timestamp bool   `json:"timestamp,omitempty"`
+	// FontSize and Color of the text, the color is written as #rrggbb
+	FontSize float64 `json:"font_size,omitempty"`
+	Color    string  `json:"color,omitempty"`
+	// Image names an image asset of the template drawn Width points wide, used when the stamp has no text
+	Image string  `json:"image,omitempty"`
+	Width float64 `json:"width,omitempty"`
+	// Opacity goes from 0 for the default of opaque to 1, Rotation is the counterclockwise rotation in degrees
+	Opacity  float64 `json:"opacity,omitempty"`
+	Rotation float64 `json:"rotation,omitempty"`
+	// Position places the stamp on the page, center by default, Margin is its distance from the page edges in points
+	Position string   `json:"position,omitempty"`
+	Margin   *float64 `json:"margin,omitempty"`
+	// Pages are the numbers of the stamped pages from 1, negative numbers count back from the last page
+	Pages []int `json:"pages,omitempty"`
+}
+
+// Anchor returns the anchor of the position as fractions of the page from the lower left corner
+func (s Stamp) Anchor() (float64, float64) {
+	switch s.Position {
+	case PositionTop:
+		return 0.5, 1
+	case PositionBottom:
+		return 0.5, 0
+	case PositionLeft:
+		return 0, 0.5
+	case PositionRight:
+		return 1, 0.5
+	case PositionTopLeft:
+		return 0, 1
+	case PositionTopRight:
+		return 1, 1
+	case PositionBottomLeft:
+		return 0, 0
+	case PositionBottomRight:
+		return 1, 0
+	}
+	return 0.5, 0.5
+}
+
+// Valid checks the options of the stamp, a stamp has either a text or an image
+func (s Stamp) Valid() bool {
+	if (s.Text == "" && !s.Timestamp) == (s.Image == "") {
+		return false
+	}
+	if len(s.Text) > MaxStampTextLength || !utf8.ValidString(s.Text) {
+		return false
+	}
+	if s.Color != "" && !stampColorRegex.MatchString(s.Color) {
+		return false
+	}
+	if s.Image != "" {
+		if _, ok := CleanAssetName(s.Image); !ok {
+			return false
+		}
+	}
+	switch s.Position {
+	case "", PositionCenter, PositionTop, PositionBottom, PositionLeft, PositionRight,
+		PositionTopLeft, PositionTopRight, PositionBottomLeft, PositionBottomRight:
+	default:
+		return false
+	}
+	if s.Margin != nil && (*s.Margin < 0 || *s.Margin > MaxStampMargin) {
+		return false
+	}
+	return s.FontSize >= 0 && s.FontSize <= MaxStampFontSize && s.Width >= 0 && s.Width <= MaxStampWidth &&
+		s.Opacity >= 0 && s.Opacity <= 1 && s.Rotation >= -360 && s.Rotation <= 360
+}
+
+// ValidStamps checks the number and the options of the stamps
+func ValidStamps(stamps []Stamp) bool {
+	if len(stamps) > MaxStamps {
+		return false
+	}
+	for _, s := range stamps {
+		if !s.Valid() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,229 @@
+package model
+
+import (
+	"path"
+	"strings"
+)
+
+const (
+	MissingKeyDefault = "default"
+	MissingKeyZero    = "zero"
+	MissingKeyError   = "error"
+)
+
+const (
+	EngineHtml     = "html"
+	EngineText     = "text"
+	EngineMustache = "mustache"
+)
+
+const (
+	FormatHtml     = "html"
+	FormatMarkdown = "markdown"
+)
+
+const ThemeDefault = "default"
+
+// TemplateSettings holds the per template defaults captured at register time.
+// They are stored alongside the wkhtmltopdf document under the "Settings" key.
+type TemplateSettings struct {
+	Engine     string `json:"engine,omitempty"`
+	Format     string `json:"format,omitempty"`
+	Theme      string `json:"theme,omitempty"`
+	MissingKey string `json:"missing_key,omitempty"`
+	ParseTime  *bool  `json:"parse_time,omitempty"`
+	// Locale is the default locale of the template, RtlLocales marks additional right to left locales
+	Locale     string             `json:"locale,omitempty"`
+	RtlLocales []string           `json:"rtl_locales,omitempty"`
+	Catalogs   map[string]Catalog `json:"catalogs,omitempty"`
+	// Assets holds the images, fonts and stylesheets referenced by the template, keyed by their relative path
+	Assets map[string][]byte `json:"assets,omitempty"`
+	// PageSetup holds the page options of the rendered document, PageOverrides the options a generate request may
+	// override, nil means DefaultPageOverrides and an empty list forbids all overrides
+	PageSetup     *PageSetup `json:"page_setup,omitempty"`
+	PageOverrides *[]string  `json:"page_overrides,omitempty"`
+	// Header and Footer are repeated on every page and executed with the same values as the pages
+	Header *HeaderFooter `json:"header,omitempty"`
+	Footer *HeaderFooter `json:"footer,omitempty"`
+	// Cover is the template of the cover page, Toc and Outline the options of the table of contents and the outline
+	Cover   []byte   `json:"cover,omitempty"`
+	Toc     *Toc     `json:"toc,omitempty"`
+	Outline *Outline `json:"outline,omitempty"`
+	// Renderer names the renderer of the template, empty means the configured default
+	Renderer string `json:"renderer,omitempty"`
+	// RenderTimeout is the render deadline of the template in milliseconds, zero uses the configured render timeout
+	RenderTimeout int `json:"render_timeout_ms,omitempty"`
+	// Output holds the options of the generated pdf, e.g. the PDF/A profile
+	Output *Output `json:"output,omitempty"`
+	// Stamps are overlaid on the pages of the generated pdf
+	Stamps []Stamp `json:"stamps,omitempty"`
+	// Clear lists the settings an update removes by their form field name, e.g. "header"
+	Clear []string `json:"-"`
+}
+
+// ClearableSetting reports whether name is the form field of a setting an update may remove
+func ClearableSetting(name string) bool {
+	switch name {
+	case "engine", "format", "theme", "missing_key", "parse_time", "locale", "rtl_locales", "catalog",
+		"page_setup", "page_overrides", "header", "footer", "cover", "toc", "outline", "renderer",
+		"render_timeout_ms", "output", "stamps":
+		return true
+	}
+	return false
+}
+
+// Update returns the settings s with the fields set in u overwritten and the fields u clears removed, the fields u
+// leaves unset are kept
+func (s TemplateSettings) Update(u TemplateSettings) TemplateSettings {
+	if u.Engine != "" {
+		s.Engine = u.Engine
+	}
+	if u.Format != "" {
+		s.Format = u.Format
+	}
+	if u.Theme != "" {
+		s.Theme = u.Theme
+	}
+	if u.MissingKey != "" {
+		s.MissingKey = u.MissingKey
+	}
+	if u.ParseTime != nil {
+		s.ParseTime = u.ParseTime
+	}
+	if u.Locale != "" {
+		s.Locale = u.Locale
+	}
+	if u.RtlLocales != nil {
+		s.RtlLocales = u.RtlLocales
+	}
+	if u.Catalogs != nil {
+		s.Catalogs = u.Catalogs
+	}
+	if u.Assets != nil {
+		s.Assets = u.Assets
+	}
+	if u.PageSetup != nil {
+		s.PageSetup = u.PageSetup
+	}
+	if u.PageOverrides != nil {
+		s.PageOverrides = u.PageOverrides
+	}
+	if u.Header != nil {
+		s.Header = u.Header
+	}
+	if u.Footer != nil {
+		s.Footer = u.Footer
+	}
+	if u.Cover != nil {
+		s.Cover = u.Cover
+	}
+	if u.Toc != nil {
+		s.Toc = u.Toc
+	}
+	if u.Outline != nil {
+		s.Outline = u.Outline
+	}
+	if u.Renderer != "" {
+		s.Renderer = u.Renderer
+	}
+	if u.RenderTimeout != 0 {
+		s.RenderTimeout = u.RenderTimeout
+	}
+	if u.Output != nil {
+		s.Output = u.Output
+	}
+	if u.Stamps != nil {
+		s.Stamps = u.Stamps
+	}
+	for _, name := range u.Clear {
+		s.clear(name)
+	}
+	return s
+}
+
+// clear removes the setting of the form field name
+func (s *TemplateSettings) clear(name string) {
+	switch name {
+	case "engine":
+		s.Engine = ""
+	case "format":
+		s.Format = ""
+	case "theme":
+		s.Theme = ""
+	case "missing_key":
+		s.MissingKey = ""
+	case "parse_time":
+		s.ParseTime = nil
+	case "locale":
+		s.Locale = ""
+	case "rtl_locales":
+		s.RtlLocales = nil
+	case "catalog":
+		s.Catalogs = nil
+	case "page_setup":
+		s.PageSetup = nil
+	case "page_overrides":
+		s.PageOverrides = nil
+	case "header":
+		s.Header = nil
+	case "footer":
+		s.Footer = nil
+	case "cover":
+		s.Cover = nil
+	case "toc":
+		s.Toc = nil
+	case "outline":
+		s.Outline = nil
+	case "renderer":
+		s.Renderer = ""
+	case "render_timeout_ms":
+		s.RenderTimeout = 0
+	case "output":
+		s.Output = nil
+	case "stamps":
+		s.Stamps = nil
+	}
+}
+
+// Catalog maps message keys to a translation, a translation is either a string
+// or an object of CLDR plural categories (zero, one, two, few, many, other) to strings.
+type Catalog map[string]interface{}
+
+// ValidMissingKey reports whether s is an accepted missingkey option, empty means unset.
+func ValidMissingKey(s string) bool {
+	switch s {
+	case "", MissingKeyDefault, MissingKeyZero, MissingKeyError:
+		return true
+	}
+	return false
+}
+
+// ValidEngine reports whether s names a supported template engine, empty means html/template.
+func ValidEngine(s string) bool {
+	switch s {
+	case "", EngineHtml, EngineText, EngineMustache:
+		return true
+	}
+	return false
+}
+
+// ValidFormat reports whether s is a supported source format of a template, empty means html.
+func ValidFormat(s string) bool {
+	switch s {
+	case "", FormatHtml, FormatMarkdown:
+		return true
+	}
+	return false
+}
+
+// CleanAssetName returns the canonical relative path of an asset, ok is false for absolute paths or paths leaving the template
+func CleanAssetName(name string) (string, bool) {
+	if name == "" || strings.Contains(name, "\\") || strings.HasPrefix(name, "/") {
+		return "", false
+	}
+	name = path.Clean(name)
+	if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+		return "", false
+	}
+	return name, true
+}
@@ -0,0 +1,620 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+var (
+	ErrEncrypted = errors.New("encrypted pdf files are not supported")
+	ErrNoCatalog = errors.New("pdf file without a document catalog")
+)
+
+// xrefEntry locates an object in the file or, for objects of object streams, the stream and index
+type xrefEntry struct {
+	offset int
+	stream int
+	index  int
+}
+
+// Document is a pdf file read into memory, its objects can be changed and written as a new file
+type Document struct {
+	// Version is the version of the file header, e.g. 1.4
+	Version string
+	Trailer Dict
+	objects map[int]Object
+	next    int
+}
+
+// Parse reads a pdf file. The cross reference table is used when it is valid, otherwise the objects are
+// located by scanning the file.
+func Parse(b []byte) (*Document, error) {
+	m := regexp.MustCompile(`^%PDF-(\d\.\d)`).FindSubmatch(b[:minInt(len(b), 1024)])
+	if m == nil {
+		// the header may be preceded by garbage
+		i := bytes.Index(b[:minInt(len(b), 1024)], []byte("%PDF-"))
+		if i < 0 {
+			return nil, fmt.Errorf("%w: no pdf header", errSyntax)
+		}
+		m = regexp.MustCompile(`^%PDF-(\d\.\d)`).FindSubmatch(b[i:])
+		if m == nil {
+			return nil, fmt.Errorf("%w: no pdf header", errSyntax)
+		}
+	}
+	r := &reader{b: b, entries: map[int]xrefEntry{}}
+	trailer, err := r.readXrefs()
+	if err == nil {
+		err = r.loadAll(false)
+	}
+	if err != nil {
+		r.entries = map[int]xrefEntry{}
+		trailer, err = r.scan()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := trailer["Encrypt"]; ok {
+		return nil, ErrEncrypted
+	}
+	d := &Document{Version: string(m[1]), Trailer: trailer, objects: r.objects}
+	for n := range d.objects {
+		if n >= d.next {
+			d.next = n + 1
+		}
+	}
+	if _, ok := d.Resolve(trailer["Root"]).(Dict); !ok {
+		return nil, ErrNoCatalog
+	}
+	return d, nil
+}
+
+// Object returns the indirect object r, nil if the file has no such object
+func (d *Document) Object(r Ref) Object {
+	return d.objects[int(r)]
+}
+
+// Set replaces the indirect object r
+func (d *Document) Set(r Ref, o Object) {
+	d.objects[int(r)] = o
+}
+
+// Add adds an indirect object and returns its reference
+func (d *Document) Add(o Object) Ref {
+	r := Ref(d.next)
+	d.next++
+	d.objects[int(r)] = o
+	return r
+}
+
+// Refs returns the numbers of all indirect objects in ascending order
+func (d *Document) Refs() []Ref {
+	refs := make([]Ref, 0, len(d.objects))
+	for n := range d.objects {
+		refs = append(refs, Ref(n))
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i] < refs[j] })
+	return refs
+}
+
+// Resolve follows references until o is a direct object
+func (d *Document) Resolve(o Object) Object {
+	for i := 0; i < 32; i++ {
+		r, ok := o.(Ref)
+		if !ok {
+			return o
+		}
+		o = d.objects[int(r)]
+	}
+	return nil
+}
+
+// Dict resolves o and returns it as a dictionary, the dictionary of a stream included
+func (d *Document) Dict(o Object) Dict {
+	switch v := d.Resolve(o).(type) {
+	case Dict:
+		return v
+	case *Stream:
+		return v.Dict
+	}
+	return nil
+}
+
+// Catalog returns the document catalog
+func (d *Document) Catalog() Dict {
+	return d.Dict(d.Trailer["Root"])
+}
+
+// Info returns the document information dictionary, nil if the document has none
+func (d *Document) Info() Dict {
+	return d.Dict(d.Trailer["Info"])
+}
+
+// Pages returns the page objects in document order
+func (d *Document) Pages() []Ref {
+	var pages []Ref
+	seen := map[Ref]bool{}
+	var walk func(o Object)
+	walk = func(o Object) {
+		r, ok := o.(Ref)
+		if !ok || seen[r] {
+			return
+		}
+		seen[r] = true
+		n := d.Dict(r)
+		if n.Name("Type") == "Page" || (n["Kids"] == nil && n["Contents"] != nil) {
+			pages = append(pages, r)
+			return
+		}
+		kids, _ := d.Resolve(n["Kids"]).(Array)
+		for _, k := range kids {
+			walk(k)
+		}
+	}
+	walk(d.Catalog()["Pages"])
+	return pages
+}
+
+// PageAttr returns the attribute key of the page, inherited from the page tree when the page lacks it
+func (d *Document) PageAttr(page Ref, key Name) Object {
+	n := d.Dict(page)
+	for i := 0; n != nil && i < 64; i++ {
+		if v, ok := n[key]; ok {
+			return v
+		}
+		n = d.Dict(n["Parent"])
+	}
+	return nil
+}
+
+// MediaBox returns the media box of the page, a letter page when the document has none
+func (d *Document) MediaBox(page Ref) [4]float64 {
+	box := [4]float64{0, 0, 612, 792}
+	arr, _ := d.Resolve(d.PageAttr(page, "MediaBox")).(Array)
+	if len(arr) != 4 {
+		return box
+	}
+	for i, v := range arr {
+		if f, ok := toFloat(d.Resolve(v)); ok {
+			box[i] = f
+		}
+	}
+	return box
+}
+
+// Write writes the objects reachable from the trailer as a new file with a single cross reference table.
+// Objects are renumbered in the order they are reached, unreachable objects are left out.
+func (d *Document) Write(out io.Writer) error {
+	w := NewWriter()
+	c := newCopier(d, w)
+	root := c.ref(d.Trailer["Root"])
+	var info Ref
+	if _, ok := d.Trailer["Info"].(Ref); ok {
+		info = c.ref(d.Trailer["Info"])
+	}
+	c.flush()
+	if id, ok := d.Trailer["ID"].(Array); ok && len(id) == 2 {
+		a, _ := id[0].(Str)
+		b, _ := id[1].(Str)
+		w.SetID(a, b)
+	}
+	return w.WriteTo(out, root, info)
+}
+
+// copier copies objects of a document into a writer, references are mapped to the objects of the writer
+type copier struct {
+	src     *Document
+	w       *Writer
+	refs    map[Ref]Ref
+	pending []Ref
+	// skip are objects replaced by another object of the writer, e.g. the parent of copied pages
+	skip map[Ref]Ref
+}
+
+func newCopier(src *Document, w *Writer) *copier {
+	return &copier{src: src, w: w, refs: map[Ref]Ref{}, skip: map[Ref]Ref{}}
+}
+
+// ref returns the writer reference of the source object r, the object is copied by flush
+func (c *copier) ref(o Object) Ref {
+	r, ok := o.(Ref)
+	if !ok {
+		// a direct object where a reference is expected becomes an indirect object
+		nr := c.w.Reserve()
+		c.w.SetObject(nr, c.copy(o))
+		return nr
+	}
+	if nr, ok := c.skip[r]; ok {
+		return nr
+	}
+	if nr, ok := c.refs[r]; ok {
+		return nr
+	}
+	nr := c.w.Reserve()
+	c.refs[r] = nr
+	c.pending = append(c.pending, r)
+	return nr
+}
+
+// flush copies the objects referenced so far and the objects they reference
+func (c *copier) flush() {
+	for len(c.pending) > 0 {
+		r := c.pending[0]
+		c.pending = c.pending[1:]
+		c.w.SetObject(c.refs[r], c.copy(c.src.Object(r)))
+	}
+}
+
+func (c *copier) copy(o Object) Object {
+	switch v := o.(type) {
+	case Ref:
+		if c.src.Object(v) == nil {
+			// a reference to a missing object is null
+			return nil
+		}
+		return c.ref(v)
+	case Array:
+		arr := make(Array, len(v))
+		for i, e := range v {
+			arr[i] = c.copy(e)
+		}
+		return arr
+	case Dict:
+		d := make(Dict, len(v))
+		for k, e := range v {
+			d[k] = c.copy(e)
+		}
+		return d
+	case *Stream:
+		d := c.copy(v.Dict).(Dict)
+		return &Stream{Dict: d, Data: v.Data}
+	}
+	return o
+}
+
+// reader locates the objects of a file
+type reader struct {
+	b       []byte
+	entries map[int]xrefEntry
+	objects map[int]Object
+}
+
+var startxrefPattern = regexp.MustCompile(`startxref\s+(\d+)`)
+
+// readXrefs reads the cross reference sections from the last one backwards, entries of later sections win
+func (r *reader) readXrefs() (Dict, error) {
+	tail := r.b[maxInt(0, len(r.b)-2048):]
+	ms := startxrefPattern.FindAllSubmatch(tail, -1)
+	if ms == nil {
+		return nil, fmt.Errorf("%w: no startxref", errSyntax)
+	}
+	offset, _ := strconv.Atoi(string(ms[len(ms)-1][1]))
+	var trailer Dict
+	seen := map[int]bool{}
+	for offset > 0 || trailer == nil {
+		if seen[offset] || offset < 0 || offset >= len(r.b) {
+			return nil, fmt.Errorf("%w: invalid cross reference offset %d", errSyntax, offset)
+		}
+		seen[offset] = true
+		t, err := r.readXref(offset)
+		if err != nil {
+			return nil, err
+		}
+		if trailer == nil {
+			trailer = t
+		}
+		// a hybrid file has a cross reference stream in addition to the table
+		if stm, ok := t["XRefStm"].(int64); ok && !seen[int(stm)] {
+			seen[int(stm)] = true
+			if _, err := r.readXref(int(stm)); err != nil {
+				return nil, err
+			}
+		}
+		prev, ok := t["Prev"].(int64)
+		if !ok {
+			break
+		}
+		offset = int(prev)
+	}
+	if _, ok := trailer["Root"].(Ref); !ok {
+		return nil, ErrNoCatalog
+	}
+	return trailer, nil
+}
+
+func (r *reader) readXref(offset int) (Dict, error) {
+	p := &parser{b: r.b, pos: offset}
+	if p.peekKeyword() != "xref" {
+		return r.readXrefStream(offset)
+	}
+	p.keyword()
+	for {
+		k := p.peekKeyword()
+		if k == "trailer" {
+			p.keyword()
+			break
+		}
+		start, err := strconv.Atoi(p.keyword())
+		if err != nil {
+			return nil, p.errorf("invalid cross reference section")
+		}
+		count, err := strconv.Atoi(p.keyword())
+		if err != nil || count < 0 {
+			return nil, p.errorf("invalid cross reference section")
+		}
+		for i := 0; i < count; i++ {
+			off, err1 := strconv.Atoi(p.keyword())
+			_, err2 := strconv.Atoi(p.keyword())
+			kind := p.keyword()
+			if err1 != nil || err2 != nil || (kind != "n" && kind != "f") {
+				return nil, p.errorf("invalid cross reference entry")
+			}
+			if _, ok := r.entries[start+i]; !ok && kind == "n" && start+i > 0 {
+				r.entries[start+i] = xrefEntry{offset: off}
+			}
+		}
+	}
+	o, err := p.object(0)
+	if err != nil {
+		return nil, err
+	}
+	t, ok := o.(Dict)
+	if !ok {
+		return nil, p.errorf("trailer is no dictionary")
+	}
+	return t, nil
+}
+
+func (r *reader) readXrefStream(offset int) (Dict, error) {
+	p := &parser{b: r.b, pos: offset}
+	_, o, err := p.indirect()
+	if err != nil {
+		return nil, err
+	}
+	s, ok := o.(*Stream)
+	if !ok || s.Dict.Name("Type") != "XRef" {
+		return nil, p.errorf("no cross reference stream")
+	}
+	data, err := Decode(s)
+	if err != nil {
+		return nil, err
+	}
+	var widths [3]int
+	wa, _ := s.Dict["W"].(Array)
+	if len(wa) != 3 {
+		return nil, p.errorf("invalid cross reference stream widths")
+	}
+	for i, v := range wa {
+		n, _ := v.(int64)
+		if n < 0 || n > 8 {
+			return nil, p.errorf("invalid cross reference stream widths")
+		}
+		widths[i] = int(n)
+	}
+	size, _ := s.Dict["Size"].(int64)
+	index := Array{int64(0), size}
+	if ia, ok := s.Dict["Index"].(Array); ok {
+		index = ia
+	}
+	field := func(b []byte, def int) int {
+		if len(b) == 0 {
+			return def
+		}
+		v := 0
+		for _, c := range b {
+			v = v<<8 | int(c)
+		}
+		return v
+	}
+	rowLen := widths[0] + widths[1] + widths[2]
+	if rowLen == 0 {
+		return nil, p.errorf("invalid cross reference stream widths")
+	}
+	for i := 0; i+1 < len(index); i += 2 {
+		start, _ := index[i].(int64)
+		count, _ := index[i+1].(int64)
+		for j := 0; j < int(count); j++ {
+			if len(data) < rowLen {
+				return nil, p.errorf("truncated cross reference stream")
+			}
+			row := data[:rowLen]
+			data = data[rowLen:]
+			kind := field(row[:widths[0]], 1)
+			f2 := field(row[widths[0]:widths[0]+widths[1]], 0)
+			f3 := field(row[widths[0]+widths[1]:], 0)
+			num := int(start) + j
+			if _, ok := r.entries[num]; ok || num == 0 {
+				continue
+			}
+			switch kind {
+			case 1:
+				r.entries[num] = xrefEntry{offset: f2}
+			case 2:
+				r.entries[num] = xrefEntry{stream: f2, index: f3}
+			}
+		}
+	}
+	return s.Dict, nil
+}
+
+var objPattern = regexp.MustCompile(`(?m)(?:^|[\r\n\s])(\d+)\s+\d+\s+obj\b`)
+
+// scan locates the objects of a damaged file by their obj keywords, the last definition of an object wins
+func (r *reader) scan() (Dict, error) {
+	for _, m := range objPattern.FindAllSubmatchIndex(r.b, -1) {
+		num, err := strconv.Atoi(string(r.b[m[2]:m[3]]))
+		if err != nil || num == 0 {
+			continue
+		}
+		r.entries[num] = xrefEntry{offset: m[2]}
+	}
+	var trailer Dict
+	for _, m := range regexp.MustCompile(`trailer\s*<<`).FindAllIndex(r.b, -1) {
+		p := &parser{b: r.b, pos: m[1] - 2}
+		if t, err := p.object(0); err == nil {
+			if td, ok := t.(Dict); ok && td["Root"] != nil {
+				trailer = td
+			}
+		}
+	}
+	if err := r.loadAll(true); err != nil {
+		return nil, err
+	}
+	if trailer == nil {
+		// a file with cross reference streams keeps the trailer entries in the stream dictionary
+		for _, n := range sortedKeys(r.objects) {
+			if s, ok := r.objects[n].(*Stream); ok && s.Dict.Name("Type") == "XRef" && s.Dict["Root"] != nil {
+				trailer = s.Dict
+			}
+		}
+	}
+	if trailer == nil {
+		for _, n := range sortedKeys(r.objects) {
+			if d, ok := r.objects[n].(Dict); ok && d.Name("Type") == "Catalog" {
+				trailer = Dict{"Root": Ref(n)}
+				break
+			}
+		}
+	}
+	if trailer == nil {
+		return nil, ErrNoCatalog
+	}
+	return trailer, nil
+}
+
+func sortedKeys(m map[int]Object) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// loadAll reads every object located by the entries, objects of object streams after the other objects.
+// A lenient load skips the objects that can't be read.
+func (r *reader) loadAll(lenient bool) error {
+	r.objects = map[int]Object{}
+	p := &parser{b: r.b, length: r.length}
+	for num, e := range r.entries {
+		if e.stream != 0 {
+			continue
+		}
+		if e.offset < 0 || e.offset >= len(r.b) {
+			if lenient {
+				continue
+			}
+			return fmt.Errorf("%w: object %d out of range", errSyntax, num)
+		}
+		p.pos = e.offset
+		n, o, err := p.indirect()
+		if err == nil && n != num {
+			err = fmt.Errorf("%w: object %d found at the offset of object %d", errSyntax, n, num)
+		}
+		if err != nil {
+			if lenient {
+				continue
+			}
+			return err
+		}
+		r.objects[num] = o
+	}
+	streams := map[int][]Object{}
+	for num, e := range r.entries {
+		if e.stream == 0 {
+			continue
+		}
+		objs, ok := streams[e.stream]
+		if !ok {
+			var err error
+			objs, err = r.objectStream(e.stream)
+			if err != nil && !lenient {
+				return err
+			}
+			streams[e.stream] = objs
+		}
+		if e.index < len(objs) {
+			r.objects[num] = objs[e.index]
+		}
+	}
+	// cross reference and object streams are rebuilt when the file is written
+	for num, o := range r.objects {
+		if s, ok := o.(*Stream); ok && (s.Dict.Name("Type") == "ObjStm" || s.Dict.Name("Type") == "XRef") {
+			delete(r.objects, num)
+		}
+	}
+	return nil
+}
+
+// length resolves the length of a stream given as a reference
+func (r *reader) length(ref Ref) (int, bool) {
+	e, ok := r.entries[int(ref)]
+	if !ok || e.stream != 0 {
+		return 0, false
+	}
+	p := &parser{b: r.b, pos: e.offset}
+	_, o, err := p.indirect()
+	if err != nil {
+		return 0, false
+	}
+	n, ok := o.(int64)
+	return int(n), ok
+}
+
+func (r *reader) objectStream(num int) ([]Object, error) {
+	s, ok := r.objects[num].(*Stream)
+	if !ok {
+		return nil, fmt.Errorf("%w: object stream %d missing", errSyntax, num)
+	}
+	data, err := Decode(s)
+	if err != nil {
+		return nil, err
+	}
+	n, _ := s.Dict["N"].(int64)
+	first, _ := s.Dict["First"].(int64)
+	if first < 0 || int(first) > len(data) {
+		return nil, fmt.Errorf("%w: invalid object stream %d", errSyntax, num)
+	}
+	p := &parser{b: data}
+	offsets := make([]int, n)
+	for i := range offsets {
+		if _, err := strconv.Atoi(p.keyword()); err != nil {
+			return nil, fmt.Errorf("%w: invalid object stream %d", errSyntax, num)
+		}
+		off, err := strconv.Atoi(p.keyword())
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid object stream %d", errSyntax, num)
+		}
+		offsets[i] = int(first) + off
+	}
+	objs := make([]Object, n)
+	for i, off := range offsets {
+		if off >= len(data) {
+			return nil, fmt.Errorf("%w: invalid object stream %d", errSyntax, num)
+		}
+		p.pos = off
+		objs[i], err = p.object(0)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return objs, nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,190 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/PereRohit/util/testutil"
+)
+
+// testDocument writes a document of two pages in a page tree whose root holds the media box and resources, an
+// unreachable object and an information dictionary
+func testDocument(t *testing.T) []byte {
+	t.Helper()
+	w := NewWriter()
+	pages := w.Reserve()
+	font := w.Add(Helvetica.Dict())
+	content := w.AddStream("", []byte("BT /F1 12 Tf 10 10 Td (hi) Tj ET"))
+	p1 := w.Add(fmt.Sprintf("<< /Type /Page /Parent %s /Contents %s >>", pages, content))
+	p2 := w.Add(fmt.Sprintf("<< /Type /Page /Parent %s /Contents %s /MediaBox [0 0 200 300] >>", pages, content))
+	w.Set(pages, fmt.Sprintf("<< /Type /Pages /Kids [%s %s] /Count 2 /MediaBox [0 0 100 100] /Resources << /Font << /F1 %s >> >> >>", p1, p2, font))
+	w.Add("(unreachable)")
+	root := w.Add(fmt.Sprintf("<< /Type /Catalog /Pages %s >>", pages))
+	info := w.Add("<< /Title (Invoice) >>")
+	out := new(bytes.Buffer)
+	if err := w.WriteTo(out, root, info); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func TestParse(t *testing.T) {
+	d, err := Parse(testDocument(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Version != "1.7" {
+		t.Errorf("want version 1.7 got %s", d.Version)
+	}
+	pages := d.Pages()
+	if len(pages) != 2 {
+		t.Fatalf("want 2 pages got %v", pages)
+	}
+	if box := d.MediaBox(pages[0]); box != [4]float64{0, 0, 100, 100} {
+		t.Errorf("want the inherited media box got %v", box)
+	}
+	if box := d.MediaBox(pages[1]); box != [4]float64{0, 0, 200, 300} {
+		t.Errorf("want the media box of the page got %v", box)
+	}
+	font := d.Dict(d.Dict(d.PageAttr(pages[1], "Resources"))["Font"])
+	if d.Dict(font["F1"]).Name("BaseFont") != "Helvetica" {
+		t.Errorf("unexpected fonts %v", font)
+	}
+	if title, _ := d.Info()["Title"].(Str); string(title) != "Invoice" {
+		t.Errorf("unexpected information %v", d.Info())
+	}
+	s, ok := d.Resolve(d.Dict(pages[0])["Contents"]).(*Stream)
+	if !ok {
+		t.Fatal("content stream not found")
+	}
+	data, err := Decode(s)
+	if err != nil || string(data) != "BT /F1 12 Tf 10 10 Td (hi) Tj ET" {
+		t.Errorf("unexpected content %q %v", data, err)
+	}
+}
+
+func TestParse_Damaged(t *testing.T) {
+	b := testDocument(t)
+	// the offsets of the cross reference table point nowhere once the objects move
+	b = bytes.Replace(b, []byte("%PDF-1.7\n"), []byte("%PDF-1.7\n% shifted\n"), 1)
+	d, err := Parse(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Pages()) != 2 || d.Info() == nil {
+		t.Errorf("the damaged file was not recovered: %v", d.Trailer)
+	}
+}
+
+func TestParse_XrefStream(t *testing.T) {
+	objStm := "1 0 2 45 " +
+		"<< /Type /Catalog /Pages 3 0 R /Lang (en) >> " +
+		"<< /Type /Page /Parent 3 0 R >>"
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.5\n")
+	offsets := map[int]int{}
+	offsets[3] = b.Len()
+	b.WriteString("3 0 obj\n<< /Type /Pages /Kids [2 0 R] /Count 1 >>\nendobj\n")
+	offsets[4] = b.Len()
+	data := Deflate([]byte(objStm))
+	fmt.Fprintf(&b, "4 0 obj\n<< /Type /ObjStm /N 2 /First 9 /Filter /FlateDecode /Length 5 0 R >>\nstream\n%s\nendstream\nendobj\n", data)
+	offsets[5] = b.Len()
+	fmt.Fprintf(&b, "5 0 obj\n%d\nendobj\n", len(data))
+	xref := b.Len()
+	// rows of type, offset or stream and generation or index, encoded with the up predictor of png
+	rows := [][]byte{
+		{0, 0, 0, 0},
+		{2, 0, 4, 0},
+		{2, 0, 4, 1},
+		{1, byte(offsets[3] >> 8), byte(offsets[3]), 0},
+		{1, byte(offsets[4] >> 8), byte(offsets[4]), 0},
+		{1, byte(offsets[5] >> 8), byte(offsets[5]), 0},
+		{1, byte(xref >> 8), byte(xref), 0},
+	}
+	var raw []byte
+	prev := make([]byte, 4)
+	for _, r := range rows {
+		raw = append(raw, 2)
+		for i := range r {
+			raw = append(raw, r[i]-prev[i])
+		}
+		prev = r
+	}
+	data = Deflate(raw)
+	fmt.Fprintf(&b, "6 0 obj\n<< /Type /XRef /Size 7 /W [1 2 1] /Root 1 0 R /ID [<01> <02>] /Filter /FlateDecode "+
+		"/DecodeParms << /Predictor 12 /Columns 4 >> /Length %d >>\nstream\n%s\nendstream\nendobj\n", len(data), data)
+	fmt.Fprintf(&b, "startxref\n%d\n%%%%EOF\n", xref)
+
+	d, err := Parse(b.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lang, _ := d.Catalog()["Lang"].(Str); string(lang) != "en" {
+		t.Errorf("unexpected catalog %v", d.Catalog())
+	}
+	if pages := d.Pages(); len(pages) != 1 || pages[0] != 2 {
+		t.Errorf("unexpected pages %v", pages)
+	}
+	// the object and cross reference streams are rebuilt when the file is written
+	diff := testutil.Diff(d.Refs(), []Ref{1, 2, 3, 5})
+	if diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		err  error
+	}{
+		{name: "no header", in: []byte("hello"), err: errSyntax},
+		{name: "no catalog", in: []byte("%PDF-1.4\n1 0 obj\n<< /Type /Page >>\nendobj\n"), err: ErrNoCatalog},
+		{
+			name: "encrypted",
+			in:   bytes.Replace(testDocument(t), []byte("trailer\n<<"), []byte("trailer\n<< /Encrypt 9 0 R"), 1),
+			err:  ErrEncrypted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.in)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("want %v got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestDocument_Write(t *testing.T) {
+	d, err := Parse(testDocument(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Trailer["ID"] = Array{Str("a"), Str("b")}
+	d.Catalog()["Lang"] = Str("de")
+	d.Add(Dict{"Unused": true})
+	out := new(bytes.Buffer)
+	if err := d.Write(out); err != nil {
+		t.Fatal(err)
+	}
+	again, err := Parse(out.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again.Pages()) != 2 || again.Info() == nil {
+		t.Errorf("unexpected copy %v", again.Trailer)
+	}
+	if lang, _ := again.Catalog()["Lang"].(Str); string(lang) != "de" {
+		t.Errorf("the change of the catalog was not written")
+	}
+	// the unreachable objects are left out
+	if n := len(again.Refs()); n != 7 {
+		t.Errorf("want 7 objects got %d", n)
+	}
+	if !bytes.Contains(out.Bytes(), []byte("/ID [<61> <62>]")) {
+		t.Errorf("the file identifier was not kept")
+	}
+}
@@ -0,0 +1,215 @@
+package pdf
+
+import (
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gobolditalic"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/gomonobold"
+	"golang.org/x/image/font/gofont/gomonobolditalic"
+	"golang.org/x/image/font/gofont/gomonoitalic"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// substitute is a Go font embedded in place of a font the file lacks
+type substitute struct {
+	name string
+	ttf  []byte
+}
+
+// substituteFor returns the Go font closest to the base font name, fonts of symbols have no substitute
+func substituteFor(base Name) (substitute, bool) {
+	n := strings.ToLower(string(base))
+	if i := strings.IndexByte(n, '+'); i == 6 {
+		// the tag of a subset
+		n = n[i+1:]
+	}
+	if strings.Contains(n, "symbol") || strings.Contains(n, "dingbats") {
+		return substitute{}, false
+	}
+	bold := strings.Contains(n, "bold") || strings.Contains(n, "black") || strings.Contains(n, "heavy")
+	italic := strings.Contains(n, "italic") || strings.Contains(n, "oblique")
+	mono := strings.Contains(n, "courier") || strings.Contains(n, "mono")
+	switch {
+	case mono && bold && italic:
+		return substitute{"Go-Mono-Bold-Italic", gomonobolditalic.TTF}, true
+	case mono && bold:
+		return substitute{"Go-Mono-Bold", gomonobold.TTF}, true
+	case mono && italic:
+		return substitute{"Go-Mono-Italic", gomonoitalic.TTF}, true
+	case mono:
+		return substitute{"Go-Mono", gomono.TTF}, true
+	case bold && italic:
+		return substitute{"Go-Bold-Italic", gobolditalic.TTF}, true
+	case bold:
+		return substitute{"Go-Bold", gobold.TTF}, true
+	case italic:
+		return substitute{"Go-Italic", goitalic.TTF}, true
+	}
+	return substitute{"Go-Regular", goregular.TTF}, true
+}
+
+// embedded reports whether the simple or composite font f has its font program in the file
+func (d *Document) embedded(f Dict) bool {
+	switch f.Name("Subtype") {
+	case "Type3":
+		return true
+	case "Type0":
+		desc, _ := d.Resolve(f["DescendantFonts"]).(Array)
+		if len(desc) == 0 {
+			return false
+		}
+		f = d.Dict(desc[0])
+	}
+	fd := d.Dict(f["FontDescriptor"])
+	return fd["FontFile"] != nil || fd["FontFile2"] != nil || fd["FontFile3"] != nil
+}
+
+// fontEmbedder replaces simple fonts without a font program by an embedded Go font, the font programs are added
+// to the document once
+type fontEmbedder struct {
+	d     *Document
+	descs map[string]Ref
+	fonts map[string]*sfnt.Font
+}
+
+func newFontEmbedder(d *Document) *fontEmbedder {
+	return &fontEmbedder{d: d, descs: map[string]Ref{}, fonts: map[string]*sfnt.Font{}}
+}
+
+// embed changes the font dictionary f in place into a TrueType font with an embedded substitute. Only fonts with
+// WinAnsiEncoding or the standard encoding can be substituted, the codes of other encodings name other glyphs.
+func (e *fontEmbedder) embed(f Dict) bool {
+	switch f.Name("Subtype") {
+	case "Type1", "MMType1", "TrueType":
+	default:
+		return false
+	}
+	switch enc := e.d.Resolve(f["Encoding"]).(type) {
+	case nil:
+	case Name:
+		if enc != "WinAnsiEncoding" && enc != "StandardEncoding" {
+			return false
+		}
+	default:
+		return false
+	}
+	sub, ok := substituteFor(f.Name("BaseFont"))
+	if !ok {
+		return false
+	}
+	sf, err := e.font(sub)
+	if err != nil {
+		return false
+	}
+	desc, err := e.descriptor(sub, sf)
+	if err != nil {
+		return false
+	}
+	var b sfnt.Buffer
+	upem := fixed.I(int(sf.UnitsPerEm()))
+	widths := make(Array, 0, 224)
+	for c := 32; c <= 255; c++ {
+		r, ok := decodeWinAnsi(byte(c))
+		var w int64
+		if ok {
+			if g, err := sf.GlyphIndex(&b, r); err == nil && g != 0 {
+				if adv, err := sf.GlyphAdvance(&b, g, upem, font.HintingNone); err == nil {
+					w = int64(adv.Round()) * 1000 / int64(sf.UnitsPerEm())
+				}
+			}
+		}
+		widths = append(widths, w)
+	}
+	for k := range f {
+		delete(f, k)
+	}
+	f["Type"] = Name("Font")
+	f["Subtype"] = Name("TrueType")
+	f["BaseFont"] = Name(sub.name)
+	f["FirstChar"] = int64(32)
+	f["LastChar"] = int64(255)
+	f["Widths"] = widths
+	f["Encoding"] = Name("WinAnsiEncoding")
+	f["FontDescriptor"] = desc
+	return true
+}
+
+func (e *fontEmbedder) font(sub substitute) (*sfnt.Font, error) {
+	if sf, ok := e.fonts[sub.name]; ok {
+		return sf, nil
+	}
+	sf, err := sfnt.Parse(sub.ttf)
+	if err != nil {
+		return nil, err
+	}
+	e.fonts[sub.name] = sf
+	return sf, nil
+}
+
+// descriptor adds the font descriptor and the font program of the substitute
+func (e *fontEmbedder) descriptor(sub substitute, sf *sfnt.Font) (Ref, error) {
+	if r, ok := e.descs[sub.name]; ok {
+		return r, nil
+	}
+	var b sfnt.Buffer
+	upem := fixed.I(int(sf.UnitsPerEm()))
+	scale := func(v fixed.Int26_6) int64 {
+		return int64(v.Round()) * 1000 / int64(sf.UnitsPerEm())
+	}
+	bounds, err := sf.Bounds(&b, upem, font.HintingNone)
+	if err != nil {
+		return 0, err
+	}
+	m, err := sf.Metrics(&b, upem, font.HintingNone)
+	if err != nil {
+		return 0, err
+	}
+	// nonsymbolic, the glyphs are named by the standard latin character set
+	flags := int64(32)
+	italic := float64(0)
+	if strings.Contains(sub.name, "Mono") {
+		flags |= 1
+	}
+	if strings.Contains(sub.name, "Italic") {
+		flags |= 64
+		italic = -12
+	}
+	file := e.d.Add(&Stream{
+		Dict: Dict{"Filter": Name("FlateDecode"), "Length1": int64(len(sub.ttf))},
+		Data: Deflate(sub.ttf),
+	})
+	// the bounds of sfnt grow downwards, the bounding box of pdf upwards
+	r := e.d.Add(Dict{
+		"Type":        Name("FontDescriptor"),
+		"FontName":    Name(sub.name),
+		"Flags":       flags,
+		"FontBBox":    Array{scale(bounds.Min.X), -scale(bounds.Max.Y), scale(bounds.Max.X), -scale(bounds.Min.Y)},
+		"ItalicAngle": italic,
+		"Ascent":      scale(m.Ascent),
+		"Descent":     -scale(m.Descent),
+		"CapHeight":   scale(m.CapHeight),
+		"StemV":       int64(80),
+		"FontFile2":   file,
+	})
+	e.descs[sub.name] = r
+	return r, nil
+}
+
+// decodeWinAnsi returns the character of a WinAnsiEncoding code, ok is false for the unused codes
+func decodeWinAnsi(c byte) (rune, bool) {
+	if (c >= 32 && c <= 126) || c >= 0xa0 {
+		return rune(c), true
+	}
+	for r, code := range winAnsiSpecials {
+		if code == c {
+			return r, true
+		}
+	}
+	return 0, false
+}
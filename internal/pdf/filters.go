@@ -0,0 +1,181 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maxDecodedSize bounds the decoded size of a stream so a compressed bomb can't exhaust the memory
+const maxDecodedSize = 256 << 20
+
+var errFilter = errors.New("unsupported stream filter")
+
+// Decode returns the data of the stream with its filters removed, only the filters the renderers and
+// cross reference streams use are supported
+func Decode(s *Stream) ([]byte, error) {
+	filters, params := streamFilters(s.Dict)
+	data := s.Data
+	for i, f := range filters {
+		var err error
+		switch f {
+		case "FlateDecode", "Fl":
+			data, err = inflate(data)
+			if err == nil {
+				data, err = unpredict(data, params[i])
+			}
+		case "ASCIIHexDecode", "AHx":
+			data, err = hexDecode(data)
+		default:
+			return nil, fmt.Errorf("%w %s", errFilter, f)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// streamFilters returns the filters of a stream with the parameters of every filter
+func streamFilters(d Dict) ([]Name, []Dict) {
+	var filters []Name
+	switch f := d["Filter"].(type) {
+	case Name:
+		filters = []Name{f}
+	case Array:
+		for _, e := range f {
+			if n, ok := e.(Name); ok {
+				filters = append(filters, n)
+			}
+		}
+	}
+	params := make([]Dict, len(filters))
+	switch p := d["DecodeParms"].(type) {
+	case Dict:
+		if len(params) > 0 {
+			params[0] = p
+		}
+	case Array:
+		for i, e := range p {
+			if pd, ok := e.(Dict); ok && i < len(params) {
+				params[i] = pd
+			}
+		}
+	}
+	return filters, params
+}
+
+func inflate(data []byte) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	out, err := io.ReadAll(io.LimitReader(zr, maxDecodedSize+1))
+	if len(out) > maxDecodedSize {
+		return nil, errors.New("stream too large")
+	}
+	// renderers often end the stream without the checksum, the data read so far is complete
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	return out, nil
+}
+
+func hexDecode(data []byte) ([]byte, error) {
+	digits := make([]byte, 0, len(data))
+	for _, c := range data {
+		if c == '>' {
+			break
+		}
+		if !isWhite(c) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	_, err := hex.Decode(out, digits)
+	return out, err
+}
+
+// unpredict removes the png predictors of flate compressed data, tiff predictors are not supported
+func unpredict(data []byte, params Dict) ([]byte, error) {
+	predictor, _ := params["Predictor"].(int64)
+	if predictor < 10 {
+		if predictor == 2 {
+			return nil, fmt.Errorf("%w tiff predictor", errFilter)
+		}
+		return data, nil
+	}
+	colors, bpc, columns := int64(1), int64(8), int64(1)
+	if v, ok := params["Colors"].(int64); ok {
+		colors = v
+	}
+	if v, ok := params["BitsPerComponent"].(int64); ok {
+		bpc = v
+	}
+	if v, ok := params["Columns"].(int64); ok {
+		columns = v
+	}
+	bpp := int((colors*bpc + 7) / 8)
+	rowLen := int((colors*bpc*columns + 7) / 8)
+	if bpp <= 0 || rowLen <= 0 {
+		return nil, errors.New("invalid predictor parameters")
+	}
+	out := make([]byte, 0, len(data))
+	prev := make([]byte, rowLen)
+	for len(data) > 0 {
+		if len(data) < rowLen+1 {
+			return nil, errors.New("truncated predictor row")
+		}
+		kind, row := data[0], append([]byte(nil), data[1:rowLen+1]...)
+		data = data[rowLen+1:]
+		for i := range row {
+			var left, upLeft byte
+			if i >= bpp {
+				left, upLeft = row[i-bpp], prev[i-bpp]
+			}
+			up := prev[i]
+			switch kind {
+			case 0:
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += up
+			case 3:
+				row[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[i] += paeth(left, up, upLeft)
+			default:
+				return nil, fmt.Errorf("invalid png predictor %d", kind)
+			}
+		}
+		out = append(out, row...)
+		prev = row
+	}
+	return out, nil
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	if pa <= pb && pa <= pc {
+		return a
+	}
+	if pb <= pc {
+		return b
+	}
+	return c
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,73 @@
+package pdf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/PereRohit/util/testutil"
+)
+
+func TestDecode(t *testing.T) {
+	hello := Deflate([]byte("hello"))
+	tests := []struct {
+		name   string
+		stream *Stream
+		want   []byte
+		err    error
+	}{
+		{
+			name:   "no filter",
+			stream: &Stream{Dict: Dict{}, Data: []byte("raw")},
+			want:   []byte("raw"),
+		},
+		{
+			name:   "flate",
+			stream: &Stream{Dict: Dict{"Filter": Name("FlateDecode")}, Data: hello},
+			want:   []byte("hello"),
+		},
+		{
+			name:   "flate without checksum",
+			stream: &Stream{Dict: Dict{"Filter": Name("FlateDecode")}, Data: hello[:len(hello)-4]},
+			want:   []byte("hello"),
+		},
+		{
+			name: "png predictors",
+			stream: &Stream{
+				Dict: Dict{"Filter": Name("FlateDecode"), "DecodeParms": Dict{"Predictor": int64(12), "Columns": int64(2)}},
+				// a row without prediction, one predicted by the row above and one predicted by the left byte
+				Data: Deflate([]byte{0, 1, 2, 2, 1, 1, 1, 5, 1}),
+			},
+			want: []byte{1, 2, 2, 3, 5, 6},
+		},
+		{
+			name:   "hex and flate",
+			stream: &Stream{Dict: Dict{"Filter": Array{Name("AHx"), Name("Fl")}}, Data: []byte("789c cb48cdc9c907000 62c0215>")},
+			want:   []byte("hello"),
+		},
+		{
+			name:   "unsupported filter",
+			stream: &Stream{Dict: Dict{"Filter": Name("DCTDecode")}, Data: []byte{0xff}},
+			err:    errFilter,
+		},
+		{
+			name: "tiff predictor",
+			stream: &Stream{
+				Dict: Dict{"Filter": Name("FlateDecode"), "DecodeParms": Dict{"Predictor": int64(2)}},
+				Data: Deflate([]byte{1}),
+			},
+			err: errFilter,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(tt.stream)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("want %v got %v", tt.err, err)
+			}
+			diff := testutil.Diff(got, tt.want)
+			if diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package pdf
+
+import "unicode/utf8"
+
+// Font is one of the standard fonts every pdf reader provides, the text is encoded with WinAnsiEncoding
+type Font struct {
+	// Name is the base font name, Key the resource name used in content streams
+	Name string
+	Key  string
+	// widths of the printable ascii characters in thousandths of the font size, nil for a monospaced font
+	widths       []int
+	defaultWidth int
+}
+
+// helveticaWidths and helveticaBoldWidths are the widths of the characters from space to tilde
+var helveticaWidths = []int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = []int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
+
+// the standard fonts used by the renderer, the oblique fonts share the widths of the upright ones
+var (
+	Helvetica            = &Font{Name: "Helvetica", Key: "F1", widths: helveticaWidths, defaultWidth: 556}
+	HelveticaBold        = &Font{Name: "Helvetica-Bold", Key: "F2", widths: helveticaBoldWidths, defaultWidth: 611}
+	HelveticaOblique     = &Font{Name: "Helvetica-Oblique", Key: "F3", widths: helveticaWidths, defaultWidth: 556}
+	HelveticaBoldOblique = &Font{Name: "Helvetica-BoldOblique", Key: "F4", widths: helveticaBoldWidths, defaultWidth: 611}
+	Courier              = &Font{Name: "Courier", Key: "F5", defaultWidth: 600}
+	CourierBold          = &Font{Name: "Courier-Bold", Key: "F6", defaultWidth: 600}
+)
+
+// Dict returns the font dictionary
+func (f *Font) Dict() string {
+	return "<< /Type /Font /Subtype /Type1 /BaseFont /" + f.Name + " /Encoding /WinAnsiEncoding >>"
+}
+
+// Width returns the width of s set in the font at size
+func (f *Font) Width(s string, size float64) float64 {
+	w := 0
+	for _, c := range EncodeWinAnsi(s) {
+		w += f.charWidth(c)
+	}
+	return float64(w) * size / 1000
+}
+
+func (f *Font) charWidth(c byte) int {
+	if f.widths == nil {
+		return f.defaultWidth
+	}
+	if c >= 32 && int(c-32) < len(f.widths) {
+		return f.widths[c-32]
+	}
+	switch c {
+	case 0x85, 0x89, 0x97:
+		// ellipsis, per mille and em dash
+		return 1000
+	case 0x91, 0x92, 0x82:
+		return 222
+	case 0x93, 0x94, 0x84:
+		return 333
+	case 0x95:
+		return 350
+	case 0xa0:
+		return 278
+	}
+	return f.defaultWidth
+}
+
+// winAnsiSpecials are the characters WinAnsiEncoding maps to the codes 0x80 to 0x9f
+var winAnsiSpecials = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88, '‰': 0x89,
+	'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95,
+	'–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b, 'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+// EncodeWinAnsi encodes s with WinAnsiEncoding, characters the encoding lacks become a question mark
+func EncodeWinAnsi(s string) []byte {
+	b := make([]byte, 0, len(s))
+	for len(s) > 0 {
+		r, n := utf8.DecodeRuneInString(s)
+		s = s[n:]
+		switch {
+		case r >= 32 && r <= 126, r >= 0xa0 && r <= 0xff:
+			b = append(b, byte(r))
+		case winAnsiSpecials[r] != 0:
+			b = append(b, winAnsiSpecials[r])
+		default:
+			b = append(b, '?')
+		}
+	}
+	return b
+}
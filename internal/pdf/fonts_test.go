@@ -0,0 +1,43 @@
+package pdf
+
+import "testing"
+
+func TestFont_Width(t *testing.T) {
+	tests := []struct {
+		name string
+		font *Font
+		text string
+		size float64
+		want float64
+	}{
+		{name: "helvetica", font: Helvetica, text: "Hello", size: 10, want: 22.78},
+		{name: "helvetica bold", font: HelveticaBold, text: "Hello", size: 10, want: 24.45},
+		{name: "oblique shares the widths", font: HelveticaOblique, text: "Hello", size: 10, want: 22.78},
+		{name: "courier", font: Courier, text: "Hello", size: 10, want: 30},
+		{name: "special characters", font: Helvetica, text: "•—", size: 10, want: 13.5},
+		{name: "empty", font: Helvetica, text: "", size: 10, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.font.Width(tt.text, tt.size)
+			if got < tt.want-0.001 || got > tt.want+0.001 {
+				t.Errorf("want %v got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFont_Dict(t *testing.T) {
+	want := "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>"
+	if got := HelveticaBold.Dict(); got != want {
+		t.Errorf("want %s got %s", want, got)
+	}
+}
+
+func TestEncodeWinAnsi(t *testing.T) {
+	got := string(EncodeWinAnsi("Aä€“” Ω"))
+	want := "A\xe4\x80\x93\x94\xa0?"
+	if got != want {
+		t.Errorf("want %q got %q", want, got)
+	}
+}
@@ -0,0 +1,108 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// SRGBCondition is the output condition identifier of the sRGB profile
+const SRGBCondition = "sRGB IEC61966-2.1"
+
+// iccTag is a tag of an icc profile, tags with the same data share it
+type iccTag struct {
+	sig  string
+	data []byte
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+// SRGBProfile returns a version 2 icc display profile of the sRGB color space, the primaries adapted to the D50
+// white of the profile connection space and the sRGB tone curve as a table
+func SRGBProfile() []byte {
+	xyz := func(x, y, z float64) []byte {
+		b := []byte("XYZ \x00\x00\x00\x00")
+		for _, v := range []float64{x, y, z} {
+			b = appendUint32(b, uint32(int32(math.Round(v*65536))))
+		}
+		return b
+	}
+	// the sRGB transfer function sampled at 1024 points
+	curve := []byte("curv\x00\x00\x00\x00")
+	curve = appendUint32(curve, 1024)
+	for i := 0; i < 1024; i++ {
+		v := float64(i) / 1023
+		if v <= 0.04045 {
+			v /= 12.92
+		} else {
+			v = math.Pow((v+0.055)/1.055, 2.4)
+		}
+		c := uint16(math.Round(v * 65535))
+		curve = append(curve, byte(c>>8), byte(c))
+	}
+	desc := []byte("desc\x00\x00\x00\x00")
+	desc = appendUint32(desc, uint32(len(SRGBCondition)+1))
+	desc = append(desc, SRGBCondition...)
+	// the terminating null, the empty unicode and the empty macintosh description
+	desc = append(desc, make([]byte, 1+4+4+2+1+67)...)
+	cprt := append([]byte("text\x00\x00\x00\x00"), "No copyright, use freely\x00"...)
+	tags := []iccTag{
+		{"desc", desc},
+		{"cprt", cprt},
+		{"wtpt", xyz(0.9642, 1, 0.8249)},
+		{"rXYZ", xyz(0.4361, 0.2225, 0.0139)},
+		{"gXYZ", xyz(0.3851, 0.7169, 0.0971)},
+		{"bXYZ", xyz(0.1431, 0.0606, 0.7141)},
+		{"rTRC", curve},
+		{"gTRC", curve},
+		{"bTRC", curve},
+	}
+	table := appendUint32(nil, uint32(len(tags)))
+	data := new(bytes.Buffer)
+	offsets := map[string]int{}
+	start := 128 + 4 + 12*len(tags)
+	for _, t := range tags {
+		off, ok := offsets[string(t.data)]
+		if !ok {
+			off = start + data.Len()
+			offsets[string(t.data)] = off
+			data.Write(t.data)
+			// tag data starts on a four byte boundary
+			for data.Len()%4 != 0 {
+				data.WriteByte(0)
+			}
+		}
+		table = append(table, t.sig...)
+		table = appendUint32(table, uint32(off))
+		table = appendUint32(table, uint32(len(t.data)))
+	}
+	header := make([]byte, 128)
+	binary.BigEndian.PutUint32(header[0:], uint32(128+len(table)+data.Len()))
+	binary.BigEndian.PutUint32(header[8:], 0x02100000)
+	copy(header[12:], "mntrRGB XYZ ")
+	for i, v := range []uint16{2024, 1, 1} {
+		binary.BigEndian.PutUint16(header[24+2*i:], v)
+	}
+	copy(header[36:], "acsp")
+	// the D50 illuminant of the profile connection space
+	copy(header[68:], xyz(0.9642, 1, 0.8249)[8:])
+	return append(append(header, table...), data.Bytes()...)
+}
+
+// iccColorSpace checks the header of an icc profile and returns the number of color components of its color space
+func iccColorSpace(p []byte) (int, bool) {
+	if len(p) < 132 || string(p[36:40]) != "acsp" || int(binary.BigEndian.Uint32(p)) != len(p) {
+		return 0, false
+	}
+	switch string(p[16:20]) {
+	case "GRAY":
+		return 1, true
+	case "RGB ":
+		return 3, true
+	case "CMYK":
+		return 4, true
+	}
+	return 0, false
+}
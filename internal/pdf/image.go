@@ -0,0 +1,35 @@
+package pdf
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// AddImage adds an image XObject of img, transparent pixels are blended with white and grayscale stores
+// the image in DeviceGray
+func (w *Writer) AddImage(img image.Image, grayscale bool) Ref {
+	b := img.Bounds()
+	channels, space := 3, "DeviceRGB"
+	if grayscale {
+		channels, space = 1, "DeviceGray"
+	}
+	data := make([]byte, 0, b.Dx()*b.Dy()*channels)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			r, g, bl := blendWhite(c.R, c.A), blendWhite(c.G, c.A), blendWhite(c.B, c.A)
+			if grayscale {
+				data = append(data, color.GrayModel.Convert(color.RGBA{R: r, G: g, B: bl, A: 0xff}).(color.Gray).Y)
+				continue
+			}
+			data = append(data, r, g, bl)
+		}
+	}
+	dict := fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /%s /BitsPerComponent 8", b.Dx(), b.Dy(), space)
+	return w.AddStream(dict, data)
+}
+
+func blendWhite(c, alpha uint8) uint8 {
+	return uint8((int(c)*int(alpha) + 255*(255-int(alpha))) / 255)
+}
@@ -0,0 +1,45 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"image"
+	"image/color"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestWriter_AddImage(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.NRGBA{R: 255, A: 255})
+	img.Set(1, 0, color.NRGBA{A: 0})
+	tests := []struct {
+		name      string
+		grayscale bool
+		wantDict  string
+		wantData  []byte
+	}{
+		{name: "rgb", wantDict: "/Width 2 /Height 1 /ColorSpace /DeviceRGB", wantData: []byte{255, 0, 0, 255, 255, 255}},
+		{name: "grayscale", grayscale: true, wantDict: "/ColorSpace /DeviceGray", wantData: []byte{76, 255}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewWriter()
+			r := w.AddImage(img, tt.grayscale)
+			obj := w.objects[r-1]
+			if !strings.Contains(string(obj), tt.wantDict) {
+				t.Errorf("unexpected image dictionary %s", obj)
+			}
+			i := bytes.Index(obj, []byte("stream\n"))
+			zr, err := zlib.NewReader(bytes.NewReader(obj[i+len("stream\n"):]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := io.ReadAll(zr)
+			if !bytes.Equal(data, tt.wantData) {
+				t.Errorf("want %v got %v", tt.wantData, data)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package pdf
+
+import (
+	"errors"
+)
+
+// ErrNoPages is returned by Merge when the parts have no pages
+var ErrNoPages = errors.New("no pages to merge")
+
+// maxOutlineItems bounds the outline items read from a part so a cyclic outline can't loop forever
+const maxOutlineItems = 100000
+
+// inheritable are the page attributes a page inherits from its ancestors in the page tree
+var inheritable = []Name{"Resources", "MediaBox", "CropBox", "Rotate"}
+
+// MergePart is a document appended by Merge. The outline of the part is nested below an item named Title that
+// points to the first page of the part, without a title the items of the part are added at the top level.
+type MergePart struct {
+	Doc   *Document
+	Title string
+}
+
+// Merge appends the pages of the parts in order to a new document with a combined outline. The objects of every
+// part are renumbered and named destinations are replaced by the destinations they name. Catalog entries other
+// than the pages and the outline, e.g. forms and the structure tree, are left out. The information dictionary
+// is the one of the first part.
+func Merge(parts []MergePart) (*Document, error) {
+	m := &Document{Version: "1.4", Trailer: Dict{}, objects: map[int]Object{}, next: 1}
+	pages := Dict{"Type": Name("Pages")}
+	pagesRef := m.Add(pages)
+	var kids Array
+	var outline []Ref
+	for i, p := range parts {
+		base := m.next - 1
+		for n, o := range p.Doc.objects {
+			m.objects[n+base] = renumber(o, base)
+		}
+		m.next = base + p.Doc.next
+		if p.Doc.Version > m.Version {
+			m.Version = p.Doc.Version
+		}
+		if i == 0 && p.Doc.Trailer["Info"] != nil {
+			m.Trailer["Info"] = renumber(p.Doc.Trailer["Info"], base)
+		}
+		named := func(dest Object) Object {
+			if d := p.Doc.namedDest(dest); d != nil {
+				return renumber(d, base)
+			}
+			return dest
+		}
+		src := p.Doc.Pages()
+		for _, r := range src {
+			page := m.Dict(r + Ref(base))
+			if page == nil {
+				continue
+			}
+			// the page tree of the part is left behind, the attributes the page inherited from it move to the page
+			for _, key := range inheritable {
+				if _, ok := page[key]; !ok {
+					if v := p.Doc.PageAttr(r, key); v != nil {
+						page[key] = renumber(v, base)
+					}
+				}
+			}
+			page["Parent"] = pagesRef
+			kids = append(kids, r+Ref(base))
+			annots, _ := m.Resolve(page["Annots"]).(Array)
+			for _, a := range annots {
+				resolveDests(m, m.Dict(a), named)
+			}
+		}
+		items := m.outlineItems(m.Dict(renumber(p.Doc.Catalog()["Outlines"], base)))
+		for _, it := range items {
+			resolveDests(m, m.Dict(it), named)
+		}
+		top := m.outlineKids(m.Dict(renumber(p.Doc.Catalog()["Outlines"], base)))
+		if p.Title == "" || len(src) == 0 {
+			outline = append(outline, top...)
+			continue
+		}
+		item := Dict{"Title": EncodeText(p.Title), "Dest": Array{src[0] + Ref(base), Name("XYZ"), nil, nil, nil}}
+		r := m.Add(item)
+		m.linkOutline(r, top)
+		outline = append(outline, r)
+	}
+	if len(kids) == 0 {
+		return nil, ErrNoPages
+	}
+	pages["Kids"] = kids
+	pages["Count"] = int64(len(kids))
+	catalog := Dict{"Type": Name("Catalog"), "Pages": pagesRef}
+	if len(outline) > 0 {
+		root := m.Add(Dict{"Type": Name("Outlines")})
+		m.linkOutline(root, outline)
+		catalog["Outlines"] = root
+		catalog["PageMode"] = Name("UseOutlines")
+	}
+	m.Trailer["Root"] = m.Add(catalog)
+	return m, nil
+}
+
+// renumber returns a copy of o with the references moved by base
+func renumber(o Object, base int) Object {
+	switch v := o.(type) {
+	case Ref:
+		return v + Ref(base)
+	case Array:
+		arr := make(Array, len(v))
+		for i, e := range v {
+			arr[i] = renumber(e, base)
+		}
+		return arr
+	case Dict:
+		d := make(Dict, len(v))
+		for k, e := range v {
+			d[k] = renumber(e, base)
+		}
+		return d
+	case *Stream:
+		return &Stream{Dict: renumber(v.Dict, base).(Dict), Data: v.Data}
+	}
+	return o
+}
+
+// resolveDests replaces the named destinations of an outline item or link annotation
+func resolveDests(d *Document, item Dict, named func(Object) Object) {
+	if item == nil {
+		return
+	}
+	if dest, ok := item["Dest"]; ok {
+		item["Dest"] = named(dest)
+	}
+	if a := d.Dict(item["A"]); a.Name("S") == "GoTo" {
+		a["D"] = named(a["D"])
+	}
+}
+
+// namedDest returns the destination named by dest from the Dests dictionary or name tree of the catalog, nil
+// if dest is no name or the name is unknown
+func (d *Document) namedDest(dest Object) Object {
+	var key string
+	switch v := d.Resolve(dest).(type) {
+	case Name:
+		key = string(v)
+	case Str:
+		key = string(v)
+	default:
+		return nil
+	}
+	cat := d.Catalog()
+	if v, ok := d.Dict(cat["Dests"])[Name(key)]; ok {
+		return d.explicitDest(v)
+	}
+	if v := d.nameTreeLookup(d.Dict(d.Dict(cat["Names"])["Dests"]), key, 0); v != nil {
+		return d.explicitDest(v)
+	}
+	return nil
+}
+
+// explicitDest returns the destination array of a named destination, which is the array or a dictionary holding
+// it under D
+func (d *Document) explicitDest(v Object) Object {
+	switch t := d.Resolve(v).(type) {
+	case Array:
+		return t
+	case Dict:
+		if arr, ok := d.Resolve(t["D"]).(Array); ok {
+			return arr
+		}
+	}
+	return nil
+}
+
+func (d *Document) nameTreeLookup(node Dict, key string, depth int) Object {
+	if node == nil || depth > 32 {
+		return nil
+	}
+	names, _ := d.Resolve(node["Names"]).(Array)
+	for i := 0; i+1 < len(names); i += 2 {
+		if k, ok := d.Resolve(names[i]).(Str); ok && string(k) == key {
+			return names[i+1]
+		}
+	}
+	kids, _ := d.Resolve(node["Kids"]).(Array)
+	for _, k := range kids {
+		kid := d.Dict(k)
+		if limits, ok := d.Resolve(kid["Limits"]).(Array); ok && len(limits) == 2 {
+			lo, _ := d.Resolve(limits[0]).(Str)
+			hi, _ := d.Resolve(limits[1]).(Str)
+			if key < string(lo) || key > string(hi) {
+				continue
+			}
+		}
+		if v := d.nameTreeLookup(kid, key, depth+1); v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+// outlineKids returns the children of an outline node
+func (d *Document) outlineKids(node Dict) []Ref {
+	var kids []Ref
+	seen := map[Ref]bool{}
+	r, ok := node["First"].(Ref)
+	for ok && !seen[r] && len(kids) < maxOutlineItems {
+		seen[r] = true
+		kids = append(kids, r)
+		r, ok = d.Dict(r)["Next"].(Ref)
+	}
+	return kids
+}
+
+// outlineItems returns all items below an outline node
+func (d *Document) outlineItems(node Dict) []Ref {
+	var items []Ref
+	seen := map[Ref]bool{}
+	stack := d.outlineKids(node)
+	for len(stack) > 0 && len(items) < maxOutlineItems {
+		r := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[r] {
+			continue
+		}
+		seen[r] = true
+		items = append(items, r)
+		stack = append(stack, d.outlineKids(d.Dict(r))...)
+	}
+	return items
+}
+
+// linkOutline makes the items the children of the outline node parent and returns the number of visible
+// descendants, which is stored as the count of an open node
+func (d *Document) linkOutline(parent Ref, items []Ref) int {
+	p := d.Dict(parent)
+	delete(p, "First")
+	delete(p, "Last")
+	delete(p, "Count")
+	if len(items) == 0 {
+		return 0
+	}
+	p["First"], p["Last"] = items[0], items[len(items)-1]
+	count := 0
+	for i, r := range items {
+		it := d.Dict(r)
+		it["Parent"] = parent
+		delete(it, "Prev")
+		delete(it, "Next")
+		if i > 0 {
+			it["Prev"] = items[i-1]
+		}
+		if i < len(items)-1 {
+			it["Next"] = items[i+1]
+		}
+		count++
+		if c, _ := it["Count"].(int64); c > 0 {
+			count += int(c)
+		}
+	}
+	if p.Name("Type") == "Outlines" || count > 0 {
+		p["Count"] = int64(count)
+	}
+	return count
+}
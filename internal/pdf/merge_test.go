@@ -0,0 +1,116 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/PereRohit/util/testutil"
+)
+
+// outlined writes a document of one page with an outline of a chapter holding a section, the section and a link
+// on the page point to the named destination sec
+func outlined(t *testing.T) []byte {
+	t.Helper()
+	w := NewWriter()
+	pages := w.Reserve()
+	outlines := w.Reserve()
+	chapter := w.Reserve()
+	link := w.Add("<< /Type /Annot /Subtype /Link /Rect [0 0 10 10] /A << /S /GoTo /D (sec) >> >>")
+	page := w.Add(fmt.Sprintf("<< /Type /Page /Parent %s /Annots [%s] >>", pages, link))
+	w.Set(pages, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count 1 /MediaBox [0 0 50 50] >>", page))
+	section := w.Add(fmt.Sprintf("<< /Title (Section) /Parent %s /Dest (sec) >>", chapter))
+	w.Set(chapter, fmt.Sprintf("<< /Title (Chapter) /Parent %s /First %s /Last %s /Count 1 /Dest [%s /Fit] >>", outlines, section, section, page))
+	w.Set(outlines, fmt.Sprintf("<< /Type /Outlines /First %s /Last %s /Count 2 >>", chapter, chapter))
+	dests := w.Add(fmt.Sprintf("<< /Names [(sec) << /D [%s /XYZ 0 40 null] >>] >>", page))
+	root := w.Add(fmt.Sprintf("<< /Type /Catalog /Pages %s /Outlines %s /Names << /Dests %s >> >>", pages, outlines, dests))
+	out := new(bytes.Buffer)
+	if err := w.WriteTo(out, root, 0); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func TestMerge(t *testing.T) {
+	plain, err := Parse(testDocument(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	withOutline, err := Parse(outlined(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := Merge([]MergePart{{Doc: plain, Title: "Invoice"}, {Doc: withOutline}, {Doc: withOutline, Title: "Again"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(bytes.Buffer)
+	if err := m.Write(out); err != nil {
+		t.Fatal(err)
+	}
+	d, err := Parse(out.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages := d.Pages()
+	if len(pages) != 4 {
+		t.Fatalf("want 4 pages got %v", pages)
+	}
+	var boxes [][4]float64
+	for _, p := range pages {
+		boxes = append(boxes, d.MediaBox(p))
+	}
+	diff := testutil.Diff(boxes, [][4]float64{{0, 0, 100, 100}, {0, 0, 200, 300}, {0, 0, 50, 50}, {0, 0, 50, 50}})
+	if diff != "" {
+		t.Error(diff)
+	}
+	if d.Dict(d.Dict(d.Dict(pages[0])["Resources"])["Font"])["F1"] == nil {
+		t.Error("the inherited resources were not moved to the page")
+	}
+	if title, _ := d.Info()["Title"].(Str); string(title) != "Invoice" {
+		t.Errorf("unexpected information %v", d.Info())
+	}
+
+	// the outline and the link of every copy point to the page of that copy
+	var titles []string
+	var targets []Object
+	var walk func(node Dict, depth int)
+	walk = func(node Dict, depth int) {
+		for _, r := range d.outlineKids(node) {
+			it := d.Dict(r)
+			titles = append(titles, fmt.Sprintf("%d %s", depth, DecodeText(it["Title"].(Str))))
+			dest, _ := d.Resolve(it["Dest"]).(Array)
+			targets = append(targets, dest[0])
+			walk(it, depth+1)
+		}
+	}
+	walk(d.Dict(d.Catalog()["Outlines"]), 0)
+	diff = testutil.Diff(titles, []string{"0 Invoice", "0 Chapter", "1 Section", "0 Again", "1 Chapter", "2 Section"})
+	if diff != "" {
+		t.Error(diff)
+	}
+	diff = testutil.Diff(targets, []Object{pages[0], pages[2], pages[2], pages[3], pages[3], pages[3]})
+	if diff != "" {
+		t.Error(diff)
+	}
+	if count := d.Dict(d.Catalog()["Outlines"])["Count"]; count != int64(6) {
+		t.Errorf("want 6 visible items got %v", count)
+	}
+	for _, p := range pages[2:] {
+		annots, _ := d.Resolve(d.Dict(p)["Annots"]).(Array)
+		dest, _ := d.Resolve(d.Dict(d.Dict(annots[0])["A"])["D"]).(Array)
+		if len(dest) != 5 || dest[0] != p {
+			t.Errorf("the link of page %v points to %v", p, dest)
+		}
+	}
+	if d.Catalog().Name("PageMode") != "UseOutlines" {
+		t.Errorf("unexpected catalog %v", d.Catalog())
+	}
+}
+
+func TestMerge_NoPages(t *testing.T) {
+	if _, err := Merge(nil); !errors.Is(err, ErrNoPages) {
+		t.Errorf("want %v got %v", ErrNoPages, err)
+	}
+}
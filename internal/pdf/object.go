@@ -0,0 +1,136 @@
+package pdf
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Object is a pdf object read from a file: nil, bool, int64, float64, Name, Str, Array, Dict, *Stream or Ref
+type Object interface{}
+
+// Name is a pdf name without the leading slash
+type Name string
+
+// Str is a pdf string, the bytes of a literal or hex string
+type Str []byte
+
+// Array is a pdf array
+type Array []Object
+
+// Dict is a pdf dictionary
+type Dict map[Name]Object
+
+// Stream is a stream object, Data holds the stream data as it is stored with the filters of the dictionary applied
+type Stream struct {
+	Dict Dict
+	Data []byte
+}
+
+// Name returns the name stored at key, "" if the entry is no name
+func (d Dict) Name(key Name) Name {
+	n, _ := d[key].(Name)
+	return n
+}
+
+// Clone returns a shallow copy of the dictionary
+func (d Dict) Clone() Dict {
+	c := make(Dict, len(d))
+	for k, v := range d {
+		c[k] = v
+	}
+	return c
+}
+
+// Format returns the pdf syntax of o, the entries of dictionaries are sorted by key. Streams can't be formatted,
+// they are written as indirect objects by the Writer.
+func Format(o Object) string {
+	sb := new(strings.Builder)
+	format(sb, o)
+	return sb.String()
+}
+
+func format(sb *strings.Builder, o Object) {
+	switch v := o.(type) {
+	case nil:
+		sb.WriteString("null")
+	case bool:
+		sb.WriteString(strconv.FormatBool(v))
+	case int64:
+		sb.WriteString(strconv.FormatInt(v, 10))
+	case int:
+		sb.WriteString(strconv.Itoa(v))
+	case float64:
+		sb.WriteString(formatReal(v))
+	case Name:
+		sb.WriteString(formatName(v))
+	case Str:
+		sb.WriteString(LiteralString(v))
+	case Ref:
+		sb.WriteString(v.String())
+	case Array:
+		sb.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				sb.WriteByte(' ')
+			}
+			format(sb, e)
+		}
+		sb.WriteByte(']')
+	case Dict:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, string(k))
+		}
+		sort.Strings(keys)
+		sb.WriteString("<<")
+		for _, k := range keys {
+			sb.WriteByte(' ')
+			sb.WriteString(formatName(Name(k)))
+			sb.WriteByte(' ')
+			format(sb, v[Name(k)])
+		}
+		sb.WriteString(" >>")
+	default:
+		// streams are only valid as indirect objects
+		sb.WriteString("null")
+	}
+}
+
+// formatReal writes a real number without an exponent, which pdf does not support
+func formatReal(f float64) string {
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if strings.Contains(s, ".") && len(s) > 12 {
+		s = strconv.FormatFloat(f, 'f', 6, 64)
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+// formatName escapes the delimiters, white space and bytes outside printable ascii with #xx
+func formatName(n Name) string {
+	sb := new(strings.Builder)
+	sb.WriteByte('/')
+	for i := 0; i < len(n); i++ {
+		c := n[i]
+		if c < 33 || c > 126 || c == '#' || isDelimiter(c) {
+			sb.WriteByte('#')
+			sb.WriteString(strconv.FormatUint(uint64(c)>>4, 16))
+			sb.WriteString(strconv.FormatUint(uint64(c)&0xf, 16))
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+// toFloat returns the value of a number object
+func toFloat(o Object) (float64, bool) {
+	switch v := o.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
@@ -0,0 +1,82 @@
+package pdf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OutlineItem is a bookmark of the document outline pointing to a position on a page, Level 1 items are the top level
+type OutlineItem struct {
+	Title string
+	Level int
+	Page  Ref
+	// Top is the distance of the target from the bottom of the page
+	Top float64
+}
+
+type outlineNode struct {
+	item  OutlineItem
+	ref   Ref
+	kids  []*outlineNode
+	count int
+}
+
+// AddOutline adds the outline of the items in document order and returns its root, or 0 without items.
+// An item nested deeper than the item before it becomes a child of that item.
+func (w *Writer) AddOutline(items []OutlineItem) Ref {
+	if len(items) == 0 {
+		return 0
+	}
+	root := &outlineNode{ref: w.Reserve()}
+	stack := []*outlineNode{root}
+	for _, it := range items {
+		// the root is level 0, an item is added to the last open node above its level
+		for len(stack) > 1 && stack[len(stack)-1].item.Level >= it.Level {
+			stack = stack[:len(stack)-1]
+		}
+		n := &outlineNode{item: it, ref: w.Reserve()}
+		parent := stack[len(stack)-1]
+		parent.kids = append(parent.kids, n)
+		stack = append(stack, n)
+	}
+	countOutline(root)
+	w.Set(root.ref, fmt.Sprintf("<< /Type /Outlines%s /Count %d >>", kidsEntries(root), root.count))
+	w.setOutlineKids(root)
+	return root.ref
+}
+
+// countOutline sets the number of descendants of every node, all items are open
+func countOutline(n *outlineNode) int {
+	n.count = 0
+	for _, k := range n.kids {
+		n.count += 1 + countOutline(k)
+	}
+	return n.count
+}
+
+func kidsEntries(n *outlineNode) string {
+	if len(n.kids) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" /First %s /Last %s", n.kids[0].ref, n.kids[len(n.kids)-1].ref)
+}
+
+func (w *Writer) setOutlineKids(parent *outlineNode) {
+	for i, n := range parent.kids {
+		sb := new(strings.Builder)
+		fmt.Fprintf(sb, "<< /Title %s /Parent %s", TextString(n.item.Title), parent.ref)
+		if i > 0 {
+			fmt.Fprintf(sb, " /Prev %s", parent.kids[i-1].ref)
+		}
+		if i < len(parent.kids)-1 {
+			fmt.Fprintf(sb, " /Next %s", parent.kids[i+1].ref)
+		}
+		sb.WriteString(kidsEntries(n))
+		if n.count > 0 {
+			fmt.Fprintf(sb, " /Count %d", n.count)
+		}
+		fmt.Fprintf(sb, " /Dest [%s /XYZ null %s null] >>", n.item.Page, Number(n.item.Top))
+		w.Set(n.ref, sb.String())
+		w.setOutlineKids(n)
+	}
+}
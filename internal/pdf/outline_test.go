@@ -0,0 +1,36 @@
+package pdf
+
+import (
+	"testing"
+
+	"github.com/PereRohit/util/testutil"
+)
+
+func TestWriter_AddOutline(t *testing.T) {
+	w := NewWriter()
+	page := w.Add("<< /Type /Page >>")
+	if r := w.AddOutline(nil); r != 0 {
+		t.Errorf("want no outline got %v", r)
+	}
+	root := w.AddOutline([]OutlineItem{
+		{Title: "Intro", Level: 1, Page: page, Top: 800},
+		{Title: "Scope", Level: 2, Page: page, Top: 700},
+		{Title: "Details", Level: 3, Page: page, Top: 600},
+		{Title: "Terms", Level: 1, Page: page, Top: 500},
+	})
+	var got []string
+	for _, o := range w.objects[root-1:] {
+		got = append(got, string(o))
+	}
+	want := []string{
+		"<< /Type /Outlines /First 3 0 R /Last 6 0 R /Count 4 >>",
+		"<< /Title (Intro) /Parent 2 0 R /Next 6 0 R /First 4 0 R /Last 4 0 R /Count 2 /Dest [1 0 R /XYZ null 800 null] >>",
+		"<< /Title (Scope) /Parent 3 0 R /First 5 0 R /Last 5 0 R /Count 1 /Dest [1 0 R /XYZ null 700 null] >>",
+		"<< /Title (Details) /Parent 4 0 R /Dest [1 0 R /XYZ null 600 null] >>",
+		"<< /Title (Terms) /Parent 2 0 R /Prev 3 0 R /Dest [1 0 R /XYZ null 500 null] >>",
+	}
+	diff := testutil.Diff(got, want)
+	if diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,323 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var errSyntax = errors.New("pdf syntax error")
+
+// maxNesting bounds the nesting of arrays and dictionaries so a malicious file can't exhaust the stack
+const maxNesting = 256
+
+func isWhite(c byte) bool {
+	switch c {
+	case 0, '\t', '\n', '\f', '\r', ' ':
+		return true
+	}
+	return false
+}
+
+func isDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+// parser reads objects from the bytes of a file, lengths given as references are resolved with length
+type parser struct {
+	b      []byte
+	pos    int
+	length func(Ref) (int, bool)
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.b) {
+		c := p.b[p.pos]
+		if c == '%' {
+			for p.pos < len(p.b) && p.b[p.pos] != '\n' && p.b[p.pos] != '\r' {
+				p.pos++
+			}
+			continue
+		}
+		if !isWhite(c) {
+			return
+		}
+		p.pos++
+	}
+}
+
+// keyword reads a regular token such as a number, true, obj or R
+func (p *parser) keyword() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.b) && !isWhite(p.b[p.pos]) && !isDelimiter(p.b[p.pos]) {
+		p.pos++
+	}
+	return string(p.b[start:p.pos])
+}
+
+// peekKeyword returns the next regular token without consuming it
+func (p *parser) peekKeyword() string {
+	pos := p.pos
+	k := p.keyword()
+	p.pos = pos
+	return k
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w at offset %d: %s", errSyntax, p.pos, fmt.Sprintf(format, args...))
+}
+
+// object reads the next object, references are returned as they are
+func (p *parser) object(depth int) (Object, error) {
+	if depth > maxNesting {
+		return nil, p.errorf("objects nested too deep")
+	}
+	p.skipSpace()
+	if p.pos >= len(p.b) {
+		return nil, p.errorf("unexpected end of file")
+	}
+	switch c := p.b[p.pos]; {
+	case c == '/':
+		return p.name()
+	case c == '(':
+		return p.literalString()
+	case c == '<' && p.pos+1 < len(p.b) && p.b[p.pos+1] == '<':
+		return p.dict(depth)
+	case c == '<':
+		return p.hexString()
+	case c == '[':
+		p.pos++
+		arr := Array{}
+		for {
+			p.skipSpace()
+			if p.pos >= len(p.b) {
+				return nil, p.errorf("unterminated array")
+			}
+			if p.b[p.pos] == ']' {
+				p.pos++
+				return arr, nil
+			}
+			o, err := p.object(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, o)
+		}
+	}
+	k := p.keyword()
+	switch k {
+	case "":
+		return nil, p.errorf("unexpected %q", p.b[p.pos])
+	case "null":
+		return nil, nil
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	if i, err := strconv.ParseInt(k, 10, 64); err == nil {
+		// a reference is an object number, a generation and R
+		pos := p.pos
+		if _, err := strconv.Atoi(p.keyword()); err == nil && p.keyword() == "R" && i > 0 {
+			return Ref(i), nil
+		}
+		p.pos = pos
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(k, 64); err == nil {
+		return f, nil
+	}
+	return nil, p.errorf("unexpected keyword %q", k)
+}
+
+func (p *parser) name() (Name, error) {
+	p.pos++
+	buf := new(bytes.Buffer)
+	for p.pos < len(p.b) && !isWhite(p.b[p.pos]) && !isDelimiter(p.b[p.pos]) {
+		c := p.b[p.pos]
+		if c == '#' && p.pos+2 < len(p.b) {
+			if v, err := strconv.ParseUint(string(p.b[p.pos+1:p.pos+3]), 16, 8); err == nil {
+				buf.WriteByte(byte(v))
+				p.pos += 3
+				continue
+			}
+		}
+		buf.WriteByte(c)
+		p.pos++
+	}
+	return Name(buf.String()), nil
+}
+
+func (p *parser) literalString() (Str, error) {
+	p.pos++
+	buf := new(bytes.Buffer)
+	depth := 1
+	for p.pos < len(p.b) {
+		c := p.b[p.pos]
+		p.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return Str(buf.Bytes()), nil
+			}
+		case '\r':
+			// an end of line in a string is a line feed
+			if p.pos < len(p.b) && p.b[p.pos] == '\n' {
+				p.pos++
+			}
+			c = '\n'
+		case '\\':
+			if p.pos >= len(p.b) {
+				return nil, p.errorf("unterminated string")
+			}
+			c = p.b[p.pos]
+			p.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if p.pos < len(p.b) && p.b[p.pos] == '\n' {
+					p.pos++
+				}
+				continue
+			case '\n':
+				continue
+			case '0', '1', '2', '3', '4', '5', '6', '7':
+				v := int(c - '0')
+				for i := 0; i < 2 && p.pos < len(p.b) && p.b[p.pos] >= '0' && p.b[p.pos] <= '7'; i++ {
+					v = v*8 + int(p.b[p.pos]-'0')
+					p.pos++
+				}
+				c = byte(v)
+			}
+		}
+		buf.WriteByte(c)
+	}
+	return nil, p.errorf("unterminated string")
+}
+
+func (p *parser) hexString() (Str, error) {
+	p.pos++
+	var digits []byte
+	for p.pos < len(p.b) && p.b[p.pos] != '>' {
+		c := p.b[p.pos]
+		p.pos++
+		if isWhite(c) {
+			continue
+		}
+		if _, err := strconv.ParseUint(string(c), 16, 8); err != nil {
+			return nil, p.errorf("invalid hex string")
+		}
+		digits = append(digits, c)
+	}
+	if p.pos >= len(p.b) {
+		return nil, p.errorf("unterminated hex string")
+	}
+	p.pos++
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	s := make(Str, len(digits)/2)
+	for i := range s {
+		v, _ := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		s[i] = byte(v)
+	}
+	return s, nil
+}
+
+// dict reads a dictionary and the stream following it
+func (p *parser) dict(depth int) (Object, error) {
+	p.pos += 2
+	d := Dict{}
+	for {
+		p.skipSpace()
+		if p.pos+1 < len(p.b) && p.b[p.pos] == '>' && p.b[p.pos+1] == '>' {
+			p.pos += 2
+			break
+		}
+		if p.pos >= len(p.b) || p.b[p.pos] != '/' {
+			return nil, p.errorf("dictionary key is no name")
+		}
+		k, _ := p.name()
+		v, err := p.object(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			d[k] = v
+		}
+	}
+	if p.peekKeyword() != "stream" {
+		return d, nil
+	}
+	p.keyword()
+	// the keyword is followed by a line feed or carriage return and line feed
+	if p.pos < len(p.b) && p.b[p.pos] == '\r' {
+		p.pos++
+	}
+	if p.pos < len(p.b) && p.b[p.pos] == '\n' {
+		p.pos++
+	}
+	start := p.pos
+	n, ok := -1, false
+	switch l := d["Length"].(type) {
+	case int64:
+		n, ok = int(l), true
+	case Ref:
+		if p.length != nil {
+			n, ok = p.length(l)
+		}
+	}
+	if ok && n >= 0 && start+n <= len(p.b) {
+		p.pos = start + n
+		if p.peekKeyword() == "endstream" {
+			p.keyword()
+			return &Stream{Dict: d, Data: p.b[start : start+n]}, nil
+		}
+	}
+	// the length is missing or wrong, the data ends before the endstream keyword
+	end := bytes.Index(p.b[start:], []byte("endstream"))
+	if end < 0 {
+		return nil, p.errorf("unterminated stream")
+	}
+	p.pos = start + end + len("endstream")
+	data := bytes.TrimSuffix(p.b[start:start+end], []byte("\n"))
+	data = bytes.TrimSuffix(data, []byte("\r"))
+	return &Stream{Dict: d, Data: data}, nil
+}
+
+// indirect reads an indirect object "n g obj ... endobj" and returns its number
+func (p *parser) indirect() (int, Object, error) {
+	num, err := strconv.Atoi(p.keyword())
+	if err != nil {
+		return 0, nil, p.errorf("no object number")
+	}
+	if _, err = strconv.Atoi(p.keyword()); err != nil {
+		return 0, nil, p.errorf("no generation number")
+	}
+	if p.keyword() != "obj" {
+		return 0, nil, p.errorf("no obj keyword")
+	}
+	o, err := p.object(0)
+	if err != nil {
+		return 0, nil, err
+	}
+	return num, o, nil
+}
@@ -0,0 +1,105 @@
+package pdf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/PereRohit/util/testutil"
+)
+
+func TestParser_Object(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want Object
+	}{
+		{name: "integer", in: "-42", want: int64(-42)},
+		{name: "real", in: "  .5", want: 0.5},
+		{name: "reference", in: "12 0 R", want: Ref(12)},
+		{name: "integers", in: "[1 2 3]", want: Array{int64(1), int64(2), int64(3)}},
+		{name: "booleans and null", in: "[true false null]", want: Array{true, false, nil}},
+		{name: "name escapes", in: "/A#20B#2f", want: Name("A B/")},
+		{name: "literal string", in: `(a (b) \(c\) \101\n\\ d\
+e)`, want: Str("a (b) (c) A\n\\ de")},
+		{name: "literal line end", in: "(a\r\nb)", want: Str("a\nb")},
+		{name: "hex string", in: "<48 65 6c6c 6f2>", want: Str("Hello ")},
+		{name: "comment", in: "% skipped\n/N", want: Name("N")},
+		{name: "dictionary", in: "<</Type/Page/Kids[1 0 R]/None null>>", want: Dict{"Type": Name("Page"), "Kids": Array{Ref(1)}}},
+		{
+			name: "stream",
+			in:   "<< /Length 5 >>\nstream\nhello\nendstream",
+			want: &Stream{Dict: Dict{"Length": int64(5)}, Data: []byte("hello")},
+		},
+		{
+			name: "stream with wrong length",
+			in:   "<< /Length 2 >>\r\nstream\r\nhello\r\nendstream",
+			want: &Stream{Dict: Dict{"Length": int64(2)}, Data: []byte("hello")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &parser{b: []byte(tt.in)}
+			got, err := p.object(0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			diff := testutil.Diff(got, tt.want)
+			if diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestParser_Object_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{name: "empty", in: " "},
+		{name: "unterminated array", in: "[1 2"},
+		{name: "unterminated string", in: "(abc"},
+		{name: "invalid hex string", in: "<4g>"},
+		{name: "key is no name", in: "<< 1 2 >>"},
+		{name: "unknown keyword", in: "foo"},
+		{name: "unterminated stream", in: "<< /Length 1 >>\nstream\nab"},
+		{name: "nested too deep", in: strings.Repeat("[", maxNesting+2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &parser{b: []byte(tt.in)}
+			_, err := p.object(0)
+			if !errors.Is(err, errSyntax) {
+				t.Errorf("want a syntax error got %v", err)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	o := Dict{
+		"Type":  Name("Annot"),
+		"Rect":  Array{int64(0), 1.5, -2.25, 1.0 / 3},
+		"T":     Str("a(b)\n"),
+		"N":     Name("A B"),
+		"Ref":   Ref(3),
+		"Print": true,
+		"Empty": nil,
+	}
+	want := "<< /Empty null /N /A#20B /Print true /Rect [0 1.5 -2.25 0.333333] /Ref 3 0 R /T (a\\(b\\)\\012) /Type /Annot >>"
+	if got := Format(o); got != want {
+		t.Errorf("want %s got %s", want, got)
+	}
+	p := &parser{b: []byte(Format(o))}
+	back, err := p.object(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delete(o, "Empty")
+	o["Rect"] = Array{int64(0), 1.5, -2.25, 0.333333}
+	diff := testutil.Diff(back, Object(o))
+	if diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,434 @@
+package pdf
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// the actions PDF/A-2 forbids, they run code or depend on content outside the file
+var forbiddenActions = map[Name]bool{
+	"Launch": true, "Sound": true, "Movie": true, "ResetForm": true, "ImportData": true, "Hide": true,
+	"SetOCGState": true, "Rendition": true, "Trans": true, "GoTo3DView": true, "JavaScript": true,
+}
+
+// the annotations PDF/A-2 forbids, file attachments are only allowed for attached PDF/A files
+var forbiddenAnnots = map[Name]bool{
+	"Sound": true, "Movie": true, "Screen": true, "3D": true, "FileAttachment": true,
+}
+
+// annotation flags
+const (
+	annotInvisible    = 1
+	annotHidden       = 2
+	annotPrint        = 4
+	annotNoView       = 32
+	annotToggleNoView = 256
+)
+
+// ConvertPdfA2b turns the document into a PDF/A-2b file. It embeds substitutes of the fonts the file lacks, adds
+// an sRGB output intent and the XMP metadata, and strips the actions, annotations and keys the standard forbids.
+// The information dictionary and the metadata are dated now.
+func ConvertPdfA2b(d *Document, now time.Time) {
+	cat := d.Catalog()
+	delete(cat, "AA")
+	delete(cat, "NeedsRendering")
+	delete(cat, "Version")
+	if a, ok := d.Resolve(cat["OpenAction"]).(Dict); ok && forbiddenActions[a.Name("S")] {
+		delete(cat, "OpenAction")
+	}
+	if names := d.Dict(cat["Names"]); names != nil {
+		delete(names, "JavaScript")
+		delete(names, "EmbeddedFiles")
+	}
+	if form := d.Dict(cat["AcroForm"]); form != nil {
+		delete(form, "XFA")
+		delete(form, "NeedAppearances")
+	}
+	for _, page := range d.Pages() {
+		annots, ok := d.Resolve(d.Dict(page)["Annots"]).(Array)
+		if !ok {
+			continue
+		}
+		kept := annots[:0]
+		for _, a := range annots {
+			if !forbiddenAnnots[d.Dict(a).Name("Subtype")] {
+				kept = append(kept, a)
+			}
+		}
+		d.Dict(page)["Annots"] = kept
+	}
+	fonts := newFontEmbedder(d)
+	for _, r := range d.Refs() {
+		sanitize(d, fonts, d.Object(r), 0)
+	}
+
+	info := d.Info()
+	if info == nil {
+		info = Dict{}
+		d.Trailer["Info"] = d.Add(info)
+	}
+	date := "D:" + now.UTC().Format("20060102150405") + "Z"
+	info["CreationDate"], info["ModDate"] = Str(date), Str(date)
+	// only True and False are allowed, Unknown is the same as a missing entry
+	delete(info, "Trapped")
+	xmp := xmpMetadata(d, info, now)
+	cat["Metadata"] = d.Add(&Stream{Dict: Dict{"Type": Name("Metadata"), "Subtype": Name("XML")}, Data: xmp})
+
+	icc := SRGBProfile()
+	profile := d.Add(&Stream{Dict: Dict{"N": int64(3), "Filter": Name("FlateDecode")}, Data: Deflate(icc)})
+	cat["OutputIntents"] = Array{Dict{
+		"Type":                      Name("OutputIntent"),
+		"S":                         Name("GTS_PDFA1"),
+		"OutputConditionIdentifier": Str(SRGBCondition),
+		"Info":                      Str(SRGBCondition),
+		"DestOutputProfile":         profile,
+	}}
+
+	if id, ok := d.Trailer["ID"].(Array); !ok || len(id) != 2 {
+		sum := md5.Sum([]byte(now.String() + string(xmp)))
+		d.Trailer["ID"] = Array{Str(sum[:]), Str(sum[:])}
+	}
+}
+
+// sanitize strips the forbidden entries from o and the direct objects it contains and embeds the fonts it finds
+func sanitize(d *Document, fonts *fontEmbedder, o Object, depth int) {
+	if depth > maxNesting {
+		return
+	}
+	var dict Dict
+	switch v := o.(type) {
+	case Array:
+		for _, e := range v {
+			sanitize(d, fonts, e, depth+1)
+		}
+		return
+	case *Stream:
+		dict = v.Dict
+		// streams must hold their data in the file
+		delete(dict, "F")
+		delete(dict, "FFilter")
+		delete(dict, "FDecodeParms")
+	case Dict:
+		dict = v
+	default:
+		return
+	}
+	delete(dict, "AA")
+	if a, ok := d.Resolve(dict["A"]).(Dict); ok && forbiddenActions[a.Name("S")] {
+		delete(dict, "A")
+	}
+	switch {
+	case dict.Name("Type") == "Annot" || (dict["Rect"] != nil && dict["Subtype"] != nil && dict.Name("Type") == ""):
+		if dict.Name("Subtype") != "Popup" {
+			f, _ := dict["F"].(int64)
+			dict["F"] = (f | annotPrint) &^ (annotInvisible | annotHidden | annotNoView | annotToggleNoView)
+		}
+	case dict.Name("Type") == "Font":
+		if !d.embedded(dict) {
+			fonts.embed(dict)
+		}
+	case dict.Name("Subtype") == "Image":
+		delete(dict, "Alternates")
+		delete(dict, "OPI")
+		if dict["Interpolate"] == true {
+			delete(dict, "Interpolate")
+		}
+	case dict.Name("Subtype") == "Form":
+		delete(dict, "OPI")
+		delete(dict, "PS")
+		if dict.Name("Subtype2") == "PS" {
+			delete(dict, "Subtype2")
+		}
+	case dict.Name("Type") == "ExtGState" || dict["TR"] != nil:
+		delete(dict, "TR")
+		delete(dict, "HTP")
+		if dict.Name("TR2") != "Default" {
+			delete(dict, "TR2")
+		}
+	}
+	for _, v := range dict {
+		sanitize(d, fonts, v, depth+1)
+	}
+}
+
+// xmpMetadata returns the XMP packet identifying the file as PDF/A-2b with the entries of the information
+// dictionary, the standard requires both to agree
+func xmpMetadata(d *Document, info Dict, now time.Time) []byte {
+	esc := func(s string) string {
+		b := new(bytes.Buffer)
+		_ = xml.EscapeText(b, []byte(s))
+		return b.String()
+	}
+	text := func(key Name) (string, bool) {
+		s, ok := d.Resolve(info[key]).(Str)
+		return esc(DecodeText(s)), ok
+	}
+	b := new(bytes.Buffer)
+	b.WriteString("<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString(`<x:xmpmeta xmlns:x="adobe:ns:meta/">` + "\n")
+	b.WriteString(`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` + "\n")
+	b.WriteString(`<rdf:Description rdf:about="" xmlns:pdfaid="http://www.aiim.org/pdfa/ns/id/"` +
+		` xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:xmp="http://ns.adobe.com/xap/1.0/"` +
+		` xmlns:pdf="http://ns.adobe.com/pdf/1.3/">` + "\n")
+	b.WriteString("<pdfaid:part>2</pdfaid:part>\n<pdfaid:conformance>B</pdfaid:conformance>\n")
+	b.WriteString("<dc:format>application/pdf</dc:format>\n")
+	if s, ok := text("Title"); ok {
+		fmt.Fprintf(b, "<dc:title><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:title>\n", s)
+	}
+	if s, ok := text("Author"); ok {
+		fmt.Fprintf(b, "<dc:creator><rdf:Seq><rdf:li>%s</rdf:li></rdf:Seq></dc:creator>\n", s)
+	}
+	if s, ok := text("Subject"); ok {
+		fmt.Fprintf(b, "<dc:description><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:description>\n", s)
+	}
+	if s, ok := text("Keywords"); ok {
+		fmt.Fprintf(b, "<pdf:Keywords>%s</pdf:Keywords>\n", s)
+	}
+	if s, ok := text("Creator"); ok {
+		fmt.Fprintf(b, "<xmp:CreatorTool>%s</xmp:CreatorTool>\n", s)
+	}
+	if s, ok := text("Producer"); ok {
+		fmt.Fprintf(b, "<pdf:Producer>%s</pdf:Producer>\n", s)
+	}
+	date := now.UTC().Format("2006-01-02T15:04:05Z")
+	fmt.Fprintf(b, "<xmp:CreateDate>%s</xmp:CreateDate>\n<xmp:ModifyDate>%s</xmp:ModifyDate>\n", date, date)
+	b.WriteString("</rdf:Description>\n</rdf:RDF>\n</x:xmpmeta>\n")
+	// padding lets editors update the packet in place
+	b.WriteString(strings.Repeat(strings.Repeat(" ", 99)+"\n", 20))
+	b.WriteString(`<?xpacket end="w"?>`)
+	return b.Bytes()
+}
+
+// DecodeText returns a text string of the file, UTF-16 with a byte order mark or else PDFDocEncoding which is
+// read as Latin-1
+func DecodeText(s Str) string {
+	if len(s) >= 2 && s[0] == 0xfe && s[1] == 0xff {
+		u := make([]uint16, 0, len(s)/2)
+		for i := 2; i+1 < len(s); i += 2 {
+			u = append(u, uint16(s[i])<<8|uint16(s[i+1]))
+		}
+		return string(utf16.Decode(u))
+	}
+	r := make([]rune, len(s))
+	for i, c := range s {
+		r[i] = rune(c)
+	}
+	return string(r)
+}
+
+// ValidationError lists the problems that keep a file from conforming to a standard
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "pdf/a-2b validation failed: " + strings.Join(e.Problems, "; ")
+}
+
+var (
+	headerPattern = regexp.MustCompile(`^%PDF-1\.[0-7]\r?\n?%`)
+	partPattern   = regexp.MustCompile(`pdfaid:part(?:>|="|=')\s*2\b`)
+	confPattern   = regexp.MustCompile(`pdfaid:conformance(?:>|="|=')\s*B\b`)
+)
+
+// ValidatePdfA2b checks the structure of a file against PDF/A-2b: the header, the trailer, the metadata, the
+// output intent, embedded fonts and the forbidden actions, annotations and keys. The content streams are not
+// parsed, the colors they use are not checked.
+func ValidatePdfA2b(b []byte) error {
+	v := &validator{}
+	if m := headerPattern.Find(b); m == nil || len(b) < len(m)+4 || !binaryComment(b[len(m):len(m)+4]) {
+		v.add("the header is not a pdf 1.7 or earlier header followed by a binary comment")
+	}
+	d, err := Parse(b)
+	if err != nil {
+		v.add("the file can't be read: " + err.Error())
+		return v.err()
+	}
+	v.d = d
+	if id, ok := d.Trailer["ID"].(Array); !ok || len(id) != 2 {
+		v.add("the trailer has no file identifier")
+	}
+	cat := d.Catalog()
+	v.metadata(cat)
+	v.outputIntent(cat)
+	if cat["AA"] != nil {
+		v.add("the catalog has additional actions")
+	}
+	if names := d.Dict(cat["Names"]); names["JavaScript"] != nil || names["EmbeddedFiles"] != nil {
+		v.add("the catalog names javascript or embedded files")
+	}
+	if form := d.Dict(cat["AcroForm"]); form["XFA"] != nil || form["NeedAppearances"] == true {
+		v.add("the form has xfa data or needs appearances")
+	}
+	for _, r := range d.Refs() {
+		v.object(r, d.Object(r), 0)
+	}
+	return v.err()
+}
+
+// binaryComment reports whether the comment after the header starts with four bytes above 127
+func binaryComment(b []byte) bool {
+	for _, c := range b {
+		if c < 128 {
+			return false
+		}
+	}
+	return true
+}
+
+type validator struct {
+	d        *Document
+	problems []string
+	seen     map[string]bool
+	// colors is the number of color components of the output intent
+	colors int
+}
+
+func (v *validator) add(p string) {
+	if v.seen == nil {
+		v.seen = map[string]bool{}
+	}
+	if !v.seen[p] {
+		v.seen[p] = true
+		v.problems = append(v.problems, p)
+	}
+}
+
+func (v *validator) err() error {
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: v.problems}
+}
+
+func (v *validator) metadata(cat Dict) {
+	s, ok := v.d.Resolve(cat["Metadata"]).(*Stream)
+	if !ok {
+		v.add("the catalog has no metadata stream")
+		return
+	}
+	if s.Dict["Filter"] != nil {
+		v.add("the metadata stream is compressed")
+		return
+	}
+	if !partPattern.Match(s.Data) || !confPattern.Match(s.Data) {
+		v.add("the metadata does not identify the file as pdf/a-2b")
+	}
+	info := v.d.Info()
+	for _, key := range []Name{"Title", "Author", "Producer"} {
+		text, ok := v.d.Resolve(info[key]).(Str)
+		if !ok {
+			continue
+		}
+		b := new(bytes.Buffer)
+		_ = xml.EscapeText(b, []byte(DecodeText(text)))
+		if !bytes.Contains(s.Data, b.Bytes()) {
+			v.add(fmt.Sprintf("the metadata does not agree with the %s of the information dictionary", strings.ToLower(string(key))))
+		}
+	}
+}
+
+func (v *validator) outputIntent(cat Dict) {
+	intents, _ := v.d.Resolve(cat["OutputIntents"]).(Array)
+	for _, i := range intents {
+		intent := v.d.Dict(i)
+		if intent.Name("S") != "GTS_PDFA1" {
+			continue
+		}
+		s, ok := v.d.Resolve(intent["DestOutputProfile"]).(*Stream)
+		if !ok {
+			v.add("the output intent has no icc profile")
+			return
+		}
+		icc, err := Decode(s)
+		n, ok := iccColorSpace(icc)
+		if err != nil || !ok {
+			v.add("the icc profile of the output intent is invalid")
+			return
+		}
+		if want, _ := s.Dict["N"].(int64); int(want) != n {
+			v.add("the icc profile of the output intent does not match its number of components")
+		}
+		v.colors = n
+		return
+	}
+	v.add("the catalog has no pdf/a output intent")
+}
+
+func (v *validator) object(r Ref, o Object, depth int) {
+	if depth > maxNesting {
+		return
+	}
+	var dict Dict
+	switch t := o.(type) {
+	case Array:
+		for _, e := range t {
+			v.object(r, e, depth+1)
+		}
+		return
+	case *Stream:
+		dict = t.Dict
+		if dict["F"] != nil || dict["FFilter"] != nil || dict["FDecodeParms"] != nil {
+			v.add(fmt.Sprintf("stream %d refers to an external file", r))
+		}
+		filters, _ := streamFilters(dict)
+		for _, f := range filters {
+			if f == "LZWDecode" || f == "LZW" {
+				v.add(fmt.Sprintf("stream %d uses the lzw filter", r))
+			}
+		}
+	case Dict:
+		dict = t
+	default:
+		return
+	}
+	if dict["AA"] != nil {
+		v.add(fmt.Sprintf("object %d has additional actions", r))
+	}
+	for _, key := range []Name{"A", "OpenAction"} {
+		if a, ok := v.d.Resolve(dict[key]).(Dict); ok && forbiddenActions[a.Name("S")] {
+			v.add(fmt.Sprintf("object %d has a %s action", r, a.Name("S")))
+		}
+	}
+	switch {
+	case dict.Name("Type") == "Annot" || (dict["Rect"] != nil && dict["Subtype"] != nil && dict.Name("Type") == ""):
+		sub := dict.Name("Subtype")
+		if forbiddenAnnots[sub] {
+			v.add(fmt.Sprintf("annotation %d is a %s annotation", r, sub))
+		}
+		f, _ := dict["F"].(int64)
+		if sub != "Popup" && (f&annotPrint == 0 || f&(annotInvisible|annotHidden|annotNoView|annotToggleNoView) != 0) {
+			v.add(fmt.Sprintf("annotation %d is hidden or not printed", r))
+		}
+	case dict.Name("Type") == "Font":
+		if !v.d.embedded(dict) {
+			v.add(fmt.Sprintf("font %s is not embedded", dict.Name("BaseFont")))
+		}
+	case dict.Name("Subtype") == "Image":
+		if dict["Interpolate"] == true || dict["Alternates"] != nil || dict["OPI"] != nil {
+			v.add(fmt.Sprintf("image %d is interpolated or has alternates", r))
+		}
+		if cs := v.d.Resolve(dict["ColorSpace"]); cs == Name("DeviceCMYK") && v.colors != 4 {
+			v.add(fmt.Sprintf("image %d uses DeviceCMYK without a cmyk output intent", r))
+		}
+	case dict.Name("Subtype") == "PS":
+		v.add(fmt.Sprintf("object %d is a postscript xobject", r))
+	case dict.Name("Subtype") == "Form":
+		if dict["OPI"] != nil || dict["PS"] != nil || dict.Name("Subtype2") == "PS" {
+			v.add(fmt.Sprintf("form %d has postscript or opi entries", r))
+		}
+	case dict.Name("Type") == "ExtGState" || dict["TR"] != nil:
+		if dict["TR"] != nil || dict["HTP"] != nil || (dict["TR2"] != nil && dict.Name("TR2") != "Default") {
+			v.add(fmt.Sprintf("graphics state %d has a transfer function", r))
+		}
+	}
+	for _, e := range dict {
+		v.object(r, e, depth+1)
+	}
+}
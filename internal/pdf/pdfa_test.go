@@ -0,0 +1,168 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/PereRohit/util/testutil"
+)
+
+// nonConforming writes a document with the standard fonts, a javascript open action, a hidden link with a
+// launch action, a sound annotation and an interpolated image
+func nonConforming(t *testing.T) []byte {
+	t.Helper()
+	w := NewWriter()
+	pages := w.Reserve()
+	fonts := new(bytes.Buffer)
+	for _, f := range []*Font{Helvetica, HelveticaBold, Courier} {
+		fmt.Fprintf(fonts, " /%s %s", f.Key, w.Add(f.Dict()))
+	}
+	img := w.AddStream("/Type /XObject /Subtype /Image /Width 1 /Height 1 /ColorSpace /DeviceRGB /BitsPerComponent 8 /Interpolate true", []byte{255, 0, 0})
+	content := w.AddStream("", []byte("BT /F1 12 Tf 10 10 Td (hi) Tj ET"))
+	link := w.Add("<< /Type /Annot /Subtype /Link /Rect [0 0 10 10] /F 2 /A << /S /Launch /F (calc.exe) >> >>")
+	sound := w.Add("<< /Type /Annot /Subtype /Sound /Rect [0 0 10 10] >>")
+	page := w.Add(fmt.Sprintf("<< /Type /Page /Parent %s /MediaBox [0 0 100 100] /Resources << /Font <<%s >> /XObject << /Im0 %s >> >> /Contents %s /Annots [%s %s] /AA << /O << /S /JavaScript /JS (x) >> >> >>",
+		pages, fonts, img, content, link, sound))
+	w.Set(pages, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count 1 >>", page))
+	root := w.Add(fmt.Sprintf("<< /Type /Catalog /Pages %s /OpenAction << /S /JavaScript /JS (app.alert(1)) >> >>", pages))
+	info := w.Add("<< /Title (Invoice <1> & co) /Producer (html-pdf-service) /Trapped /Unknown >>")
+	out := new(bytes.Buffer)
+	if err := w.WriteTo(out, root, info); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func TestValidatePdfA2b(t *testing.T) {
+	err := ValidatePdfA2b(nonConforming(t))
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("want a validation error got %v", err)
+	}
+	want := map[string]bool{
+		"the trailer has no file identifier":            true,
+		"the catalog has no metadata stream":            true,
+		"the catalog has no pdf/a output intent":        true,
+		"font Helvetica is not embedded":                true,
+		"font Helvetica-Bold is not embedded":           true,
+		"font Courier is not embedded":                  true,
+		"object 10 has a JavaScript action":             true,
+		"annotation 7 is hidden or not printed":         true,
+		"object 7 has a Launch action":                  true,
+		"annotation 8 is a Sound annotation":            true,
+		"annotation 8 is hidden or not printed":         true,
+		"object 9 has additional actions":               true,
+		"image 5 is interpolated or has alternates":     true,
+		"object 9 has a JavaScript action":              false,
+		"the header is not a pdf 1.7 or earlier header": false,
+	}
+	got := map[string]bool{}
+	for _, p := range verr.Problems {
+		got[p] = true
+	}
+	for p, found := range want {
+		if got[p] != found {
+			t.Errorf("problem %q reported %v, want %v", p, got[p], found)
+		}
+	}
+	if err := ValidatePdfA2b([]byte("%PDF-2.0\n")); err == nil {
+		t.Error("want an error for a file that can't be read")
+	}
+}
+
+func TestConvertPdfA2b(t *testing.T) {
+	d, err := Parse(nonConforming(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 3, 1, 12, 30, 0, 0, time.FixedZone("IST", 19800))
+	ConvertPdfA2b(d, now)
+	out := new(bytes.Buffer)
+	if err := d.Write(out); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidatePdfA2b(out.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err = Parse(out.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := d.Dict(d.Pages()[0])
+	annots, _ := d.Resolve(page["Annots"]).(Array)
+	if len(annots) != 1 || d.Dict(annots[0])["F"] != int64(4) || d.Dict(annots[0])["A"] != nil {
+		t.Errorf("unexpected annotations %v", annots)
+	}
+	fonts := d.Dict(d.Dict(page["Resources"])["Font"])
+	var got []Name
+	for _, key := range []Name{"F1", "F2", "F5"} {
+		f := d.Dict(fonts[key])
+		got = append(got, f.Name("BaseFont"))
+		if w, _ := f["Widths"].(Array); len(w) != 224 || w[0] == int64(0) {
+			t.Errorf("unexpected widths of %s: %v", key, w)
+		}
+	}
+	diff := testutil.Diff(got, []Name{"Go-Regular", "Go-Bold", "Go-Mono"})
+	if diff != "" {
+		t.Error(diff)
+	}
+	// every substitute has a descriptor and font program of its own
+	if d.Dict(fonts["F1"])["FontDescriptor"] == d.Dict(fonts["F5"])["FontDescriptor"] {
+		t.Error("the regular and the mono font share a descriptor")
+	}
+	info := d.Info()
+	if date, _ := info["CreationDate"].(Str); string(date) != "D:20240301070000Z" || info["Trapped"] != nil {
+		t.Errorf("unexpected information %v", info)
+	}
+	xmp := d.Resolve(d.Catalog()["Metadata"]).(*Stream).Data
+	for _, s := range []string{
+		"<pdfaid:part>2</pdfaid:part>",
+		"<rdf:li xml:lang=\"x-default\">Invoice &lt;1&gt; &amp; co</rdf:li>",
+		"<xmp:CreateDate>2024-03-01T07:00:00Z</xmp:CreateDate>",
+	} {
+		if !bytes.Contains(xmp, []byte(s)) {
+			t.Errorf("the metadata lacks %s", s)
+		}
+	}
+}
+
+func TestSRGBProfile(t *testing.T) {
+	p := SRGBProfile()
+	if n, ok := iccColorSpace(p); !ok || n != 3 {
+		t.Errorf("want an rgb profile got %d %v", n, ok)
+	}
+	if string(p[12:16]) != "mntr" || p[8] != 2 {
+		t.Errorf("unexpected header %q", p[:40])
+	}
+	if _, ok := iccColorSpace(p[:len(p)-1]); ok {
+		t.Error("a truncated profile is valid")
+	}
+}
+
+func TestSubstituteFor(t *testing.T) {
+	tests := []struct {
+		base Name
+		want string
+		ok   bool
+	}{
+		{base: "Helvetica", want: "Go-Regular", ok: true},
+		{base: "Helvetica-BoldOblique", want: "Go-Bold-Italic", ok: true},
+		{base: "ABCDEF+Arial-ItalicMT", want: "Go-Italic", ok: true},
+		{base: "Courier-Bold", want: "Go-Mono-Bold", ok: true},
+		{base: "DejaVuSansMono-Oblique", want: "Go-Mono-Italic", ok: true},
+		{base: "Symbol", ok: false},
+		{base: "ZapfDingbats", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.base), func(t *testing.T) {
+			got, ok := substituteFor(tt.base)
+			if ok != tt.ok || got.name != tt.want {
+				t.Errorf("want %s %v got %s %v", tt.want, tt.ok, got.name, ok)
+			}
+		})
+	}
+}
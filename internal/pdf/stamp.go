@@ -0,0 +1,199 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+)
+
+// stampCapHeight is the height of the capital letters of Helvetica-Bold in thousandths of the font size
+const stampCapHeight = 718
+
+// Overlay is a text or an image drawn over the content of pages, e.g. a watermark or a stamp
+type Overlay struct {
+	// Text is set in Helvetica-Bold at FontSize in the rgb Color, an overlay without text draws the Image
+	Text     string
+	FontSize float64
+	Color    [3]float64
+	// Image is drawn Width points wide keeping its aspect ratio
+	Image image.Image
+	Width float64
+	// Opacity goes from 0 for invisible to 1 for opaque
+	Opacity float64
+	// Rotation is the counterclockwise rotation in degrees around the anchor
+	Rotation float64
+	// AnchorX and AnchorY are the anchor as fractions of the page inside the Margin from the lower left corner.
+	// The overlay is placed with the point at the same fractions of its own box on the anchor, 0.5 and 0.5 center
+	// the overlay on the page and 1 and 0 put it in the lower right corner.
+	AnchorX, AnchorY float64
+	Margin           float64
+	// Pages are the numbers of the stamped pages from 1, negative numbers count back from the last page, no pages
+	// stamps every page
+	Pages []int
+}
+
+// Stamp draws the overlays over the content of the pages. The overlays are placed in the crop box of the page,
+// the rotation of the page is not taken into account.
+func (d *Document) Stamp(overlays []Overlay) {
+	pages := d.Pages()
+	if len(pages) == 0 || len(overlays) == 0 {
+		return
+	}
+	s := &stamper{d: d, resources: map[Ref]Dict{}, contents: map[Ref]Array{}}
+	for _, o := range overlays {
+		var gs, xobj Ref
+		if o.Opacity < 1 {
+			gs = d.Add(Dict{"Type": Name("ExtGState"), "ca": o.Opacity, "CA": o.Opacity})
+		}
+		if o.Text == "" && o.Image != nil {
+			xobj = d.addImage(o.Image)
+		} else if s.font == 0 {
+			s.font = d.Add(Dict{"Type": Name("Font"), "Subtype": Name("Type1"), "BaseFont": Name(HelveticaBold.Name), "Encoding": Name("WinAnsiEncoding")})
+		}
+		for _, r := range selectPages(pages, o.Pages) {
+			s.stamp(r, o, gs, xobj)
+		}
+	}
+}
+
+// selectPages returns the pages with the numbers, every page if there are no numbers
+func selectPages(pages []Ref, numbers []int) []Ref {
+	if len(numbers) == 0 {
+		return pages
+	}
+	var sel []Ref
+	seen := map[int]bool{}
+	for _, n := range numbers {
+		if n < 0 {
+			n += len(pages) + 1
+		}
+		if n < 1 || n > len(pages) || seen[n] {
+			continue
+		}
+		seen[n] = true
+		sel = append(sel, pages[n-1])
+	}
+	return sel
+}
+
+// stamper collects the resources and contents of the stamped pages
+type stamper struct {
+	d    *Document
+	font Ref
+	// save and restore wrap the content of a page so a graphics state it leaves behind doesn't move the overlays
+	save, restore Ref
+	resources     map[Ref]Dict
+	contents      map[Ref]Array
+}
+
+func (s *stamper) stamp(r Ref, o Overlay, gs, xobj Ref) {
+	d := s.d
+	page := d.Dict(r)
+	if page == nil {
+		return
+	}
+	res, ok := s.resources[r]
+	if !ok {
+		// the resources may be inherited or shared with other pages, the page gets a copy of its own
+		res = d.Dict(d.PageAttr(r, "Resources")).Clone()
+		page["Resources"] = res
+		s.resources[r] = res
+	}
+	contents, ok := s.contents[r]
+	if !ok {
+		if s.save == 0 {
+			s.save = d.Add(&Stream{Dict: Dict{}, Data: []byte("q\n")})
+			s.restore = d.Add(&Stream{Dict: Dict{}, Data: []byte("\nQ\n")})
+		}
+		contents = Array{s.save}
+		switch c := d.Resolve(page["Contents"]).(type) {
+		case Array:
+			contents = append(contents, c...)
+		case *Stream:
+			contents = append(contents, page["Contents"])
+		}
+		contents = append(contents, s.restore)
+	}
+
+	box := d.MediaBox(r)
+	if crop, ok := d.Resolve(d.PageAttr(r, "CropBox")).(Array); ok && len(crop) == 4 {
+		for i, v := range crop {
+			if f, ok := toFloat(d.Resolve(v)); ok {
+				box[i] = f
+			}
+		}
+	}
+	x := box[0] + o.Margin + o.AnchorX*(box[2]-box[0]-2*o.Margin)
+	y := box[1] + o.Margin + o.AnchorY*(box[3]-box[1]-2*o.Margin)
+	sin, cos := math.Sincos(o.Rotation * math.Pi / 180)
+
+	buf := new(bytes.Buffer)
+	buf.WriteString("q\n")
+	if gs != 0 {
+		fmt.Fprintf(buf, "/%s gs\n", addResource(d, res, "ExtGState", "GS", gs))
+	}
+	fmt.Fprintf(buf, "%s %s %s %s %s %s cm\n", Number(cos), Number(sin), Number(-sin), Number(cos), Number(x), Number(y))
+	if xobj != 0 {
+		b := o.Image.Bounds()
+		w := o.Width
+		h := w * float64(b.Dy()) / float64(b.Dx())
+		fmt.Fprintf(buf, "%s 0 0 %s %s %s cm\n/%s Do\n", Number(w), Number(h), Number(-o.AnchorX*w), Number(-o.AnchorY*h),
+			addResource(d, res, "XObject", "Im", xobj))
+	} else {
+		w := HelveticaBold.Width(o.Text, o.FontSize)
+		h := o.FontSize * stampCapHeight / 1000
+		fmt.Fprintf(buf, "%s %s %s rg\nBT\n/%s %s Tf\n%s %s Td\n%s Tj\nET\n", Number(o.Color[0]), Number(o.Color[1]), Number(o.Color[2]),
+			addResource(d, res, "Font", "F", s.font), Number(o.FontSize), Number(-o.AnchorX*w), Number(-o.AnchorY*h), String(o.Text))
+	}
+	buf.WriteString("Q")
+	s.contents[r] = append(contents, d.Add(&Stream{Dict: Dict{"Filter": Name("FlateDecode")}, Data: Deflate(buf.Bytes())}))
+	page["Contents"] = s.contents[r]
+}
+
+// addResource adds the object r to the category of the resources under a new name with the prefix and returns
+// the name. The category is copied as it may be shared with other pages.
+func addResource(d *Document, res Dict, category Name, prefix string, r Ref) Name {
+	cat := d.Dict(res[category]).Clone()
+	for name, o := range cat {
+		if o == r {
+			return name
+		}
+	}
+	name := Name(prefix + "0")
+	for i := 1; cat[name] != nil; i++ {
+		name = Name(prefix + strconv.Itoa(i))
+	}
+	cat[name] = r
+	res[category] = cat
+	return name
+}
+
+// addImage adds an image XObject of img, the alpha channel of an image that isn't opaque becomes its soft mask
+func (d *Document) addImage(img image.Image) Ref {
+	b := img.Bounds()
+	rgb := make([]byte, 0, b.Dx()*b.Dy()*3)
+	alpha := make([]byte, 0, b.Dx()*b.Dy())
+	opaque := true
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			rgb = append(rgb, c.R, c.G, c.B)
+			alpha = append(alpha, c.A)
+			opaque = opaque && c.A == 0xff
+		}
+	}
+	imageDict := func(space Name) Dict {
+		return Dict{
+			"Type": Name("XObject"), "Subtype": Name("Image"), "Width": int64(b.Dx()), "Height": int64(b.Dy()),
+			"ColorSpace": space, "BitsPerComponent": int64(8), "Filter": Name("FlateDecode"),
+		}
+	}
+	dict := imageDict("DeviceRGB")
+	if !opaque {
+		dict["SMask"] = d.Add(&Stream{Dict: imageDict("DeviceGray"), Data: Deflate(alpha)})
+	}
+	return d.Add(&Stream{Dict: dict, Data: Deflate(rgb)})
+}
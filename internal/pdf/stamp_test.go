@@ -0,0 +1,94 @@
+package pdf
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PereRohit/util/testutil"
+)
+
+func TestDocument_Stamp(t *testing.T) {
+	d, err := Parse(testDocument(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.NRGBA{R: 255, A: 255})
+	img.Set(1, 0, color.NRGBA{B: 255, A: 128})
+	d.Stamp([]Overlay{
+		{Text: "DRAFT", FontSize: 40, Color: [3]float64{0.5, 0.5, 0.5}, Opacity: 0.3, Rotation: 90, AnchorX: 0.5, AnchorY: 0.5},
+		{Image: img, Width: 20, Opacity: 1, AnchorX: 1, AnchorY: 0, Margin: 10, Pages: []int{-1, 5}},
+	})
+	out := new(bytes.Buffer)
+	if err := d.Write(out); err != nil {
+		t.Fatal(err)
+	}
+	d, err = Parse(out.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages := d.Pages()
+	var got []string
+	for _, p := range pages {
+		page := d.Dict(p)
+		contents, _ := d.Resolve(page["Contents"]).(Array)
+		var streams []string
+		for _, c := range contents {
+			data, err := Decode(d.Resolve(c).(*Stream))
+			if err != nil {
+				t.Fatal(err)
+			}
+			streams = append(streams, string(data))
+		}
+		got = append(got, strings.Join(streams, "|"))
+		// the resources of the page tree are copied to the page along with the stamp resources
+		res := d.Dict(page["Resources"])
+		if d.Dict(res["Font"])["F1"] == nil || d.Dict(res["Font"])["F0"] == nil {
+			t.Errorf("unexpected resources %v", res)
+		}
+	}
+	content := "BT /F1 12 Tf 10 10 Td (hi) Tj ET"
+	draft := "q\n/GS0 gs\n0 1 -1 0 %s cm\n0.5 0.5 0.5 rg\nBT\n/F0 40 Tf\n-67.76 -14.36 Td\n(DRAFT) Tj\nET\nQ"
+	diff := testutil.Diff(got, []string{
+		"q\n|" + content + "|\nQ\n|" + strings.Replace(draft, "%s", "50 50", 1),
+		"q\n|" + content + "|\nQ\n|" + strings.Replace(draft, "%s", "100 150", 1) + "|q\n1 0 0 1 190 10 cm\n20 0 0 10 -20 0 cm\n/Im0 Do\nQ",
+	})
+	if diff != "" {
+		t.Error(diff)
+	}
+	im := d.Dict(d.Dict(d.Dict(pages[1])["Resources"])["XObject"])["Im0"]
+	mask, ok := d.Resolve(d.Dict(im)["SMask"]).(*Stream)
+	if !ok {
+		t.Fatal("the image has no soft mask")
+	}
+	alpha, _ := Decode(mask)
+	diff = testutil.Diff(alpha, []byte{255, 128})
+	if diff != "" {
+		t.Error(diff)
+	}
+	// the overlays survive the conversion to PDF/A
+	ConvertPdfA2b(d, time.Now())
+	out.Reset()
+	if err := d.Write(out); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidatePdfA2b(out.Bytes()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSelectPages(t *testing.T) {
+	pages := []Ref{1, 2, 3}
+	diff := testutil.Diff(selectPages(pages, []int{3, -3, 0, 4, -4, 1}), []Ref{3, 1})
+	if diff != "" {
+		t.Error(diff)
+	}
+	diff = testutil.Diff(selectPages(pages, nil), pages)
+	if diff != "" {
+		t.Error(diff)
+	}
+}
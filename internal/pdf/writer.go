@@ -0,0 +1,218 @@
+// Package pdf writes pdf files from objects, the standard fonts and simple images without external processes.
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Ref is the number of an indirect object of a pdf file
+type Ref int
+
+// String returns the indirect reference to the object
+func (r Ref) String() string {
+	return strconv.Itoa(int(r)) + " 0 R"
+}
+
+// Writer collects the objects of a pdf file and writes them together with the cross reference table
+type Writer struct {
+	objects [][]byte
+	id      [2][]byte
+}
+
+func NewWriter() *Writer {
+	return &Writer{}
+}
+
+// Reserve returns the reference of a new object whose content is set later, e.g. a parent referenced by its kids
+func (w *Writer) Reserve() Ref {
+	w.objects = append(w.objects, nil)
+	return Ref(len(w.objects))
+}
+
+// Set sets the content of a reserved object
+func (w *Writer) Set(r Ref, obj string) {
+	w.objects[r-1] = []byte(obj)
+}
+
+// Add adds an object and returns its reference
+func (w *Writer) Add(obj string) Ref {
+	r := w.Reserve()
+	w.Set(r, obj)
+	return r
+}
+
+// AddStream adds a flate compressed stream, dict holds the entries of the stream dictionary without the brackets
+func (w *Writer) AddStream(dict string, data []byte) Ref {
+	buf := bytes.NewBuffer(Deflate(data))
+	if dict = strings.TrimSpace(dict); dict != "" {
+		dict += " "
+	}
+	obj := new(bytes.Buffer)
+	fmt.Fprintf(obj, "<< %s/Filter /FlateDecode /Length %d >>\nstream\n", dict, buf.Len())
+	obj.Write(buf.Bytes())
+	obj.WriteString("\nendstream")
+	r := w.Reserve()
+	w.objects[r-1] = obj.Bytes()
+	return r
+}
+
+// Deflate compresses data for a stream with the FlateDecode filter
+func Deflate(data []byte) []byte {
+	buf := new(bytes.Buffer)
+	zw := zlib.NewWriter(buf)
+	_, _ = zw.Write(data)
+	_ = zw.Close()
+	return buf.Bytes()
+}
+
+// AddObject adds the object o and returns its reference
+func (w *Writer) AddObject(o Object) Ref {
+	r := w.Reserve()
+	w.SetObject(r, o)
+	return r
+}
+
+// SetObject sets a reserved object to o, the data of a stream is written as it is with its filters
+func (w *Writer) SetObject(r Ref, o Object) {
+	s, ok := o.(*Stream)
+	if !ok {
+		w.Set(r, Format(o))
+		return
+	}
+	d := s.Dict.Clone()
+	d["Length"] = int64(len(s.Data))
+	obj := new(bytes.Buffer)
+	obj.WriteString(Format(d))
+	obj.WriteString("\nstream\n")
+	obj.Write(s.Data)
+	obj.WriteString("\nendstream")
+	w.objects[r-1] = obj.Bytes()
+}
+
+// SetID sets the file identifier of the trailer, the permanent identifier of the document and the identifier
+// of this version
+func (w *Writer) SetID(permanent, changing []byte) {
+	w.id = [2][]byte{permanent, changing}
+}
+
+// WriteTo writes the pdf file with the catalog root and the optional document information info
+func (w *Writer) WriteTo(out io.Writer, root, info Ref) error {
+	bw := bufio.NewWriter(out)
+	cw := &countingWriter{w: bw}
+	// the binary comment marks the file as binary for transfer programs
+	_, _ = cw.Write([]byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n"))
+	offsets := make([]int64, len(w.objects))
+	for i, obj := range w.objects {
+		if obj == nil {
+			return fmt.Errorf("object %d reserved but not set", i+1)
+		}
+		offsets[i] = cw.n
+		fmt.Fprintf(cw, "%d 0 obj\n", i+1)
+		_, _ = cw.Write(obj)
+		_, _ = cw.Write([]byte("\nendobj\n"))
+	}
+	xref := cw.n
+	fmt.Fprintf(cw, "xref\n0 %d\n0000000000 65535 f \n", len(w.objects)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(cw, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(cw, "trailer\n<< /Size %d /Root %s", len(w.objects)+1, root)
+	if info > 0 {
+		fmt.Fprintf(cw, " /Info %s", info)
+	}
+	if w.id[0] != nil {
+		fmt.Fprintf(cw, " /ID [<%x> <%x>]", w.id[0], w.id[1])
+	}
+	fmt.Fprintf(cw, " >>\nstartxref\n%d\n%%%%EOF\n", xref)
+	if cw.err != nil {
+		return cw.err
+	}
+	return bw.Flush()
+}
+
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
+
+// String returns s as a literal string, s is encoded with WinAnsiEncoding
+func String(s string) string {
+	return LiteralString(EncodeWinAnsi(s))
+}
+
+// LiteralString returns b as a literal string escaping the delimiters and the bytes outside printable ascii
+func LiteralString(b []byte) string {
+	sb := new(strings.Builder)
+	sb.WriteByte('(')
+	for _, c := range b {
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case c < 32 || c > 126:
+			fmt.Fprintf(sb, "\\%03o", c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	sb.WriteByte(')')
+	return sb.String()
+}
+
+// TextString returns s as a text string for document information and outline titles, ascii text is written
+// as it is and any other text as UTF-16 with a byte order mark
+func TextString(s string) string {
+	return LiteralString(EncodeText(s))
+}
+
+// EncodeText returns the bytes of the text string s, see TextString
+func EncodeText(s string) Str {
+	ascii := true
+	for _, r := range s {
+		if r > 126 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return Str(s)
+	}
+	b := Str{0xfe, 0xff}
+	for _, r := range s {
+		if r > 0xffff {
+			r -= 0x10000
+			hi, lo := 0xd800+(r>>10), 0xdc00+(r&0x3ff)
+			b = append(b, byte(hi>>8), byte(hi), byte(lo>>8), byte(lo))
+			continue
+		}
+		b = append(b, byte(r>>8), byte(r))
+	}
+	return b
+}
+
+// Number formats a coordinate or size with at most three decimals
+func Number(f float64) string {
+	s := strconv.FormatFloat(f, 'f', 3, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" || s == "" {
+		return "0"
+	}
+	return s
+}
@@ -0,0 +1,91 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	w := NewWriter()
+	pages := w.Reserve()
+	content := w.AddStream("", []byte("BT /F1 12 Tf 10 10 Td (hi) Tj ET"))
+	page := w.Add("<< /Type /Page /Parent " + pages.String() + " /MediaBox [0 0 100 100] /Contents " + content.String() + " >>")
+	w.Set(pages, "<< /Type /Pages /Kids ["+page.String()+"] /Count 1 >>")
+	root := w.Add("<< /Type /Catalog /Pages " + pages.String() + " >>")
+	out := new(bytes.Buffer)
+	err := w.WriteTo(out, root, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := out.Bytes()
+	if !bytes.HasPrefix(b, []byte("%PDF-1.7\n")) || !bytes.HasSuffix(b, []byte("%%EOF\n")) {
+		t.Fatalf("unexpected file %q", b)
+	}
+	// every cross reference entry points at its object
+	m := regexp.MustCompile(`startxref\n(\d+)`).FindSubmatch(b)
+	xref, _ := strconv.Atoi(string(m[1]))
+	entries := strings.Split(string(b[xref:]), "\n")[3:7]
+	for i, e := range entries {
+		off, _ := strconv.Atoi(e[:10])
+		if !bytes.HasPrefix(b[off:], []byte(strconv.Itoa(i+1)+" 0 obj\n")) {
+			t.Errorf("entry %d points at %q", i+1, b[off:off+10])
+		}
+	}
+	if !bytes.Contains(b, []byte("trailer\n<< /Size 5 /Root 4 0 R >>")) {
+		t.Errorf("unexpected trailer %s", b[xref:])
+	}
+	// the stream is compressed and its length matches
+	s := regexp.MustCompile(`(?s)<< /Filter /FlateDecode /Length (\d+) >>\nstream\n(.*?)\nendstream`).FindSubmatch(b)
+	if s == nil {
+		t.Fatal("stream not found")
+	}
+	if n, _ := strconv.Atoi(string(s[1])); n != len(s[2]) {
+		t.Errorf("length %d of a %d byte stream", n, len(s[2]))
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(s[2]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(zr)
+	if string(data) != "BT /F1 12 Tf 10 10 Td (hi) Tj ET" {
+		t.Errorf("unexpected stream %q", data)
+	}
+}
+
+func TestWriter_Unset(t *testing.T) {
+	w := NewWriter()
+	root := w.Reserve()
+	if err := w.WriteTo(io.Discard, root, 0); err == nil {
+		t.Error("want error for a reserved object without content")
+	}
+}
+
+func TestStrings(t *testing.T) {
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{name: "literal", got: String(`a (b) \ c`), want: `(a \(b\) \\ c)`},
+		{name: "win ansi", got: String("€ é – 日"), want: `(\200 \351 \226 ?)`},
+		{name: "text ascii", got: TextString("Invoice"), want: "(Invoice)"},
+		{name: "text utf16", got: TextString("É"), want: `(\376\377\000\311)`},
+		{name: "text surrogates", got: TextString("😀"), want: `(\376\377\330=\336\000)`},
+		{name: "number", got: Number(12.5), want: "12.5"},
+		{name: "number integer", got: Number(100), want: "100"},
+		{name: "number rounded", got: Number(0.12345), want: "0.123"},
+		{name: "number negative zero", got: Number(-0.0001), want: "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("want %s got %s", tt.want, tt.got)
+			}
+		})
+	}
+}
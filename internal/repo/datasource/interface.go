@@ -1,6 +1,7 @@
 package datasource
 
 import (
+	"context"
 	"time"
 )
 
@@ -8,7 +9,7 @@ import (
 
 type DataSource interface {
 	HealthCheck() bool
-	GetFile(s string) ([]byte, error)
-	SaveFile(key string, val interface{}, exp time.Duration) error
-	DeleteFile(key string) error
+	GetFile(ctx context.Context, s string) ([]byte, error)
+	SaveFile(ctx context.Context, key string, val interface{}, exp time.Duration) error
+	DeleteFile(ctx context.Context, key string) error
 }
@@ -1,6 +1,7 @@
 package datasource
 
 import (
+	"context"
 	"github.com/vatsal278/html-pdf-service/internal/config"
 	"time"
 )
@@ -21,7 +22,13 @@ func (r redisDs) HealthCheck() bool {
 	}
 	return true
 }
-func (r redisDs) GetFile(s string) ([]byte, error) {
+
+// GetFile returns the file stored at s, the cacher takes no context so a canceled request is checked before the
+// call only
+func (r redisDs) GetFile(ctx context.Context, s string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	x := r.redisSvc.Cacher
 	val, err := x.Get(s)
 	if err != nil {
@@ -30,7 +37,10 @@ func (r redisDs) GetFile(s string) ([]byte, error) {
 	return val, nil
 }
 
-func (r redisDs) SaveFile(key string, val interface{}, exp time.Duration) error {
+func (r redisDs) SaveFile(ctx context.Context, key string, val interface{}, exp time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	x := r.redisSvc.Cacher
 	err := x.Set(key, val, exp)
 	if err != nil {
@@ -38,7 +48,10 @@ func (r redisDs) SaveFile(key string, val interface{}, exp time.Duration) error
 	}
 	return nil
 }
-func (r redisDs) DeleteFile(key string) error {
+func (r redisDs) DeleteFile(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	x := r.redisSvc.Cacher
 	err := x.Delete(key)
 	if err != nil {
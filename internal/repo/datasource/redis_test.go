@@ -1,6 +1,7 @@
 package datasource
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/golang/mock/gomock"
@@ -112,7 +113,7 @@ func Test_SaveFile(t *testing.T) {
 			mockcacher := tt.setupFunc()
 			cacherSvc := config.CacherSvc{Cacher: mockcacher}
 			ds := NewRedisDs(&cacherSvc)
-			err := ds.SaveFile("1", []byte("abc"), tt.expiry)
+			err := ds.SaveFile(context.Background(), "1", []byte("abc"), tt.expiry)
 			tt.validateFunc(err)
 		})
 	}
@@ -160,7 +161,7 @@ func Test_DeleteFile(t *testing.T) {
 			mockcacher := tt.setupFunc()
 			cacherSvc := config.CacherSvc{Cacher: mockcacher}
 			ds := NewRedisDs(&cacherSvc)
-			err := ds.DeleteFile("1")
+			err := ds.DeleteFile(context.Background(), "1")
 			tt.validateFunc(err)
 		})
 	}
@@ -214,9 +215,30 @@ func Test_GetFile(t *testing.T) {
 			cacherSvc := config.CacherSvc{Cacher: mockcacher}
 			ds := NewRedisDs(&cacherSvc)
 			key := tt.requestBody
-			x, err := ds.GetFile(key)
+			x, err := ds.GetFile(context.Background(), key)
 			tt.validateFunc(x, "Hello", err)
 		})
 	}
 
 }
+
+func Test_CanceledContext(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	// the cacher is not called for a canceled request
+	ds := NewRedisDs(&config.CacherSvc{Cacher: mocks.NewMockCacher(mockCtrl)})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := ds.GetFile(ctx, "1")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("want %v got %v", context.Canceled, err)
+	}
+	err = ds.SaveFile(ctx, "1", []byte("abc"), 0)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("want %v got %v", context.Canceled, err)
+	}
+	err = ds.DeleteFile(ctx, "1")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("want %v got %v", context.Canceled, err)
+	}
+}
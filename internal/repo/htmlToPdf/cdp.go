@@ -0,0 +1,139 @@
+package htmlToPdf
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+var errCdpClosed = errors.New("devtools connection closed")
+
+// cdpConn is a connection to the DevTools protocol of a browser started with --remote-debugging-pipe,
+// every message is a json object terminated by a NUL byte
+type cdpConn struct {
+	w       io.Writer
+	mu      sync.Mutex
+	nextId  int64
+	pending map[int64]chan cdpMessage
+	waiters map[string]chan struct{}
+	done    chan struct{}
+	err     error
+}
+
+type cdpMessage struct {
+	Id        int64           `json:"id,omitempty"`
+	SessionId string          `json:"sessionId,omitempty"`
+	Method    string          `json:"method,omitempty"`
+	Params    json.RawMessage `json:"params,omitempty"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     *cdpError       `json:"error,omitempty"`
+}
+
+type cdpError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e cdpError) Error() string {
+	return fmt.Sprintf("devtools error %d: %s", e.Code, e.Message)
+}
+
+// newCdpConn writes the commands to w and reads the responses and events from r until r is closed
+func newCdpConn(w io.Writer, r io.Reader) *cdpConn {
+	c := &cdpConn{
+		w:       w,
+		pending: map[int64]chan cdpMessage{},
+		waiters: map[string]chan struct{}{},
+		done:    make(chan struct{}),
+	}
+	go c.read(r)
+	return c
+}
+
+func (c *cdpConn) read(r io.Reader) {
+	br := bufio.NewReader(r)
+	var err error
+	for {
+		var b []byte
+		b, err = br.ReadBytes(0)
+		if err != nil {
+			break
+		}
+		var m cdpMessage
+		err = json.Unmarshal(b[:len(b)-1], &m)
+		if err != nil {
+			break
+		}
+		c.mu.Lock()
+		if m.Id != 0 {
+			if ch, ok := c.pending[m.Id]; ok {
+				delete(c.pending, m.Id)
+				ch <- m
+			}
+		} else if ch, ok := c.waiters[m.SessionId+" "+m.Method]; ok {
+			delete(c.waiters, m.SessionId+" "+m.Method)
+			close(ch)
+		}
+		c.mu.Unlock()
+	}
+	if err == io.EOF {
+		err = errCdpClosed
+	}
+	c.mu.Lock()
+	c.err = err
+	c.mu.Unlock()
+	close(c.done)
+}
+
+// call sends a command to the browser, or to the page of the session when sessionId is set, and decodes the
+// result into result unless it is nil
+func (c *cdpConn) call(sessionId, method string, params interface{}, result interface{}) error {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.nextId++
+	id := c.nextId
+	ch := make(chan cdpMessage, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+	b, err := json.Marshal(struct {
+		Id        int64       `json:"id"`
+		SessionId string      `json:"sessionId,omitempty"`
+		Method    string      `json:"method"`
+		Params    interface{} `json:"params,omitempty"`
+	}{id, sessionId, method, params})
+	if err != nil {
+		return err
+	}
+	_, err = c.w.Write(append(b, 0))
+	if err != nil {
+		return err
+	}
+	select {
+	case m := <-ch:
+		if m.Error != nil {
+			return fmt.Errorf("%s: %w", method, *m.Error)
+		}
+		if result == nil {
+			return nil
+		}
+		return json.Unmarshal(m.Result, result)
+	case <-c.done:
+		return c.err
+	}
+}
+
+// event returns a channel closed on the next event of method on the session, it is registered before the
+// command triggering the event is sent
+func (c *cdpConn) event(sessionId, method string) <-chan struct{} {
+	ch := make(chan struct{})
+	c.mu.Lock()
+	c.waiters[sessionId+" "+method] = ch
+	c.mu.Unlock()
+	return ch
+}
@@ -0,0 +1,107 @@
+package htmlToPdf
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/PereRohit/util/testutil"
+)
+
+// fakeBrowser is the other end of a devtools connection, it answers every command with the result of handle
+// and sends the events handle returns after the response
+type fakeBrowser struct {
+	mu       sync.Mutex
+	commands []cdpMessage
+}
+
+func newFakeBrowser(t *testing.T, handle func(m cdpMessage) (interface{}, *cdpError, []cdpMessage)) (*cdpConn, *fakeBrowser, func()) {
+	cmdR, cmdW := io.Pipe()
+	outR, outW := io.Pipe()
+	f := &fakeBrowser{}
+	go func() {
+		br := bufio.NewReader(cmdR)
+		for {
+			b, err := br.ReadBytes(0)
+			if err != nil {
+				return
+			}
+			var m cdpMessage
+			err = json.Unmarshal(b[:len(b)-1], &m)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			f.mu.Lock()
+			f.commands = append(f.commands, m)
+			f.mu.Unlock()
+			result, cdpErr, events := handle(m)
+			raw, _ := json.Marshal(result)
+			out := []cdpMessage{{Id: m.Id, SessionId: m.SessionId, Result: raw, Error: cdpErr}}
+			for _, e := range append(out, events...) {
+				b, _ = json.Marshal(e)
+				_, err = outW.Write(append(b, 0))
+				if err != nil {
+					return
+				}
+			}
+		}
+	}()
+	return newCdpConn(cmdW, outR), f, func() {
+		_ = outW.Close()
+		_ = cmdR.Close()
+	}
+}
+
+func (f *fakeBrowser) methods() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var methods []string
+	for _, m := range f.commands {
+		methods = append(methods, m.SessionId+" "+m.Method)
+	}
+	return methods
+}
+
+func TestCdpConn(t *testing.T) {
+	conn, browser, closeBrowser := newFakeBrowser(t, func(m cdpMessage) (interface{}, *cdpError, []cdpMessage) {
+		switch m.Method {
+		case "Page.navigate":
+			return map[string]string{"frameId": "1"}, nil, []cdpMessage{{SessionId: m.SessionId, Method: "Page.loadEventFired"}}
+		case "Page.crash":
+			return nil, &cdpError{Code: -32000, Message: "not allowed"}, nil
+		}
+		return map[string]string{"product": "Chrome"}, nil, nil
+	})
+	var version struct {
+		Product string `json:"product"`
+	}
+	err := conn.call("", "Browser.getVersion", nil, &version)
+	if err != nil || version.Product != "Chrome" {
+		t.Errorf("unexpected version %v %v", version, err)
+	}
+	loaded := conn.event("s1", "Page.loadEventFired")
+	err = conn.call("s1", "Page.navigate", map[string]string{"url": "about:blank"}, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	<-loaded
+	err = conn.call("s1", "Page.crash", nil, nil)
+	var cdpErr cdpError
+	if !errors.As(err, &cdpErr) || cdpErr.Message != "not allowed" {
+		t.Errorf("want devtools error got %v", err)
+	}
+	diff := testutil.Diff(browser.methods(), []string{" Browser.getVersion", "s1 Page.navigate", "s1 Page.crash"})
+	if diff != "" {
+		t.Error(diff)
+	}
+	closeBrowser()
+	<-conn.done
+	err = conn.call("", "Browser.close", nil, nil)
+	if !errors.Is(err, errCdpClosed) {
+		t.Errorf("want %v got %v", errCdpClosed, err)
+	}
+}
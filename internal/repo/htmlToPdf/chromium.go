@@ -0,0 +1,412 @@
+package htmlToPdf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/vatsal278/html-pdf-service/internal/model"
+)
+
+// chromiumBins are the names of the chromium binary looked up in PATH when no path is configured
+var chromiumBins = []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "headless_shell"}
+
+// chromiumTimeout is the longest a document may take to load and print before the browser is killed, shorter
+// request deadlines kill it earlier
+const chromiumTimeout = time.Minute
+
+var errTocUnsupported = errors.New("the renderer doesn't support a table of contents")
+
+var headRegex = regexp.MustCompile(`(?is)<head(?:\s[^>]*)?>(.*?)</head\s*>`)
+var htmlAttrRegex = regexp.MustCompile(`(?i)<html(\s[^>]*)?>`)
+var bodyRegex = regexp.MustCompile(`(?is)<body(\s[^>]*)?>(.*)</body\s*>`)
+
+// noBackgroundStyle hides the backgrounds of the pages with the no_background setting
+const noBackgroundStyle = `<style>.html-pdf-no-background, .html-pdf-no-background * { background: none !important; }</style>`
+
+type chromium struct {
+	cfg    model.ChromiumConfig
+	health *health
+}
+
+// NewChromiumSvc returns a HtmlToPdf printing the documents with a locally installed headless chromium,
+// it reads the same document as the wkhtmltopdf renderer. The canary of the health check is run by the canary
+// scheduler s, see NewCanaryScheduler, a nil scheduler runs it right away.
+func NewChromiumSvc(cfg model.ChromiumConfig, hc model.HealthConfig, s *Scheduler) HtmlToPdf {
+	c := &chromium{cfg: cfg}
+	c.health = newHealth(model.RendererChromium, hc, s, c.probe)
+	c.health.start()
+	return c
+}
+
+// HealthCheck reports the version of chromium and the cached result of the last canary render
+func (c chromium) HealthCheck() (string, bool) {
+	return c.health.status()
+}
+
+// probe prints the canary page
+func (c chromium) probe(ctx context.Context) (string, error) {
+	path, err := c.binary()
+	if err != nil {
+		return "", err
+	}
+	out, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		return "", err
+	}
+	version := firstLine(out)
+	doc, err := c.GetJsonFromHtml(canaryHtml)
+	if err != nil {
+		return version, err
+	}
+	buf := new(bytes.Buffer)
+	err = c.GeneratePdf(ctx, buf, doc)
+	if err != nil {
+		return version, err
+	}
+	return version, checkCanary(buf.Bytes())
+}
+
+// GetJsonFromHtml returns the same document as the wkhtmltopdf renderer so that templates can switch renderers
+func (c chromium) GetJsonFromHtml(pages ...[]byte) ([]byte, error) {
+	return wkHtmlToPdf{}.GetJsonFromHtml(pages...)
+}
+
+func (c chromium) GeneratePdf(ctx context.Context, wr io.Writer, b []byte) error {
+	settings, err := docSettings(b)
+	if err != nil {
+		return err
+	}
+	if settings.Toc != nil && settings.Toc.Enabled {
+		return errTocUnsupported
+	}
+	pages, err := documentPages(b, settings.PageSetup)
+	if err != nil {
+		return err
+	}
+	path, err := c.binary()
+	if err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp("", "html-pdf-chromium-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	doc := filepath.Join(dir, "document.html")
+	err = os.WriteFile(doc, chromiumHtml(settings.Cover, pages), 0600)
+	if err != nil {
+		return err
+	}
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(doc)}
+	pdf, err := c.print(ctx, path, dir, u.String(), printOptions(settings, pages, time.Now()))
+	if err != nil {
+		return err
+	}
+	_, err = wr.Write(pdf)
+	return err
+}
+
+func (c chromium) binary() (string, error) {
+	if c.cfg.Path != "" {
+		return exec.LookPath(c.cfg.Path)
+	}
+	var err error
+	for _, name := range chromiumBins {
+		var path string
+		path, err = exec.LookPath(name)
+		if err == nil {
+			return path, nil
+		}
+	}
+	return "", err
+}
+
+// print starts a browser with its profile in dir and prints the document at u. The browser reads the devtools
+// commands from file descriptor 3 and writes the responses to file descriptor 4.
+func (c chromium) print(ctx context.Context, path, dir, u string, opts chromiumOptions) ([]byte, error) {
+	cmdR, cmdW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer cmdW.Close()
+	outR, outW, err := os.Pipe()
+	if err != nil {
+		cmdR.Close()
+		return nil, err
+	}
+	defer outR.Close()
+	// a file and not a buffer, the browser's child processes may keep a stderr pipe open after it exits
+	stderr, err := os.Create(filepath.Join(dir, "stderr.log"))
+	if err != nil {
+		cmdR.Close()
+		outW.Close()
+		return nil, err
+	}
+	defer stderr.Close()
+	args := []string{
+		"--headless", "--disable-gpu", "--remote-debugging-pipe", "--no-first-run", "--no-default-browser-check",
+		"--hide-scrollbars", "--mute-audio", "--user-data-dir=" + filepath.Join(dir, "profile"),
+	}
+	if c.cfg.NoSandbox {
+		args = append(args, "--no-sandbox")
+	}
+	cmd := exec.Command(path, append(args, "about:blank")...)
+	cmd.ExtraFiles = []*os.File{cmdR, outW}
+	cmd.Stderr = stderr
+	err = cmd.Start()
+	cmdR.Close()
+	outW.Close()
+	if err != nil {
+		return nil, err
+	}
+	// the browser is killed when ctx is done, the pipes close and the pending devtools call fails
+	ctx, cancel := context.WithTimeout(ctx, chromiumTimeout)
+	defer cancel()
+	exited := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = cmd.Process.Kill()
+		case <-exited:
+		}
+	}()
+	conn := newCdpConn(cmdW, outR)
+	pdf, err := printPage(ctx, conn, u, opts)
+	_ = conn.call("", "Browser.close", nil, nil)
+	_ = cmd.Wait()
+	close(exited)
+	if err != nil && ctx.Err() != nil {
+		return nil, fmt.Errorf("chromium killed: %w", ctx.Err())
+	}
+	if err != nil {
+		if msg, _ := os.ReadFile(stderr.Name()); len(bytes.TrimSpace(msg)) > 0 {
+			return nil, fmt.Errorf("%w: %s", err, lastLine(msg))
+		}
+		return nil, err
+	}
+	return pdf, nil
+}
+
+// printPage opens a new page, loads the document at u and prints it
+func printPage(ctx context.Context, conn *cdpConn, u string, opts chromiumOptions) ([]byte, error) {
+	var target struct {
+		TargetId string `json:"targetId"`
+	}
+	err := conn.call("", "Target.createTarget", map[string]interface{}{"url": "about:blank"}, &target)
+	if err != nil {
+		return nil, err
+	}
+	var session struct {
+		SessionId string `json:"sessionId"`
+	}
+	err = conn.call("", "Target.attachToTarget", map[string]interface{}{"targetId": target.TargetId, "flatten": true}, &session)
+	if err != nil {
+		return nil, err
+	}
+	s := session.SessionId
+	err = conn.call(s, "Page.enable", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = conn.call(s, "Emulation.setEmulatedMedia", map[string]interface{}{"media": opts.Media}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if opts.DisableJavascript {
+		err = conn.call(s, "Emulation.setScriptExecutionDisabled", map[string]interface{}{"value": true}, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	loaded := conn.event(s, "Page.loadEventFired")
+	var nav struct {
+		ErrorText string `json:"errorText"`
+	}
+	err = conn.call(s, "Page.navigate", map[string]interface{}{"url": u}, &nav)
+	if err != nil {
+		return nil, err
+	}
+	if nav.ErrorText != "" {
+		return nil, fmt.Errorf("loading document: %s", nav.ErrorText)
+	}
+	select {
+	case <-loaded:
+	case <-conn.done:
+		return nil, conn.err
+	}
+	if opts.JavascriptDelay > 0 {
+		select {
+		case <-time.After(opts.JavascriptDelay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-conn.done:
+			return nil, conn.err
+		}
+	}
+	var pdf struct {
+		Data []byte `json:"data"`
+	}
+	err = conn.call(s, "Page.printToPDF", opts.Print, &pdf)
+	if err != nil {
+		return nil, err
+	}
+	return pdf.Data, nil
+}
+
+// chromiumOptions are the document wide options of a print, chromium can't change them per page
+type chromiumOptions struct {
+	Media             string
+	DisableJavascript bool
+	JavascriptDelay   time.Duration
+	Print             printParams
+}
+
+// printParams are the parameters of Page.printToPDF, sizes and margins are in inches
+type printParams struct {
+	Landscape               bool    `json:"landscape,omitempty"`
+	DisplayHeaderFooter     bool    `json:"displayHeaderFooter,omitempty"`
+	PrintBackground         bool    `json:"printBackground"`
+	PaperWidth              float64 `json:"paperWidth"`
+	PaperHeight             float64 `json:"paperHeight"`
+	MarginTop               float64 `json:"marginTop"`
+	MarginBottom            float64 `json:"marginBottom"`
+	MarginLeft              float64 `json:"marginLeft"`
+	MarginRight             float64 `json:"marginRight"`
+	HeaderTemplate          string  `json:"headerTemplate,omitempty"`
+	FooterTemplate          string  `json:"footerTemplate,omitempty"`
+	GenerateDocumentOutline bool    `json:"generateDocumentOutline,omitempty"`
+}
+
+// printOptions maps the page setup, header, footer and outline of the document to the print parameters. The page
+// settings apply to the whole document: print media and disabled javascript when any page asks for them and the
+// longest javascript delay of the pages.
+func printOptions(s renderSettings, pages []documentPage, now time.Time) chromiumOptions {
+	opts := chromiumOptions{Media: "screen"}
+	if s.PageSetup.PrintMediaType {
+		opts.Media = "print"
+	}
+	for _, p := range pages {
+		if p.settings.PrintMediaType {
+			opts.Media = "print"
+		}
+		if p.settings.DisableJavascript {
+			opts.DisableJavascript = true
+		}
+		if d := time.Duration(p.settings.JavascriptDelay) * time.Millisecond; d > opts.JavascriptDelay {
+			opts.JavascriptDelay = d
+		}
+	}
+	size := paperSize(s.PageSetup)
+	margin := func(m *uint) float64 {
+		return marginSize(m) / mmPerInch
+	}
+	opts.Print = printParams{
+		Landscape:               strings.ToLower(s.PageSetup.Orientation) == model.OrientationLandscape,
+		PrintBackground:         true,
+		PaperWidth:              size[0] / mmPerInch,
+		PaperHeight:             size[1] / mmPerInch,
+		MarginTop:               margin(s.PageSetup.MarginTop),
+		MarginBottom:            margin(s.PageSetup.MarginBottom),
+		MarginLeft:              margin(s.PageSetup.MarginLeft),
+		MarginRight:             margin(s.PageSetup.MarginRight),
+		GenerateDocumentOutline: s.Outline == nil || !s.Outline.Disabled,
+	}
+	if s.Header != nil || s.Footer != nil {
+		opts.Print.DisplayHeaderFooter = true
+		// chromium prints its own header or footer when a template is left empty
+		opts.Print.HeaderTemplate, opts.Print.FooterTemplate = "<span></span>", "<span></span>"
+		if s.Header != nil {
+			opts.Print.HeaderTemplate = runningTemplate(s.Header.Html, now)
+		}
+		if s.Footer != nil {
+			opts.Print.FooterTemplate = runningTemplate(s.Footer.Html, now)
+		}
+	}
+	return opts
+}
+
+// runningTemplate replaces the header and footer variables with the elements chromium fills in when printing,
+// the variables chromium doesn't know are replaced with their value at the time of rendering or removed
+func runningTemplate(html []byte, now time.Time) string {
+	return strings.NewReplacer(
+		"[page]", `<span class="pageNumber"></span>`,
+		"[frompage]", "1",
+		"[topage]", `<span class="totalPages"></span>`,
+		"[section]", "",
+		"[subsection]", "",
+		"[title]", `<span class="title"></span>`,
+		"[doctitle]", `<span class="title"></span>`,
+		"[date]", `<span class="date"></span>`,
+		"[isodate]", now.Format("2006-01-02"),
+		"[time]", now.Format("15:04:05"),
+	).Replace(string(html))
+}
+
+// chromiumHtml joins the cover and the pages into a single html document, chromium prints one document per page
+// load. The head of every page is kept, the html and body attributes move to wrapping elements and every page but
+// the last ends with a page break. A single page without zoom or hidden background is printed as it is.
+func chromiumHtml(cover []byte, pages []documentPage) []byte {
+	if cover != nil {
+		pages = append([]documentPage{{html: cover}}, pages...)
+	}
+	if len(pages) == 1 && pages[0].settings.Zoom == 0 && !pages[0].settings.NoBackground {
+		return pages[0].html
+	}
+	head, body := new(bytes.Buffer), new(bytes.Buffer)
+	head.WriteString(`<meta charset="utf-8">`)
+	noBackground := false
+	for i, p := range pages {
+		var htmlAttrs, bodyAttrs []byte
+		content := p.html
+		if m := headRegex.FindSubmatch(p.html); m != nil {
+			head.Write(m[1])
+		}
+		if m := htmlAttrRegex.FindSubmatch(p.html); m != nil {
+			htmlAttrs = m[1]
+		}
+		if m := bodyRegex.FindSubmatch(p.html); m != nil {
+			bodyAttrs, content = m[1], m[2]
+		}
+		style := ""
+		if i < len(pages)-1 {
+			style += "break-after: page;"
+		}
+		if p.settings.Zoom > 0 {
+			style += fmt.Sprintf(" zoom: %g;", p.settings.Zoom)
+		}
+		class := ""
+		if p.settings.NoBackground {
+			class = ` class="html-pdf-no-background"`
+			noBackground = true
+		}
+		fmt.Fprintf(body, `<div style="%s"%s><div%s><div%s>`, strings.TrimSpace(style), class, htmlAttrs, bodyAttrs)
+		body.Write(content)
+		body.WriteString("</div></div></div>")
+	}
+	if noBackground {
+		head.WriteString(noBackgroundStyle)
+	}
+	out := new(bytes.Buffer)
+	out.WriteString("<!DOCTYPE html><html><head>")
+	out.Write(head.Bytes())
+	out.WriteString("</head><body>")
+	out.Write(body.Bytes())
+	out.WriteString("</body></html>")
+	return out.Bytes()
+}
+
+// lastLine returns the last non empty line of the output of the browser
+func lastLine(b []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
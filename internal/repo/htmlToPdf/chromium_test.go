@@ -0,0 +1,211 @@
+package htmlToPdf
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PereRohit/util/testutil"
+
+	"github.com/vatsal278/html-pdf-service/internal/model"
+)
+
+func TestChromium_GeneratePdf(t *testing.T) {
+	svc := NewChromiumSvc(model.ChromiumConfig{NoSandbox: true}, model.HealthConfig{}, nil)
+	if _, err := svc.(*chromium).binary(); err != nil {
+		t.Skip("chromium not installed")
+	}
+	doc, err := svc.GetJsonFromHtml(
+		[]byte(`<html><head><style>.row { display: flex; gap: 1em }</style></head><body><div class="row"><h1>One</h1></div></body></html>`),
+		[]byte(`<html dir="rtl"><body><div style="display: grid"><h1>Two</h1></div></body></html>`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc = bytes.Replace(doc, []byte(`"Pages"`), []byte(`"Settings":{"page_setup":{"page_size":"A5"},"footer":{"html":"PHA+W3BhZ2VdL1t0b3BhZ2VdPC9wPg=="}},"Pages"`), 1)
+	out := new(bytes.Buffer)
+	err = svc.GeneratePdf(context.Background(), out, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(out.Bytes(), []byte("%PDF-")) {
+		t.Errorf("want a pdf got %q", out.Bytes()[:20])
+	}
+}
+
+func TestChromium_GeneratePdf_Toc(t *testing.T) {
+	doc := []byte(`{"Settings":{"toc":{"enabled":true}},"Pages":[{"Base64PageData":"PHA+MTwvcD4="}]}`)
+	err := NewChromiumSvc(model.ChromiumConfig{}, model.HealthConfig{}, nil).GeneratePdf(context.Background(), new(bytes.Buffer), doc)
+	if err != errTocUnsupported {
+		t.Errorf("want %v got %v", errTocUnsupported, err)
+	}
+}
+
+func TestPrintPage(t *testing.T) {
+	conn, browser, closeBrowser := newFakeBrowser(t, func(m cdpMessage) (interface{}, *cdpError, []cdpMessage) {
+		switch m.Method {
+		case "Target.createTarget":
+			return map[string]string{"targetId": "t1"}, nil, nil
+		case "Target.attachToTarget":
+			return map[string]string{"sessionId": "s1"}, nil, nil
+		case "Page.navigate":
+			return map[string]string{"frameId": "f1"}, nil, []cdpMessage{{SessionId: m.SessionId, Method: "Page.loadEventFired"}}
+		case "Page.printToPDF":
+			if !strings.Contains(string(m.Params), `"paperWidth":8.5`) {
+				t.Errorf("unexpected print parameters %s", m.Params)
+			}
+			return map[string]string{"data": base64.StdEncoding.EncodeToString([]byte("%PDF-1.4"))}, nil, nil
+		}
+		return struct{}{}, nil, nil
+	})
+	defer closeBrowser()
+	pdf, err := printPage(context.Background(), conn, "file:///tmp/document.html", chromiumOptions{
+		Media:             "print",
+		DisableJavascript: true,
+		Print:             printParams{PaperWidth: 8.5, PaperHeight: 11},
+	})
+	if err != nil || string(pdf) != "%PDF-1.4" {
+		t.Fatalf("unexpected pdf %q %v", pdf, err)
+	}
+	diff := testutil.Diff(browser.methods(), []string{
+		" Target.createTarget", " Target.attachToTarget", "s1 Page.enable", "s1 Emulation.setEmulatedMedia",
+		"s1 Emulation.setScriptExecutionDisabled", "s1 Page.navigate", "s1 Page.printToPDF",
+	})
+	if diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestPrintPage_NavigationError(t *testing.T) {
+	conn, _, closeBrowser := newFakeBrowser(t, func(m cdpMessage) (interface{}, *cdpError, []cdpMessage) {
+		if m.Method == "Page.navigate" {
+			return map[string]string{"errorText": "net::ERR_FILE_NOT_FOUND"}, nil, nil
+		}
+		return struct{}{}, nil, nil
+	})
+	defer closeBrowser()
+	_, err := printPage(context.Background(), conn, "file:///missing.html", chromiumOptions{Media: "screen"})
+	if err == nil || !strings.Contains(err.Error(), "ERR_FILE_NOT_FOUND") {
+		t.Errorf("want navigation error got %v", err)
+	}
+}
+
+func TestPrintPage_JavascriptDelayCanceled(t *testing.T) {
+	conn, browser, closeBrowser := newFakeBrowser(t, func(m cdpMessage) (interface{}, *cdpError, []cdpMessage) {
+		if m.Method == "Page.navigate" {
+			return map[string]string{"frameId": "f1"}, nil, []cdpMessage{{SessionId: m.SessionId, Method: "Page.loadEventFired"}}
+		}
+		return struct{}{}, nil, nil
+	})
+	defer closeBrowser()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := printPage(ctx, conn, "file:///tmp/document.html", chromiumOptions{Media: "screen", JavascriptDelay: time.Minute})
+	if err != context.DeadlineExceeded {
+		t.Errorf("want %v got %v", context.DeadlineExceeded, err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("want the delay to stop with the context got %v", d)
+	}
+	for _, m := range browser.methods() {
+		if strings.HasSuffix(m, "Page.printToPDF") {
+			t.Error("want no print after the context is done")
+		}
+	}
+}
+
+func TestPrintOptions(t *testing.T) {
+	five := uint(5)
+	now := time.Date(2024, 3, 1, 14, 30, 0, 0, time.UTC)
+	inch := func(mm float64) float64 {
+		return mm / mmPerInch
+	}
+	tests := []struct {
+		name     string
+		settings renderSettings
+		pages    []documentPage
+		want     chromiumOptions
+	}{
+		{
+			name:     "defaults",
+			settings: renderSettings{},
+			pages:    []documentPage{{}},
+			want: chromiumOptions{Media: "screen", Print: printParams{
+				PrintBackground: true, PaperWidth: inch(210), PaperHeight: inch(297),
+				MarginTop: inch(10), MarginBottom: inch(10), MarginLeft: inch(10), MarginRight: inch(10),
+				GenerateDocumentOutline: true,
+			}},
+		},
+		{
+			name: "page setup, footer and page settings",
+			settings: renderSettings{
+				PageSetup: model.PageSetup{PageSize: "letter", Orientation: "Landscape", MarginTop: &five},
+				Footer:    &model.HeaderFooter{Html: []byte("<p>[page] of [topage], [isodate]</p>")},
+				Outline:   &model.Outline{Disabled: true},
+			},
+			pages: []documentPage{
+				{settings: model.PageSettings{JavascriptDelay: 200}},
+				{settings: model.PageSettings{PrintMediaType: true, DisableJavascript: true, JavascriptDelay: 100}},
+			},
+			want: chromiumOptions{Media: "print", DisableJavascript: true, JavascriptDelay: 200 * time.Millisecond, Print: printParams{
+				Landscape: true, DisplayHeaderFooter: true, PrintBackground: true,
+				PaperWidth: inch(215.9), PaperHeight: inch(279.4),
+				MarginTop: inch(5), MarginBottom: inch(10), MarginLeft: inch(10), MarginRight: inch(10),
+				HeaderTemplate: "<span></span>",
+				FooterTemplate: `<p><span class="pageNumber"></span> of <span class="totalPages"></span>, 2024-03-01</p>`,
+			}},
+		},
+		{
+			name:     "custom size",
+			settings: renderSettings{PageSetup: model.PageSetup{PageWidth: 100, PageHeight: 150, PrintMediaType: true}},
+			pages:    []documentPage{{}},
+			want: chromiumOptions{Media: "print", Print: printParams{
+				PrintBackground: true, PaperWidth: inch(100), PaperHeight: inch(150),
+				MarginTop: inch(10), MarginBottom: inch(10), MarginLeft: inch(10), MarginRight: inch(10),
+				GenerateDocumentOutline: true,
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := testutil.Diff(printOptions(tt.settings, tt.pages, now), tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestChromiumHtml(t *testing.T) {
+	single := []byte("<html><body><p>1</p></body></html>")
+	got := chromiumHtml(nil, []documentPage{{html: single}})
+	if !bytes.Equal(got, single) {
+		t.Errorf("want the page unchanged got %s", got)
+	}
+	got = chromiumHtml([]byte("<h1>cover</h1>"), []documentPage{
+		{html: []byte(`<!DOCTYPE html><html lang="ar" dir="rtl"><head><style>p { color: red }</style></head><body class="a"><header>x</header><p>1</p></body></html>`)},
+		{html: []byte("<p>2</p>"), settings: model.PageSettings{Zoom: 1.5, NoBackground: true}},
+	})
+	want := `<!DOCTYPE html><html><head><meta charset="utf-8"><style>p { color: red }</style>` + noBackgroundStyle + `</head><body>` +
+		`<div style="break-after: page;"><div><div><h1>cover</h1></div></div></div>` +
+		`<div style="break-after: page;"><div lang="ar" dir="rtl"><div class="a"><header>x</header><p>1</p></div></div></div>` +
+		`<div style="zoom: 1.5;" class="html-pdf-no-background"><div><div><p>2</p></div></div></div>` +
+		`</body></html>`
+	diff := testutil.Diff(string(got), want)
+	if diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestRunningTemplate(t *testing.T) {
+	now := time.Date(2024, 3, 1, 14, 30, 5, 0, time.UTC)
+	got := runningTemplate([]byte("[doctitle] [section] [frompage]-[topage] [date] [time] [unknown]"), now)
+	want := `<span class="title"></span>  1-<span class="totalPages"></span> <span class="date"></span> 14:30:05 [unknown]`
+	if got != want {
+		t.Errorf("want %s got %s", want, got)
+	}
+}
@@ -0,0 +1,190 @@
+package htmlToPdf
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/SebastiaanKlippert/go-wkhtmltopdf"
+
+	"github.com/vatsal278/html-pdf-service/internal/model"
+)
+
+var bodyEndRegex = regexp.MustCompile(`(?i)</body\s*>`)
+var doctypeRegex = regexp.MustCompile(`(?i)^\s*<!doctype`)
+
+// substituteScript replaces the [page], [topage], [section], ... variables wkhtmltopdf passes in the query string
+// of a header or footer page, the first %s is true to hide the header or footer on the first page
+const substituteScript = `<script>
+(function () {
+	var vars = {};
+	var query = window.location.search.substring(1).split('&');
+	for (var i = 0; i < query.length; i++) {
+		var kv = query[i].split('=');
+		if (kv.length > 1) {
+			vars[kv[0]] = decodeURIComponent(kv.slice(1).join('=').replace(/\+/g, ' '));
+		}
+	}
+	if (%s && vars.page === '1') {
+		document.body.style.visibility = 'hidden';
+		return;
+	}
+	var escape = function (s) {
+		return s.replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;');
+	};
+	document.body.innerHTML = document.body.innerHTML.replace(/\[(%s)\]/g, function (m, name) {
+		return vars[name] === undefined ? m : escape(vars[name]);
+	});
+})();
+</script>`
+
+// renderFiles are the parts of the document wkhtmltopdf only reads from files, written to a temporary directory
+type renderFiles struct {
+	dir           string
+	header        string
+	footer        string
+	headerSpacing float64
+	footerSpacing float64
+	cover         string
+	xsl           string
+}
+
+// writeRenderFiles writes the header, footer, cover and table of contents style sheet to a temporary directory,
+// nothing is written when none of them is set
+func writeRenderFiles(s renderSettings) (renderFiles, error) {
+	var files renderFiles
+	var xsl []byte
+	if s.Toc != nil {
+		xsl = s.Toc.Xsl
+	}
+	if s.Header == nil && s.Footer == nil && s.Cover == nil && xsl == nil {
+		return files, nil
+	}
+	dir, err := os.MkdirTemp("", "html-pdf-")
+	if err != nil {
+		return files, err
+	}
+	files.dir = dir
+	write := func(name string, b []byte) string {
+		if err != nil {
+			return ""
+		}
+		path := filepath.Join(dir, name)
+		err = os.WriteFile(path, b, 0600)
+		return path
+	}
+	if s.Header != nil {
+		files.header = write("header.html", runningHtml(*s.Header))
+		files.headerSpacing = s.Header.Spacing
+	}
+	if s.Footer != nil {
+		files.footer = write("footer.html", runningHtml(*s.Footer))
+		files.footerSpacing = s.Footer.Spacing
+	}
+	if s.Cover != nil {
+		files.cover = write("cover.html", s.Cover)
+	}
+	if xsl != nil {
+		files.xsl = write("toc.xsl", xsl)
+	}
+	if err != nil {
+		files.remove()
+		return renderFiles{}, err
+	}
+	return files, nil
+}
+
+// remove deletes the temporary directory of the files
+func (f renderFiles) remove() {
+	if f.dir != "" {
+		_ = os.RemoveAll(f.dir)
+	}
+}
+
+// pageOptions sets the header and footer of a page, wkhtmltopdf may only load them from the temporary directory
+func (f renderFiles) pageOptions(po *wkhtmltopdf.PageOptions) {
+	if f.dir == "" {
+		return
+	}
+	po.Allow.Set(f.dir)
+	if f.header != "" {
+		po.HeaderHTML.Set(f.header)
+		po.HeaderSpacing.Set(f.headerSpacing)
+	}
+	if f.footer != "" {
+		po.FooterHTML.Set(f.footer)
+		po.FooterSpacing.Set(f.footerSpacing)
+	}
+}
+
+// documentOptions adds the cover and the table of contents to the document and sets the outline options,
+// the table of contents gets the same header and footer as the pages
+func documentOptions(pdfg *wkhtmltopdf.PDFGenerator, s renderSettings, f renderFiles) {
+	if f.cover != "" {
+		pdfg.Cover.Input = f.cover
+		pdfg.Cover.Allow.Set(f.dir)
+	}
+	if s.Toc != nil && s.Toc.Enabled {
+		pdfg.TOC.Include = true
+		if s.Toc.HeaderText != "" {
+			pdfg.TOC.TocHeaderText.Set(s.Toc.HeaderText)
+		}
+		if s.Toc.DisableDottedLines {
+			pdfg.TOC.DisableDottedLines.Set(true)
+		}
+		if s.Toc.DisableLinks {
+			pdfg.TOC.DisableTocLinks.Set(true)
+		}
+		if s.Toc.LevelIndentation > 0 {
+			pdfg.TOC.TocLevelIndentation.Set(s.Toc.LevelIndentation)
+		}
+		if s.Toc.TextSizeShrink > 0 {
+			pdfg.TOC.TocTextSizeShrink.Set(s.Toc.TextSizeShrink)
+		}
+		if f.dir != "" {
+			pdfg.TOC.Allow.Set(f.dir)
+		}
+		if f.xsl != "" {
+			pdfg.TOC.XslStyleSheet.Set(f.xsl)
+		}
+		if f.header != "" {
+			pdfg.TOC.HeaderHTML.Set(f.header)
+			pdfg.TOC.HeaderSpacing.Set(f.headerSpacing)
+		}
+		if f.footer != "" {
+			pdfg.TOC.FooterHTML.Set(f.footer)
+			pdfg.TOC.FooterSpacing.Set(f.footerSpacing)
+		}
+	}
+	if s.Outline != nil {
+		if s.Outline.Disabled {
+			pdfg.NoOutline.Set(true)
+		}
+		if s.Outline.Depth > 0 {
+			pdfg.OutlineDepth.Set(s.Outline.Depth)
+		}
+	}
+}
+
+// runningHtml returns the html of a header or footer page with the variable substitution script,
+// wkhtmltopdf needs a doctype to size header and footer pages correctly
+func runningHtml(hf model.HeaderFooter) []byte {
+	script := fmt.Sprintf(substituteScript, fmt.Sprint(hf.SkipFirstPage), strings.Join(model.HeaderFooterVars, "|"))
+	var buf bytes.Buffer
+	if !doctypeRegex.Match(hf.Html) {
+		buf.WriteString("<!DOCTYPE html>\n")
+	}
+	loc := bodyEndRegex.FindIndex(hf.Html)
+	if loc == nil {
+		buf.Write(hf.Html)
+		buf.WriteString("\n" + script)
+		return buf.Bytes()
+	}
+	buf.Write(hf.Html[:loc[0]])
+	buf.WriteString(script)
+	buf.Write(hf.Html[loc[0]:])
+	return buf.Bytes()
+}
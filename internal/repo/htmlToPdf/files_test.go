@@ -0,0 +1,143 @@
+package htmlToPdf
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PereRohit/util/testutil"
+	"github.com/SebastiaanKlippert/go-wkhtmltopdf"
+
+	"github.com/vatsal278/html-pdf-service/internal/model"
+)
+
+func TestRunningHtml(t *testing.T) {
+	tests := []struct {
+		name       string
+		hf         model.HeaderFooter
+		wantPrefix string
+		wantSuffix string
+		wantSkip   string
+	}{
+		{
+			name:       "fragment",
+			hf:         model.HeaderFooter{Html: []byte(`<p>Page [page] of [topage]</p>`)},
+			wantPrefix: "<!DOCTYPE html>\n<p>Page [page] of [topage]</p>\n<script>",
+			wantSuffix: "</script>",
+			wantSkip:   "if (false && vars.page === '1')",
+		},
+		{
+			name:       "document",
+			hf:         model.HeaderFooter{Html: []byte("<!doctype html><html><body><p>[section]</p></body></html>"), SkipFirstPage: true},
+			wantPrefix: "<!doctype html><html><body><p>[section]</p><script>",
+			wantSuffix: "</script></body></html>",
+			wantSkip:   "if (true && vars.page === '1')",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(runningHtml(tt.hf))
+			if !strings.HasPrefix(got, tt.wantPrefix) || !strings.HasSuffix(got, tt.wantSuffix) {
+				t.Errorf("unexpected html %s", got)
+			}
+			if !strings.Contains(got, tt.wantSkip) || !strings.Contains(got, `\[(page|frompage|topage|section|subsection|title|doctitle|date|isodate|time)\]`) {
+				t.Errorf("unexpected script %s", got)
+			}
+		})
+	}
+}
+
+func TestWriteRenderFiles(t *testing.T) {
+	files, err := writeRenderFiles(renderSettings{Toc: &model.Toc{Enabled: true}})
+	if err != nil || files.dir != "" {
+		t.Fatalf("want no files got %v %v", files, err)
+	}
+	files, err = writeRenderFiles(renderSettings{
+		Header: &model.HeaderFooter{Html: []byte("<p>head</p>"), Spacing: 5},
+		Cover:  []byte("<h1>cover</h1>"),
+		Toc:    &model.Toc{Xsl: []byte("<xsl/>")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"header.html": "<p>head</p>", "cover.html": "<h1>cover</h1>", "toc.xsl": "<xsl/>"} {
+		b, err := os.ReadFile(filepath.Join(files.dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(b), want) {
+			t.Errorf("unexpected %s %s", name, b)
+		}
+	}
+	po := wkhtmltopdf.NewPageOptions()
+	files.pageOptions(&po)
+	diff := testutil.Diff(po.Args(), []string{"--allow", files.dir, "--header-html", files.header, "--header-spacing", "5.000"})
+	if diff != "" {
+		t.Error(testutil.Callers(), diff)
+	}
+	files.remove()
+	_, err = os.Stat(files.dir)
+	if !os.IsNotExist(err) {
+		t.Errorf("want directory removed got %v", err)
+	}
+}
+
+func TestApplyPageSettings_HeaderFooter(t *testing.T) {
+	doc := []byte(`{"Pages":[{"Base64PageData":"PHA+MTwvcD4="},{"Base64PageData":"PHA+MjwvcD4="}]}`)
+	files := renderFiles{dir: "/tmp/x", footer: "/tmp/x/footer.html", footerSpacing: 2}
+	b, err := applyPageSettings(doc, model.PageSetup{}, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Pages []struct {
+			PageOptions wkhtmltopdf.PageOptions
+		}
+	}
+	err = json.Unmarshal(b, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range got.Pages {
+		diff := testutil.Diff(p.PageOptions.Args(), []string{"--allow", "/tmp/x", "--footer-html", "/tmp/x/footer.html", "--footer-spacing", "2.000"})
+		if diff != "" {
+			t.Error(testutil.Callers(), diff)
+		}
+	}
+}
+
+func TestDocumentOptions(t *testing.T) {
+	files := renderFiles{dir: "/tmp/x", header: "/tmp/x/header.html", headerSpacing: 3, cover: "/tmp/x/cover.html", xsl: "/tmp/x/toc.xsl"}
+	tests := []struct {
+		name     string
+		settings renderSettings
+		files    renderFiles
+		want     []string
+	}{
+		{
+			name:     "cover, toc and outline",
+			settings: renderSettings{Toc: &model.Toc{Enabled: true, HeaderText: "Contents", DisableDottedLines: true}, Outline: &model.Outline{Depth: 2}},
+			files:    files,
+			want: []string{"--outline-depth", "2", "cover", "/tmp/x/cover.html", "--allow", "/tmp/x",
+				"toc", "--allow", "/tmp/x", "--disable-dotted-lines", "--toc-header-text", "Contents", "--xsl-style-sheet", "/tmp/x/toc.xsl",
+				"--header-html", "/tmp/x/header.html", "--header-spacing", "3.000", "-"},
+		},
+		{
+			name:     "toc disabled and no outline",
+			settings: renderSettings{Toc: &model.Toc{HeaderText: "Contents"}, Outline: &model.Outline{Disabled: true}},
+			want:     []string{"--no-outline", "-"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pdfg := wkhtmltopdf.NewPDFPreparer()
+			documentOptions(pdfg, tt.settings, tt.files)
+			diff := testutil.Diff(pdfg.Args(), tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
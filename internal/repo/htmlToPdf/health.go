@@ -0,0 +1,156 @@
+package htmlToPdf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vatsal278/html-pdf-service/internal/model"
+)
+
+const (
+	defaultCanaryInterval = time.Minute
+	defaultCanaryTimeout  = 10 * time.Second
+)
+
+// canaryHtml is the page the health check renders
+var canaryHtml = []byte(`<html><head><title>canary</title></head><body><p>canary</p></body></html>`)
+
+var errCanaryOutput = errors.New("canary render returned no pdf")
+
+// probe finds the binary of a renderer, reads its version and renders the canary page
+type probe func(ctx context.Context) (version string, err error)
+
+// health runs the canary render of a renderer in the background once per interval and caches the result, the
+// canary takes a slot of the scheduler of the canaries
+type health struct {
+	name     string
+	interval time.Duration
+	timeout  time.Duration
+	probe    probe
+	s        *Scheduler
+	now      func() time.Time
+
+	mu          sync.Mutex
+	checked     time.Time
+	version     string
+	lastSuccess time.Time
+	err         error
+}
+
+// NewCanaryScheduler returns the scheduler of the canaries of all renderers. It runs one canary at a time outside
+// the render slots, so that a canary never holds back a render, and queues the canary of the other renderer until
+// the running one is done or timed out.
+func NewCanaryScheduler(cfg model.HealthConfig) *Scheduler {
+	timeout := cfg.CanaryTimeoutMs
+	if timeout <= 0 {
+		timeout = int(defaultCanaryTimeout / time.Millisecond)
+	}
+	return NewScheduler(model.SchedulerConfig{Concurrency: 1, QueueSize: 1, QueueTimeout: 2 * timeout})
+}
+
+func newHealth(name string, cfg model.HealthConfig, s *Scheduler, p probe) *health {
+	h := &health{
+		name:     name,
+		interval: time.Duration(cfg.CanaryIntervalMs) * time.Millisecond,
+		timeout:  time.Duration(cfg.CanaryTimeoutMs) * time.Millisecond,
+		probe:    p,
+		s:        s,
+		now:      time.Now,
+	}
+	if h.interval <= 0 {
+		h.interval = defaultCanaryInterval
+	}
+	if h.timeout <= 0 {
+		h.timeout = defaultCanaryTimeout
+	}
+	return h
+}
+
+// start runs the canary now and then once per interval for the lifetime of the process
+func (h *health) start() {
+	go func() {
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+		for {
+			h.check()
+			<-ticker.C
+		}
+	}()
+}
+
+// check runs the canary and caches its result, the timeout starts once the scheduler runs the canary. A canary
+// rejected because the scheduler is busy keeps the previous result.
+func (h *health) check() {
+	var version string
+	render := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+		defer cancel()
+		var err error
+		version, err = h.probe(ctx)
+		if err != nil && ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("canary render timed out after %s", h.timeout)
+		}
+		return err
+	}
+	var err error
+	if h.s != nil {
+		err = h.s.run(context.Background(), render)
+	} else {
+		err = render()
+	}
+	var busy *BusyError
+	if errors.As(err, &busy) {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := h.now()
+	h.checked, h.err = now, err
+	if version != "" {
+		h.version = version
+	}
+	if err == nil {
+		h.lastSuccess = now
+	}
+}
+
+// status returns the health message of the last canary with the version, the last successful canary and the
+// failure reason, the renderer is unhealthy until the first canary succeeded
+func (h *health) status() (string, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	msg := h.name
+	if h.version != "" {
+		msg = h.version
+	}
+	last := "never"
+	if !h.lastSuccess.IsZero() {
+		last = h.lastSuccess.UTC().Format(time.RFC3339)
+	}
+	msg += ", last success " + last
+	if h.checked.IsZero() {
+		return msg + ", canary pending", false
+	}
+	if h.err != nil {
+		msg += ", failure: " + h.err.Error()
+	}
+	return msg, h.err == nil
+}
+
+// firstLine returns the first line of the version output of a binary
+func firstLine(out []byte) string {
+	return strings.TrimSpace(strings.SplitN(string(bytes.TrimSpace(out)), "\n", 2)[0])
+}
+
+// checkCanary checks that the canary render produced a pdf
+func checkCanary(out []byte) error {
+	if !bytes.HasPrefix(out, []byte("%PDF-")) {
+		return errCanaryOutput
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package htmlToPdf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vatsal278/html-pdf-service/internal/model"
+)
+
+func TestHealth(t *testing.T) {
+	start := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	type check struct {
+		after   time.Duration
+		err     error
+		wantMsg string
+		wantOk  bool
+	}
+	tests := []struct {
+		name    string
+		version string
+		checks  []check
+	}{
+		{
+			name:    "failure keeps the last success",
+			version: "wkhtmltopdf 0.12.6",
+			checks: []check{
+				{wantMsg: "wkhtmltopdf 0.12.6, last success 2026-10-19T10:00:00Z", wantOk: true},
+				{after: time.Minute, err: errors.New("exit status 1"), wantMsg: "wkhtmltopdf 0.12.6, last success 2026-10-19T10:00:00Z, failure: exit status 1"},
+				{after: 2 * time.Minute, wantMsg: "wkhtmltopdf 0.12.6, last success 2026-10-19T10:02:00Z", wantOk: true},
+			},
+		},
+		{
+			name: "binary not found",
+			checks: []check{
+				{err: errors.New("wkhtmltopdf not found"), wantMsg: "wkhtmltopdf, last success never, failure: wkhtmltopdf not found"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			var err error
+			h := newHealth(model.RendererWkhtmltopdf, model.HealthConfig{}, nil, func(ctx context.Context) (string, error) {
+				calls++
+				if err != nil {
+					return "", err
+				}
+				return tt.version, nil
+			})
+			msg, ok := h.status()
+			if ok || msg != "wkhtmltopdf, last success never, canary pending" {
+				t.Errorf("unexpected %v %q", ok, msg)
+			}
+			for _, c := range tt.checks {
+				h.now = func() time.Time { return start.Add(c.after) }
+				err = c.err
+				h.check()
+				// the status only reads the result of the last canary
+				for i := 0; i < 2; i++ {
+					msg, ok := h.status()
+					if msg != c.wantMsg || ok != c.wantOk {
+						t.Errorf("want %v %q got %v %q", c.wantOk, c.wantMsg, ok, msg)
+					}
+				}
+			}
+			if calls != len(tt.checks) {
+				t.Errorf("want %d canary renders got %d", len(tt.checks), calls)
+			}
+		})
+	}
+}
+
+func TestHealth_Timeout(t *testing.T) {
+	h := newHealth(model.RendererChromium, model.HealthConfig{CanaryTimeoutMs: 10}, nil, func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "Chromium 118", ctx.Err()
+	})
+	h.check()
+	msg, ok := h.status()
+	if ok || msg != "Chromium 118, last success never, failure: canary render timed out after 10ms" {
+		t.Errorf("unexpected %v %q", ok, msg)
+	}
+}
+
+func TestHealth_Scheduler(t *testing.T) {
+	s := NewScheduler(model.SchedulerConfig{Concurrency: 1, QueueSize: 1, QueueTimeout: 10})
+	calls := 0
+	h := newHealth(model.RendererWkhtmltopdf, model.HealthConfig{}, s, func(ctx context.Context) (string, error) {
+		calls++
+		if st := s.Stats(); st.Running != 1 {
+			t.Errorf("want the canary to run in a render slot got %+v", st)
+		}
+		return "wkhtmltopdf 0.12.6", nil
+	})
+	h.check()
+	if _, ok := h.status(); !ok || calls != 1 {
+		t.Fatalf("want a successful canary got %d renders", calls)
+	}
+	// a canary rejected by the busy scheduler keeps the previous result
+	b := &blockingRenderer{release: make(chan struct{})}
+	pdf := NewScheduledSvc(s, b)
+	done := make(chan error)
+	go func() {
+		done <- pdf.GeneratePdf(context.Background(), new(bytes.Buffer), nil)
+	}()
+	waitStats(t, s, 1, 0)
+	h.check()
+	close(b.release)
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+	msg, ok := h.status()
+	if !ok || calls != 1 || msg != "wkhtmltopdf 0.12.6, last success "+h.lastSuccess.UTC().Format(time.RFC3339) {
+		t.Errorf("unexpected %v %q after %d renders", ok, msg, calls)
+	}
+}
+
+func TestNewCanaryScheduler(t *testing.T) {
+	s := NewCanaryScheduler(model.HealthConfig{CanaryTimeoutMs: 1000})
+	release := make(chan struct{})
+	running := 0
+	probe := func(ctx context.Context) (string, error) {
+		running++
+		if running > 1 {
+			t.Errorf("want one canary at a time got %d", running)
+		}
+		<-release
+		running--
+		return "", nil
+	}
+	wk := newHealth(model.RendererWkhtmltopdf, model.HealthConfig{}, s, probe)
+	cr := newHealth(model.RendererChromium, model.HealthConfig{}, s, probe)
+	done := make(chan struct{})
+	go func() {
+		wk.check()
+		done <- struct{}{}
+	}()
+	waitStats(t, s, 1, 0)
+	go func() {
+		cr.check()
+		done <- struct{}{}
+	}()
+	waitStats(t, s, 1, 1)
+	release <- struct{}{}
+	<-done
+	release <- struct{}{}
+	<-done
+	for _, h := range []*health{wk, cr} {
+		if msg, ok := h.status(); !ok {
+			t.Errorf("want a successful canary got %q", msg)
+		}
+	}
+}
+
+func TestFirstLine(t *testing.T) {
+	got := firstLine([]byte("\nwkhtmltopdf 0.12.6 (with patched qt)\r\nmore\n"))
+	if got != "wkhtmltopdf 0.12.6 (with patched qt)" {
+		t.Errorf("unexpected %q", got)
+	}
+}
@@ -1,11 +1,32 @@
 package htmlToPdf
 
-import "io"
+import (
+	"context"
+	"io"
+)
 
-//go:generate mockgen --build_flags=--mod=mod --destination=./../../../pkg/mock/mock_htmltopdf.go --package=mock github.com/vatsal278/html-pdf-service/internal/repo/htmlToPdf HtmlToPdf
+//go:generate mockgen --build_flags=--mod=mod --destination=./../../../pkg/mock/mock_htmltopdf.go --package=mock github.com/vatsal278/html-pdf-service/internal/repo/htmlToPdf HtmlToPdf,Renderer
 
 type HtmlToPdf interface {
-	HealthCheck() bool
-	GeneratePdf(io.Writer, []byte) error
-	GetJsonFromHtml([]byte) ([]byte, error)
+	// HealthCheck returns a message describing the renderer and whether it can render documents
+	HealthCheck() (string, bool)
+	// GeneratePdf renders the document b into the writer, the render is stopped when ctx is done
+	GeneratePdf(context.Context, io.Writer, []byte) error
+	GetJsonFromHtml(...[]byte) ([]byte, error)
+}
+
+// Renderer renders the stored json document of a template into another output format
+type Renderer interface {
+	// HealthCheck returns a message describing the renderer and whether it can render documents
+	HealthCheck() (string, bool)
+	Render(context.Context, io.Writer, []byte, RenderOptions) error
+}
+
+// RenderOptions are the output options of a render request, zero values leave the renderer defaults
+type RenderOptions struct {
+	Format  string
+	Width   int
+	Quality int
+	// Page is the 1-based page with page data rendered as an image, the first when zero
+	Page int
 }
@@ -0,0 +1,749 @@
+package htmlToPdf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	// the decoders of the supported image formats
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/vatsal278/html-pdf-service/internal/pdf"
+)
+
+// layout sizes in points, the base font size is the 16px default of the browsers
+const (
+	ptPerPx        = 0.75
+	baseFontSize   = 12
+	lineSpacing    = 1.2
+	listIndent     = 18
+	cellPadding    = 4
+	borderWidth    = 0.5
+	descentPerSize = 0.2
+)
+
+// headingSizes are the font sizes of h1 to h6 relative to the base font size
+var headingSizes = []float64{2, 1.5, 1.17, 1, 0.83, 0.67}
+
+// skippedTags are not rendered, neither are their children
+var skippedTags = map[string]bool{
+	"head": true, "script": true, "style": true, "title": true, "meta": true, "link": true,
+	"noscript": true, "template": true, "svg": true, "iframe": true, "object": true,
+}
+
+// inlineTags are laid out within the line of the surrounding text, all other elements are blocks
+var inlineTags = map[string]bool{
+	"a": true, "abbr": true, "b": true, "bdi": true, "bdo": true, "cite": true, "code": true, "data": true,
+	"del": true, "dfn": true, "em": true, "font": true, "i": true, "ins": true, "kbd": true, "label": true,
+	"mark": true, "q": true, "s": true, "samp": true, "small": true, "span": true, "strong": true, "sub": true,
+	"sup": true, "time": true, "tt": true, "u": true, "var": true,
+}
+
+type textStyle struct {
+	size      float64
+	bold      bool
+	italic    bool
+	mono      bool
+	underline bool
+	pre       bool
+}
+
+func (s textStyle) font() *pdf.Font {
+	switch {
+	case s.mono && s.bold:
+		return pdf.CourierBold
+	case s.mono:
+		return pdf.Courier
+	case s.bold && s.italic:
+		return pdf.HelveticaBoldOblique
+	case s.bold:
+		return pdf.HelveticaBold
+	case s.italic:
+		return pdf.HelveticaOblique
+	}
+	return pdf.Helvetica
+}
+
+// inlineStyle returns the style of the children of an inline element
+func inlineStyle(tag string, st textStyle) textStyle {
+	switch tag {
+	case "b", "strong", "th":
+		st.bold = true
+	case "i", "em", "cite", "dfn", "var":
+		st.italic = true
+	case "code", "kbd", "samp", "tt":
+		st.mono = true
+	case "u", "ins", "a":
+		st.underline = true
+	case "small", "sub", "sup":
+		st.size *= 0.83
+	}
+	return st
+}
+
+// fragment is a word, an image or a forced line break of a line
+type fragment struct {
+	text      string
+	style     textStyle
+	space     bool
+	img       *layoutImage
+	width     float64
+	height    float64
+	lineBreak bool
+}
+
+func (f fragment) spaceWidth() float64 {
+	if !f.space {
+		return 0
+	}
+	return f.style.font().Width(" ", f.style.size)
+}
+
+type layoutImage struct {
+	index int
+}
+
+// line is a laid out line of fragments, the fragments after the first keep their preceding space
+type line struct {
+	frags  []fragment
+	width  float64
+	height float64
+	// descent is the distance of the text baseline from the bottom of the line
+	descent float64
+}
+
+// collector gathers the fragments of inline content, whitespace is collapsed like in html outside pre elements
+type collector struct {
+	frags []fragment
+	space bool
+}
+
+func (c *collector) text(s string, st textStyle) {
+	if st.pre {
+		for i, l := range strings.Split(s, "\n") {
+			if i > 0 {
+				c.lineBreak()
+			}
+			if l != "" {
+				c.add(fragment{text: strings.ReplaceAll(l, "\t", "    "), style: st})
+			}
+		}
+		return
+	}
+	if s == "" {
+		return
+	}
+	words := strings.Fields(s)
+	if isSpace(s[0]) {
+		c.space = true
+	}
+	for _, w := range words {
+		c.add(fragment{text: w, style: st, space: c.space})
+		c.space = true
+	}
+	c.space = isSpace(s[len(s)-1])
+}
+
+func (c *collector) add(f fragment) {
+	if len(c.frags) == 0 || c.frags[len(c.frags)-1].lineBreak {
+		f.space = false
+	}
+	if f.img == nil && !f.lineBreak {
+		f.width = f.style.font().Width(f.text, f.style.size)
+		f.height = f.style.size * lineSpacing
+	}
+	c.frags = append(c.frags, f)
+	c.space = false
+}
+
+func (c *collector) lineBreak() {
+	c.frags = append(c.frags, fragment{lineBreak: true})
+	c.space = false
+}
+
+// take returns the collected fragments and starts over
+func (c *collector) take() []fragment {
+	frags := c.frags
+	c.frags, c.space = nil, false
+	return frags
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
+
+// wrap breaks the fragments into lines no wider than width, a word wider than the line is split
+func wrap(frags []fragment, width float64) []line {
+	var lines []line
+	var cur line
+	end := func() {
+		if len(cur.frags) > 0 || cur.height > 0 {
+			lines = append(lines, cur)
+		}
+		cur = line{}
+	}
+	for _, f := range frags {
+		if f.lineBreak {
+			if len(cur.frags) == 0 {
+				cur.height = baseFontSize * lineSpacing
+				if len(lines) > 0 {
+					cur.height = lines[len(lines)-1].height
+				}
+			}
+			end()
+			continue
+		}
+		if len(cur.frags) == 0 {
+			f.space = false
+		}
+		if len(cur.frags) > 0 && cur.width+f.spaceWidth()+f.width > width {
+			end()
+			f.space = false
+		}
+		for f.img == nil && f.width > width && len(cur.frags) == 0 {
+			head, rest := splitWord(f, width)
+			if rest.text == "" {
+				break
+			}
+			cur.add(head)
+			end()
+			f = rest
+		}
+		cur.add(f)
+	}
+	end()
+	return lines
+}
+
+func (l *line) add(f fragment) {
+	l.width += f.spaceWidth() + f.width
+	l.frags = append(l.frags, f)
+	if f.height > l.height {
+		l.height = f.height
+	}
+	if f.img == nil {
+		if d := f.style.size * descentPerSize; d > l.descent {
+			l.descent = d
+		}
+	}
+}
+
+// splitWord splits the word of f after the last character fitting into width, at least one character is kept
+func splitWord(f fragment, width float64) (fragment, fragment) {
+	runes := []rune(f.text)
+	font := f.style.font()
+	n := 1
+	for n < len(runes) && font.Width(string(runes[:n+1]), f.style.size) <= width {
+		n++
+	}
+	head, rest := f, f
+	head.text, rest.text = string(runes[:n]), string(runes[n:])
+	head.width = font.Width(head.text, f.style.size)
+	rest.width = font.Width(rest.text, f.style.size)
+	rest.space = false
+	return head, rest
+}
+
+// box is the horizontal extent of a block
+type box struct {
+	left  float64
+	width float64
+	align string
+}
+
+// layoutPage is a laid out page, the content stream is written when the document is complete
+type layoutPage struct {
+	content bytes.Buffer
+}
+
+// heading is a heading of the document, the entries of the outline and the sections of headers and footers
+type heading struct {
+	text  string
+	level int
+	page  int
+	top   float64
+	// outline is false for headings of pages excluded from the outline
+	outline bool
+}
+
+// flow lays out the html of the document pages onto pages of the given size
+type flow struct {
+	width, height            float64
+	top, right, bottom, left float64
+	pages                    []*layoutPage
+	page                     *layoutPage
+	y                        float64
+	headings                 []heading
+	images                   []image.Image
+	title                    string
+	// zoom scales the fonts and images of the current document page, minFontSize is the smallest font size
+	zoom        float64
+	minFontSize float64
+	outline     bool
+	collector
+}
+
+func (f *flow) contentBox() box {
+	return box{left: f.left, width: f.width - f.left - f.right}
+}
+
+func (f *flow) newPage() {
+	f.page = &layoutPage{}
+	f.pages = append(f.pages, f.page)
+	f.y = f.top
+}
+
+// render lays out a document page starting on a new page
+func (f *flow) render(doc []byte, zoom float64, minFontSize float64, outline bool) error {
+	root, err := html.Parse(bytes.NewReader(doc))
+	if err != nil {
+		return err
+	}
+	if f.title == "" {
+		f.title = strings.TrimSpace(textContent(find(root, "title")))
+	}
+	f.zoom, f.minFontSize, f.outline = zoom, minFontSize, outline
+	if f.zoom <= 0 {
+		f.zoom = 1
+	}
+	f.newPage()
+	st := f.style(textStyle{size: baseFontSize})
+	f.node(root, st, f.contentBox())
+	f.flush(f.contentBox())
+	return nil
+}
+
+func (f *flow) style(st textStyle) textStyle {
+	st.size *= f.zoom
+	if st.size < f.minFontSize {
+		st.size = f.minFontSize
+	}
+	return st
+}
+
+func (f *flow) node(n *html.Node, st textStyle, b box) {
+	switch n.Type {
+	case html.DocumentNode:
+		f.children(n, st, b)
+	case html.TextNode:
+		f.text(n.Data, st)
+	case html.ElementNode:
+		f.element(n, st, b)
+	}
+}
+
+func (f *flow) children(n *html.Node, st textStyle, b box) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		f.node(c, st, b)
+	}
+}
+
+func (f *flow) element(n *html.Node, st textStyle, b box) {
+	tag := n.Data
+	if skippedTags[tag] {
+		return
+	}
+	style := strings.ToLower(strings.ReplaceAll(attr(n, "style"), " ", ""))
+	if strings.Contains(style, "display:none") {
+		return
+	}
+	switch {
+	case tag == "br":
+		f.lineBreak()
+		return
+	case tag == "img":
+		f.image(n)
+		return
+	case inlineTags[tag]:
+		f.children(n, inlineStyle(tag, st), b)
+		return
+	}
+	f.flush(b)
+	if strings.Contains(style, "page-break-before:always") || strings.Contains(style, "break-before:page") {
+		f.pageBreak()
+	}
+	if a := align(n, style); a != "" {
+		b.align = a
+	}
+	switch tag {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level := int(tag[1] - '0')
+		st.size = f.style(textStyle{size: baseFontSize * headingSizes[level-1]}).size
+		st.bold = true
+		f.gap(st.size * 0.67)
+		f.children(n, st, b)
+		f.heading(level, st)
+		f.flush(b)
+		f.gap(st.size * 0.67)
+	case "p", "blockquote", "pre", "figure", "dl":
+		if tag == "pre" {
+			st.pre, st.mono = true, true
+		}
+		if tag == "blockquote" || tag == "figure" {
+			b.left, b.width = b.left+listIndent*2, b.width-listIndent*2
+		}
+		f.gap(st.size * 0.5)
+		f.children(n, st, b)
+		f.flush(b)
+		f.gap(st.size * 0.5)
+	case "ul", "ol":
+		f.gap(st.size * 0.5)
+		f.list(n, st, b)
+		f.gap(st.size * 0.5)
+	case "table":
+		f.gap(st.size * 0.5)
+		f.table(n, st, b)
+		f.gap(st.size * 0.5)
+	case "hr":
+		f.gap(st.size * 0.5)
+		f.ensure(borderWidth)
+		fmt.Fprintf(&f.page.content, "%s w %s %s m %s %s l S\n", pdf.Number(borderWidth),
+			pdf.Number(b.left), pdf.Number(f.height-f.y), pdf.Number(b.left+b.width), pdf.Number(f.height-f.y))
+		f.gap(st.size * 0.5)
+	case "th":
+		f.children(n, inlineStyle(tag, st), b)
+	default:
+		f.children(n, st, b)
+	}
+	f.flush(b)
+	if strings.Contains(style, "page-break-after:always") || strings.Contains(style, "break-after:page") {
+		f.pageBreak()
+	}
+}
+
+// heading records the collected text as a heading of the current page before it is flushed
+func (f *flow) heading(level int, st textStyle) {
+	words := make([]string, 0, len(f.frags))
+	for _, fr := range f.frags {
+		if fr.text != "" {
+			words = append(words, fr.text)
+		}
+	}
+	if len(words) == 0 {
+		return
+	}
+	// the heading moves to the next page with its first line
+	if f.y+st.size*lineSpacing > f.height-f.bottom && f.y > f.top {
+		f.newPage()
+	}
+	f.headings = append(f.headings, heading{
+		text:    strings.Join(words, " "),
+		level:   level,
+		page:    len(f.pages) - 1,
+		top:     f.height - f.y,
+		outline: f.outline,
+	})
+}
+
+func (f *flow) list(n *html.Node, st textStyle, b box) {
+	item := 0
+	inner := box{left: b.left + listIndent, width: b.width - listIndent, align: b.align}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		if c.Data != "li" {
+			f.node(c, st, inner)
+			continue
+		}
+		item++
+		marker := "•"
+		if n.Data == "ol" {
+			marker = strconv.Itoa(item) + "."
+		}
+		f.flush(inner)
+		f.add(fragment{text: marker, style: textStyle{size: st.size}})
+		f.collector.space = true
+		f.children(c, st, inner)
+		f.flush(inner)
+	}
+}
+
+// table lays out the rows of a table with equally wide columns, every cell holds its content as inline text
+func (f *flow) table(n *html.Node, st textStyle, b box) {
+	var rows [][]*html.Node
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.Data {
+			case "thead", "tbody", "tfoot":
+				walk(c)
+			case "tr":
+				var cells []*html.Node
+				for td := c.FirstChild; td != nil; td = td.NextSibling {
+					if td.Type == html.ElementNode && (td.Data == "td" || td.Data == "th") {
+						cells = append(cells, td)
+					}
+				}
+				rows = append(rows, cells)
+			}
+		}
+	}
+	walk(n)
+	cols := 0
+	for _, r := range rows {
+		if len(r) > cols {
+			cols = len(r)
+		}
+	}
+	if cols == 0 {
+		return
+	}
+	colWidth := b.width / float64(cols)
+	for _, r := range rows {
+		cells := make([][]line, len(r))
+		aligns := make([]string, len(r))
+		height := 0.0
+		for i, td := range r {
+			cst := st
+			aligns[i] = align(td, strings.ToLower(strings.ReplaceAll(attr(td, "style"), " ", "")))
+			if td.Data == "th" {
+				cst.bold = true
+				if aligns[i] == "" {
+					aligns[i] = "center"
+				}
+			}
+			cells[i] = wrap(f.inline(td, cst), colWidth-2*cellPadding)
+			h := 2.0 * cellPadding
+			for _, l := range cells[i] {
+				h += l.height
+			}
+			if h > height {
+				height = h
+			}
+		}
+		f.ensure(height)
+		for i := 0; i < cols; i++ {
+			x := b.left + float64(i)*colWidth
+			fmt.Fprintf(&f.page.content, "%s w %s %s %s %s re S\n", pdf.Number(borderWidth),
+				pdf.Number(x), pdf.Number(f.height-f.y-height), pdf.Number(colWidth), pdf.Number(height))
+			if i >= len(cells) {
+				continue
+			}
+			y := f.y + cellPadding
+			for _, l := range cells[i] {
+				f.drawLine(l, box{left: x + cellPadding, width: colWidth - 2*cellPadding, align: aligns[i]}, y)
+				y += l.height
+			}
+		}
+		f.y += height
+	}
+}
+
+// inline returns the content of n as fragments, nested blocks start on a new line
+func (f *flow) inline(n *html.Node, st textStyle) []fragment {
+	c := &collector{}
+	var walk func(n *html.Node, st textStyle)
+	walk = func(n *html.Node, st textStyle) {
+		for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+			switch ch.Type {
+			case html.TextNode:
+				c.text(ch.Data, st)
+			case html.ElementNode:
+				switch {
+				case skippedTags[ch.Data]:
+				case ch.Data == "br":
+					c.lineBreak()
+				case ch.Data == "img":
+					if fr, ok := f.imageFragment(ch); ok {
+						c.add(fr)
+					}
+				case inlineTags[ch.Data]:
+					walk(ch, inlineStyle(ch.Data, st))
+				default:
+					if len(c.frags) > 0 {
+						c.lineBreak()
+					}
+					walk(ch, inlineStyle(ch.Data, st))
+				}
+			}
+		}
+	}
+	walk(n, st)
+	return c.frags
+}
+
+func (f *flow) image(n *html.Node) {
+	fr, ok := f.imageFragment(n)
+	if !ok {
+		if alt := attr(n, "alt"); alt != "" {
+			f.text(alt, f.style(textStyle{size: baseFontSize}))
+		}
+		return
+	}
+	fr.space = f.collector.space
+	f.add(fr)
+}
+
+// imageFragment decodes an image inlined as a data url, the size follows the width and height attributes in pixels
+// and shrinks to fit onto the page
+func (f *flow) imageFragment(n *html.Node) (fragment, bool) {
+	src := attr(n, "src")
+	if !strings.HasPrefix(src, "data:") {
+		return fragment{}, false
+	}
+	i := strings.Index(src, ",")
+	if i < 0 || !strings.HasSuffix(src[:i], ";base64") {
+		return fragment{}, false
+	}
+	data, err := base64.StdEncoding.DecodeString(src[i+1:])
+	if err != nil {
+		return fragment{}, false
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fragment{}, false
+	}
+	w, h := float64(img.Bounds().Dx()), float64(img.Bounds().Dy())
+	aw, errW := strconv.ParseFloat(strings.TrimSuffix(attr(n, "width"), "px"), 64)
+	ah, errH := strconv.ParseFloat(strings.TrimSuffix(attr(n, "height"), "px"), 64)
+	switch {
+	case errW == nil && errH == nil:
+		w, h = aw, ah
+	case errW == nil && w > 0:
+		w, h = aw, h*aw/w
+	case errH == nil && h > 0:
+		w, h = w*ah/h, ah
+	}
+	w, h = w*ptPerPx*f.zoom, h*ptPerPx*f.zoom
+	maxW, maxH := f.width-f.left-f.right, f.height-f.top-f.bottom
+	if w > maxW {
+		w, h = maxW, h*maxW/w
+	}
+	if h > maxH {
+		w, h = w*maxH/h, maxH
+	}
+	if w <= 0 || h <= 0 {
+		return fragment{}, false
+	}
+	f.images = append(f.images, img)
+	return fragment{img: &layoutImage{index: len(f.images) - 1}, width: w, height: h}, true
+}
+
+// flush lays out the collected fragments as lines of the block
+func (f *flow) flush(b box) {
+	frags := f.take()
+	if len(frags) == 0 {
+		return
+	}
+	for _, l := range wrap(frags, b.width) {
+		f.ensure(l.height)
+		f.drawLine(l, b, f.y)
+		f.y += l.height
+	}
+}
+
+// ensure starts a new page when height doesn't fit onto the current page
+func (f *flow) ensure(height float64) {
+	if f.y+height > f.height-f.bottom && f.y > f.top {
+		f.newPage()
+	}
+}
+
+// gap adds vertical space unless the block starts at the top of a page
+func (f *flow) gap(h float64) {
+	if f.y > f.top {
+		f.y += h
+	}
+}
+
+func (f *flow) pageBreak() {
+	if f.y > f.top {
+		f.newPage()
+	}
+}
+
+// drawLine writes the fragments of l into the content of the current page, top is the distance of the line from
+// the top of the page
+func (f *flow) drawLine(l line, b box, top float64) {
+	drawLine(&f.page.content, l, b, f.height-top-l.height)
+}
+
+// drawLine writes the fragments of l with the bottom of the line at bottom
+func drawLine(w *bytes.Buffer, l line, b box, bottom float64) {
+	x := b.left
+	switch b.align {
+	case "center":
+		x += (b.width - l.width) / 2
+	case "right":
+		x += b.width - l.width
+	}
+	baseline := bottom + l.descent
+	for _, fr := range l.frags {
+		x += fr.spaceWidth()
+		if fr.img != nil {
+			fmt.Fprintf(w, "q %s 0 0 %s %s %s cm /Im%d Do Q\n", pdf.Number(fr.width), pdf.Number(fr.height),
+				pdf.Number(x), pdf.Number(baseline), fr.img.index)
+			x += fr.width
+			continue
+		}
+		font := fr.style.font()
+		fmt.Fprintf(w, "BT /%s %s Tf %s %s Td %s Tj ET\n", font.Key, pdf.Number(fr.style.size),
+			pdf.Number(x), pdf.Number(baseline), pdf.String(fr.text))
+		if fr.style.underline {
+			fmt.Fprintf(w, "%s w %s %s m %s %s l S\n", pdf.Number(fr.style.size/18), pdf.Number(x),
+				pdf.Number(baseline-fr.style.size/10), pdf.Number(x+fr.width), pdf.Number(baseline-fr.style.size/10))
+		}
+		x += fr.width
+	}
+}
+
+// align returns the text alignment of the align attribute or the text-align style of an element
+func align(n *html.Node, style string) string {
+	a := strings.ToLower(attr(n, "align"))
+	if i := strings.Index(style, "text-align:"); i >= 0 {
+		a = strings.SplitN(style[i+len("text-align:"):], ";", 2)[0]
+	}
+	switch a {
+	case "center", "right":
+		return a
+	case "left":
+		return "left"
+	}
+	return ""
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// find returns the first element named tag in the tree of n
+func find(n *html.Node, tag string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == tag {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := find(c, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func textContent(n *html.Node) string {
+	if n == nil {
+		return ""
+	}
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	sb := new(strings.Builder)
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sb.WriteString(textContent(c))
+	}
+	return sb.String()
+}
@@ -0,0 +1,142 @@
+package htmlToPdf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/PereRohit/util/testutil"
+
+	"github.com/vatsal278/html-pdf-service/internal/model"
+)
+
+func TestCollector(t *testing.T) {
+	c := &collector{}
+	st := textStyle{size: 10}
+	c.text("  Hello ", st)
+	c.text("big", textStyle{size: 10, bold: true})
+	c.text("gest\n world ", st)
+	c.lineBreak()
+	c.text(" next", st)
+	var got []string
+	for _, f := range c.take() {
+		switch {
+		case f.lineBreak:
+			got = append(got, "<br>")
+		case f.space:
+			got = append(got, " "+f.text)
+		default:
+			got = append(got, f.text)
+		}
+	}
+	diff := testutil.Diff(got, []string{"Hello", " big", "gest", " world", "<br>", "next"})
+	if diff != "" {
+		t.Error(diff)
+	}
+	if c.frags != nil {
+		t.Error("take keeps the fragments")
+	}
+}
+
+func TestCollector_Pre(t *testing.T) {
+	c := &collector{}
+	c.text("a  b\n\tc", textStyle{size: 10, pre: true})
+	frags := c.take()
+	if len(frags) != 3 || frags[0].text != "a  b" || !frags[1].lineBreak || frags[2].text != "    c" {
+		t.Errorf("unexpected fragments %+v", frags)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	c := &collector{}
+	c.text("aaa bbb ccc", textStyle{size: 10})
+	// a word of three a is 16.68 wide, a space 2.78
+	tests := []struct {
+		name  string
+		width float64
+		want  []string
+	}{
+		{name: "single line", width: 100, want: []string{"aaa bbb ccc"}},
+		{name: "two words per line", width: 40, want: []string{"aaa bbb", "ccc"}},
+		{name: "split words", width: 12, want: []string{"aa", "a", "bb", "b", "cc", "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, l := range wrap(c.frags, tt.width) {
+				sb := new(strings.Builder)
+				for _, f := range l.frags {
+					if f.space {
+						sb.WriteString(" ")
+					}
+					sb.WriteString(f.text)
+				}
+				got = append(got, sb.String())
+				if l.height != 12 {
+					t.Errorf("want line height 12 got %v", l.height)
+				}
+			}
+			diff := testutil.Diff(got, tt.want)
+			if diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestFlow_Render(t *testing.T) {
+	f := newFlow(model.PageSetup{PageWidth: 100, PageHeight: 100})
+	err := f.render([]byte(`<html><head><title>Doc</title><style>p { color: red }</style></head><body>
+<h1>Title</h1><p style="text-align: right">x</p><script>document.write("no")</script>
+<ul><li>one</li></ul><ol><li>two</li></ol><table><tr><th>A</th></tr><tr><td>b</td></tr></table>
+<p style="page-break-before: always"><b>y</b><img alt="logo" src="logo.png"></p></body></html>`), 0, 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.pages) != 2 || f.title != "Doc" {
+		t.Fatalf("want 2 pages of Doc got %d of %s", len(f.pages), f.title)
+	}
+	first, second := f.pages[0].content.String(), f.pages[1].content.String()
+	for _, want := range []string{"/F2 24 Tf", "(Title) Tj", "(x) Tj", "(\\225) Tj", "(one) Tj", "(1.) Tj", "(two) Tj", "re S", "(A) Tj", "(b) Tj"} {
+		if !strings.Contains(first, want) {
+			t.Errorf("first page lacks %s:\n%s", want, first)
+		}
+	}
+	if strings.Contains(first, "no") || strings.Contains(first, "red") {
+		t.Errorf("script or style rendered:\n%s", first)
+	}
+	if !strings.Contains(second, "/F2 12 Tf") || !strings.Contains(second, "(y) Tj") || !strings.Contains(second, "(logo) Tj") {
+		t.Errorf("unexpected second page:\n%s", second)
+	}
+	diff := testutil.Diff(f.headings, []heading{{text: "Title", level: 1, page: 0, top: f.height - f.top, outline: true}})
+	if diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestFlow_RenderImage(t *testing.T) {
+	buf := new(bytes.Buffer)
+	err := png.Encode(buf, image.NewGray(image.Rect(0, 0, 40, 20)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+	f := newFlow(model.PageSetup{})
+	err = f.render([]byte(`<img src="`+src+`"><img width="80" src="`+src+`"><img src="`+src+`" width="4000">`), 2, 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := f.pages[0].content.String()
+	// 40x20 pixels are 30x15 points, zoom doubles them and the last image shrinks to the content width
+	for _, want := range []string{"q 60 0 0 30 ", "/Im0 Do Q", "q 120 0 0 60 ", "/Im1 Do Q", "q 538.583 0 0 269.291 ", "/Im2 Do Q"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("missing %s in\n%s", want, content)
+		}
+	}
+	if len(f.images) != 3 {
+		t.Errorf("want 3 images got %d", len(f.images))
+	}
+}
@@ -0,0 +1,207 @@
+package htmlToPdf
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"github.com/vatsal278/html-pdf-service/internal/model"
+	"github.com/vatsal278/html-pdf-service/internal/pdf"
+)
+
+// defaultOutlineDepth is the number of heading levels in the outline when the template doesn't set a depth
+const defaultOutlineDepth = 4
+
+// pdfProducer is the producer recorded in the document information of the native renderer
+const pdfProducer = "html-pdf-service"
+
+type native struct {
+	now func() time.Time
+}
+
+// NewNativeSvc returns a HtmlToPdf laying out a subset of html, text, headings, lists, tables and inlined images,
+// in Go without external processes. It reads the same document as the wkhtmltopdf renderer.
+func NewNativeSvc() HtmlToPdf {
+	return &native{now: time.Now}
+}
+
+// HealthCheck is always true without a message, the native renderer has no dependencies
+func (n native) HealthCheck() (string, bool) {
+	return "", true
+}
+
+// GetJsonFromHtml returns the same document as the wkhtmltopdf renderer so that templates can switch renderers
+func (n native) GetJsonFromHtml(pages ...[]byte) ([]byte, error) {
+	return wkHtmlToPdf{}.GetJsonFromHtml(pages...)
+}
+
+// GeneratePdf lays out the document, a canceled ctx stops the layout between pages
+func (n native) GeneratePdf(ctx context.Context, wr io.Writer, b []byte) error {
+	settings, err := docSettings(b)
+	if err != nil {
+		return err
+	}
+	if settings.Toc != nil && settings.Toc.Enabled {
+		return errTocUnsupported
+	}
+	pages, err := documentPages(b, settings.PageSetup)
+	if err != nil {
+		return err
+	}
+	f := newFlow(settings.PageSetup)
+	if settings.Cover != nil {
+		err = f.render(settings.Cover, 0, 0, false)
+		if err != nil {
+			return err
+		}
+	}
+	for _, p := range pages {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		err = f.render(p.html, p.settings.Zoom, float64(p.settings.MinimumFontSize), !p.settings.ExcludeFromOutline)
+		if err != nil {
+			return err
+		}
+	}
+	now := n.now()
+	for i, p := range f.pages {
+		for _, hf := range []struct {
+			hf     *model.HeaderFooter
+			header bool
+		}{{settings.Header, true}, {settings.Footer, false}} {
+			if hf.hf == nil || hf.hf.SkipFirstPage && i == 0 {
+				continue
+			}
+			err = f.running(&p.content, *hf.hf, hf.header, i, now)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return writePdf(wr, f, settings)
+}
+
+func newFlow(s model.PageSetup) *flow {
+	size := paperSize(s)
+	if strings.ToLower(s.Orientation) == model.OrientationLandscape {
+		size[0], size[1] = size[1], size[0]
+	}
+	pt := 72 / mmPerInch
+	return &flow{
+		width:  size[0] * pt,
+		height: size[1] * pt,
+		top:    marginSize(s.MarginTop) * pt,
+		right:  marginSize(s.MarginRight) * pt,
+		bottom: marginSize(s.MarginBottom) * pt,
+		left:   marginSize(s.MarginLeft) * pt,
+	}
+}
+
+// running draws a header above or a footer below the content of page i, the variables are replaced before the
+// html is laid out
+func (f *flow) running(w *bytes.Buffer, hf model.HeaderFooter, header bool, i int, now time.Time) error {
+	section, subsection := "", ""
+	for _, h := range f.headings {
+		if h.page > i {
+			break
+		}
+		switch h.level {
+		case 1:
+			section, subsection = h.text, ""
+		case 2:
+			subsection = h.text
+		}
+	}
+	src := strings.NewReplacer(
+		"[page]", strconv.Itoa(i+1),
+		"[frompage]", "1",
+		"[topage]", strconv.Itoa(len(f.pages)),
+		"[section]", html.EscapeString(section),
+		"[subsection]", html.EscapeString(subsection),
+		"[title]", html.EscapeString(f.title),
+		"[doctitle]", html.EscapeString(f.title),
+		"[date]", now.Format("02/01/2006"),
+		"[isodate]", now.Format("2006-01-02"),
+		"[time]", now.Format("15:04:05"),
+	).Replace(string(hf.Html))
+	root, err := html.Parse(strings.NewReader(src))
+	if err != nil {
+		return err
+	}
+	b := f.contentBox()
+	if a := align(find(root, "body"), strings.ToLower(strings.ReplaceAll(attr(find(root, "body"), "style"), " ", ""))); a != "" {
+		b.align = a
+	}
+	lines := wrap(f.inline(root, textStyle{size: baseFontSize * 0.75}), b.width)
+	height := 0.0
+	for _, l := range lines {
+		height += l.height
+	}
+	spacing := hf.Spacing * 72 / mmPerInch
+	// the header ends spacing above the content, the footer starts spacing below it
+	top := f.top - spacing - height
+	if !header {
+		top = f.height - f.bottom + spacing
+	}
+	for _, l := range lines {
+		drawLine(w, l, b, f.height-top-l.height)
+		top += l.height
+	}
+	return nil
+}
+
+// writePdf writes the laid out pages with the fonts and images they use and the outline of the headings
+func writePdf(wr io.Writer, f *flow, s renderSettings) error {
+	if len(f.pages) == 0 {
+		f.newPage()
+	}
+	w := pdf.NewWriter()
+	fonts := new(strings.Builder)
+	for _, font := range []*pdf.Font{pdf.Helvetica, pdf.HelveticaBold, pdf.HelveticaOblique, pdf.HelveticaBoldOblique, pdf.Courier, pdf.CourierBold} {
+		fmt.Fprintf(fonts, " /%s %s", font.Key, w.Add(font.Dict()))
+	}
+	images := new(strings.Builder)
+	for i, img := range f.images {
+		fmt.Fprintf(images, " /Im%d %s", i, w.AddImage(img, s.PageSetup.Grayscale))
+	}
+	resources := w.Add(fmt.Sprintf("<< /Font <<%s >> /XObject <<%s >> >>", fonts, images))
+	pagesRef := w.Reserve()
+	refs := make([]pdf.Ref, len(f.pages))
+	kids := make([]string, len(f.pages))
+	for i, p := range f.pages {
+		content := w.AddStream("", p.content.Bytes())
+		refs[i] = w.Add(fmt.Sprintf("<< /Type /Page /Parent %s /MediaBox [0 0 %s %s] /Resources %s /Contents %s >>",
+			pagesRef, pdf.Number(f.width), pdf.Number(f.height), resources, content))
+		kids[i] = refs[i].String()
+	}
+	w.Set(pagesRef, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(refs)))
+	catalog := fmt.Sprintf("<< /Type /Catalog /Pages %s", pagesRef)
+	if s.Outline == nil || !s.Outline.Disabled {
+		depth := defaultOutlineDepth
+		if s.Outline != nil && s.Outline.Depth > 0 {
+			depth = int(s.Outline.Depth)
+		}
+		var items []pdf.OutlineItem
+		for _, h := range f.headings {
+			if h.outline && h.level <= depth {
+				items = append(items, pdf.OutlineItem{Title: h.text, Level: h.level, Page: refs[h.page], Top: h.top})
+			}
+		}
+		if outline := w.AddOutline(items); outline > 0 {
+			catalog += fmt.Sprintf(" /Outlines %s /PageMode /UseOutlines", outline)
+		}
+	}
+	catalog += " >>"
+	info := "<< /Producer " + pdf.TextString(pdfProducer)
+	if f.title != "" {
+		info += " /Title " + pdf.TextString(f.title)
+	}
+	return w.WriteTo(wr, w.Add(catalog), w.Add(info+" >>"))
+}
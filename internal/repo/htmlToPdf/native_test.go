@@ -0,0 +1,154 @@
+package htmlToPdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pdfContent returns the inflated streams of a pdf written by the native renderer
+func pdfContent(t *testing.T, b []byte) string {
+	t.Helper()
+	sb := new(strings.Builder)
+	for _, m := range regexp.MustCompile(`(?s)stream\n(.*?)\nendstream`).FindAllSubmatch(b, -1) {
+		r, err := zlib.NewReader(bytes.NewReader(m[1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sb.Write(data)
+	}
+	return sb.String()
+}
+
+func nativeDoc(t *testing.T, settings string, pages ...string) []byte {
+	t.Helper()
+	var in [][]byte
+	for _, p := range pages {
+		in = append(in, []byte(p))
+	}
+	doc, err := NewNativeSvc().GetJsonFromHtml(in...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.Replace(doc, []byte(`"Pages"`), []byte(`"Settings":`+settings+`,"Pages"`), 1)
+}
+
+func TestNative_GeneratePdf(t *testing.T) {
+	footer := base64.StdEncoding.EncodeToString([]byte(`<p>[section] [page]/[topage] [date]</p>`))
+	tests := []struct {
+		name     string
+		settings string
+		pages    []string
+		want     []string
+		dontWant []string
+	}{
+		{
+			name:     "pages, footer and outline",
+			settings: `{"footer":{"html":"` + footer + `"}}`,
+			pages: []string{
+				`<html><head><title>Doc</title></head><body><h1>One</h1><h2>Sub</h2></body></html>`,
+				`<html><body><h1>Two</h1></body></html>`,
+			},
+			want: []string{"/Type /Pages", "/Count 2 >>", "/Title (Doc)", "/Outlines", "/Title (One)", "/Title (Sub)", "/Title (Two)",
+				"(1/2) Tj", "(2/2) Tj", "(19/10/2026) Tj"},
+		},
+		{
+			name:     "outline disabled",
+			settings: `{"outline":{"disabled":true},"page_setup":{"orientation":"Landscape"}}`,
+			pages:    []string{`<h1>One</h1>`},
+			want:     []string{"/Count 1 >>", "/MediaBox [0 0 841.89 595.276]"},
+			dontWant: []string{"/Outlines", "/Title (One)"},
+		},
+		{
+			name:     "outline depth",
+			settings: `{"outline":{"depth":1}}`,
+			pages:    []string{`<h1>One</h1><h2>Sub</h2>`},
+			want:     []string{"/Title (One)"},
+			dontWant: []string{"/Title (Sub)"},
+		},
+		{
+			name:     "cover",
+			settings: `{"cover":"` + base64.StdEncoding.EncodeToString([]byte(`<h1>Cover</h1>`)) + `"}`,
+			pages:    []string{`<p style="page-break-before: always">x</p>`},
+			want:     []string{"/Count 2 >>", "(Cover) Tj"},
+			// like wkhtmltopdf the cover isn't part of the outline
+			dontWant: []string{"/Title (Cover)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &native{now: func() time.Time { return time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC) }}
+			doc := nativeDoc(t, tt.settings, tt.pages...)
+			out := new(bytes.Buffer)
+			err := svc.GeneratePdf(context.Background(), out, doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(out.Bytes(), []byte("%PDF-1.7")) || !bytes.HasSuffix(out.Bytes(), []byte("%%EOF\n")) {
+				t.Fatalf("want a pdf got %q", out.Bytes())
+			}
+			again := new(bytes.Buffer)
+			err = svc.GeneratePdf(context.Background(), again, doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out.Bytes(), again.Bytes()) {
+				t.Error("the same document rendered different files")
+			}
+			got := out.String() + pdfContent(t, out.Bytes())
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("missing %s", w)
+				}
+			}
+			for _, w := range tt.dontWant {
+				if strings.Contains(got, w) {
+					t.Errorf("unexpected %s", w)
+				}
+			}
+		})
+	}
+}
+
+func TestNative_GeneratePdf_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  []byte
+		want error
+	}{
+		{
+			name: "toc",
+			doc:  []byte(`{"Settings":{"toc":{"enabled":true}},"Pages":[{"Base64PageData":"PHA+MTwvcD4="}]}`),
+			want: errTocUnsupported,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewNativeSvc().GeneratePdf(context.Background(), new(bytes.Buffer), tt.doc)
+			if err != tt.want {
+				t.Errorf("want %v got %v", tt.want, err)
+			}
+		})
+	}
+	err := NewNativeSvc().GeneratePdf(context.Background(), new(bytes.Buffer), []byte(`{`))
+	if err == nil {
+		t.Error("want an error for an invalid document")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = NewNativeSvc().GeneratePdf(ctx, new(bytes.Buffer), []byte(`{"Pages":[{"Base64PageData":"PHA+MTwvcD4="}]}`))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("want %v got %v", context.Canceled, err)
+	}
+}
@@ -0,0 +1,38 @@
+package htmlToPdf
+
+import "github.com/vatsal278/html-pdf-service/internal/model"
+
+// millimeters per inch, pdf points are 1/72 inch and the devtools protocol takes sizes in inches
+const mmPerInch = 25.4
+
+// defaultMargin is the margin in millimeters wkhtmltopdf uses when the page setup leaves it unset
+const defaultMargin = 10
+
+// paperDimensions are the width and height in millimeters of the named paper sizes in portrait orientation
+var paperDimensions = map[string][2]float64{
+	"A0": {841, 1189}, "A1": {594, 841}, "A2": {420, 594}, "A3": {297, 420}, "A4": {210, 297},
+	"A5": {148, 210}, "A6": {105, 148}, "A7": {74, 105}, "A8": {52, 74}, "A9": {37, 52},
+	"B0": {1000, 1414}, "B1": {707, 1000}, "B2": {500, 707}, "B3": {353, 500}, "B4": {250, 353},
+	"B5": {176, 250}, "B6": {125, 176}, "B7": {88, 125}, "B8": {62, 88}, "B9": {44, 62}, "B10": {31, 44},
+	"C5E": {163, 229}, "Comm10E": {105, 241}, "DLE": {110, 220}, "Executive": {190.5, 254}, "Folio": {210, 330},
+	"Ledger": {431.8, 279.4}, "Legal": {215.9, 355.6}, "Letter": {215.9, 279.4}, "Tabloid": {279.4, 431.8},
+}
+
+// paperSize returns the width and height in millimeters of the page setup in portrait orientation, A4 when unset
+func paperSize(s model.PageSetup) [2]float64 {
+	if s.PageWidth > 0 && s.PageHeight > 0 {
+		return [2]float64{float64(s.PageWidth), float64(s.PageHeight)}
+	}
+	if name, ok := model.PaperSize(s.PageSize); ok {
+		return paperDimensions[name]
+	}
+	return paperDimensions["A4"]
+}
+
+// marginSize returns a margin of the page setup in millimeters, the wkhtmltopdf default when unset
+func marginSize(m *uint) float64 {
+	if m == nil {
+		return defaultMargin
+	}
+	return float64(*m)
+}
@@ -0,0 +1,39 @@
+package htmlToPdf
+
+import (
+	"testing"
+
+	"github.com/vatsal278/html-pdf-service/internal/model"
+)
+
+func TestPaperDimensions(t *testing.T) {
+	for name := range paperDimensions {
+		if size, ok := model.PaperSize(name); !ok || size != name {
+			t.Errorf("%s is not a paper size of the page setup", name)
+		}
+	}
+	for _, name := range []string{"A4", "Letter", "B10", "Comm10E"} {
+		if _, ok := paperDimensions[name]; !ok {
+			t.Errorf("missing dimensions of %s", name)
+		}
+	}
+}
+
+func TestPaperSize(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup model.PageSetup
+		want  [2]float64
+	}{
+		{name: "default", setup: model.PageSetup{}, want: [2]float64{210, 297}},
+		{name: "named size", setup: model.PageSetup{PageSize: "letter"}, want: [2]float64{215.9, 279.4}},
+		{name: "custom size", setup: model.PageSetup{PageWidth: 100, PageHeight: 50}, want: [2]float64{100, 50}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := paperSize(tt.setup); got != tt.want {
+				t.Errorf("want %v got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package htmlToPdf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/vatsal278/html-pdf-service/internal/model"
+)
+
+var (
+	ErrQueueFull    = errors.New("render queue is full")
+	ErrQueueTimeout = errors.New("timed out waiting for a free renderer")
+)
+
+// ewmaWeight is the weight of the newest sample in the moving averages of the wait and render times
+const ewmaWeight = 0.2
+
+// BusyError rejects a render because all renderers are busy, it wraps ErrQueueFull or ErrQueueTimeout
+type BusyError struct {
+	Err error
+	// RetryAfter estimates when a render slot is free again
+	RetryAfter time.Duration
+	QueueDepth int
+}
+
+func (e *BusyError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *BusyError) Unwrap() error {
+	return e.Err
+}
+
+// SchedulerStats are the queue depth and wait times of a scheduler, the averages are moving averages
+type SchedulerStats struct {
+	Running     int
+	Concurrency int
+	Queued      int
+	QueueSize   int
+	AvgWait     time.Duration
+	MaxWait     time.Duration
+	AvgRender   time.Duration
+}
+
+func (s SchedulerStats) String() string {
+	return fmt.Sprintf("%d/%d running, %d/%d queued, average wait %s, max wait %s, average render %s",
+		s.Running, s.Concurrency, s.Queued, s.QueueSize,
+		s.AvgWait.Round(time.Millisecond), s.MaxWait.Round(time.Millisecond), s.AvgRender.Round(time.Millisecond))
+}
+
+// Scheduler bounds the renders running at once, renders beyond the concurrency wait in a bounded queue.
+// One scheduler is shared by the pdf and image renderers so that together they never exceed the concurrency.
+type Scheduler struct {
+	cfg   model.SchedulerConfig
+	slots chan struct{}
+	queue chan struct{}
+
+	mu        sync.Mutex
+	waits     int
+	avgWait   time.Duration
+	maxWait   time.Duration
+	renders   int
+	avgRender time.Duration
+}
+
+func NewScheduler(cfg model.SchedulerConfig) *Scheduler {
+	cfg = cfg.WithDefaults()
+	return &Scheduler{
+		cfg:   cfg,
+		slots: make(chan struct{}, cfg.Concurrency),
+		queue: make(chan struct{}, cfg.QueueSize),
+	}
+}
+
+// Stats returns the current queue depth and the wait and render times
+func (s *Scheduler) Stats() SchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return SchedulerStats{
+		Running:     len(s.slots),
+		Concurrency: s.cfg.Concurrency,
+		Queued:      len(s.queue),
+		QueueSize:   s.cfg.QueueSize,
+		AvgWait:     s.avgWait,
+		MaxWait:     s.maxWait,
+		AvgRender:   s.avgRender,
+	}
+}
+
+// run runs render once a slot is free, it fails with a BusyError when the queue is full or the wait times out and
+// with the error of ctx when ctx is done while waiting
+func (s *Scheduler) run(ctx context.Context, render func() error) error {
+	err := s.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { <-s.slots }()
+	start := time.Now()
+	err = render()
+	s.mu.Lock()
+	s.avgRender = ewma(s.avgRender, time.Since(start), s.renders)
+	s.renders++
+	s.mu.Unlock()
+	return err
+}
+
+func (s *Scheduler) acquire(ctx context.Context) error {
+	select {
+	case s.slots <- struct{}{}:
+		s.waited(0)
+		return nil
+	default:
+	}
+	select {
+	case s.queue <- struct{}{}:
+	default:
+		return s.busy(ErrQueueFull)
+	}
+	defer func() { <-s.queue }()
+	start := time.Now()
+	timer := time.NewTimer(s.cfg.QueueDuration())
+	defer timer.Stop()
+	select {
+	case s.slots <- struct{}{}:
+		s.waited(time.Since(start))
+		return nil
+	case <-timer.C:
+		s.waited(time.Since(start))
+		return s.busy(ErrQueueTimeout)
+	case <-ctx.Done():
+		s.waited(time.Since(start))
+		return ctx.Err()
+	}
+}
+
+func (s *Scheduler) waited(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.avgWait = ewma(s.avgWait, d, s.waits)
+	s.waits++
+	if d > s.maxWait {
+		s.maxWait = d
+	}
+}
+
+// busy returns a BusyError retrying after the queued and running renders are expected to finish, at least
+// after a second
+func (s *Scheduler) busy(err error) error {
+	s.mu.Lock()
+	avg := s.avgRender
+	s.mu.Unlock()
+	depth := len(s.queue)
+	retry := avg * time.Duration(depth+s.cfg.Concurrency) / time.Duration(s.cfg.Concurrency)
+	retry = retry.Truncate(time.Second) + time.Second
+	return &BusyError{Err: err, RetryAfter: retry, QueueDepth: depth}
+}
+
+// ewma adds the sample d to the moving average avg of n samples, the first sample is the average
+func ewma(avg, d time.Duration, n int) time.Duration {
+	if n == 0 {
+		return d
+	}
+	return time.Duration(ewmaWeight*float64(d) + (1-ewmaWeight)*float64(avg))
+}
+
+type scheduledPdf struct {
+	next HtmlToPdf
+	s    *Scheduler
+}
+
+// NewScheduledSvc returns next with every GeneratePdf run by the scheduler s
+func NewScheduledSvc(s *Scheduler, next HtmlToPdf) HtmlToPdf {
+	return &scheduledPdf{next: next, s: s}
+}
+
+// HealthCheck reports the health of the renderers and the queue of the scheduler
+func (p scheduledPdf) HealthCheck() (string, bool) {
+	msg, ok := p.next.HealthCheck()
+	if msg != "" {
+		msg += "; "
+	}
+	return msg + "scheduler: " + p.s.Stats().String(), ok
+}
+
+func (p scheduledPdf) GetJsonFromHtml(pages ...[]byte) ([]byte, error) {
+	return p.next.GetJsonFromHtml(pages...)
+}
+
+func (p scheduledPdf) GeneratePdf(ctx context.Context, wr io.Writer, b []byte) error {
+	return p.s.run(ctx, func() error {
+		return p.next.GeneratePdf(ctx, wr, b)
+	})
+}
+
+type scheduledRenderer struct {
+	next Renderer
+	s    *Scheduler
+}
+
+// NewScheduledRenderer returns next with every Render run by the scheduler s
+func NewScheduledRenderer(s *Scheduler, next Renderer) Renderer {
+	return &scheduledRenderer{next: next, s: s}
+}
+
+func (r scheduledRenderer) HealthCheck() (string, bool) {
+	return r.next.HealthCheck()
+}
+
+func (r scheduledRenderer) Render(ctx context.Context, wr io.Writer, b []byte, opts RenderOptions) error {
+	return r.s.run(ctx, func() error {
+		return r.next.Render(ctx, wr, b, opts)
+	})
+}
@@ -0,0 +1,175 @@
+package htmlToPdf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vatsal278/html-pdf-service/internal/model"
+)
+
+// blockingRenderer renders until release is closed and records the most renders running at once
+type blockingRenderer struct {
+	release chan struct{}
+	mu      sync.Mutex
+	running int
+	max     int
+}
+
+func (b *blockingRenderer) render(w io.Writer) error {
+	b.mu.Lock()
+	b.running++
+	if b.running > b.max {
+		b.max = b.running
+	}
+	b.mu.Unlock()
+	<-b.release
+	b.mu.Lock()
+	b.running--
+	b.mu.Unlock()
+	_, err := w.Write([]byte("%PDF-"))
+	return err
+}
+
+func (b *blockingRenderer) HealthCheck() (string, bool) {
+	return "blocking", true
+}
+
+func (b *blockingRenderer) GeneratePdf(_ context.Context, w io.Writer, _ []byte) error {
+	return b.render(w)
+}
+
+func (b *blockingRenderer) GetJsonFromHtml(...[]byte) ([]byte, error) {
+	return nil, nil
+}
+
+// blockingImage is the image Renderer of a blockingRenderer
+type blockingImage struct {
+	*blockingRenderer
+}
+
+func (b blockingImage) HealthCheck() (string, bool) {
+	return "", true
+}
+
+func (b blockingImage) Render(_ context.Context, w io.Writer, _ []byte, _ RenderOptions) error {
+	return b.render(w)
+}
+
+// waitStats waits until the scheduler runs and queues the given number of renders
+func waitStats(t *testing.T, s *Scheduler, running, queued int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		st := s.Stats()
+		if st.Running == running && st.Queued == queued {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("want %d running and %d queued got %+v", running, queued, st)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	s := NewScheduler(model.SchedulerConfig{Concurrency: 2, QueueSize: 2})
+	b := &blockingRenderer{release: make(chan struct{})}
+	pdf := NewScheduledSvc(s, b)
+	img := NewScheduledRenderer(s, blockingImage{b})
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		go func(i int) {
+			if i%2 == 0 {
+				errs <- pdf.GeneratePdf(context.Background(), new(bytes.Buffer), nil)
+				return
+			}
+			errs <- img.Render(context.Background(), new(bytes.Buffer), nil, RenderOptions{})
+		}(i)
+	}
+	waitStats(t, s, 2, 2)
+
+	err := pdf.GeneratePdf(context.Background(), new(bytes.Buffer), nil)
+	var busy *BusyError
+	if !errors.As(err, &busy) || !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("want %v got %v", ErrQueueFull, err)
+	}
+	if busy.QueueDepth != 2 || busy.RetryAfter != time.Second {
+		t.Errorf("unexpected %+v", busy)
+	}
+	msg, ok := pdf.HealthCheck()
+	if !ok || !strings.HasPrefix(msg, "blocking; scheduler: 2/2 running, 2/2 queued, average wait ") {
+		t.Errorf("unexpected health %v %q", ok, msg)
+	}
+
+	close(b.release)
+	for i := 0; i < 4; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+	if b.max != 2 {
+		t.Errorf("want 2 renders at once got %d", b.max)
+	}
+	st := s.Stats()
+	if st.Running != 0 || st.Queued != 0 || st.MaxWait <= 0 || st.AvgRender <= 0 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+}
+
+func TestScheduler_QueueTimeout(t *testing.T) {
+	s := NewScheduler(model.SchedulerConfig{Concurrency: 1, QueueSize: 1, QueueTimeout: 10})
+	b := &blockingRenderer{release: make(chan struct{})}
+	defer close(b.release)
+	pdf := NewScheduledSvc(s, b)
+	go func() {
+		_ = pdf.GeneratePdf(context.Background(), new(bytes.Buffer), nil)
+	}()
+	waitStats(t, s, 1, 0)
+	err := pdf.GeneratePdf(context.Background(), new(bytes.Buffer), nil)
+	if !errors.Is(err, ErrQueueTimeout) {
+		t.Fatalf("want %v got %v", ErrQueueTimeout, err)
+	}
+	if st := s.Stats(); st.Queued != 0 || st.MaxWait < 10*time.Millisecond {
+		t.Errorf("unexpected stats %+v", st)
+	}
+}
+
+func TestScheduler_Canceled(t *testing.T) {
+	s := NewScheduler(model.SchedulerConfig{Concurrency: 1, QueueSize: 1, QueueTimeout: 5000})
+	b := &blockingRenderer{release: make(chan struct{})}
+	defer close(b.release)
+	pdf := NewScheduledSvc(s, b)
+	go func() {
+		_ = pdf.GeneratePdf(context.Background(), new(bytes.Buffer), nil)
+	}()
+	waitStats(t, s, 1, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- pdf.GeneratePdf(ctx, new(bytes.Buffer), nil)
+	}()
+	waitStats(t, s, 1, 1)
+	cancel()
+	err := <-done
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("want %v got %v", context.Canceled, err)
+	}
+	waitStats(t, s, 1, 0)
+}
+
+func TestEwma(t *testing.T) {
+	avg := ewma(0, time.Second, 0)
+	if avg != time.Second {
+		t.Errorf("want the first sample got %s", avg)
+	}
+	avg = ewma(avg, 2*time.Second, 1)
+	if avg != 1200*time.Millisecond {
+		t.Errorf("want 1.2s got %s", avg)
+	}
+}
@@ -0,0 +1,87 @@
+package htmlToPdf
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/vatsal278/html-pdf-service/internal/model"
+)
+
+type selector struct {
+	def       string
+	renderers map[string]HtmlToPdf
+}
+
+// NewSelectorSvc returns a HtmlToPdf generating every document with the renderer named in its template settings,
+// documents without a renderer use def, an empty def means wkhtmltopdf
+func NewSelectorSvc(def string, renderers map[string]HtmlToPdf) HtmlToPdf {
+	if def == "" {
+		def = model.RendererWkhtmltopdf
+	}
+	return &selector{def: def, renderers: renderers}
+}
+
+// HealthCheck reports the messages of all renderers starting with the default, the selector is healthy when
+// the default renderer is
+func (s selector) HealthCheck() (string, bool) {
+	names := make([]string, 0, len(s.renderers))
+	for name := range s.renderers {
+		if name != s.def {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	healthy := false
+	msgs := make([]string, 0, len(s.renderers))
+	if _, ok := s.renderers[s.def]; ok {
+		names = append([]string{s.def}, names...)
+	} else {
+		msgs = append(msgs, fmt.Sprintf("%s: not configured", s.def))
+	}
+	for _, name := range names {
+		msg, ok := s.renderers[name].HealthCheck()
+		if name == s.def {
+			healthy = ok
+		}
+		if msg != "" {
+			msgs = append(msgs, name+": "+msg)
+		}
+	}
+	return strings.Join(msgs, "; "), healthy
+}
+
+// GetJsonFromHtml returns the document of the default renderer, all renderers read the same document
+func (s selector) GetJsonFromHtml(pages ...[]byte) ([]byte, error) {
+	r, err := s.renderer(s.def)
+	if err != nil {
+		return nil, err
+	}
+	return r.GetJsonFromHtml(pages...)
+}
+
+func (s selector) GeneratePdf(ctx context.Context, wr io.Writer, b []byte) error {
+	settings, err := docSettings(b)
+	if err != nil {
+		return err
+	}
+	name := settings.Renderer
+	if name == "" {
+		name = s.def
+	}
+	r, err := s.renderer(name)
+	if err != nil {
+		return err
+	}
+	return r.GeneratePdf(ctx, wr, b)
+}
+
+func (s selector) renderer(name string) (HtmlToPdf, error) {
+	r, ok := s.renderers[name]
+	if !ok {
+		return nil, fmt.Errorf("renderer %q is not configured", name)
+	}
+	return r, nil
+}
@@ -0,0 +1,76 @@
+package htmlToPdf
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/vatsal278/html-pdf-service/internal/model"
+)
+
+// namedRenderer writes its name as the generated document
+type namedRenderer struct {
+	name    string
+	healthy bool
+}
+
+func (n namedRenderer) HealthCheck() (string, bool) {
+	if n.healthy {
+		return n.name + " ok", true
+	}
+	return n.name + " down", false
+}
+
+func (n namedRenderer) GeneratePdf(_ context.Context, w io.Writer, _ []byte) error {
+	_, err := w.Write([]byte(n.name))
+	return err
+}
+
+func (n namedRenderer) GetJsonFromHtml(...[]byte) ([]byte, error) {
+	return []byte(n.name), nil
+}
+
+func TestSelector(t *testing.T) {
+	renderers := map[string]HtmlToPdf{
+		model.RendererWkhtmltopdf: namedRenderer{name: model.RendererWkhtmltopdf, healthy: true},
+		model.RendererChromium:    namedRenderer{name: model.RendererChromium},
+	}
+	tests := []struct {
+		name        string
+		def         string
+		doc         string
+		want        string
+		wantErr     bool
+		wantHealthy bool
+		wantMsg     string
+	}{
+		{name: "default renderer", doc: `{"Pages":[]}`, want: model.RendererWkhtmltopdf, wantHealthy: true,
+			wantMsg: "wkhtmltopdf: wkhtmltopdf ok; chromium: chromium down"},
+		{name: "configured default", def: model.RendererChromium, doc: `{"Pages":[]}`, want: model.RendererChromium,
+			wantMsg: "chromium: chromium down; wkhtmltopdf: wkhtmltopdf ok"},
+		{name: "template renderer", doc: `{"Settings":{"renderer":"chromium"},"Pages":[]}`, want: model.RendererChromium, wantHealthy: true,
+			wantMsg: "wkhtmltopdf: wkhtmltopdf ok; chromium: chromium down"},
+		{name: "unknown renderer", doc: `{"Settings":{"renderer":"prince"},"Pages":[]}`, wantErr: true, wantHealthy: true,
+			wantMsg: "wkhtmltopdf: wkhtmltopdf ok; chromium: chromium down"},
+		{name: "unknown default", def: "prince", doc: `{"Pages":[]}`, wantErr: true,
+			wantMsg: "prince: not configured; chromium: chromium down; wkhtmltopdf: wkhtmltopdf ok"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewSelectorSvc(tt.def, renderers)
+			msg, healthy := svc.HealthCheck()
+			if healthy != tt.wantHealthy || msg != tt.wantMsg {
+				t.Errorf("want %v %q got %v %q", tt.wantHealthy, tt.wantMsg, healthy, msg)
+			}
+			out := new(bytes.Buffer)
+			err := svc.GeneratePdf(context.Background(), out, []byte(tt.doc))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("want error %v got %v", tt.wantErr, err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("want %s got %s", tt.want, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package htmlToPdf
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+const wkHtmlToImageBin = "wkhtmltoimage"
+
+type wkHtmlToImage struct {
+}
+
+func NewWkHtmlToImageSvc() Renderer {
+	return &wkHtmlToImage{}
+}
+
+// HealthCheck reports the path of the wkhtmltoimage binary
+func (w wkHtmlToImage) HealthCheck() (string, bool) {
+	path, err := exec.LookPath(wkHtmlToImageBin)
+	if err != nil {
+		return wkHtmlToImageBin + ": not found", false
+	}
+	return wkHtmlToImageBin + ": " + path, true
+}
+
+// Render writes an image of the page opts.Page of the document, wkhtmltoimage renders a single page only. The
+// process is killed when ctx is done.
+func (w wkHtmlToImage) Render(ctx context.Context, wr io.Writer, b []byte, opts RenderOptions) error {
+	page, err := nthPage(b, opts.Page)
+	if err != nil {
+		return err
+	}
+	path, err := exec.LookPath(wkHtmlToImageBin)
+	if err != nil {
+		return err
+	}
+	out, stderr := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	cmd := exec.CommandContext(ctx, path, imageArgs(opts)...)
+	cmd.Stdin = bytes.NewReader(page)
+	cmd.Stdout = out
+	cmd.Stderr = stderr
+	err = cmd.Run()
+	if err != nil {
+		return fmt.Errorf("%s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	_, err = io.Copy(wr, out)
+	return err
+}
+
+// imageArgs returns the wkhtmltoimage arguments reading the page from stdin and writing the image to stdout
+func imageArgs(opts RenderOptions) []string {
+	format := opts.Format
+	if format == "jpeg" {
+		format = "jpg"
+	}
+	args := []string{"--quiet", "--format", format}
+	if opts.Width > 0 {
+		args = append(args, "--width", strconv.Itoa(opts.Width))
+	}
+	if opts.Quality > 0 {
+		args = append(args, "--quality", strconv.Itoa(opts.Quality))
+	}
+	return append(args, "-", "-")
+}
+
+// nthPage returns the html of the n-th page with page data of a document created by GetJsonFromHtml, the first
+// when n is zero
+func nthPage(b []byte, n int) ([]byte, error) {
+	var doc struct {
+		Pages []struct {
+			Base64PageData string
+		}
+	}
+	err := json.Unmarshal(b, &doc)
+	if err != nil {
+		return nil, err
+	}
+	if n < 1 {
+		n = 1
+	}
+	for _, p := range doc.Pages {
+		if p.Base64PageData == "" {
+			continue
+		}
+		if n--; n == 0 {
+			return base64.StdEncoding.DecodeString(p.Base64PageData)
+		}
+	}
+	return nil, errors.New("document has no page data for the requested page")
+}
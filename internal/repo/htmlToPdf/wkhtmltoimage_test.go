@@ -0,0 +1,86 @@
+package htmlToPdf
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image"
+	_ "image/png"
+	"os/exec"
+	"testing"
+
+	"github.com/PereRohit/util/testutil"
+)
+
+func TestImageArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		opts RenderOptions
+		want []string
+	}{
+		{name: "png defaults", opts: RenderOptions{Format: "png"}, want: []string{"--quiet", "--format", "png", "-", "-"}},
+		{name: "jpeg with width and quality", opts: RenderOptions{Format: "jpeg", Width: 1200, Quality: 80},
+			want: []string{"--quiet", "--format", "jpg", "--width", "1200", "--quality", "80", "-", "-"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := testutil.Diff(imageArgs(tt.opts), tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestNthPage(t *testing.T) {
+	page := func(html string) string {
+		return `{"Base64PageData":"` + base64.StdEncoding.EncodeToString([]byte(html)) + `"}`
+	}
+	doc := []byte(`{"Pages":[{"InputFile":"a.html"},` + page("<p>1</p>") + `,` + page("<p>2</p>") + `]}`)
+	tests := []struct {
+		name    string
+		n       int
+		want    string
+		wantErr bool
+	}{
+		{name: "first page by default", n: 0, want: "<p>1</p>"},
+		{name: "first page", n: 1, want: "<p>1</p>"},
+		{name: "second page", n: 2, want: "<p>2</p>"},
+		{name: "out of range", n: 3, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := nthPage(doc, tt.n)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("want error %v got %v", tt.wantErr, err)
+			}
+			diff := testutil.Diff(string(got), tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+	_, err := nthPage([]byte(`{"Pages":[]}`), 0)
+	if err == nil {
+		t.Error("want error for a document without page data")
+	}
+}
+
+func TestRenderImage(t *testing.T) {
+	if _, err := exec.LookPath(wkHtmlToImageBin); err != nil || testing.Short() {
+		t.Skip("skipping testing due to unavailability of wkhtmltoimage")
+	}
+	doc := []byte(`{"Pages":[{"Base64PageData":"` + base64.StdEncoding.EncodeToString([]byte("<p>hello</p>")) + `"}]}`)
+	w := bytes.NewBuffer(nil)
+	err := NewWkHtmlToImageSvc().Render(context.Background(), w, doc, RenderOptions{Format: "png", Width: 400})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, format, err := image.Decode(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "png" || img.Bounds().Dx() != 400 {
+		t.Errorf("want a 400 px wide png got %v %v", format, img.Bounds().Dx())
+	}
+}
@@ -2,39 +2,293 @@ package htmlToPdf
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"github.com/SebastiaanKlippert/go-wkhtmltopdf"
+	"github.com/vatsal278/html-pdf-service/internal/model"
 	"io"
+	"os/exec"
+	"strings"
 )
 
 type wkHtmlToPdf struct {
+	cfg    model.WkhtmltopdfConfig
+	health *health
 }
 
-func NewWkHtmlToPdfSvc() HtmlToPdf {
-	return &wkHtmlToPdf{}
+// NewWkHtmlToPdfSvc returns a HtmlToPdf running the wkhtmltopdf binary at the configured path, the binary is
+// looked up like go-wkhtmltopdf does when the path is empty. The canary of the health check is run by the
+// canary scheduler s, see NewCanaryScheduler, a nil scheduler runs it right away.
+func NewWkHtmlToPdfSvc(cfg model.WkhtmltopdfConfig, hc model.HealthConfig, s *Scheduler) HtmlToPdf {
+	if cfg.Path != "" {
+		wkhtmltopdf.SetPath(cfg.Path)
+	}
+	w := &wkHtmlToPdf{cfg: cfg}
+	w.health = newHealth(model.RendererWkhtmltopdf, hc, s, w.probe)
+	w.health.start()
+	return w
+}
+
+// HealthCheck reports the version of wkhtmltopdf and the cached result of the last canary render
+func (w wkHtmlToPdf) HealthCheck() (string, bool) {
+	return w.health.status()
 }
 
-func (w wkHtmlToPdf) HealthCheck() bool {
-	return true
+func (w wkHtmlToPdf) binary() (string, error) {
+	if w.cfg.Path != "" {
+		return exec.LookPath(w.cfg.Path)
+	}
+	_, err := wkhtmltopdf.NewPDFGenerator()
+	if err != nil {
+		return "", err
+	}
+	return wkhtmltopdf.GetPath(), nil
 }
 
-func (w wkHtmlToPdf) GetJsonFromHtml(b []byte) ([]byte, error) {
+func (w wkHtmlToPdf) probe(ctx context.Context) (string, error) {
+	path, err := w.binary()
+	if err != nil {
+		return "", err
+	}
+	out, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		return "", err
+	}
+	version := firstLine(out)
+	pdfg, err := wkhtmltopdf.NewPDFGenerator()
+	if err != nil {
+		return version, err
+	}
+	pdfg.AddPage(wkhtmltopdf.NewPageReader(bytes.NewReader(canaryHtml)))
+	buf := new(bytes.Buffer)
+	pdfg.SetOutput(buf)
+	err = pdfg.CreateContext(ctx)
+	if err != nil {
+		return version, err
+	}
+	return version, checkCanary(buf.Bytes())
+}
+
+// GetJsonFromHtml returns the document with one page per html file in the given order
+func (w wkHtmlToPdf) GetJsonFromHtml(pages ...[]byte) ([]byte, error) {
 	pdfg := wkhtmltopdf.NewPDFPreparer()
-	pdfg.AddPage(wkhtmltopdf.NewPageReader(bytes.NewReader(b)))
+	for _, b := range pages {
+		pdfg.AddPage(wkhtmltopdf.NewPageReader(bytes.NewReader(b)))
+	}
 	jb, err := pdfg.ToJSON()
 	if err != nil {
 		return nil, err
 	}
 	return jb, nil
 }
-func (w wkHtmlToPdf) GeneratePdf(wr io.Writer, b []byte) error {
+
+// GeneratePdf runs wkhtmltopdf, the process is killed when ctx is done
+func (w wkHtmlToPdf) GeneratePdf(ctx context.Context, wr io.Writer, b []byte) error {
+	settings, err := docSettings(b)
+	if err != nil {
+		return err
+	}
+	files, err := writeRenderFiles(settings)
+	if err != nil {
+		return err
+	}
+	defer files.remove()
+	b, err = applyPageSettings(b, settings.PageSetup, files)
+	if err != nil {
+		return err
+	}
 	pdfgFromJSON, err := wkhtmltopdf.NewPDFGeneratorFromJSON(bytes.NewBuffer(b))
 	if err != nil {
 		return err
 	}
+	globalOptions(pdfgFromJSON, settings.PageSetup)
+	documentOptions(pdfgFromJSON, settings, files)
 	pdfgFromJSON.SetOutput(wr)
-	err = pdfgFromJSON.Create()
+	err = pdfgFromJSON.CreateContext(ctx)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// renderSettings are the template settings stored with the document that are applied by the renderer
+type renderSettings struct {
+	PageSetup model.PageSetup     `json:"page_setup"`
+	Header    *model.HeaderFooter `json:"header"`
+	Footer    *model.HeaderFooter `json:"footer"`
+	Cover     []byte              `json:"cover"`
+	Toc       *model.Toc          `json:"toc"`
+	Outline   *model.Outline      `json:"outline"`
+	Renderer  string              `json:"renderer"`
+}
+
+// docSettings reads the render settings stored with the template settings of the document
+func docSettings(b []byte) (renderSettings, error) {
+	var doc struct {
+		Settings renderSettings
+	}
+	err := json.Unmarshal(b, &doc)
+	return doc.Settings, err
+}
+
+// documentPage is a page of the document with its settings, the page setup provides the defaults of the settings
+type documentPage struct {
+	html     []byte
+	settings model.PageSettings
+}
+
+// documentPages returns the pages with page data of a document created by GetJsonFromHtml
+func documentPages(b []byte, setup model.PageSetup) ([]documentPage, error) {
+	var doc struct {
+		Pages []struct {
+			Base64PageData string
+			Settings       json.RawMessage
+		}
+	}
+	err := json.Unmarshal(b, &doc)
+	if err != nil {
+		return nil, err
+	}
+	var pages []documentPage
+	for _, p := range doc.Pages {
+		if p.Base64PageData == "" {
+			continue
+		}
+		page := documentPage{settings: model.PageSettings{Zoom: setup.Zoom, PrintMediaType: setup.PrintMediaType}}
+		page.html, err = base64.StdEncoding.DecodeString(p.Base64PageData)
+		if err != nil {
+			return nil, err
+		}
+		if len(p.Settings) > 0 {
+			err = json.Unmarshal(p.Settings, &page.settings)
+			if err != nil {
+				return nil, err
+			}
+		}
+		pages = append(pages, page)
+	}
+	if len(pages) == 0 {
+		return nil, errors.New("document has no pages")
+	}
+	return pages, nil
+}
+
+// globalOptions sets the document wide wkhtmltopdf options from the page setup
+func globalOptions(pdfg *wkhtmltopdf.PDFGenerator, s model.PageSetup) {
+	if size, ok := model.PaperSize(s.PageSize); ok {
+		pdfg.PageSize.Set(size)
+	}
+	if s.PageWidth > 0 && s.PageHeight > 0 {
+		pdfg.PageWidth.Set(s.PageWidth)
+		pdfg.PageHeight.Set(s.PageHeight)
+	}
+	switch strings.ToLower(s.Orientation) {
+	case model.OrientationLandscape:
+		pdfg.Orientation.Set(wkhtmltopdf.OrientationLandscape)
+	case model.OrientationPortrait:
+		pdfg.Orientation.Set(wkhtmltopdf.OrientationPortrait)
+	}
+	if s.MarginTop != nil {
+		pdfg.MarginTop.Set(*s.MarginTop)
+	}
+	if s.MarginBottom != nil {
+		pdfg.MarginBottom.Set(*s.MarginBottom)
+	}
+	if s.MarginLeft != nil {
+		pdfg.MarginLeft.Set(*s.MarginLeft)
+	}
+	if s.MarginRight != nil {
+		pdfg.MarginRight.Set(*s.MarginRight)
+	}
+	if s.Dpi > 0 {
+		pdfg.Dpi.Set(s.Dpi)
+	}
+	if s.Grayscale {
+		pdfg.Grayscale.Set(true)
+	}
+	if s.ImageQuality > 0 {
+		pdfg.ImageQuality.Set(s.ImageQuality)
+	}
+}
+
+// applyPageSettings sets the wkhtmltopdf page options of every page from the page setup of the document and
+// the renderer independent settings of the page, the settings of a page take precedence. Every page gets the
+// running header and footer files.
+func applyPageSettings(b []byte, setup model.PageSetup, files renderFiles) ([]byte, error) {
+	var doc map[string]json.RawMessage
+	err := json.Unmarshal(b, &doc)
+	if err != nil {
+		return nil, err
+	}
+	var pages []map[string]json.RawMessage
+	if raw, ok := doc["Pages"]; ok {
+		err = json.Unmarshal(raw, &pages)
+		if err != nil {
+			return nil, err
+		}
+	}
+	defaults := model.PageSettings{Zoom: setup.Zoom, PrintMediaType: setup.PrintMediaType}
+	changed := false
+	for _, p := range pages {
+		raw, ok := p["Settings"]
+		if !ok && defaults == (model.PageSettings{}) && files.dir == "" {
+			continue
+		}
+		settings := defaults
+		if ok {
+			err = json.Unmarshal(raw, &settings)
+			if err != nil {
+				return nil, err
+			}
+		}
+		po := wkhtmltopdf.NewPageOptions()
+		if opts, ok := p["PageOptions"]; ok {
+			err = json.Unmarshal(opts, &po)
+			if err != nil {
+				return nil, err
+			}
+		}
+		pageOptions(&po, settings)
+		files.pageOptions(&po)
+		// the options marshal through pointer receivers
+		p["PageOptions"], err = json.Marshal(&po)
+		if err != nil {
+			return nil, err
+		}
+		changed = true
+	}
+	if !changed {
+		return b, nil
+	}
+	doc["Pages"], err = json.Marshal(pages)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+func pageOptions(po *wkhtmltopdf.PageOptions, s model.PageSettings) {
+	if s.Zoom > 0 {
+		po.Zoom.Set(s.Zoom)
+	}
+	if s.PrintMediaType {
+		po.PrintMediaType.Set(true)
+	}
+	if s.NoBackground {
+		po.NoBackground.Set(true)
+	}
+	if s.ExcludeFromOutline {
+		po.ExcludeFromOutline.Set(true)
+	}
+	if s.DisableJavascript {
+		po.DisableJavascript.Set(true)
+	}
+	if s.JavascriptDelay > 0 {
+		po.JavascriptDelay.Set(s.JavascriptDelay)
+	}
+	if s.MinimumFontSize > 0 {
+		po.MinimumFontSize.Set(s.MinimumFontSize)
+	}
+}
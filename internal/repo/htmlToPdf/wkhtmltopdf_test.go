@@ -1,17 +1,33 @@
 package htmlToPdf
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/PereRohit/util/testutil"
+	"github.com/SebastiaanKlippert/go-wkhtmltopdf"
+	"github.com/vatsal278/html-pdf-service/internal/model"
 	"io"
 	"net/http/httptest"
 	"os"
+	"os/exec"
 	"testing"
 )
 
-func TestGetJsonFromHtml(t *testing.T) {
+// skipWithoutWkhtmltopdf skips tests running the wkhtmltopdf binary in short mode or when it is not installed,
+// the native renderer tests cover rendering without it
+func skipWithoutWkhtmltopdf(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping testing due to unavailability of testing environment")
 	}
-	htmlToPdf := NewWkHtmlToPdfSvc()
+	if _, err := exec.LookPath("wkhtmltopdf"); err != nil {
+		t.Skip("wkhtmltopdf not installed")
+	}
+}
+
+func TestGetJsonFromHtml(t *testing.T) {
+	skipWithoutWkhtmltopdf(t)
+	htmlToPdf := NewWkHtmlToPdfSvc(model.WkhtmltopdfConfig{}, model.HealthConfig{}, nil)
 	tests := []struct {
 		name         string
 		setupFunc    func() []byte
@@ -31,7 +47,7 @@ func TestGetJsonFromHtml(t *testing.T) {
 					t.Error(err)
 				}
 				w := httptest.NewRecorder()
-				err = htmlToPdf.GeneratePdf(w, jsBytes)
+				err = htmlToPdf.GeneratePdf(context.Background(), w, jsBytes)
 				if err != nil {
 					t.Error(err)
 				}
@@ -47,10 +63,8 @@ func TestGetJsonFromHtml(t *testing.T) {
 	}
 }
 func TestGeneratePdf(t *testing.T) {
-	if testing.Short() {
-		t.Skip("skipping testing due to unavailability of testing environment")
-	}
-	htmlToPdf := NewWkHtmlToPdfSvc()
+	skipWithoutWkhtmltopdf(t)
+	htmlToPdf := NewWkHtmlToPdfSvc(model.WkhtmltopdfConfig{}, model.HealthConfig{}, nil)
 	tests := []struct {
 		name         string
 		setupFunc    func() []byte
@@ -76,9 +90,134 @@ func TestGeneratePdf(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			w := httptest.NewRecorder()
-			err := htmlToPdf.GeneratePdf(w, tt.setupFunc())
+			err := htmlToPdf.GeneratePdf(context.Background(), w, tt.setupFunc())
 			tt.validateFunc(w, err)
 
 		})
 	}
 }
+
+func TestApplyPageSettings(t *testing.T) {
+	doc := []byte(`{"GlobalOptions":{},"Pages":[{"Base64PageData":"PHA+MTwvcD4=","Settings":{"zoom":1.5,"print_media_type":true,"javascript_delay":500}},{"Base64PageData":"PHA+MjwvcD4="}]}`)
+	b, err := applyPageSettings(doc, model.PageSetup{}, renderFiles{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Pages []struct {
+			PageOptions    wkhtmltopdf.PageOptions
+			Base64PageData string
+		}
+	}
+	err = json.Unmarshal(b, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := testutil.Diff(got.Pages[0].PageOptions.Args(), []string{"--javascript-delay", "500", "--print-media-type", "--zoom", "1.500"})
+	if diff != "" {
+		t.Error(testutil.Callers(), diff)
+	}
+	diff = testutil.Diff(len(got.Pages[1].PageOptions.Args()), 0)
+	if diff != "" {
+		t.Error(testutil.Callers(), diff)
+	}
+	diff = testutil.Diff(got.Pages[1].Base64PageData, "PHA+MjwvcD4=")
+	if diff != "" {
+		t.Error(testutil.Callers(), diff)
+	}
+}
+
+func TestApplyPageSettings_PageSetup(t *testing.T) {
+	doc := []byte(`{"Pages":[{"Base64PageData":"PHA+MTwvcD4=","Settings":{"zoom":2}},{"Base64PageData":"PHA+MjwvcD4="}]}`)
+	b, err := applyPageSettings(doc, model.PageSetup{Zoom: 1.5, PrintMediaType: true}, renderFiles{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Pages []struct {
+			PageOptions wkhtmltopdf.PageOptions
+		}
+	}
+	err = json.Unmarshal(b, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := testutil.Diff(got.Pages[0].PageOptions.Args(), []string{"--print-media-type", "--zoom", "2.000"})
+	if diff != "" {
+		t.Error(testutil.Callers(), diff)
+	}
+	diff = testutil.Diff(got.Pages[1].PageOptions.Args(), []string{"--print-media-type", "--zoom", "1.500"})
+	if diff != "" {
+		t.Error(testutil.Callers(), diff)
+	}
+}
+
+func TestGlobalOptions(t *testing.T) {
+	zero, ten := uint(0), uint(10)
+	tests := []struct {
+		name  string
+		setup model.PageSetup
+		want  []string
+	}{
+		{
+			name:  "named size",
+			setup: model.PageSetup{PageSize: "letter", Orientation: "landscape", MarginTop: &zero, MarginLeft: &ten, Dpi: 300, Grayscale: true, ImageQuality: 80},
+			want:  []string{"--dpi", "300", "--grayscale", "--image-quality", "80", "--margin-left", "10", "--margin-top", "0", "--orientation", "Landscape", "--page-size", "Letter", "-"},
+		},
+		{
+			name:  "custom size",
+			setup: model.PageSetup{PageWidth: 100, PageHeight: 150},
+			want:  []string{"--page-height", "150", "--page-width", "100", "-"},
+		},
+		{
+			name:  "defaults",
+			setup: model.PageSetup{},
+			want:  []string{"-"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pdfg := wkhtmltopdf.NewPDFPreparer()
+			globalOptions(pdfg, tt.setup)
+			diff := testutil.Diff(pdfg.Args(), tt.want)
+			if diff != "" {
+				t.Error(testutil.Callers(), diff)
+			}
+		})
+	}
+}
+
+func TestDocSettings(t *testing.T) {
+	got, err := docSettings([]byte(`{"Settings":{"page_setup":{"page_size":"A5","grayscale":true},"footer":{"html":"PHA+MTwvcD4=","spacing":5}},"Pages":[]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := testutil.Diff(got, renderSettings{
+		PageSetup: model.PageSetup{PageSize: "A5", Grayscale: true},
+		Footer:    &model.HeaderFooter{Html: []byte("<p>1</p>"), Spacing: 5},
+	})
+	if diff != "" {
+		t.Error(testutil.Callers(), diff)
+	}
+}
+
+func TestDocumentPages(t *testing.T) {
+	page := base64.StdEncoding.EncodeToString([]byte("<p>1</p>"))
+	doc := []byte(`{"Pages":[{"InputFile":"cover.html"},{"Base64PageData":"` + page + `"},{"Base64PageData":"` + page + `","Settings":{"zoom":2,"no_background":true}}]}`)
+	got, err := documentPages(doc, model.PageSetup{Zoom: 1.5, PrintMediaType: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []documentPage{
+		{html: []byte("<p>1</p>"), settings: model.PageSettings{Zoom: 1.5, PrintMediaType: true}},
+		{html: []byte("<p>1</p>"), settings: model.PageSettings{Zoom: 2, PrintMediaType: true, NoBackground: true}},
+	}
+	diff := testutil.Diff(got, want)
+	if diff != "" {
+		t.Error(diff)
+	}
+	_, err = documentPages([]byte(`{"Pages":[]}`), model.PageSetup{})
+	if err == nil {
+		t.Error("want error for a document without pages")
+	}
+}
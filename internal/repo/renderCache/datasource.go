@@ -0,0 +1,105 @@
+package renderCache
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/PereRohit/util/log"
+
+	"github.com/vatsal278/html-pdf-service/internal/model"
+	"github.com/vatsal278/html-pdf-service/internal/repo/datasource"
+)
+
+// keyPrefix separates the cached documents from the templates stored in the same datasource
+const keyPrefix = "render:"
+
+// indexKey stores the keys and sizes of the cached documents, render keys always contain a colon so no document
+// is stored at it
+const indexKey = keyPrefix + "index"
+
+// indexEntry is a document of the index, the index lists the documents in the order they were stored
+type indexEntry struct {
+	Key     string    `json:"key"`
+	Size    int64     `json:"size"`
+	Expires time.Time `json:"expires"`
+}
+
+// dataSourceCache stores the documents in the datasource, the datasource expires them after the ttl. Their total
+// size is bounded by an index of the stored documents, the oldest documents are evicted beyond it.
+type dataSourceCache struct {
+	cfg model.RenderCacheConfig
+	ds  datasource.DataSource
+	now func() time.Time
+
+	// mu serializes the changes of the index made by this process
+	mu sync.Mutex
+}
+
+func NewDataSourceCache(cfg model.RenderCacheConfig, ds datasource.DataSource) RenderCache {
+	return &dataSourceCache{cfg: cfg.WithDefaults(), ds: ds, now: time.Now}
+}
+
+func (d *dataSourceCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	b, err := d.ds.GetFile(ctx, keyPrefix+key)
+	if err != nil {
+		// a missing key is an error of the datasource as well
+		return nil, false
+	}
+	return b, true
+}
+
+// Set stores the document b and evicts the oldest documents until the documents fit into the max size. A lost
+// index forgets the documents stored before, they are still expired after the ttl.
+func (d *dataSourceCache) Set(ctx context.Context, key string, b []byte) {
+	size := int64(len(b))
+	if size > d.cfg.MaxEntrySize {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := d.now()
+	var index []indexEntry
+	var total int64
+	for _, e := range d.index(ctx) {
+		if e.Key != key && now.Before(e.Expires) {
+			index = append(index, e)
+			total += e.Size
+		}
+	}
+	for len(index) > 0 && total+size > d.cfg.MaxSize {
+		err := d.ds.DeleteFile(ctx, keyPrefix+index[0].Key)
+		if err != nil {
+			log.Error(err)
+		}
+		total -= index[0].Size
+		index = index[1:]
+	}
+	err := d.ds.SaveFile(ctx, keyPrefix+key, b, d.cfg.TTLDuration())
+	if err != nil {
+		log.Error(err)
+	} else {
+		index = append(index, indexEntry{Key: key, Size: size, Expires: now.Add(d.cfg.TTLDuration())})
+	}
+	jb, err := json.Marshal(index)
+	if err == nil {
+		err = d.ds.SaveFile(ctx, indexKey, jb, d.cfg.TTLDuration())
+	}
+	if err != nil {
+		log.Error(err)
+	}
+}
+
+// index returns the stored index, a missing or unreadable index is empty
+func (d *dataSourceCache) index(ctx context.Context) []indexEntry {
+	b, err := d.ds.GetFile(ctx, indexKey)
+	if err != nil {
+		return nil
+	}
+	var index []indexEntry
+	if json.Unmarshal(b, &index) != nil {
+		return nil
+	}
+	return index
+}
@@ -0,0 +1,96 @@
+package renderCache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/PereRohit/util/testutil"
+	"github.com/golang/mock/gomock"
+
+	"github.com/vatsal278/html-pdf-service/internal/model"
+	"github.com/vatsal278/html-pdf-service/pkg/mock"
+)
+
+// storeDataSource returns a datasource keeping the files in store
+func storeDataSource(mockCtrl *gomock.Controller, store map[string][]byte) *mock.MockDataSource {
+	ds := mock.NewMockDataSource(mockCtrl)
+	ds.EXPECT().GetFile(gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(func(_ context.Context, key string) ([]byte, error) {
+		b, ok := store[key]
+		if !ok {
+			return nil, errors.New("redis: nil")
+		}
+		return b, nil
+	})
+	ds.EXPECT().SaveFile(gomock.Any(), gomock.Any(), gomock.Any(), time.Second).AnyTimes().DoAndReturn(func(_ context.Context, key string, v interface{}, _ time.Duration) error {
+		if key == "render:c" {
+			return errors.New("down")
+		}
+		store[key] = v.([]byte)
+		return nil
+	})
+	ds.EXPECT().DeleteFile(gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(func(_ context.Context, key string) error {
+		delete(store, key)
+		return nil
+	})
+	return ds
+}
+
+func TestDataSourceCache(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	ctx := context.Background()
+	store := map[string][]byte{}
+	c := NewDataSourceCache(model.RenderCacheConfig{TTL: 1000, MaxSize: 8, MaxEntrySize: 4}, storeDataSource(mockCtrl, store))
+
+	c.Set(ctx, "a", []byte("aaaa"))
+	// larger than the max entry size, not stored
+	c.Set(ctx, "b", []byte("bbbbb"))
+	// a failure of the datasource leaves the document out of the index
+	c.Set(ctx, "c", []byte("c"))
+
+	if b, ok := c.Get(ctx, "a"); !ok || string(b) != "aaaa" {
+		t.Errorf("want aaaa got %q %v", b, ok)
+	}
+	if _, ok := c.Get(ctx, "b"); ok {
+		t.Error("want a miss")
+	}
+}
+
+func TestDataSourceCache_MaxSize(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	ctx := context.Background()
+	store := map[string][]byte{}
+	now := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	c := NewDataSourceCache(model.RenderCacheConfig{TTL: 1000, MaxSize: 8, MaxEntrySize: 4}, storeDataSource(mockCtrl, store)).(*dataSourceCache)
+	c.now = func() time.Time { return now }
+
+	c.Set(ctx, "a", []byte("aaaa"))
+	c.Set(ctx, "b", []byte("bbb"))
+	// storing a document again replaces its size
+	c.Set(ctx, "b", []byte("bbbb"))
+	// the oldest document is evicted to make room
+	c.Set(ctx, "d", []byte("dd"))
+	if _, ok := store["render:a"]; ok {
+		t.Error("want a evicted")
+	}
+	diff := testutil.Diff(c.index(ctx), []indexEntry{
+		{Key: "b", Size: 4, Expires: now.Add(time.Second)},
+		{Key: "d", Size: 2, Expires: now.Add(time.Second)},
+	})
+	if diff != "" {
+		t.Error(diff)
+	}
+	// expired documents no longer count
+	now = now.Add(2 * time.Second)
+	c.Set(ctx, "e", []byte("eeee"))
+	if _, ok := store["render:b"]; !ok {
+		t.Error("want the expired b left to the datasource")
+	}
+	diff = testutil.Diff(c.index(ctx), []indexEntry{{Key: "e", Size: 4, Expires: now.Add(time.Second)}})
+	if diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,28 @@
+package renderCache
+
+import (
+	"context"
+
+	"github.com/vatsal278/html-pdf-service/internal/model"
+	"github.com/vatsal278/html-pdf-service/internal/repo/datasource"
+)
+
+//go:generate mockgen --build_flags=--mod=mod --destination=./../../../pkg/mock/mock_rendercache.go --package=mock github.com/vatsal278/html-pdf-service/internal/repo/renderCache RenderCache
+
+// RenderCache keeps rendered documents for the ttl of the config, failures of the store are cache misses
+type RenderCache interface {
+	Get(ctx context.Context, key string) ([]byte, bool)
+	// Set stores the document b at key, documents larger than the max entry size are not stored
+	Set(ctx context.Context, key string, b []byte)
+}
+
+// NewRenderCache returns the store selected by the config, nil when the cache is off
+func NewRenderCache(cfg model.RenderCacheConfig, ds datasource.DataSource) RenderCache {
+	switch cfg.Store {
+	case model.CacheStoreMemory:
+		return NewMemoryCache(cfg)
+	case model.CacheStoreDataSource:
+		return NewDataSourceCache(cfg, ds)
+	}
+	return nil
+}
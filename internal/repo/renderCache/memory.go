@@ -0,0 +1,74 @@
+package renderCache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/vatsal278/html-pdf-service/internal/model"
+)
+
+type memoryEntry struct {
+	key     string
+	b       []byte
+	expires time.Time
+}
+
+// memoryCache is a least recently used cache bounded by the total size of the documents
+type memoryCache struct {
+	cfg model.RenderCacheConfig
+	now func() time.Time
+
+	mu      sync.Mutex
+	size    int64
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+func NewMemoryCache(cfg model.RenderCacheConfig) RenderCache {
+	return &memoryCache{
+		cfg:     cfg.WithDefaults(),
+		now:     time.Now,
+		lru:     list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+func (m *memoryCache) Get(_ context.Context, key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*memoryEntry)
+	if !m.now().Before(e.expires) {
+		m.remove(el)
+		return nil, false
+	}
+	m.lru.MoveToFront(el)
+	return e.b, true
+}
+
+func (m *memoryCache) Set(_ context.Context, key string, b []byte) {
+	if int64(len(b)) > m.cfg.MaxEntrySize {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if el, ok := m.entries[key]; ok {
+		m.remove(el)
+	}
+	m.entries[key] = m.lru.PushFront(&memoryEntry{key: key, b: b, expires: m.now().Add(m.cfg.TTLDuration())})
+	m.size += int64(len(b))
+	for m.size > m.cfg.MaxSize {
+		m.remove(m.lru.Back())
+	}
+}
+
+func (m *memoryCache) remove(el *list.Element) {
+	e := m.lru.Remove(el).(*memoryEntry)
+	delete(m.entries, e.key)
+	m.size -= int64(len(e.b))
+}
@@ -0,0 +1,75 @@
+package renderCache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vatsal278/html-pdf-service/internal/model"
+)
+
+func TestMemoryCache(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	c := NewMemoryCache(model.RenderCacheConfig{TTL: 1000, MaxSize: 10, MaxEntrySize: 6}).(*memoryCache)
+	c.now = func() time.Time { return now }
+
+	c.Set(ctx, "a", []byte("aaaa"))
+	c.Set(ctx, "b", []byte("bbbb"))
+	if b, ok := c.Get(ctx, "a"); !ok || string(b) != "aaaa" {
+		t.Fatalf("want aaaa got %q %v", b, ok)
+	}
+	// a was used last, b is evicted to make room for c
+	c.Set(ctx, "c", []byte("cccc"))
+	if _, ok := c.Get(ctx, "b"); ok {
+		t.Error("want b evicted")
+	}
+	if _, ok := c.Get(ctx, "a"); !ok {
+		t.Error("want a cached")
+	}
+	c.Set(ctx, "d", []byte("ddddddd"))
+	if _, ok := c.Get(ctx, "d"); ok {
+		t.Error("want an entry larger than the max entry size not cached")
+	}
+	c.Set(ctx, "a", []byte("a"))
+	if b, _ := c.Get(ctx, "a"); string(b) != "a" || c.size != 5 {
+		t.Errorf("want a replaced got %q size %d", b, c.size)
+	}
+	now = now.Add(time.Second)
+	if _, ok := c.Get(ctx, "c"); ok {
+		t.Error("want c expired")
+	}
+	if c.size != 1 || c.lru.Len() != 1 {
+		t.Errorf("want the expired entry removed got size %d entries %d", c.size, c.lru.Len())
+	}
+}
+
+func TestNewRenderCache(t *testing.T) {
+	tests := []struct {
+		store string
+		want  interface{}
+	}{
+		{store: "", want: nil},
+		{store: model.CacheStoreMemory, want: &memoryCache{}},
+		{store: model.CacheStoreDataSource, want: &dataSourceCache{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.store, func(t *testing.T) {
+			got := NewRenderCache(model.RenderCacheConfig{Store: tt.store}, nil)
+			switch tt.want.(type) {
+			case nil:
+				if got != nil {
+					t.Errorf("want no cache got %T", got)
+				}
+			case *memoryCache:
+				if _, ok := got.(*memoryCache); !ok {
+					t.Errorf("want memory cache got %T", got)
+				}
+			case *dataSourceCache:
+				if _, ok := got.(*dataSourceCache); !ok {
+					t.Errorf("want datasource cache got %T", got)
+				}
+			}
+		})
+	}
+}
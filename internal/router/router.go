@@ -10,7 +10,10 @@ import (
 
 	"github.com/vatsal278/html-pdf-service/internal/config"
 	"github.com/vatsal278/html-pdf-service/internal/handler"
+	"github.com/vatsal278/html-pdf-service/internal/logic"
+	"github.com/vatsal278/html-pdf-service/internal/model"
 	"github.com/vatsal278/html-pdf-service/internal/repo/datasource"
+	"github.com/vatsal278/html-pdf-service/internal/repo/renderCache"
 )
 
 func Register(svcCfg *config.SvcConfig) *mux.Router {
@@ -38,12 +41,29 @@ func Register(svcCfg *config.SvcConfig) *mux.Router {
 
 func attachHtmlPdfServiceRoutes(m *mux.Router, svcCfg *config.SvcConfig) *mux.Router {
 	dataSource := datasource.NewRedisDs(&svcCfg.CacherSvc)
-	htmlTopdfSvc := htmlToPdf.NewWkHtmlToPdfSvc()
+	// renders of pdfs and images share the worker pool of the scheduler
+	scheduler := htmlToPdf.NewScheduler(svcCfg.Scheduler)
+	// the canaries of the health check run one at a time outside the render slots
+	canaries := htmlToPdf.NewCanaryScheduler(svcCfg.Renderer.Health)
+	htmlTopdfSvc := htmlToPdf.NewSelectorSvc(svcCfg.Renderer.Default, map[string]htmlToPdf.HtmlToPdf{
+		model.RendererWkhtmltopdf: htmlToPdf.NewWkHtmlToPdfSvc(svcCfg.Renderer.Wkhtmltopdf, svcCfg.Renderer.Health, canaries),
+		model.RendererChromium:    htmlToPdf.NewChromiumSvc(svcCfg.Renderer.Chromium, svcCfg.Renderer.Health, canaries),
+		model.RendererNative:      htmlToPdf.NewNativeSvc(),
+	})
 
-	svc := handler.NewHtmlPdfService(dataSource, htmlTopdfSvc, svcCfg.MaxMemmory)
+	svc := handler.NewHtmlPdfService(dataSource, htmlToPdf.NewScheduledSvc(scheduler, htmlTopdfSvc), svcCfg.MaxMemmory, svcCfg.Limits,
+		logic.WithImageRenderer(htmlToPdf.NewScheduledRenderer(scheduler, htmlToPdf.NewWkHtmlToImageSvc())),
+		logic.WithRenderCache(renderCache.NewRenderCache(svcCfg.RenderCache, dataSource)))
 
 	m.HandleFunc("/register", svc.Upload).Methods(http.MethodPost)
 	m.HandleFunc("/generate/{id}", svc.ConvertToPdf).Methods(http.MethodPost)
+	m.HandleFunc("/preview/{id}", svc.Preview).Methods(http.MethodPost)
+	m.HandleFunc("/compose", svc.Compose).Methods(http.MethodPost)
 	m.HandleFunc("/register/{id}", svc.ReplaceHtml).Methods(http.MethodPut)
+	m.HandleFunc("/register/{id}/assets/{name:.+}", svc.ReplaceAsset).Methods(http.MethodPut)
+	m.HandleFunc("/register/{id}/assets/{name:.+}", svc.DeleteAsset).Methods(http.MethodDelete)
+	m.HandleFunc("/register/{id}/pages", svc.InsertPage).Methods(http.MethodPost)
+	m.HandleFunc("/register/{id}/pages/order", svc.ReorderPages).Methods(http.MethodPut)
+	m.HandleFunc("/register/{id}/pages/{page}", svc.RemovePage).Methods(http.MethodDelete)
 	return m
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/vatsal278/go-redis-cache/mocks"
 	"net/http"
 	"net/http/httptest"
+	"os/exec"
 	"testing"
 
 	respModel "github.com/PereRohit/util/model"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/vatsal278/html-pdf-service/internal/config"
 	"github.com/vatsal278/html-pdf-service/internal/handler"
+	"github.com/vatsal278/html-pdf-service/internal/model"
 )
 
 func TestRegister(t *testing.T) {
@@ -32,6 +34,8 @@ func TestRegister(t *testing.T) {
 				//mock cacher
 				return &config.SvcConfig{
 					ServiceRouteVersion: "v1",
+					// the native renderer is healthy without binaries installed
+					Renderer: model.RendererConfig{Default: model.RendererNative},
 					CacherSvc: config.CacherSvc{Cacher: func() redis.Cacher {
 						mockCacher := mocks.NewMockCacher(mockCtrl)
 						mockCacher.EXPECT().Health().Return("", nil)
@@ -75,14 +79,23 @@ func TestRegister(t *testing.T) {
 				if diff != "" {
 					t.Error(testutil.Callers(), diff)
 				}
+				// the message reports the binaries installed on the machine running the test
+				stat := hCdata[handler.HtmlPdfServiceName]
+				stat.Message = ""
+				hCdata[handler.HtmlPdfServiceName] = stat
 				resp.Data = hCdata
+				// the image renderer is reported as well, it is unhealthy without wkhtmltoimage
+				status := http.StatusText(http.StatusOK)
+				if _, err := exec.LookPath("wkhtmltoimage"); err != nil {
+					status = "Not OK"
+				}
 
 				diff = testutil.Diff(resp, respModel.Response{
 					Status:  http.StatusOK,
 					Message: http.StatusText(http.StatusOK),
 					Data: map[string]svcHealthStat{
 						handler.HtmlPdfServiceName: {
-							Status:  http.StatusText(http.StatusOK),
+							Status:  status,
 							Message: "",
 						},
 					},
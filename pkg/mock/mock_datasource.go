@@ -5,6 +5,7 @@
 package mock
 
 import (
+	context "context"
 	reflect "reflect"
 	time "time"
 
@@ -35,32 +36,32 @@ func (m *MockDataSource) EXPECT() *MockDataSourceMockRecorder {
 }
 
 // DeleteFile mocks base method.
-func (m *MockDataSource) DeleteFile(arg0 string) error {
+func (m *MockDataSource) DeleteFile(arg0 context.Context, arg1 string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteFile", arg0)
+	ret := m.ctrl.Call(m, "DeleteFile", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteFile indicates an expected call of DeleteFile.
-func (mr *MockDataSourceMockRecorder) DeleteFile(arg0 interface{}) *gomock.Call {
+func (mr *MockDataSourceMockRecorder) DeleteFile(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFile", reflect.TypeOf((*MockDataSource)(nil).DeleteFile), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFile", reflect.TypeOf((*MockDataSource)(nil).DeleteFile), arg0, arg1)
 }
 
 // GetFile mocks base method.
-func (m *MockDataSource) GetFile(arg0 string) ([]byte, error) {
+func (m *MockDataSource) GetFile(arg0 context.Context, arg1 string) ([]byte, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetFile", arg0)
+	ret := m.ctrl.Call(m, "GetFile", arg0, arg1)
 	ret0, _ := ret[0].([]byte)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetFile indicates an expected call of GetFile.
-func (mr *MockDataSourceMockRecorder) GetFile(arg0 interface{}) *gomock.Call {
+func (mr *MockDataSourceMockRecorder) GetFile(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFile", reflect.TypeOf((*MockDataSource)(nil).GetFile), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFile", reflect.TypeOf((*MockDataSource)(nil).GetFile), arg0, arg1)
 }
 
 // HealthCheck mocks base method.
@@ -78,15 +79,15 @@ func (mr *MockDataSourceMockRecorder) HealthCheck() *gomock.Call {
 }
 
 // SaveFile mocks base method.
-func (m *MockDataSource) SaveFile(arg0 string, arg1 interface{}, arg2 time.Duration) error {
+func (m *MockDataSource) SaveFile(arg0 context.Context, arg1 string, arg2 interface{}, arg3 time.Duration) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveFile", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "SaveFile", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SaveFile indicates an expected call of SaveFile.
-func (mr *MockDataSourceMockRecorder) SaveFile(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockDataSourceMockRecorder) SaveFile(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveFile", reflect.TypeOf((*MockDataSource)(nil).SaveFile), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveFile", reflect.TypeOf((*MockDataSource)(nil).SaveFile), arg0, arg1, arg2, arg3)
 }
@@ -34,6 +34,18 @@ func (m *MockHtmlPdfServiceHandler) EXPECT() *MockHtmlPdfServiceHandlerMockRecor
 	return m.recorder
 }
 
+// Compose mocks base method.
+func (m *MockHtmlPdfServiceHandler) Compose(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Compose", arg0, arg1)
+}
+
+// Compose indicates an expected call of Compose.
+func (mr *MockHtmlPdfServiceHandlerMockRecorder) Compose(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Compose", reflect.TypeOf((*MockHtmlPdfServiceHandler)(nil).Compose), arg0, arg1)
+}
+
 // ConvertToPdf mocks base method.
 func (m *MockHtmlPdfServiceHandler) ConvertToPdf(arg0 http.ResponseWriter, arg1 *http.Request) {
 	m.ctrl.T.Helper()
@@ -46,6 +58,18 @@ func (mr *MockHtmlPdfServiceHandlerMockRecorder) ConvertToPdf(arg0, arg1 interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConvertToPdf", reflect.TypeOf((*MockHtmlPdfServiceHandler)(nil).ConvertToPdf), arg0, arg1)
 }
 
+// DeleteAsset mocks base method.
+func (m *MockHtmlPdfServiceHandler) DeleteAsset(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "DeleteAsset", arg0, arg1)
+}
+
+// DeleteAsset indicates an expected call of DeleteAsset.
+func (mr *MockHtmlPdfServiceHandlerMockRecorder) DeleteAsset(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAsset", reflect.TypeOf((*MockHtmlPdfServiceHandler)(nil).DeleteAsset), arg0, arg1)
+}
+
 // HealthCheck mocks base method.
 func (m *MockHtmlPdfServiceHandler) HealthCheck() (string, string, bool) {
 	m.ctrl.T.Helper()
@@ -62,6 +86,66 @@ func (mr *MockHtmlPdfServiceHandlerMockRecorder) HealthCheck() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HealthCheck", reflect.TypeOf((*MockHtmlPdfServiceHandler)(nil).HealthCheck))
 }
 
+// InsertPage mocks base method.
+func (m *MockHtmlPdfServiceHandler) InsertPage(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "InsertPage", arg0, arg1)
+}
+
+// InsertPage indicates an expected call of InsertPage.
+func (mr *MockHtmlPdfServiceHandlerMockRecorder) InsertPage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertPage", reflect.TypeOf((*MockHtmlPdfServiceHandler)(nil).InsertPage), arg0, arg1)
+}
+
+// Preview mocks base method.
+func (m *MockHtmlPdfServiceHandler) Preview(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Preview", arg0, arg1)
+}
+
+// Preview indicates an expected call of Preview.
+func (mr *MockHtmlPdfServiceHandlerMockRecorder) Preview(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Preview", reflect.TypeOf((*MockHtmlPdfServiceHandler)(nil).Preview), arg0, arg1)
+}
+
+// RemovePage mocks base method.
+func (m *MockHtmlPdfServiceHandler) RemovePage(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RemovePage", arg0, arg1)
+}
+
+// RemovePage indicates an expected call of RemovePage.
+func (mr *MockHtmlPdfServiceHandlerMockRecorder) RemovePage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemovePage", reflect.TypeOf((*MockHtmlPdfServiceHandler)(nil).RemovePage), arg0, arg1)
+}
+
+// ReorderPages mocks base method.
+func (m *MockHtmlPdfServiceHandler) ReorderPages(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ReorderPages", arg0, arg1)
+}
+
+// ReorderPages indicates an expected call of ReorderPages.
+func (mr *MockHtmlPdfServiceHandlerMockRecorder) ReorderPages(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReorderPages", reflect.TypeOf((*MockHtmlPdfServiceHandler)(nil).ReorderPages), arg0, arg1)
+}
+
+// ReplaceAsset mocks base method.
+func (m *MockHtmlPdfServiceHandler) ReplaceAsset(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ReplaceAsset", arg0, arg1)
+}
+
+// ReplaceAsset indicates an expected call of ReplaceAsset.
+func (mr *MockHtmlPdfServiceHandlerMockRecorder) ReplaceAsset(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceAsset", reflect.TypeOf((*MockHtmlPdfServiceHandler)(nil).ReplaceAsset), arg0, arg1)
+}
+
 // ReplaceHtml mocks base method.
 func (m *MockHtmlPdfServiceHandler) ReplaceHtml(arg0 http.ResponseWriter, arg1 *http.Request) {
 	m.ctrl.T.Helper()
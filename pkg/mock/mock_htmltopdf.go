@@ -1,14 +1,16 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/vatsal278/html-pdf-service/internal/repo/htmlToPdf (interfaces: HtmlToPdf)
+// Source: github.com/vatsal278/html-pdf-service/internal/repo/htmlToPdf (interfaces: HtmlToPdf,Renderer)
 
 // Package mock is a generated GoMock package.
 package mock
 
 import (
+	context "context"
 	io "io"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	htmlToPdf "github.com/vatsal278/html-pdf-service/internal/repo/htmlToPdf"
 )
 
 // MockHtmlToPdf is a mock of HtmlToPdf interface.
@@ -35,40 +37,45 @@ func (m *MockHtmlToPdf) EXPECT() *MockHtmlToPdfMockRecorder {
 }
 
 // GeneratePdf mocks base method.
-func (m *MockHtmlToPdf) GeneratePdf(arg0 io.Writer, arg1 []byte) error {
+func (m *MockHtmlToPdf) GeneratePdf(arg0 context.Context, arg1 io.Writer, arg2 []byte) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GeneratePdf", arg0, arg1)
+	ret := m.ctrl.Call(m, "GeneratePdf", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // GeneratePdf indicates an expected call of GeneratePdf.
-func (mr *MockHtmlToPdfMockRecorder) GeneratePdf(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockHtmlToPdfMockRecorder) GeneratePdf(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GeneratePdf", reflect.TypeOf((*MockHtmlToPdf)(nil).GeneratePdf), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GeneratePdf", reflect.TypeOf((*MockHtmlToPdf)(nil).GeneratePdf), arg0, arg1, arg2)
 }
 
 // GetJsonFromHtml mocks base method.
-func (m *MockHtmlToPdf) GetJsonFromHtml(arg0 []byte) ([]byte, error) {
+func (m *MockHtmlToPdf) GetJsonFromHtml(arg0 ...[]byte) ([]byte, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetJsonFromHtml", arg0)
+	varargs := []interface{}{}
+	for _, a := range arg0 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetJsonFromHtml", varargs...)
 	ret0, _ := ret[0].([]byte)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetJsonFromHtml indicates an expected call of GetJsonFromHtml.
-func (mr *MockHtmlToPdfMockRecorder) GetJsonFromHtml(arg0 interface{}) *gomock.Call {
+func (mr *MockHtmlToPdfMockRecorder) GetJsonFromHtml(arg0 ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJsonFromHtml", reflect.TypeOf((*MockHtmlToPdf)(nil).GetJsonFromHtml), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJsonFromHtml", reflect.TypeOf((*MockHtmlToPdf)(nil).GetJsonFromHtml), arg0...)
 }
 
 // HealthCheck mocks base method.
-func (m *MockHtmlToPdf) HealthCheck() bool {
+func (m *MockHtmlToPdf) HealthCheck() (string, bool) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "HealthCheck")
-	ret0, _ := ret[0].(bool)
-	return ret0
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
 }
 
 // HealthCheck indicates an expected call of HealthCheck.
@@ -76,3 +83,55 @@ func (mr *MockHtmlToPdfMockRecorder) HealthCheck() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HealthCheck", reflect.TypeOf((*MockHtmlToPdf)(nil).HealthCheck))
 }
+
+// MockRenderer is a mock of Renderer interface.
+type MockRenderer struct {
+	ctrl     *gomock.Controller
+	recorder *MockRendererMockRecorder
+}
+
+// MockRendererMockRecorder is the mock recorder for MockRenderer.
+type MockRendererMockRecorder struct {
+	mock *MockRenderer
+}
+
+// NewMockRenderer creates a new mock instance.
+func NewMockRenderer(ctrl *gomock.Controller) *MockRenderer {
+	mock := &MockRenderer{ctrl: ctrl}
+	mock.recorder = &MockRendererMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRenderer) EXPECT() *MockRendererMockRecorder {
+	return m.recorder
+}
+
+// HealthCheck mocks base method.
+func (m *MockRenderer) HealthCheck() (string, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HealthCheck")
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// HealthCheck indicates an expected call of HealthCheck.
+func (mr *MockRendererMockRecorder) HealthCheck() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HealthCheck", reflect.TypeOf((*MockRenderer)(nil).HealthCheck))
+}
+
+// Render mocks base method.
+func (m *MockRenderer) Render(arg0 context.Context, arg1 io.Writer, arg2 []byte, arg3 htmlToPdf.RenderOptions) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Render", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Render indicates an expected call of Render.
+func (mr *MockRendererMockRecorder) Render(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Render", reflect.TypeOf((*MockRenderer)(nil).Render), arg0, arg1, arg2, arg3)
+}
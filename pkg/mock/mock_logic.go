@@ -5,6 +5,7 @@
 package mock
 
 import (
+	context "context"
 	io "io"
 	reflect "reflect"
 
@@ -36,12 +37,41 @@ func (m *MockHtmlPdfServiceLogicIer) EXPECT() *MockHtmlPdfServiceLogicIerMockRec
 	return m.recorder
 }
 
+// Compose mocks base method.
+func (m *MockHtmlPdfServiceLogicIer) Compose(arg0 context.Context, arg1 io.Writer, arg2 *model0.ComposeReq) *model.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Compose", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*model.Response)
+	return ret0
+}
+
+// Compose indicates an expected call of Compose.
+func (mr *MockHtmlPdfServiceLogicIerMockRecorder) Compose(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Compose", reflect.TypeOf((*MockHtmlPdfServiceLogicIer)(nil).Compose), arg0, arg1, arg2)
+}
+
+// DeleteAsset mocks base method.
+func (m *MockHtmlPdfServiceLogicIer) DeleteAsset(arg0 context.Context, arg1, arg2 string) *model.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAsset", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*model.Response)
+	return ret0
+}
+
+// DeleteAsset indicates an expected call of DeleteAsset.
+func (mr *MockHtmlPdfServiceLogicIerMockRecorder) DeleteAsset(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAsset", reflect.TypeOf((*MockHtmlPdfServiceLogicIer)(nil).DeleteAsset), arg0, arg1, arg2)
+}
+
 // HealthCheck mocks base method.
-func (m *MockHtmlPdfServiceLogicIer) HealthCheck() bool {
+func (m *MockHtmlPdfServiceLogicIer) HealthCheck() (string, bool) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "HealthCheck")
-	ret0, _ := ret[0].(bool)
-	return ret0
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
 }
 
 // HealthCheck indicates an expected call of HealthCheck.
@@ -51,43 +81,113 @@ func (mr *MockHtmlPdfServiceLogicIerMockRecorder) HealthCheck() *gomock.Call {
 }
 
 // HtmlToPdf mocks base method.
-func (m *MockHtmlPdfServiceLogicIer) HtmlToPdf(arg0 io.Writer, arg1 *model0.GenerateReq) *model.Response {
+func (m *MockHtmlPdfServiceLogicIer) HtmlToPdf(arg0 context.Context, arg1 io.Writer, arg2 *model0.GenerateReq) *model.Response {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "HtmlToPdf", arg0, arg1)
+	ret := m.ctrl.Call(m, "HtmlToPdf", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*model.Response)
 	return ret0
 }
 
 // HtmlToPdf indicates an expected call of HtmlToPdf.
-func (mr *MockHtmlPdfServiceLogicIerMockRecorder) HtmlToPdf(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockHtmlPdfServiceLogicIerMockRecorder) HtmlToPdf(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HtmlToPdf", reflect.TypeOf((*MockHtmlPdfServiceLogicIer)(nil).HtmlToPdf), arg0, arg1, arg2)
+}
+
+// InsertPage mocks base method.
+func (m *MockHtmlPdfServiceLogicIer) InsertPage(arg0 context.Context, arg1 string, arg2 int, arg3 model0.TemplatePage) *model.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertPage", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*model.Response)
+	return ret0
+}
+
+// InsertPage indicates an expected call of InsertPage.
+func (mr *MockHtmlPdfServiceLogicIerMockRecorder) InsertPage(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HtmlToPdf", reflect.TypeOf((*MockHtmlPdfServiceLogicIer)(nil).HtmlToPdf), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertPage", reflect.TypeOf((*MockHtmlPdfServiceLogicIer)(nil).InsertPage), arg0, arg1, arg2, arg3)
+}
+
+// Preview mocks base method.
+func (m *MockHtmlPdfServiceLogicIer) Preview(arg0 context.Context, arg1 *model0.GenerateReq) *model.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Preview", arg0, arg1)
+	ret0, _ := ret[0].(*model.Response)
+	return ret0
+}
+
+// Preview indicates an expected call of Preview.
+func (mr *MockHtmlPdfServiceLogicIerMockRecorder) Preview(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Preview", reflect.TypeOf((*MockHtmlPdfServiceLogicIer)(nil).Preview), arg0, arg1)
+}
+
+// RemovePage mocks base method.
+func (m *MockHtmlPdfServiceLogicIer) RemovePage(arg0 context.Context, arg1, arg2 string) *model.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemovePage", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*model.Response)
+	return ret0
+}
+
+// RemovePage indicates an expected call of RemovePage.
+func (mr *MockHtmlPdfServiceLogicIerMockRecorder) RemovePage(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemovePage", reflect.TypeOf((*MockHtmlPdfServiceLogicIer)(nil).RemovePage), arg0, arg1, arg2)
+}
+
+// ReorderPages mocks base method.
+func (m *MockHtmlPdfServiceLogicIer) ReorderPages(arg0 context.Context, arg1 string, arg2 []int) *model.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReorderPages", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*model.Response)
+	return ret0
+}
+
+// ReorderPages indicates an expected call of ReorderPages.
+func (mr *MockHtmlPdfServiceLogicIerMockRecorder) ReorderPages(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReorderPages", reflect.TypeOf((*MockHtmlPdfServiceLogicIer)(nil).ReorderPages), arg0, arg1, arg2)
 }
 
 // Replace mocks base method.
-func (m *MockHtmlPdfServiceLogicIer) Replace(arg0 string, arg1 io.Reader) *model.Response {
+func (m *MockHtmlPdfServiceLogicIer) Replace(arg0 context.Context, arg1 string, arg2 []model0.TemplatePage, arg3 model0.TemplateSettings) *model.Response {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Replace", arg0, arg1)
+	ret := m.ctrl.Call(m, "Replace", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].(*model.Response)
 	return ret0
 }
 
 // Replace indicates an expected call of Replace.
-func (mr *MockHtmlPdfServiceLogicIerMockRecorder) Replace(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockHtmlPdfServiceLogicIerMockRecorder) Replace(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Replace", reflect.TypeOf((*MockHtmlPdfServiceLogicIer)(nil).Replace), arg0, arg1, arg2, arg3)
+}
+
+// ReplaceAsset mocks base method.
+func (m *MockHtmlPdfServiceLogicIer) ReplaceAsset(arg0 context.Context, arg1, arg2 string, arg3 io.Reader) *model.Response {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceAsset", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*model.Response)
+	return ret0
+}
+
+// ReplaceAsset indicates an expected call of ReplaceAsset.
+func (mr *MockHtmlPdfServiceLogicIerMockRecorder) ReplaceAsset(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Replace", reflect.TypeOf((*MockHtmlPdfServiceLogicIer)(nil).Replace), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceAsset", reflect.TypeOf((*MockHtmlPdfServiceLogicIer)(nil).ReplaceAsset), arg0, arg1, arg2, arg3)
 }
 
 // Upload mocks base method.
-func (m *MockHtmlPdfServiceLogicIer) Upload(arg0 io.Reader) *model.Response {
+func (m *MockHtmlPdfServiceLogicIer) Upload(arg0 context.Context, arg1 []model0.TemplatePage, arg2 model0.TemplateSettings) *model.Response {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Upload", arg0)
+	ret := m.ctrl.Call(m, "Upload", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*model.Response)
 	return ret0
 }
 
 // Upload indicates an expected call of Upload.
-func (mr *MockHtmlPdfServiceLogicIerMockRecorder) Upload(arg0 interface{}) *gomock.Call {
+func (mr *MockHtmlPdfServiceLogicIerMockRecorder) Upload(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Upload", reflect.TypeOf((*MockHtmlPdfServiceLogicIer)(nil).Upload), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Upload", reflect.TypeOf((*MockHtmlPdfServiceLogicIer)(nil).Upload), arg0, arg1, arg2)
 }
@@ -0,0 +1,62 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/vatsal278/html-pdf-service/internal/repo/renderCache (interfaces: RenderCache)
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockRenderCache is a mock of RenderCache interface.
+type MockRenderCache struct {
+	ctrl     *gomock.Controller
+	recorder *MockRenderCacheMockRecorder
+}
+
+// MockRenderCacheMockRecorder is the mock recorder for MockRenderCache.
+type MockRenderCacheMockRecorder struct {
+	mock *MockRenderCache
+}
+
+// NewMockRenderCache creates a new mock instance.
+func NewMockRenderCache(ctrl *gomock.Controller) *MockRenderCache {
+	mock := &MockRenderCache{ctrl: ctrl}
+	mock.recorder = &MockRenderCacheMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRenderCache) EXPECT() *MockRenderCacheMockRecorder {
+	return m.recorder
+}
+
+// Get mocks base method.
+func (m *MockRenderCache) Get(arg0 context.Context, arg1 string) ([]byte, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", arg0, arg1)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockRenderCacheMockRecorder) Get(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockRenderCache)(nil).Get), arg0, arg1)
+}
+
+// Set mocks base method.
+func (m *MockRenderCache) Set(arg0 context.Context, arg1 string, arg2 []byte) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Set", arg0, arg1, arg2)
+}
+
+// Set indicates an expected call of Set.
+func (mr *MockRenderCacheMockRecorder) Set(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockRenderCache)(nil).Set), arg0, arg1, arg2)
+}
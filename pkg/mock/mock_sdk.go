@@ -48,6 +48,21 @@ func (mr *MockHtmlToPdfSvcIMockRecorder) GeneratePdf(arg0, arg1 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GeneratePdf", reflect.TypeOf((*MockHtmlToPdfSvcI)(nil).GeneratePdf), arg0, arg1)
 }
 
+// Preview mocks base method.
+func (m *MockHtmlToPdfSvcI) Preview(arg0 map[string]interface{}, arg1 string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Preview", arg0, arg1)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Preview indicates an expected call of Preview.
+func (mr *MockHtmlToPdfSvcIMockRecorder) Preview(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Preview", reflect.TypeOf((*MockHtmlToPdfSvcI)(nil).Preview), arg0, arg1)
+}
+
 // Register mocks base method.
 func (m *MockHtmlToPdfSvcI) Register(arg0 []byte) (string, error) {
 	m.ctrl.T.Helper()
@@ -10,6 +10,7 @@ import (
 	"io/ioutil"
 	"mime/multipart"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -32,6 +33,7 @@ type HtmlToPdfSvcI interface {
 	Register([]byte) (string, error)
 	Replace([]byte, string) error
 	GeneratePdf(map[string]interface{}, string) ([]byte, error)
+	Preview(map[string]interface{}, string) ([]string, error)
 }
 
 func (h *htmlToPdfSvc) Register(fileBytes []byte) (string, error) {
@@ -162,3 +164,42 @@ func (h *htmlToPdfSvc) GeneratePdf(templateData map[string]interface{}, id strin
 	}
 	return filebyte, err
 }
+
+// Preview returns the executed html of every page of the template without rendering it
+func (h *htmlToPdfSvc) Preview(templateData map[string]interface{}, id string) ([]string, error) {
+	b, err := json.Marshal(GenPdfReq{
+		Values: templateData,
+	})
+	if err != nil {
+		return nil, err
+	}
+	r, err := http.NewRequest(http.MethodPost, h.svcUrl+"/v1/preview/"+id, bytes.NewBuffer(b))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	resp, err := h.client.Do(r)
+	if err != nil {
+		return nil, errors.New("Failed to make request" + err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("non success status code received : %v", resp.StatusCode)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	// single page templates are answered with the html itself
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		return []string{string(body)}, nil
+	}
+	var response struct {
+		Data []string `json:"data"`
+	}
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
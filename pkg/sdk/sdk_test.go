@@ -616,3 +616,73 @@ func Test_GeneratePdf(t *testing.T) {
 		})
 	}
 }
+
+func Test_Preview(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         map[string]interface{}
+		handler      func(w http.ResponseWriter, r *http.Request)
+		ValidateFunc func(pages []string, err error)
+	}{
+		{
+			name: "Success:: Preview:: single page",
+			data: map[string]interface{}{"Name": "A"},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				var data GenerateReq
+				err := json.NewDecoder(r.Body).Decode(&data)
+				if err != nil {
+					t.Error(err.Error())
+				}
+				if !reflect.DeepEqual(data.Values, map[string]interface{}{"Name": "A"}) {
+					t.Errorf("Want: %v, Got: %v", map[string]interface{}{"Name": "A"}, data.Values)
+				}
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				w.Write([]byte("<p>A</p>"))
+			},
+			ValidateFunc: func(pages []string, err error) {
+				if err != nil {
+					t.Errorf("Want: %v, Got: %v", nil, err.Error())
+				}
+				if !reflect.DeepEqual(pages, []string{"<p>A</p>"}) {
+					t.Errorf("Want: %v, Got: %v", []string{"<p>A</p>"}, pages)
+				}
+			},
+		},
+		{
+			name: "Success:: Preview:: several pages",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				response.ToJson(w, http.StatusOK, "SUCCESS", []string{"<p>1</p>", "<p>2</p>"})
+			},
+			ValidateFunc: func(pages []string, err error) {
+				if err != nil {
+					t.Errorf("Want: %v, Got: %v", nil, err.Error())
+				}
+				if !reflect.DeepEqual(pages, []string{"<p>1</p>", "<p>2</p>"}) {
+					t.Errorf("Want: %v, Got: %v", []string{"<p>1</p>", "<p>2</p>"}, pages)
+				}
+			},
+		},
+		{
+			name: "Failure:: Preview :: incorrect status code",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				response.ToJson(w, http.StatusBadRequest, "", nil)
+			},
+			ValidateFunc: func(pages []string, err error) {
+				if err == nil || err.Error() != "non success status code received : 400" {
+					t.Errorf("Want: %v, Got: %v", "non success status code received : 400", err)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svr := testServer("/v1/preview/{id}", http.MethodPost, tt.handler)
+			defer svr.Close()
+
+			calls := NewHtmlToPdfSvc(svr.URL)
+			pages, err := calls.Preview(tt.data, "1")
+
+			tt.ValidateFunc(pages, err)
+		})
+	}
+}